@@ -44,6 +44,124 @@ type Streamer interface {
 	Download(Song *models.Song) (io.ReadCloser, interfaces.AudioFormat, error)
 }
 
+// WebLinker returns links to items in server's web interface.
+type WebLinker interface {
+	// GetLink returns url that opens item in web browser.
+	GetLink(item models.Id) string
+}
+
+// ImageLinker returns urls of item images.
+type ImageLinker interface {
+	// GetImageUrl returns url of primary image of item. Tag identifies image version, it can be empty.
+	GetImageUrl(item models.Id, tag string) string
+}
+
+// StreamLinker returns stream urls that other players can open.
+type StreamLinker interface {
+	// StreamUrl returns url that streams song. Url contains access token.
+	StreamUrl(song *models.Song) string
+}
+
+
+// Library provides items from remote server.
+type Library interface {
+	// GetSongsById returns songs with given ids.
+	GetSongsById(ids []models.Id) ([]*models.Song, error)
+	// GetItems returns songs, albums, artists and playlists with given ids.
+	GetItems(ids []models.Id) ([]models.Item, error)
+	// GetInstantMix returns songs similar to item.
+	GetInstantMix(item models.Id) ([]*models.Song, error)
+	// Search returns at most limit items of itemType matching query.
+	Search(query string, itemType models.ItemType, limit int) ([]models.Item, error)
+	// GetNewReleases returns albums released within last days, newest first.
+	GetNewReleases(days int) ([]*models.Album, error)
+	// GetAlbumSongs returns songs of album in track order.
+	GetAlbumSongs(album models.Id) ([]*models.Song, error)
+	// GetArtistSongs returns all songs of artist.
+	GetArtistSongs(artist models.Id) ([]*models.Song, error)
+	// GetRandomSongs returns at most limit songs in random order. If item is album or artist, songs are
+	// picked from it, if empty, from whole library.
+	GetRandomSongs(item models.Item, limit int) ([]*models.Song, error)
+	// GetFavoriteAlbums returns page of favorite albums and total number of favorite albums.
+	GetFavoriteAlbums(paging models.Paging) ([]*models.Album, int, error)
+	// GetFavoriteSongs returns page of favorite songs and total number of favorite songs.
+	GetFavoriteSongs(paging models.Paging) ([]*models.Song, int, error)
+	// GetAlbums returns page of albums matching filter and total number of matching albums.
+	// Empty sort uses default order of server.
+	GetAlbums(filter models.Filter, sort models.Sort, paging models.Paging) ([]*models.Album, int, error)
+	// GetSongs returns page of songs matching filter and total number of matching songs.
+	GetSongs(filter models.Filter, sort models.Sort, paging models.Paging) ([]*models.Song, int, error)
+}
+
+// ItemLister lists all artists and playlists in library.
+type ItemLister interface {
+	// GetArtists returns all artists sorted by name.
+	GetArtists() ([]*models.Artist, error)
+	// GetPlaylists returns playlists of user sorted by name.
+	GetPlaylists() ([]*models.Playlist, error)
+}
+
+// ArtistInfo provides most played songs and similar artists of artist.
+type ArtistInfo interface {
+	// GetArtistTopSongs returns at most limit songs of artist that user has played most, most played first.
+	GetArtistTopSongs(artist models.Id, limit int) ([]*models.Song, error)
+	// GetSimilarArtists returns at most limit artists similar to artist.
+	GetSimilarArtists(artist models.Id, limit int) ([]*models.Artist, error)
+}
+
+// PlaylistEditor creates and modifies playlists in remote server.
+type PlaylistEditor interface {
+	// CreatePlaylist creates new playlist with songs and returns its id.
+	CreatePlaylist(name string, songs []models.Id) (models.Id, error)
+	// GetPlaylistSongs returns songs in playlist in order.
+	GetPlaylistSongs(playlist models.Id) ([]*models.Song, error)
+	// AddToPlaylist appends songs to playlist.
+	AddToPlaylist(playlist models.Id, songs []models.Id) error
+	// RemoveFromPlaylist removes song at index from playlist. First index is 0.
+	RemoveFromPlaylist(playlist models.Id, index int) error
+	// MovePlaylistItem moves song at index to newIndex. First index is 0.
+	MovePlaylistItem(playlist models.Id, index, newIndex int) error
+}
+
+// LibraryBrowser lists library folders in remote server.
+type LibraryBrowser interface {
+	// GetViews returns music libraries.
+	GetViews() ([]*models.View, error)
+	// GetFolder returns children of library or folder.
+	GetFolder(folder models.Id) ([]*models.FolderItem, error)
+}
+
+// UserSwitcher changes user of server connection at runtime.
+type UserSwitcher interface {
+	// SwitchUser makes further requests as given logged in user.
+	SwitchUser(user config.User) error
+}
+
+// Rater likes and dislikes items in remote server.
+type Rater interface {
+	// SetRating likes or dislikes item, or clears rating with models.RatingNone.
+	SetRating(item models.Id, rating models.Rating) error
+}
+
+// SessionController controls playback of other devices through server.
+type SessionController interface {
+	// GetSessions returns sessions that current user can control.
+	GetSessions() ([]*models.Session, error)
+	// PlayOnSession passes songs to session. With models.SessionPlayNow session starts playing song at
+	// startIndex from position.
+	PlayOnSession(session string, play models.SessionPlay, songs []models.Id, startIndex int,
+		position models.AudioTick) error
+	// SendSessionCommand sends transport command to session. Position is used only when seeking.
+	SendSessionCommand(session string, command models.SessionCommand, position models.AudioTick) error
+	// SetSessionVolume sets volume and mute of session.
+	SetSessionVolume(session string, volume models.AudioVolume, muted bool) error
+}
+
+// Favoriter marks items as favorites in remote server.
+type Favoriter interface {
+	// SetFavorite marks or unmarks item as favorite.
+	SetFavorite(item models.Id, favorite bool) error
+}
 
 // RemoteController controls audio player remotely as well as
 // keeps remote server updated on player status.
@@ -0,0 +1,79 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Errors returned by servers. Errors are wrapped with details, check them with errors.Is.
+var (
+	ErrInvalidRequest = errors.New("invalid request")
+	// ErrUnauthorized occurs if token is invalid or expired and user needs to log in again.
+	ErrUnauthorized = errors.New("needs authorization")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	// ErrServerUnavailable occurs if server cannot be reached or it fails to handle request, e.g. server
+	// task was cancelled. Request may succeed if retried.
+	ErrServerUnavailable = errors.New("server unavailable")
+	// ErrTranscodeNeeded occurs if audio is in format that cannot be played.
+	ErrTranscodeNeeded  = errors.New("audio format not supported, transcoding needed")
+	ErrUnexpectedStatus = errors.New("unexpected status code")
+)
+
+// RequestError is failed request with http status code. It unwraps to one of errors above.
+type RequestError struct {
+	Err        error
+	StatusCode int
+	Msg        string
+}
+
+// NewRequestError returns error for response status code. Message is response body, if any.
+func NewRequestError(statusCode int, msg string) *RequestError {
+	if msg == "" {
+		msg = "no body"
+	}
+	err := &RequestError{StatusCode: statusCode, Msg: msg}
+	switch {
+	case statusCode == http.StatusBadRequest:
+		err.Err = ErrInvalidRequest
+	case statusCode == http.StatusUnauthorized:
+		err.Err = ErrUnauthorized
+	case statusCode == http.StatusForbidden:
+		err.Err = ErrForbidden
+	case statusCode == http.StatusNotFound:
+		err.Err = ErrNotFound
+	case statusCode == http.StatusUnsupportedMediaType:
+		err.Err = ErrTranscodeNeeded
+	case statusCode >= 500:
+		err.Err = ErrServerUnavailable
+	default:
+		err.Err = ErrUnexpectedStatus
+	}
+	return err
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("%v, code: %d, msg: %s", e.Err, e.StatusCode, e.Msg)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
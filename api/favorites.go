@@ -0,0 +1,245 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/models"
+	"tryffel.net/go/jellycli/task"
+)
+
+const pendingFavoritesFile = "pending_favorites.json"
+
+// FavoriteServer is a server that supports favorites.
+type FavoriteServer interface {
+	Favoriter
+	Library
+	ConnectionOk() error
+}
+
+// favoriteChange is a change that couldn't be sent to server.
+type favoriteChange struct {
+	Id   models.Id `json:"id"`
+	Name string    `json:"name"`
+	// Favorite is the desired state
+	Favorite bool `json:"favorite"`
+	// Base is state of item when change was made. If server has different state at sync time,
+	// item was changed elsewhere meanwhile.
+	Base bool      `json:"base"`
+	Time time.Time `json:"time"`
+}
+
+// FavoriteSyncSummary describes result of syncing pending changes.
+type FavoriteSyncSummary struct {
+	Applied []string
+	// AlreadySet were already in desired state in server
+	AlreadySet []string
+	// Conflicts were changed in server after local change, server state is kept.
+	Conflicts []string
+	// Failed will be retried on next sync
+	Failed []string
+}
+
+func (f *FavoriteSyncSummary) String() string {
+	return fmt.Sprintf("applied %d, already set %d, conflicts %d, failed %d",
+		len(f.Applied), len(f.AlreadySet), len(f.Conflicts), len(f.Failed))
+}
+
+// FavoriteSync sets favorites in server. If server is not reachable, changes are stored locally and
+// synced once connection is back.
+type FavoriteSync struct {
+	task.Task
+	server FavoriteServer
+	file   string
+
+	lock    sync.Mutex
+	pending []favoriteChange
+}
+
+// NewFavoriteSync creates new favorite sync that keeps pending changes in dir.
+func NewFavoriteSync(server FavoriteServer, dir string) *FavoriteSync {
+	f := &FavoriteSync{
+		server: server,
+		file:   path.Join(dir, pendingFavoritesFile),
+	}
+	f.Name = "Favorite sync"
+	f.SetLoop(f.loop)
+	err := f.load()
+	if err != nil {
+		logrus.Errorf("load pending favorites: %v", err)
+	}
+	return f
+}
+
+// Set marks song as favorite. If server is unreachable, change is queued and nil is returned.
+func (f *FavoriteSync) Set(song *models.Song, favorite bool) (queued bool, err error) {
+	err = f.server.SetFavorite(song.Id, favorite)
+	if err == nil {
+		song.Favorite = favorite
+		return false, nil
+	}
+	if connErr := f.server.ConnectionOk(); connErr == nil {
+		// server is up, so this is a real error
+		return false, err
+	}
+
+	logrus.Infof("Server unreachable, queue favorite change for %s", song.Name)
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	change := favoriteChange{
+		Id:       song.Id,
+		Name:     song.Name,
+		Favorite: favorite,
+		Base:     song.Favorite,
+		Time:     time.Now(),
+	}
+	replaced := false
+	for i, v := range f.pending {
+		if v.Id == song.Id {
+			// keep original base, latest desired state
+			change.Base = v.Base
+			f.pending[i] = change
+			replaced = true
+		}
+	}
+	if !replaced {
+		f.pending = append(f.pending, change)
+	}
+	song.Favorite = favorite
+	return true, f.save()
+}
+
+// Pending returns number of changes waiting for sync.
+func (f *FavoriteSync) Pending() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return len(f.pending)
+}
+
+// Sync sends pending changes to server. Changes to items that were modified in server after local change
+// are skipped.
+func (f *FavoriteSync) Sync() (*FavoriteSyncSummary, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	summary := &FavoriteSyncSummary{}
+	if len(f.pending) == 0 {
+		return summary, nil
+	}
+
+	ids := make([]models.Id, len(f.pending))
+	for i, v := range f.pending {
+		ids[i] = v.Id
+	}
+	songs, err := f.server.GetSongsById(ids)
+	if err != nil {
+		return summary, fmt.Errorf("get current state: %v", err)
+	}
+	current := make(map[models.Id]bool, len(songs))
+	for _, v := range songs {
+		current[v.Id] = v.Favorite
+	}
+
+	remaining := []favoriteChange{}
+	for _, v := range f.pending {
+		state, ok := current[v.Id]
+		switch {
+		case !ok:
+			// removed from server
+			summary.Conflicts = append(summary.Conflicts, v.Name)
+		case state == v.Favorite:
+			summary.AlreadySet = append(summary.AlreadySet, v.Name)
+		case state != v.Base:
+			summary.Conflicts = append(summary.Conflicts, v.Name)
+		default:
+			err = f.server.SetFavorite(v.Id, v.Favorite)
+			if err != nil {
+				logrus.Errorf("sync favorite %s: %v", v.Name, err)
+				summary.Failed = append(summary.Failed, v.Name)
+				remaining = append(remaining, v)
+			} else {
+				summary.Applied = append(summary.Applied, v.Name)
+			}
+		}
+	}
+	f.pending = remaining
+	return summary, f.save()
+}
+
+// sync periodically when there are pending changes and server is reachable
+func (f *FavoriteSync) loop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.StopChan():
+			return
+		case <-ticker.C:
+			if f.Pending() == 0 || f.server.ConnectionOk() != nil {
+				continue
+			}
+			summary, err := f.Sync()
+			if err != nil {
+				logrus.Errorf("sync favorites: %v", err)
+				continue
+			}
+			logrus.Infof("Synced favorites: %s", summary)
+			if len(summary.Conflicts) > 0 {
+				logrus.Warningf("Favorites changed in server meanwhile, kept server state: %v", summary.Conflicts)
+			}
+		}
+	}
+}
+
+func (f *FavoriteSync) load() error {
+	data, err := ioutil.ReadFile(f.file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return json.Unmarshal(data, &f.pending)
+}
+
+func (f *FavoriteSync) save() error {
+	if len(f.pending) == 0 {
+		err := os.Remove(f.file)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(f.pending)
+	if err != nil {
+		return fmt.Errorf("encode json: %v", err)
+	}
+	err = os.MkdirAll(path.Dir(f.file), 0760)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(f.file, data, 0660)
+}
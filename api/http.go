@@ -0,0 +1,136 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/config"
+)
+
+// ProxyFunc returns proxy configured with config.Player.Proxy. If none is configured, proxy is read from
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+func ProxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	raw := config.AppConfig.Player.Proxy
+	if raw == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	if raw == "none" {
+		return nil, nil
+	}
+
+	proxyUrl, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse proxy url: %v", err)
+	}
+	switch strings.ToLower(proxyUrl.Scheme) {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme '%s', expected http, https or socks5", proxyUrl.Scheme)
+	}
+	return http.ProxyURL(proxyUrl), nil
+}
+
+// warnSkipVerify warns once about disabled certificate verification.
+var warnSkipVerify sync.Once
+
+// TLSConfig returns tls config with client certificate and custom CA, if configured. If none of tls
+// settings is configured, nil is returned and default settings are used.
+func TLSConfig() (*tls.Config, error) {
+	conf := config.AppConfig.Player
+	if conf.TLSClientCert == "" && conf.TLSClientKey == "" && conf.TLSCACert == "" && !conf.TLSSkipVerify {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{}
+	if conf.TLSSkipVerify {
+		warnSkipVerify.Do(func() {
+			logrus.Warning("Server certificate verification is disabled (player.tls_skip_verify), " +
+				"connection is not secure")
+		})
+		tlsConfig.InsecureSkipVerify = true
+	}
+	if conf.TLSClientCert != "" || conf.TLSClientKey != "" {
+		if conf.TLSClientCert == "" || conf.TLSClientKey == "" {
+			return nil, fmt.Errorf("client certificate requires both certificate and key")
+		}
+		cert, err := tls.LoadX509KeyPair(conf.TLSClientCert, conf.TLSClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if conf.TLSCACert != "" {
+		data, err := ioutil.ReadFile(conf.TLSCACert)
+		if err != nil {
+			return nil, fmt.Errorf("read ca certificate: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", conf.TLSCACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// RequestTimeout returns timeout for connecting to server and waiting for response.
+func RequestTimeout() time.Duration {
+	return time.Duration(config.AppConfig.Player.RequestTimeoutS) * time.Second
+}
+
+// NewHttpClient returns http client for connecting to server. Client uses proxy, tls and timeout settings,
+// if configured. Timeout does not limit reading response body, so that streams are not cut.
+func NewHttpClient() (*http.Client, error) {
+	proxy, err := ProxyFunc()
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+	timeout := RequestTimeout()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = timeout
+	transport.ResponseHeaderTimeout = timeout
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &http.Client{Transport: transport}, nil
+}
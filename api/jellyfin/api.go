@@ -33,6 +33,7 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"tryffel.net/go/jellycli/api"
 	"tryffel.net/go/jellycli/config"
 	"tryffel.net/go/jellycli/interfaces"
 	"tryffel.net/go/jellycli/models"
@@ -50,14 +51,23 @@ const (
 
 type Jellyfin struct {
 	task.Task
-	host      string
-	token     string
-	userId    string
-	serverId  string
-	DeviceId  string
-	SessionId string
-	client    *http.Client
-	loggedIn  bool
+	host string
+	// authLock guards token and userId, which change when switching user
+	authLock sync.RWMutex
+	token    string
+	userId   string
+	serverId string
+	DeviceId string
+	// SessionId is id of current play session, guarded by sessionLock
+	SessionId   string
+	sessionLock sync.Mutex
+	// device and client names override defaults, if set
+	device     string
+	clientName string
+	client     *http.Client
+	loggedIn   bool
+	// users are stored users, see config.Jellyfin.Users
+	users []config.User
 	// musicView string // Removed: TUI-specific concept
 
 	player interfaces.Player
@@ -66,8 +76,26 @@ type Jellyfin struct {
 	socketLock  sync.RWMutex
 	socket      *websocket.Conn
 	socketState socketState
+	// keepAlive is interval server expects keep-alive messages at, 0 if not requested
+	keepAlive     time.Duration
+	lastKeepAlive time.Time
+	// socketProgress sends progress reports over websocket
+	socketProgress bool
 
 	remoteControlEnabled bool
+
+	// user names by id, for annotating remotely queued songs
+	userLock  sync.Mutex
+	userNames map[string]string
+
+	// cache keeps recently fetched items
+	cache *itemCache
+
+	// musicViews are names or ids of views to use, from config. Empty means whole library.
+	musicViews []string
+	viewLock   sync.Mutex
+	// viewIds are resolved ids of musicViews
+	viewIds []string
 }
 
 func (jf *Jellyfin) AuthOk() error {
@@ -78,6 +106,20 @@ func (jf *Jellyfin) GetId() string {
 	return jf.serverId
 }
 
+// GetLink returns url of item details page in Jellyfin web client.
+func (jf *Jellyfin) GetLink(item models.Id) string {
+	return fmt.Sprintf("%s/web/index.html#!/details?id=%s&serverId=%s", jf.host, item, jf.serverId)
+}
+
+// GetImageUrl returns url of primary image of item.
+func (jf *Jellyfin) GetImageUrl(item models.Id, tag string) string {
+	url := fmt.Sprintf("%s/Items/%s/Images/Primary?maxHeight=%d", jf.host, item, config.ArtMaxSize)
+	if tag != "" {
+		url += "&tag=" + tag
+	}
+	return url
+}
+
 func (jf *Jellyfin) GetInfo() (*models.ServerInfo, error) {
 	info := &models.ServerInfo{
 		ServerType: "Jellyfin",
@@ -133,8 +175,14 @@ func (jf *Jellyfin) RemoteControlEnabled() error {
 }
 
 func NewJellyfin(conf *config.Jellyfin, provider config.KeyValueProvider) (*Jellyfin, error) {
+	client, err := api.NewHttpClient()
+	if err != nil {
+		return nil, fmt.Errorf("http client: %v", err)
+	}
 	jf := &Jellyfin{
-		client: &http.Client{},
+		client:      client,
+		socketState: socketDisconnected,
+		cache:       newItemCache(config.CacheTimeout),
 	}
 
 	if conf != nil {
@@ -142,7 +190,11 @@ func NewJellyfin(conf *config.Jellyfin, provider config.KeyValueProvider) (*Jell
 		jf.token = conf.Token
 		jf.userId = conf.UserId
 		jf.serverId = conf.ServerId
-		// jf.musicView = conf.MusicView // Removed: TUI-specific concept
+		jf.device = conf.DeviceName
+		jf.clientName = conf.ClientName
+		jf.musicViews = conf.MusicViews
+		jf.users = conf.Users
+		jf.socketProgress = conf.SocketProgress
 	}
 
 	id, err := config.GetClientID()
@@ -181,7 +233,7 @@ func NewJellyfin(conf *config.Jellyfin, provider config.KeyValueProvider) (*Jell
 	}
 
 	var password string
-	if jf.token == "" {
+	if jf.currentToken() == "" {
 		username, err := provider.Get("jellyfin.username", false, "Username")
 		password, err = provider.Get("jellyfin.password", true, "Password")
 		if err != nil {
@@ -194,7 +246,7 @@ func NewJellyfin(conf *config.Jellyfin, provider config.KeyValueProvider) (*Jell
 	}
 
 	if err = jf.TokenOk(); err != nil {
-		if strings.Contains(err.Error(), "invalid token") {
+		if errors.Is(err, api.ErrUnauthorized) {
 			logrus.Warningf("Authentication required")
 			username, err := provider.Get("jellyfin.username", false, "Username")
 			password, err = provider.Get("jellyfin.password", true, "Password")
@@ -240,8 +292,13 @@ func (jf *Jellyfin) ConnectionOk() error {
 }
 
 func (jf *Jellyfin) TokenOk() error {
-	if jf.token == "" {
-		return errors.New("invalid token")
+	return jf.tokenOk(jf.currentToken())
+}
+
+// tokenOk checks if token is valid, without making other requests with it.
+func (jf *Jellyfin) tokenOk(token string) error {
+	if token == "" {
+		return fmt.Errorf("invalid token: %w", api.ErrUnauthorized)
 	}
 	type serverInfo struct {
 		SystemUpdateLevel string `json:"SystemUpdateLevel"`
@@ -250,16 +307,15 @@ func (jf *Jellyfin) TokenOk() error {
 	}
 
 	// check token validity
-	body, err := jf.get("/System/Info", nil)
-	if body != nil {
+	resp, err := jf.makeRequest("GET", "/System/Info", nil, nil, map[string]string{"X-Emby-Token": token})
+	var body io.ReadCloser
+	if resp != nil && resp.Body != nil {
+		body = resp.Body
 		defer body.Close()
 	}
 	if err != nil {
-		if strings.Contains(err.Error(), "Access token is invalid or expired") {
-			return fmt.Errorf("invalid token: %v", err)
-		}
-		if strings.Contains(err.Error(), "needs authorization") {
-			return fmt.Errorf("invalid token: %v", err)
+		if errors.Is(err, api.ErrUnauthorized) {
+			return fmt.Errorf("invalid token: %w", err)
 		}
 		return err
 	}
@@ -322,6 +378,58 @@ func (jf *Jellyfin) Connect() error {
 	return nil
 }
 
+// currentToken returns access token of user requests are made as.
+func (jf *Jellyfin) currentToken() string {
+	jf.authLock.RLock()
+	defer jf.authLock.RUnlock()
+	return jf.token
+}
+
+// currentUserId returns id of user requests are made as.
+func (jf *Jellyfin) currentUserId() string {
+	jf.authLock.RLock()
+	defer jf.authLock.RUnlock()
+	return jf.userId
+}
+
+// setUser sets user requests are made as.
+func (jf *Jellyfin) setUser(token, userId string) {
+	jf.authLock.Lock()
+	defer jf.authLock.Unlock()
+	jf.token, jf.userId = token, userId
+}
+
+// SwitchUser makes further requests as given user. Cached items and resolved music views depend on user,
+// so they are cleared, and session is reported again as the new user.
+func (jf *Jellyfin) SwitchUser(user config.User) error {
+	err := jf.tokenOk(user.Token)
+	if err != nil {
+		return fmt.Errorf("user %s: %v", user.Name, err)
+	}
+	jf.setUser(user.Token, user.UserId)
+	jf.cache.clear()
+	jf.viewLock.Lock()
+	jf.viewIds = nil
+	jf.viewLock.Unlock()
+	logrus.Infof("Switched to user %s", user.Name)
+	return jf.Reconnect()
+}
+
+// Reconnect restores session after server has been unreachable. Server may have been restarted and lost
+// client capabilities, so they are reported again. Websocket is reconnected on next socket check.
+func (jf *Jellyfin) Reconnect() error {
+	err := jf.ReportCapabilities()
+	if err != nil {
+		return fmt.Errorf("report capabilities: %v", err)
+	}
+	jf.socketLock.Lock()
+	if jf.socketState == socketConnected {
+		jf.socketState = socketAwaitsReconnecting
+	}
+	jf.socketLock.Unlock()
+	return nil
+}
+
 func (jf *Jellyfin) Start() error {
 	err := jf.Connect()
 	if err != nil {
@@ -331,10 +439,7 @@ func (jf *Jellyfin) Start() error {
 }
 
 func (jf *Jellyfin) loop() {
-	if jf.socket == nil {
-		return
-	}
-
+	// if socket could not be connected on start, it is reconnected below
 	pingTicker := time.NewTicker(pingPeriod)
 
 	// how often to check socket state
@@ -346,7 +451,10 @@ func (jf *Jellyfin) loop() {
 	for true {
 		select {
 		case <-jf.StopChan():
-			break
+			pingTicker.Stop()
+			socketTimer.Stop()
+			jf.closeSocket()
+			return
 		case <-pingTicker.C:
 			logrus.Tracef("Websocket send ping")
 			timeout := time.Now().Add(time.Second * 15)
@@ -364,6 +472,7 @@ func (jf *Jellyfin) loop() {
 				}
 			}
 			jf.socketLock.Unlock()
+			jf.sendKeepAlive(false)
 		// keep websocket connected if possible
 		case <-socketTimer.C:
 			jf.socketLock.RLock()
@@ -396,7 +505,15 @@ func (jf *Jellyfin) loop() {
 			}
 		}
 	}
+}
 
+// closeSocket sends close message to server, if socket is connected.
+func (jf *Jellyfin) closeSocket() {
+	jf.socketLock.Lock()
+	defer jf.socketLock.Unlock()
+	if jf.socket == nil || jf.socketState != socketConnected {
+		return
+	}
 	err := jf.socket.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
 	if err != nil {
 		logrus.Errorf("close websocket: %v", err)
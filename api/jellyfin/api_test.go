@@ -0,0 +1,141 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package jellyfin
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"tryffel.net/go/jellycli/api/jellyfin/mockserver"
+	"tryffel.net/go/jellycli/api/servertest"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+)
+
+func TestMain(m *testing.M) {
+	config.AppConfig = &config.Config{
+		ClientID: "jellycli-test",
+		Player: config.Player{
+			OutputSampleRate:      44100,
+			HttpBufferingS:        5,
+			HttpBufferingLimitMem: 20,
+			StreamRetries:         3,
+		},
+	}
+	os.Exit(m.Run())
+}
+
+// newTestClient returns client logged in to server.
+func newTestClient(t *testing.T, server *mockserver.Server) *Jellyfin {
+	t.Helper()
+	jf, err := NewJellyfin(&config.Jellyfin{
+		Url:      server.URL,
+		Token:    mockserver.Token,
+		UserId:   mockserver.UserId,
+		ServerId: mockserver.ServerId,
+	}, nil)
+	if err != nil {
+		t.Fatalf("connect mock server: %v", err)
+	}
+	return jf
+}
+
+// testSongs returns n songs, songsPerAlbum songs in each album.
+func testSongs(n, songsPerAlbum int) []*mockserver.Song {
+	songs := make([]*mockserver.Song, n)
+	for i := range songs {
+		album := i / songsPerAlbum
+		songs[i] = &mockserver.Song{
+			Id:       fmt.Sprintf("song-%03d", i),
+			Name:     fmt.Sprintf("Song %03d", i),
+			Album:    fmt.Sprintf("Album %03d", album),
+			AlbumId:  fmt.Sprintf("album-%03d", album),
+			Artist:   "Artist",
+			ArtistId: "artist-1",
+			Duration: 180,
+			Data:     make([]byte, 4096),
+		}
+	}
+	return songs
+}
+
+func TestJellyfin_Conformance(t *testing.T) {
+	server := mockserver.New()
+	defer server.Close()
+	songs := testSongs(5, 3)
+	songs[1].Name = "Unique name"
+	server.AddSongs(songs...)
+
+	servertest.Run(t, newTestClient(t, server), servertest.Fixture{
+		Song:        "song-001",
+		Album:       "album-000",
+		SearchQuery: "unique",
+	})
+}
+
+func TestJellyfin_SwitchUser(t *testing.T) {
+	server := mockserver.New()
+	defer server.Close()
+	server.AddSongs(testSongs(3, 3)...)
+	server.AddUser("other-user", "other-token")
+	jf := newTestClient(t, server)
+
+	// requests are made as current user while switching
+	stop := make(chan bool)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if _, err := jf.GetSongsById([]models.Id{"song-000"}); err != nil {
+					t.Errorf("get songs while switching user: %v", err)
+					return
+				}
+				jf.GetConfig()
+			}
+		}()
+	}
+	users := []config.User{
+		{Name: "other", UserId: "other-user", Token: "other-token"},
+		{Name: mockserver.UserName, UserId: mockserver.UserId, Token: mockserver.Token},
+	}
+	for i := 0; i < 10; i++ {
+		if err := jf.SwitchUser(users[i%2]); err != nil {
+			t.Fatalf("switch user: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	err := jf.SwitchUser(config.User{Name: "invalid", UserId: "invalid", Token: "invalid"})
+	if err == nil {
+		t.Fatal("expected switching to invalid user to fail")
+	}
+	conf := jf.GetConfig().(*config.Jellyfin)
+	if conf.Token != mockserver.Token || conf.UserId != mockserver.UserId {
+		t.Errorf("expected user to be kept after failed switch, got %s", conf.UserId)
+	}
+}
@@ -39,6 +39,33 @@ type userResponse struct {
 	UserId   string `json:"Id"`
 }
 
+// getUserName returns name of user. Names are cached.
+func (jf *Jellyfin) getUserName(id string) (string, error) {
+	jf.userLock.Lock()
+	defer jf.userLock.Unlock()
+	if name, ok := jf.userNames[id]; ok {
+		return name, nil
+	}
+
+	resp, err := jf.get("/Users/"+id, nil)
+	if resp != nil {
+		defer resp.Close()
+	}
+	if err != nil {
+		return "", err
+	}
+	user := userResponse{}
+	err = json.NewDecoder(resp).Decode(&user)
+	if err != nil {
+		return "", fmt.Errorf("decode json: %v", err)
+	}
+	if jf.userNames == nil {
+		jf.userNames = map[string]string{}
+	}
+	jf.userNames[id] = user.Name
+	return user.Name, nil
+}
+
 func (jf *Jellyfin) login(username, password string) error {
 	body := map[string]string{}
 	body["Username"] = username
@@ -73,10 +100,10 @@ func (jf *Jellyfin) login(username, password string) error {
 			return fmt.Errorf("invalid login response: %v", err)
 		}
 
-		jf.token = dto.Token
+		jf.setUser(dto.Token, dto.User.UserId)
 		jf.serverId = dto.ServerId
-		jf.userId = dto.User.UserId
 		jf.loggedIn = true
+		jf.rememberUser(config.User{Name: dto.User.Name, UserId: dto.User.UserId, Token: dto.Token})
 		break
 	case http.StatusBadRequest:
 		reason, err := ioutil.ReadAll(resp.Body)
@@ -96,12 +123,24 @@ func (jf *Jellyfin) login(username, password string) error {
 	return nil
 }
 
+// rememberUser stores logged in user, replacing old token of same user.
+func (jf *Jellyfin) rememberUser(user config.User) {
+	conf := config.Jellyfin{Users: jf.users}
+	conf.SetUser(user)
+	jf.users = conf.Users
+}
+
 func (jf *Jellyfin) GetConfig() config.Backend {
 	return &config.Jellyfin{
-		Url:       jf.host,
-		Token:     jf.token,
-		UserId:    jf.userId,
-		DeviceId:  jf.DeviceId,
-		ServerId:  jf.ServerId(),
+		Url:            jf.host,
+		Token:          jf.currentToken(),
+		UserId:         jf.currentUserId(),
+		DeviceId:       jf.DeviceId,
+		ServerId:       jf.ServerId(),
+		DeviceName:     jf.device,
+		ClientName:     jf.clientName,
+		MusicViews:     jf.musicViews,
+		Users:          jf.users,
+		SocketProgress: jf.socketProgress,
 	}
 }
@@ -0,0 +1,115 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package jellyfin
+
+import (
+	"sync"
+	"time"
+
+	"tryffel.net/go/jellycli/models"
+)
+
+// itemCache keeps items in memory for a limited time to avoid fetching same items repeatedly.
+// Items are copied in and out, so callers may modify returned items.
+type itemCache struct {
+	lock  sync.Mutex
+	ttl   time.Duration
+	items map[models.Id]cacheEntry
+}
+
+type cacheEntry struct {
+	item    models.Item
+	expires time.Time
+}
+
+func newItemCache(ttl time.Duration) *itemCache {
+	return &itemCache{
+		ttl:   ttl,
+		items: map[models.Id]cacheEntry{},
+	}
+}
+
+// get returns item if it is cached and not expired.
+func (c *itemCache) get(id models.Id) (models.Item, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, ok := c.items[id]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.items, id)
+		return nil, false
+	}
+	return copyItem(entry.item), true
+}
+
+func (c *itemCache) put(item models.Item) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := time.Now()
+	// drop expired items every now and then so that cache doesn't grow forever
+	if len(c.items) > 0 && len(c.items)%100 == 0 {
+		for id, v := range c.items {
+			if now.After(v.expires) {
+				delete(c.items, id)
+			}
+		}
+	}
+	c.items[item.GetId()] = cacheEntry{
+		item:    copyItem(item),
+		expires: now.Add(c.ttl),
+	}
+}
+
+// remove removes item, e.g. when it has been modified.
+func (c *itemCache) remove(id models.Id) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.items, id)
+}
+
+// clear removes all items.
+func (c *itemCache) clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.items = map[models.Id]cacheEntry{}
+}
+
+func copyItem(item models.Item) models.Item {
+	switch v := item.(type) {
+	case *models.Song:
+		s := *v
+		s.Artists = append([]models.IdName{}, v.Artists...)
+		return &s
+	case *models.Album:
+		a := *v
+		a.AdditionalArtists = append([]models.IdName{}, v.AdditionalArtists...)
+		return &a
+	case *models.Artist:
+		a := *v
+		return &a
+	case *models.Playlist:
+		pl := *v
+		pl.Songs = nil
+		return &pl
+	default:
+		return item
+	}
+}
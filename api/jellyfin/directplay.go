@@ -20,44 +20,170 @@ package jellyfin
 
 import (
 	"fmt"
+	"github.com/sirupsen/logrus"
 	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
 	"tryffel.net/go/jellycli/api"
 	"tryffel.net/go/jellycli/config"
 	"tryffel.net/go/jellycli/interfaces"
 	"tryffel.net/go/jellycli/models"
 )
 
+// Download downloads original file without transcoding. Format is taken from file name, or content type if
+// there is no file name, and it may be a format that cannot be played.
 func (jf *Jellyfin) Download(song *models.Song) (io.ReadCloser, interfaces.AudioFormat, error) {
-	return jf.Stream(song)
+	resp, err := jf.makeRequest(http.MethodGet, "/Items/"+song.Id.String()+"/Download", nil, nil, nil)
+	if err != nil {
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
+		return nil, interfaces.AudioFormatNil, err
+	}
+
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition"))
+	if err == nil && path.Ext(params["filename"]) != "" {
+		ext := strings.ToLower(strings.TrimPrefix(path.Ext(params["filename"]), "."))
+		return resp.Body, interfaces.AudioFormat(ext), nil
+	}
+	format, err := interfaces.MimeToAudioFormat(resp.Header.Get("Content-Type"))
+	if err != nil {
+		resp.Body.Close()
+		return nil, format, err
+	}
+	return resp.Body, format, nil
 }
 
-// GetSongDirect retrieves a direct stream URL for a song (Placeholder - needs proper implementation)
-func (jf *Jellyfin) GetSongDirect(id string, codec string) (io.ReadCloser, error) {
-	// TODO: Implement fetching song by ID and getting stream URL
-	return nil, fmt.Errorf("GetSongDirect not fully implemented for Jellyfin")
+// GetSongDirect streams song without starting a new play session, thus it does not interfere with
+// reporting currently playing song. If codec is empty, any supported format is accepted, and format that
+// cannot be decoded is requested again as transcoded.
+func (jf *Jellyfin) GetSongDirect(id string, codec string) (io.ReadCloser, interfaces.AudioFormat, error) {
+	stream, format, err := jf.getSongDirect(id, codec)
+	if err == nil && format.NeedsTranscoding() {
+		stream.Close()
+		if codec != "" {
+			return nil, format, fmt.Errorf("%w: server sent %s instead of %s", api.ErrTranscodeNeeded, format, codec)
+		}
+		logrus.Warningf("get song %s: server sent %s, request transcoded stream", id, format)
+		return jf.GetSongDirect(id, interfaces.AudioFormatMp3.Container())
+	}
+	return stream, format, err
+}
+
+func (jf *Jellyfin) getSongDirect(id string, codec string) (io.ReadCloser, interfaces.AudioFormat, error) {
+	params := jf.streamParams(codec)
+	url := jf.host + "/Audio/" + id + "/universal"
+	stream, err := api.NewStreamDownload(url, map[string]string{"X-Emby-Token": jf.currentToken()}, *params, jf.client, 0)
+	if err != nil {
+		return nil, interfaces.AudioFormatNil, err
+	}
+	format, err := stream.AudioFormat()
+	if err != nil {
+		stream.Close()
+		return nil, format, fmt.Errorf("%w: %v", api.ErrTranscodeNeeded, err)
+	}
+	return stream, format, nil
 }
 
-func (jf *Jellyfin) Stream(song *models.Song) (rc io.ReadCloser, format interfaces.AudioFormat, err error) {
+// StreamUrl returns url of universal audio endpoint for song, authenticated with api key.
+func (jf *Jellyfin) StreamUrl(song *models.Song) string {
+	values := url.Values{}
+	for k, v := range *jf.streamParams("") {
+		values.Set(k, v)
+	}
+	values.Set("api_key", jf.currentToken())
+	return jf.host + "/Audio/" + song.Id.String() + "/universal?" + values.Encode()
+}
+
+// Stream starts new play session and streams song. If server sends format that cannot be decoded, although
+// it was not accepted, transcoded stream is requested instead.
+func (jf *Jellyfin) Stream(song *models.Song) (io.ReadCloser, interfaces.AudioFormat, error) {
+	rc, format, err := jf.stream(song, "")
+	if err != nil || !format.NeedsTranscoding() {
+		return rc, format, err
+	}
+	rc.Close()
+	logrus.Warningf("stream %s: server sent %s, request transcoded stream", song.Id, format)
+	rc, format, err = jf.stream(song, interfaces.AudioFormatMp3.Container())
+	if err == nil && format.NeedsTranscoding() {
+		rc.Close()
+		return nil, format, fmt.Errorf("%w: server did not transcode %s", api.ErrTranscodeNeeded, format)
+	}
+	return rc, format, err
+}
+
+// stream streams song in one of given containers, see streamParams.
+func (jf *Jellyfin) stream(song *models.Song, container string) (rc io.ReadCloser, format interfaces.AudioFormat, err error) {
 	format = interfaces.AudioFormatNil
-	params := jf.defaultParams()
+	params := jf.streamParams(container)
 	ptr := params.ptr()
-	ptr["MaxStreamingBitrate"] = "140000000"
-	ptr["AudioSamplingRate"] = fmt.Sprint(config.AudioSamplingRate)
-	formats := ""
-	for i, v := range interfaces.SupportedAudioFormats {
-		if i > 0 {
-			formats += ","
-		}
-		formats += v.String()
-	}
-	ptr["Container"] = formats
 	// Every new request requires new playsession
-	jf.SessionId = RandomKey(20)
-	ptr["PlaySessionId"] = jf.SessionId
+	ptr["PlaySessionId"] = jf.newPlaySession()
 	url := jf.host + "/Audio/" + song.Id.String() + "/universal"
 	var stream *api.StreamBuffer
-	stream, err = api.NewStreamDownload(url, map[string]string{"X-Emby-Token": jf.token}, *params, jf.client, song.Duration)
-	rc = stream
+	stream, err = api.NewStreamDownload(url, map[string]string{"X-Emby-Token": jf.currentToken()}, *params, jf.client, song.Duration)
+	if err != nil {
+		return
+	}
 	format, err = stream.AudioFormat()
+	if err != nil {
+		// format is detected from content when decoding
+		logrus.Warningf("stream %s: %v", song.Id, err)
+		format, err = interfaces.AudioFormatNil, nil
+	} else if preferred := config.AppConfig.Player.PreferredCodec; preferred != "" && format.String() != preferred {
+		logrus.Warningf("stream %s: server sent %s instead of preferred %s", song.Id, format, preferred)
+	}
+	rc = stream
 	return
 }
+
+// newPlaySession starts new play session and returns its id.
+func (jf *Jellyfin) newPlaySession() string {
+	jf.sessionLock.Lock()
+	defer jf.sessionLock.Unlock()
+	jf.SessionId = RandomKey(20)
+	return jf.SessionId
+}
+
+// playSession returns id of current play session.
+func (jf *Jellyfin) playSession() string {
+	jf.sessionLock.Lock()
+	defer jf.sessionLock.Unlock()
+	return jf.SessionId
+}
+
+// streamParams returns params for universal audio endpoint. If container is empty, config.Player.PreferredCodec
+// or, if it's not set, all supported formats are accepted.
+func (jf *Jellyfin) streamParams(container string) *params {
+	params := jf.defaultParams()
+	ptr := params.ptr()
+	ptr["MaxStreamingBitrate"] = "140000000"
+	if limit := config.AppConfig.Player.MaxStreamingBitrate; limit > 0 {
+		ptr["MaxStreamingBitrate"] = fmt.Sprint(limit * 1000)
+		// bitrate of transcoded stream
+		ptr["AudioBitRate"] = fmt.Sprint(limit * 1000)
+	}
+	ptr["AudioSamplingRate"] = fmt.Sprint(config.AppConfig.Player.OutputSampleRate)
+	// anything else, e.g. opus and aac, is transcoded
+	transcode := interfaces.AudioFormatMp3
+	if container == "" && config.AppConfig.Player.PreferredCodec != "" {
+		transcode = interfaces.AudioFormat(config.AppConfig.Player.PreferredCodec)
+		container = transcode.Container()
+	}
+	if container == "" {
+		for i, v := range interfaces.SupportedAudioFormats {
+			if i > 0 {
+				container += ","
+			}
+			container += v.Container()
+		}
+	}
+	ptr["Container"] = container
+	ptr["TranscodingContainer"] = transcode.String()
+	ptr["TranscodingProtocol"] = "http"
+	ptr["AudioCodec"] = transcode.Codec()
+	return params
+}
@@ -0,0 +1,95 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package jellyfin
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"tryffel.net/go/jellycli/api"
+	"tryffel.net/go/jellycli/api/jellyfin/mockserver"
+	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/models"
+)
+
+func TestJellyfin_StreamUnsupportedFormat(t *testing.T) {
+	transcoded := []byte("transcoded mp3")
+	streams := map[string]func(jf *Jellyfin) (io.ReadCloser, interfaces.AudioFormat, error){
+		"stream": func(jf *Jellyfin) (io.ReadCloser, interfaces.AudioFormat, error) {
+			return jf.Stream(&models.Song{Id: "song-000", Duration: 180})
+		},
+		"direct": func(jf *Jellyfin) (io.ReadCloser, interfaces.AudioFormat, error) {
+			return jf.GetSongDirect("song-000", "")
+		},
+	}
+	tests := []struct {
+		name       string
+		mime       string
+		transcoded []byte
+		format     interfaces.AudioFormat
+		data       []byte
+		err        error
+		requests   int
+	}{
+		{name: "supported", mime: "audio/flac", format: interfaces.AudioFormatFlac, requests: 1},
+		{name: "transcoded", mime: "audio/opus", transcoded: transcoded, format: interfaces.AudioFormatMp3,
+			data: transcoded, requests: 2},
+		{name: "not transcoded", mime: "audio/aac", err: api.ErrTranscodeNeeded, requests: 2},
+	}
+	for name, stream := range streams {
+		for _, tt := range tests {
+			t.Run(name+" "+tt.name, func(t *testing.T) {
+				server := mockserver.New()
+				defer server.Close()
+				songs := testSongs(1, 1)
+				songs[0].Mime = tt.mime
+				songs[0].Transcoded = tt.transcoded
+				server.AddSongs(songs...)
+				jf := newTestClient(t, server)
+
+				reader, format, err := stream(jf)
+				if requests := server.Requests("/Audio/song-000/universal"); requests != tt.requests {
+					t.Errorf("expected %d stream requests, got %d", tt.requests, requests)
+				}
+				if tt.err != nil {
+					if !errors.Is(err, tt.err) {
+						t.Fatalf("expected error %v, got %v", tt.err, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("stream: %v", err)
+				}
+				defer reader.Close()
+				if format != tt.format {
+					t.Errorf("expected format %s, got %s", tt.format, format)
+				}
+				data, err := ioutil.ReadAll(reader)
+				if err != nil {
+					t.Fatalf("read stream: %v", err)
+				}
+				if tt.data != nil && !bytes.Equal(data, tt.data) {
+					t.Errorf("expected transcoded data, got %q", data)
+				}
+			})
+		}
+	}
+}
@@ -21,6 +21,8 @@ package jellyfin
 import (
 	"fmt"
 	"github.com/sirupsen/logrus"
+	"strings"
+	"time"
 	"tryffel.net/go/jellycli/models"
 )
 
@@ -93,6 +95,21 @@ type userData struct {
 	PlayCount  int  `json:"PlayCount"`
 	IsFavorite bool `json:"IsFavorite"`
 	Played     bool `json:"Played"`
+	// PlaybackPositionTicks is position where item was left
+	PlaybackPositionTicks int64     `json:"PlaybackPositionTicks"`
+	LastPlayedDate        time.Time `json:"LastPlayedDate"`
+	// Likes is nil if item is not rated
+	Likes *bool `json:"Likes"`
+}
+
+func (u *userData) rating() models.Rating {
+	if u.Likes == nil {
+		return models.RatingNone
+	}
+	if *u.Likes {
+		return models.RatingLike
+	}
+	return models.RatingDislike
 }
 
 type nameId struct {
@@ -170,6 +187,8 @@ type album struct {
 	Genres    []string `json:"Genres"`
 	ImageTags images   `json:"ImageTags"`
 	UserData  userData `json:"UserData"`
+	// PremiereDate is e.g. 2020-05-01T00:00:00.0000000Z
+	PremiereDate string `json:"PremiereDate"`
 }
 
 func (a *album) ExpectType() mediaItemType {
@@ -208,9 +227,27 @@ func (a *album) toAlbum() *models.Album {
 		DiscCount:         0,
 		AdditionalArtists: artists,
 		Favorite:          a.UserData.IsFavorite,
+		PremiereDate:      parseDate(a.PremiereDate),
+		Overview:          a.Overview,
+		Genres:            a.Genres,
 	}
 }
 
+// parseDate parses date from server. Zero time is returned if date is empty or invalid.
+func parseDate(date string) time.Time {
+	if date == "" {
+		return time.Time{}
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.9999999", "2006-01-02"} {
+		t, err := time.Parse(layout, date)
+		if err == nil {
+			return t
+		}
+	}
+	logrus.Debugf("invalid date from server: %s", date)
+	return time.Time{}
+}
+
 type songs struct {
 	Songs      []song `json:"Items"`
 	TotalSongs int    `json:"TotalRecordCount"`
@@ -233,10 +270,19 @@ type song struct {
 	Type           string   `json:"Type"`
 	AlbumId        string   `json:"AlbumId"`
 	Album          string   `json:"Album"`
+	AlbumArtist    string   `json:"AlbumArtist"`
 	DiscNumber     int      `json:"ParentIndexNumber"`
 	Artists        []nameId `json:"ArtistItems"`
 
-	UserData userData `json:"UserData"`
+	Genres       []string      `json:"Genres"`
+	People       []person      `json:"People"`
+	Container    string        `json:"Container"`
+	MediaSources []mediaSource `json:"MediaSources"`
+
+	UserData          userData `json:"UserData"`
+	NormalizationGain *float64 `json:"NormalizationGain"`
+	// PlaylistItemId identifies song in playlist, if song was fetched as playlist item.
+	PlaylistItemId string `json:"PlaylistItemId"`
 }
 
 func (s *song) ExpectType() mediaItemType {
@@ -258,18 +304,57 @@ func (s *song) toSong() *models.Song {
 		artists[i].Id = models.Id(v.Id)
 	}
 
+	composers := []string{}
+	for _, v := range s.People {
+		if v.Type == "Composer" {
+			composers = append(composers, v.Name)
+		}
+	}
+	bitrate := 0
+	if len(s.MediaSources) > 0 {
+		bitrate = s.MediaSources[0].Bitrate / 1000
+	}
+
 	return &models.Song{
 		Id:         models.Id(s.Id),
 		Name:       s.Name,
 		Duration:   int(s.Duration / ticksToSecond),
 		Album:      models.Id(s.AlbumId),
+		AlbumName:  s.Album,
 		Index:      s.IndexNumber,
 		DiscNumber: s.DiscNumber,
 		Artists:    artists,
 		Favorite:   s.UserData.IsFavorite,
+
+		NormalizationGain: s.NormalizationGain,
+		AlbumArtistName:   s.AlbumArtist,
+
+		Composer:  strings.Join(composers, ", "),
+		Genre:     strings.Join(s.Genres, ", "),
+		Year:      s.ProductionYear,
+		Container: s.Container,
+		Bitrate:   bitrate,
+
+		ResumePosition: int(s.UserData.PlaybackPositionTicks / ticksToSecond),
+		LastPlayed:     s.UserData.LastPlayedDate,
+		Rating:         s.UserData.rating(),
 	}
 }
 
+// songFields are additional fields requested for songs.
+const songFields = "Genres,People,MediaSources"
+
+type person struct {
+	Name string `json:"Name"`
+	Type string `json:"Type"`
+}
+
+type mediaSource struct {
+	Container string `json:"Container"`
+	// Bitrate in bps
+	Bitrate int `json:"Bitrate"`
+}
+
 type collections struct {
 	Collections []collection `json:"Items"`
 }
@@ -282,7 +367,8 @@ type collection struct {
 }
 
 type playlists struct {
-	Playlists []playlist `json:"Items"`
+	Playlists      []playlist `json:"Items"`
+	TotalPlaylists int        `json:"TotalRecordCount"`
 }
 
 func (p *playlists) Items() []models.Item {
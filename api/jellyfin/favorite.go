@@ -0,0 +1,163 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package jellyfin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"tryffel.net/go/jellycli/models"
+)
+
+// SetFavorite marks or unmarks item as favorite.
+func (jf *Jellyfin) SetFavorite(item models.Id, favorite bool) error {
+	url := fmt.Sprintf("/Users/%s/FavoriteItems/%s", jf.currentUserId(), item)
+	var err error
+	if favorite {
+		resp, postErr := jf.post(url, nil, nil)
+		if resp != nil {
+			resp.Close()
+		}
+		err = postErr
+	} else {
+		resp, deleteErr := jf.delete(url, nil)
+		if resp != nil {
+			resp.Close()
+		}
+		err = deleteErr
+	}
+	jf.cache.remove(item)
+	return err
+}
+
+// SetRating likes or dislikes item, or clears rating.
+func (jf *Jellyfin) SetRating(item models.Id, rating models.Rating) error {
+	url := fmt.Sprintf("/Users/%s/Items/%s/Rating", jf.currentUserId(), item)
+	var resp io.ReadCloser
+	var err error
+	if rating == models.RatingNone {
+		resp, err = jf.delete(url, nil)
+	} else {
+		params := params{}
+		params["likes"] = strconv.FormatBool(rating == models.RatingLike)
+		resp, err = jf.post(url, nil, &params)
+	}
+	if resp != nil {
+		resp.Close()
+	}
+	jf.cache.remove(item)
+	return err
+}
+
+// GetAlbums returns page of sorted albums matching filter, and total number of matching albums.
+func (jf *Jellyfin) GetAlbums(filter models.Filter, sort models.Sort, paging models.Paging) ([]*models.Album,
+	int, error) {
+	dto := albums{}
+	err := jf.getItemPage(mediaTypeAlbum, filter, sort, paging, &dto)
+	if err != nil {
+		return []*models.Album{}, 0, err
+	}
+	albums := make([]*models.Album, len(dto.Albums))
+	for i, v := range dto.Albums {
+		logInvalidType(&v, "get albums")
+		albums[i] = v.toAlbum()
+	}
+	return albums, dto.TotalAlbums, nil
+}
+
+// GetSongs returns page of sorted songs matching filter, and total number of matching songs.
+func (jf *Jellyfin) GetSongs(filter models.Filter, sort models.Sort, paging models.Paging) ([]*models.Song,
+	int, error) {
+	dto := songs{}
+	err := jf.getItemPage(mediaTypeSong, filter, sort, paging, &dto)
+	if err != nil {
+		return []*models.Song{}, 0, err
+	}
+	songs := make([]*models.Song, len(dto.Songs))
+	for i, v := range dto.Songs {
+		logInvalidType(&v, "get songs")
+		songs[i] = v.toSong()
+	}
+	return songs, dto.TotalSongs, nil
+}
+
+// GetFavoriteAlbums returns page of user's favorite albums sorted by name and total number of favorite albums.
+func (jf *Jellyfin) GetFavoriteAlbums(paging models.Paging) ([]*models.Album, int, error) {
+	dto := albums{}
+	err := jf.getFavorites(mediaTypeAlbum, paging, &dto)
+	if err != nil {
+		return []*models.Album{}, 0, err
+	}
+	albums := make([]*models.Album, len(dto.Albums))
+	for i, v := range dto.Albums {
+		logInvalidType(&v, "get favorite albums")
+		albums[i] = v.toAlbum()
+	}
+	return albums, dto.TotalAlbums, nil
+}
+
+// GetFavoriteSongs returns page of user's favorite songs sorted by artist and album, and total number
+// of favorite songs.
+func (jf *Jellyfin) GetFavoriteSongs(paging models.Paging) ([]*models.Song, int, error) {
+	dto := songs{}
+	err := jf.getFavorites(mediaTypeSong, paging, &dto)
+	if err != nil {
+		return []*models.Song{}, 0, err
+	}
+	songs := make([]*models.Song, len(dto.Songs))
+	for i, v := range dto.Songs {
+		logInvalidType(&v, "get favorite songs")
+		songs[i] = v.toSong()
+	}
+	return songs, dto.TotalSongs, nil
+}
+
+// getFavorites decodes page of favorite items of itemType to dto.
+func (jf *Jellyfin) getFavorites(itemType mediaItemType, paging models.Paging, dto interface{}) error {
+	return jf.getItemPage(itemType, models.Filter{FavoriteOnly: true}, models.Sort{}, paging, dto)
+}
+
+// getItemPage decodes page of items of itemType matching filter to dto. Empty sort field sorts songs by
+// artist and album and albums by name.
+func (jf *Jellyfin) getItemPage(itemType mediaItemType, filter models.Filter, sort models.Sort,
+	paging models.Paging, dto interface{}) error {
+	params := *jf.defaultParams()
+	params.setIncludeTypes(itemType)
+	params.enableRecursive()
+	params.setPaging(paging)
+	params.setFilter(filter)
+	params.setSorting(sort, itemType)
+	if itemType == mediaTypeSong {
+		params["Fields"] = songFields
+	}
+
+	resp, err := jf.get(fmt.Sprintf("/Users/%s/Items", jf.currentUserId()), &params)
+	if resp != nil {
+		defer resp.Close()
+	}
+	if err != nil {
+		return err
+	}
+	err = json.NewDecoder(resp).Decode(dto)
+	if err != nil {
+		return fmt.Errorf("decode json: %v", err)
+	}
+	return nil
+}
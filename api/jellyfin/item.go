@@ -21,33 +21,161 @@ package jellyfin
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/config"
 	"tryffel.net/go/jellycli/models"
 )
 
-func (jf *Jellyfin) GetSongsById(ids []models.Id) ([]*models.Song, error) {
-	params := *jf.defaultParams()
-	params.setIncludeTypes(mediaTypeSong)
-	params.enableRecursive()
+// maxIdsPerQuery limits number of ids in single query, server does not accept too long id lists.
+const maxIdsPerQuery = 15
 
+// GetSongsById returns songs in same order as ids. Songs not found are omitted.
+func (jf *Jellyfin) GetSongsById(ids []models.Id) ([]*models.Song, error) {
 	if len(ids) == 0 {
 		return []*models.Song{}, fmt.Errorf("ids cannot be empty")
 	}
+	items, err := jf.GetItems(ids)
+	songs := make([]*models.Song, 0, len(items))
+	for _, v := range items {
+		if song, ok := v.(*models.Song); ok {
+			song.Index = len(songs) + 1
+			songs = append(songs, song)
+		} else {
+			logrus.Errorf("type error (get songs): expect song, got %s", v.GetType())
+		}
+	}
+	return songs, err
+}
 
-	idList := ""
-	for i, v := range ids {
-		if i > 0 {
-			idList += ","
+// GetItems returns songs, albums, artists and playlists in same order as ids. Cached items are not fetched again
+// and rest are fetched in batches. Items not found are omitted. Item of duplicate id is returned for each
+// occurrence as a separate copy.
+func (jf *Jellyfin) GetItems(ids []models.Id) ([]models.Item, error) {
+	found := make(map[models.Id]models.Item, len(ids))
+	missing := []models.Id{}
+	unique := make(map[models.Id]bool, len(ids))
+	for _, id := range ids {
+		if unique[id] {
+			continue
+		}
+		unique[id] = true
+		if item, ok := jf.cache.get(id); ok {
+			found[id] = item
+		} else {
+			missing = append(missing, id)
 		}
-		idList += v.String()
 	}
 
-	params["Ids"] = idList
+	var err error
+	for from := 0; from < len(missing); from += maxIdsPerQuery {
+		to := from + maxIdsPerQuery
+		if to > len(missing) {
+			to = len(missing)
+		}
+		var items []models.Item
+		items, err = jf.getItemsById(missing[from:to])
+		if err != nil {
+			break
+		}
+		for _, v := range items {
+			jf.cache.put(v)
+			found[v.GetId()] = v
+		}
+	}
+	if len(found) < len(unique) && err == nil {
+		logrus.Warningf("some items were not found: expect %d, got %d", len(unique), len(found))
+	}
+
+	items := make([]models.Item, 0, len(ids))
+	returned := make(map[models.Id]bool, len(found))
+	for _, id := range ids {
+		item, ok := found[id]
+		if !ok {
+			continue
+		}
+		if returned[id] {
+			item = copyItem(item)
+		}
+		returned[id] = true
+		items = append(items, item)
+	}
+	return items, err
+}
 
-	resp, err := jf.get(fmt.Sprintf("/Users/%s/Items", jf.userId), &params)
+// getItemsById fetches items with single query.
+func (jf *Jellyfin) getItemsById(ids []models.Id) ([]models.Item, error) {
+	params := *jf.defaultParams()
+	params.enableRecursive()
+	idList := make([]string, len(ids))
+	for i, v := range ids {
+		idList[i] = v.String()
+	}
+	params["Ids"] = strings.Join(idList, ",")
+	// overview is only used by albums
+	params["Fields"] = songFields + ",Overview"
+
+	resp, err := jf.get(fmt.Sprintf("/Users/%s/Items", jf.currentUserId()), &params)
 	if resp != nil {
 		defer resp.Close()
 	}
+	if err != nil {
+		return []models.Item{}, err
+	}
+
+	dto := struct {
+		Items []json.RawMessage `json:"Items"`
+	}{}
+	err = json.NewDecoder(resp).Decode(&dto)
+	if err != nil {
+		return []models.Item{}, fmt.Errorf("decode json: %v", err)
+	}
+
+	items := make([]models.Item, 0, len(dto.Items))
+	for _, raw := range dto.Items {
+		var item itemType
+		header := struct {
+			Type mediaItemType `json:"Type"`
+		}{}
+		if err := json.Unmarshal(raw, &header); err != nil {
+			return items, fmt.Errorf("decode json: %v", err)
+		}
+		switch header.Type {
+		case mediaTypeSong:
+			item = &song{}
+		case mediaTypeAlbum:
+			item = &album{}
+		case mediaTypeArtist:
+			item = &artist{}
+		case mediaTypePlaylist:
+			item = &playlist{}
+		default:
+			logrus.Debugf("get items: skip unsupported item type %s", header.Type)
+			continue
+		}
+		if err := json.Unmarshal(raw, item); err != nil {
+			return items, fmt.Errorf("decode json: %v", err)
+		}
+		items = append(items, item.ModelType())
+	}
+	return items, nil
+}
+
+// GetInstantMix returns songs similar to item, which can be song, album or artist.
+func (jf *Jellyfin) GetInstantMix(item models.Id) ([]*models.Song, error) {
+	params := *jf.defaultParams()
+	params.setLimit(config.InstantMixLimit)
+	params["Fields"] = songFields
 
+	resp, err := jf.get(fmt.Sprintf("/Items/%s/InstantMix", item), &params)
+	if resp != nil {
+		defer resp.Close()
+	}
 	if err != nil {
 		return []*models.Song{}, err
 	}
@@ -59,23 +187,237 @@ func (jf *Jellyfin) GetSongsById(ids []models.Id) ([]*models.Song, error) {
 	}
 
 	songs := make([]*models.Song, len(dto.Songs))
-
 	for i, v := range dto.Songs {
-		logInvalidType(&v, "get songs")
+		logInvalidType(&v, "get instant mix")
 		songs[i] = v.toSong()
-		songs[i].Index = i + 1
 	}
-
 	return songs, nil
 }
 
+// GetNewReleases returns albums released within given days, newest first.
+func (jf *Jellyfin) GetNewReleases(days int) ([]*models.Album, error) {
+	params := *jf.defaultParams()
+	params.setIncludeTypes(mediaTypeAlbum)
+	params.enableRecursive()
+	params["MinPremiereDate"] = time.Now().AddDate(0, 0, -days).UTC().Format(time.RFC3339)
+	params["SortBy"] = "PremiereDate,SortName"
+	params["SortOrder"] = "Descending"
+	params["Fields"] = "PremiereDate"
 
+	releases := []*models.Album{}
+	for _, p := range jf.viewParams(params, mediaTypeAlbum) {
+		albums, err := jf.getAlbums(&p, "get new releases")
+		if err != nil {
+			return releases, err
+		}
+		releases = append(releases, albums...)
+	}
+	// albums from multiple views
+	sort.SliceStable(releases, func(i, j int) bool {
+		return releases[i].PremiereDate.After(releases[j].PremiereDate)
+	})
+	return releases, nil
+}
 
+// getAlbums queries user's albums with params. Action is used for logging.
+func (jf *Jellyfin) getAlbums(params *params, action string) ([]*models.Album, error) {
+	result := []*models.Album{}
+	err := jf.getPaged(params, func(body io.Reader) (int, int, error) {
+		dto := albums{}
+		err := json.NewDecoder(body).Decode(&dto)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, v := range dto.Albums {
+			logInvalidType(&v, action)
+			result = append(result, v.toAlbum())
+		}
+		return len(dto.Albums), dto.TotalAlbums, nil
+	})
+	return result, err
+}
 
+// GetArtists returns all artists sorted by name.
+func (jf *Jellyfin) GetArtists() ([]*models.Artist, error) {
+	params := *jf.defaultParams()
+	params.setIncludeTypes(mediaTypeArtist)
+	params.enableRecursive()
+	params.setSorting(models.Sort{}, mediaTypeArtist)
+	result := []*models.Artist{}
+	err := jf.getPaged(&params, func(body io.Reader) (int, int, error) {
+		dto := artists{}
+		err := json.NewDecoder(body).Decode(&dto)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, v := range dto.Artists {
+			logInvalidType(&v, "get artists")
+			result = append(result, v.toArtist())
+		}
+		return len(dto.Artists), dto.TotalArtists, nil
+	})
+	return result, err
+}
+
+// GetPlaylists returns playlists of user sorted by name.
+func (jf *Jellyfin) GetPlaylists() ([]*models.Playlist, error) {
+	params := *jf.defaultParams()
+	params.setIncludeTypes(mediaTypePlaylist)
+	params.enableRecursive()
+	params.setSorting(models.Sort{}, mediaTypePlaylist)
+	result := []*models.Playlist{}
+	err := jf.getPaged(&params, func(body io.Reader) (int, int, error) {
+		dto := playlists{}
+		err := json.NewDecoder(body).Decode(&dto)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, v := range dto.Playlists {
+			logInvalidType(&v, "get playlists")
+			result = append(result, v.toPlaylist())
+		}
+		return len(dto.Playlists), dto.TotalPlaylists, nil
+	})
+	return result, err
+}
+
+// GetAlbumSongs returns songs of album in disc and track order.
+func (jf *Jellyfin) GetAlbumSongs(album models.Id) ([]*models.Song, error) {
+	params := *jf.defaultParams()
+	params.setIncludeTypes(mediaTypeSong)
+	params.enableRecursive()
+	params.setParentId(album.String())
+	params["SortBy"] = "ParentIndexNumber,IndexNumber,SortName"
+	return jf.getSongs(&params, "get album songs")
+}
 
+// GetArtistSongs returns all songs of artist, ordered by album.
+func (jf *Jellyfin) GetArtistSongs(artist models.Id) ([]*models.Song, error) {
+	params := *jf.defaultParams()
+	params.setIncludeTypes(mediaTypeSong)
+	params.enableRecursive()
+	params["ArtistIds"] = artist.String()
+	params["SortBy"] = "Album,ParentIndexNumber,IndexNumber,SortName"
+	return jf.getSongs(&params, "get artist songs")
+}
 
+// GetArtistTopSongs returns songs of artist that have been played most, most played first.
+// Songs that have never been played are not included.
+func (jf *Jellyfin) GetArtistTopSongs(artist models.Id, limit int) ([]*models.Song, error) {
+	params := *jf.defaultParams()
+	params.setIncludeTypes(mediaTypeSong)
+	params.enableRecursive()
+	params.setLimit(limit)
+	params["ArtistIds"] = artist.String()
+	params["Filters"] = "IsPlayed"
+	params["SortBy"] = "PlayCount,SortName"
+	params["SortOrder"] = "Descending"
+	return jf.getSongs(&params, "get artist top songs")
+}
 
+// GetSimilarArtists returns artists similar to artist.
+func (jf *Jellyfin) GetSimilarArtists(artist models.Id, limit int) ([]*models.Artist, error) {
+	params := *jf.defaultParams()
+	params.setLimit(limit)
+	resp, err := jf.get(fmt.Sprintf("/Artists/%s/Similar", artist), &params)
+	if resp != nil {
+		defer resp.Close()
+	}
+	if err != nil {
+		return []*models.Artist{}, err
+	}
 
+	dto := artists{}
+	err = json.NewDecoder(resp).Decode(&dto)
+	if err != nil {
+		return []*models.Artist{}, fmt.Errorf("decode json: %v", err)
+	}
+	result := make([]*models.Artist, len(dto.Artists))
+	for i, v := range dto.Artists {
+		result[i] = v.toArtist()
+	}
+	return result, nil
+}
 
+// GetRandomSongs returns at most limit songs in random order from album, artist or whole library
+// if item is nil.
+func (jf *Jellyfin) GetRandomSongs(item models.Item, limit int) ([]*models.Song, error) {
+	params := *jf.defaultParams()
+	params.setIncludeTypes(mediaTypeSong)
+	params.enableRecursive()
+	params.setLimit(limit)
+	params["SortBy"] = string(models.SortByRandom)
+	if item == nil {
+		songs := []*models.Song{}
+		for _, p := range jf.viewParams(params, mediaTypeSong) {
+			s, err := jf.getSongs(&p, "get random songs")
+			if err != nil {
+				return songs, err
+			}
+			songs = append(songs, s...)
+		}
+		// songs from multiple views
+		rand.Shuffle(len(songs), func(i, j int) { songs[i], songs[j] = songs[j], songs[i] })
+		if len(songs) > limit {
+			songs = songs[:limit]
+		}
+		return songs, nil
+	}
+	switch item.GetType() {
+	case models.TypeAlbum:
+		params.setParentId(item.GetId().String())
+	case models.TypeArtist:
+		params["ArtistIds"] = item.GetId().String()
+	default:
+		return []*models.Song{}, fmt.Errorf("cannot shuffle %s", item.GetType())
+	}
+	return jf.getSongs(&params, "get random songs")
+}
 
+// getSongs queries user's songs with params. Action is used for logging.
+func (jf *Jellyfin) getSongs(params *params, action string) ([]*models.Song, error) {
+	(*params)["Fields"] = songFields
+	result := []*models.Song{}
+	err := jf.getPaged(params, func(body io.Reader) (int, int, error) {
+		dto := songs{}
+		err := json.NewDecoder(body).Decode(&dto)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, v := range dto.Songs {
+			logInvalidType(&v, action)
+			result = append(result, v.toSong())
+		}
+		return len(dto.Songs), dto.TotalSongs, nil
+	})
+	return result, err
+}
 
+// getPaged queries user's items with params. Unless params has limit, items are requested page by page
+// until server has returned all of them. Decode reads single response and returns number of items in it
+// and total number of items.
+func (jf *Jellyfin) getPaged(params *params, decode func(body io.Reader) (int, int, error)) error {
+	_, limited := (*params)["Limit"]
+	paging := models.DefaultPaging()
+	for {
+		if !limited {
+			params.setPaging(paging)
+		}
+		resp, err := jf.get(fmt.Sprintf("/Users/%s/Items", jf.currentUserId()), params)
+		if err != nil {
+			if resp != nil {
+				resp.Close()
+			}
+			return err
+		}
+		n, total, err := decode(resp)
+		resp.Close()
+		if err != nil {
+			return fmt.Errorf("decode json: %v", err)
+		}
+		if limited || n == 0 || paging.Offset()+n >= total {
+			return nil
+		}
+		paging.CurrentPage++
+	}
+}
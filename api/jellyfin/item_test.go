@@ -0,0 +1,130 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package jellyfin
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"tryffel.net/go/jellycli/api/jellyfin/mockserver"
+	"tryffel.net/go/jellycli/models"
+)
+
+// checkPaged fails unless ids has want unique ids and server was asked for pages of items.
+func checkPaged(t *testing.T, server *mockserver.Server, ids []models.Id, want, pages int) {
+	t.Helper()
+	if len(ids) != want {
+		t.Errorf("expected %d items, got %d", want, len(ids))
+	}
+	seen := map[models.Id]bool{}
+	for _, v := range ids {
+		if seen[v] {
+			t.Errorf("duplicate item %s", v)
+		}
+		seen[v] = true
+	}
+	path := fmt.Sprintf("/Users/%s/Items", mockserver.UserId)
+	if n := server.Requests(path); n != pages {
+		t.Errorf("expected %d page requests, got %d", pages, n)
+	}
+}
+
+func TestJellyfin_GetAlbumSongsPaged(t *testing.T) {
+	server := mockserver.New()
+	defer server.Close()
+	server.AddSongs(testSongs(250, 250)...)
+	jf := newTestClient(t, server)
+
+	songs, err := jf.GetAlbumSongs("album-000")
+	if err != nil {
+		t.Fatalf("get album songs: %v", err)
+	}
+	ids := make([]models.Id, len(songs))
+	for i, v := range songs {
+		ids[i] = v.Id
+	}
+	checkPaged(t, server, ids, 250, 3)
+}
+
+func TestJellyfin_GetArtistSongsPaged(t *testing.T) {
+	server := mockserver.New()
+	defer server.Close()
+	server.AddSongs(testSongs(200, 10)...)
+	jf := newTestClient(t, server)
+
+	songs, err := jf.GetArtistSongs("artist-1")
+	if err != nil {
+		t.Fatalf("get artist songs: %v", err)
+	}
+	ids := make([]models.Id, len(songs))
+	for i, v := range songs {
+		ids[i] = v.Id
+	}
+	// total count ends paging without requesting empty page after last full one
+	checkPaged(t, server, ids, 200, 2)
+}
+
+func TestJellyfin_GetAlbumsPaged(t *testing.T) {
+	server := mockserver.New()
+	defer server.Close()
+	server.AddSongs(testSongs(150, 1)...)
+	jf := newTestClient(t, server)
+
+	albums, err := jf.GetNewReleases(30)
+	if err != nil {
+		t.Fatalf("get new releases: %v", err)
+	}
+	ids := make([]models.Id, len(albums))
+	for i, v := range albums {
+		ids[i] = v.Id
+	}
+	checkPaged(t, server, ids, 150, 2)
+}
+
+func TestJellyfin_GetItemsDuplicateIds(t *testing.T) {
+	server := mockserver.New()
+	defer server.Close()
+	server.AddSongs(testSongs(3, 3)...)
+	jf := newTestClient(t, server)
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	ids := []models.Id{"song-000", "song-001", "song-000", "song-002", "song-001"}
+	songs, err := jf.GetSongsById(ids)
+	if err != nil {
+		t.Fatalf("get songs: %v", err)
+	}
+	if len(songs) != len(ids) {
+		t.Fatalf("expected %d songs, got %d", len(ids), len(songs))
+	}
+	for i, v := range songs {
+		if v.Id != ids[i] {
+			t.Errorf("song %d: expected %s, got %s", i, ids[i], v.Id)
+		}
+		if v.Index != i+1 {
+			t.Errorf("song %d: expected index %d, got %d", i, i+1, v.Index)
+		}
+	}
+	for _, v := range hook.AllEntries() {
+		if v.Level <= logrus.WarnLevel {
+			t.Errorf("unexpected warning: %s", v.Message)
+		}
+	}
+}
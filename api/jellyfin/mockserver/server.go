@@ -0,0 +1,490 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+// Package mockserver implements fake Jellyfin server for integration tests. It serves canned songs
+// and albums, streams song data with range support, keeps favorites and ratings and records playback
+// reports, so that player, api and stream buffer can be tested together without real server.
+package mockserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Credentials accepted by server.
+const (
+	UserName = "user"
+	Password = "password"
+	UserId   = "mock-user-id"
+	Token    = "mock-token"
+	ServerId = "mock-server-id"
+)
+
+const ticksToSecond = int64(10000000)
+
+// Song is song served by server.
+type Song struct {
+	Id       string
+	Name     string
+	Album    string
+	AlbumId  string
+	Artist   string
+	ArtistId string
+	// Duration in seconds
+	Duration int
+	// Mime is content type of Data, defaults to audio/mpeg
+	Mime string
+	Data []byte
+	// Transcoded is served as audio/mpeg instead of Data, if set and client only accepts mp3. Otherwise
+	// Data is served regardless of accepted containers.
+	Transcoded []byte
+}
+
+// Report is playback report received from client.
+type Report struct {
+	// Event is Start, Progress or Stopped
+	Event         string
+	ItemId        string
+	PositionTicks int64
+	IsPaused      bool
+	// PlayMethod is DirectPlay or Transcode
+	PlayMethod string
+	Received   time.Time
+}
+
+// streamFailure makes next stream requests of song fail.
+type streamFailure struct {
+	// status is returned instead of data, if not 0
+	status int
+	// cutAfter aborts response after given bytes, if status is 0
+	cutAfter int
+	// times is number of requests to fail, negative fails all
+	times int
+}
+
+// Server is fake Jellyfin server. Use New to start it and Close to stop it.
+type Server struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	songs    []*Song
+	reports  []Report
+	failures map[string]*streamFailure
+	requests map[string]int
+	// tokens are accepted tokens and ids of their users
+	tokens map[string]string
+	// favorites and likes are keyed by item id, item is rated if it has likes
+	favorites map[string]bool
+	likes     map[string]bool
+}
+
+// New starts new server.
+func New() *Server {
+	s := &Server{
+		failures:  map[string]*streamFailure{},
+		requests:  map[string]int{},
+		tokens:    map[string]string{Token: UserId},
+		favorites: map[string]bool{},
+		likes:     map[string]bool{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/System/Info/Public", s.systemInfo)
+	mux.HandleFunc("/System/Info", s.auth(s.systemInfo))
+	mux.HandleFunc("/Users/authenticatebyname", s.login)
+	mux.HandleFunc("/Users/", s.auth(s.users))
+	mux.HandleFunc("/Audio/", s.auth(s.stream))
+	mux.HandleFunc("/Sessions/Playing", s.auth(s.report("Start")))
+	mux.HandleFunc("/Sessions/Playing/Progress", s.auth(s.report("Progress")))
+	mux.HandleFunc("/Sessions/Playing/Stopped", s.auth(s.report("Stopped")))
+	mux.HandleFunc("/Sessions/Capabilities/Full", s.auth(noContent))
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// AddSongs adds songs to library.
+func (s *Server) AddSongs(songs ...*Song) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.songs = append(s.songs, songs...)
+}
+
+// FailStream makes next times stream requests of song return status. Negative times fails all requests.
+func (s *Server) FailStream(id string, status int, times int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.failures[id] = &streamFailure{status: status, times: times}
+}
+
+// CutStream makes next times stream requests of song abort connection after given bytes.
+func (s *Server) CutStream(id string, after int, times int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.failures[id] = &streamFailure{cutAfter: after, times: times}
+}
+
+// Reports returns playback reports received so far, in order.
+func (s *Server) Reports() []Report {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	reports := make([]Report, len(s.reports))
+	copy(reports, s.reports)
+	return reports
+}
+
+// AddUser adds user that is accepted in addition to UserId.
+func (s *Server) AddUser(userId, token string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tokens[token] = userId
+}
+
+// Requests returns number of requests made to path.
+func (s *Server) Requests(path string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.requests[path]
+}
+
+// auth wraps handler, rejecting requests without valid token.
+func (s *Server) auth(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-Emby-Token")
+		if token == "" {
+			token = r.URL.Query().Get("api_key")
+		}
+		s.lock.Lock()
+		s.requests[r.URL.Path] += 1
+		_, ok := s.tokens[token]
+		s.lock.Unlock()
+		if !ok {
+			http.Error(w, "Access token is invalid or expired.", http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func noContent(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJson(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) systemInfo(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, map[string]interface{}{
+		"ServerName": "mockserver",
+		"Version":    "10.8.0",
+		"Id":         ServerId,
+	})
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	body := map[string]string{}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil || body["Username"] != UserName || body["PW"] != Password {
+		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		return
+	}
+	writeJson(w, map[string]interface{}{
+		"AccessToken": Token,
+		"ServerId":    ServerId,
+		"User":        map[string]string{"Name": UserName, "Id": UserId, "ServerId": ServerId},
+	})
+}
+
+// users serves /Users/{id}, /Users/{id}/Items, /Users/{id}/FavoriteItems/{item} and
+// /Users/{id}/Items/{item}/Rating.
+func (s *Server) users(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || !s.hasUser(parts[1]) {
+		http.NotFound(w, r)
+		return
+	}
+	if len(parts) == 2 {
+		name := UserName
+		if parts[1] != UserId {
+			name = parts[1]
+		}
+		writeJson(w, map[string]string{"Name": name, "Id": parts[1], "ServerId": ServerId})
+		return
+	}
+	if len(parts) == 3 && parts[2] == "Items" {
+		s.items(w, r)
+		return
+	}
+	if len(parts) == 4 && parts[2] == "FavoriteItems" {
+		s.favorite(w, r, parts[3])
+		return
+	}
+	if len(parts) == 5 && parts[2] == "Items" && parts[4] == "Rating" {
+		s.rating(w, r, parts[3])
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// hasUser returns true if user exists.
+func (s *Server) hasUser(id string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, v := range s.tokens {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// favorite marks item as favorite with POST and unmarks it with DELETE.
+func (s *Server) favorite(w http.ResponseWriter, r *http.Request, id string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.hasItem(id) {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		s.favorites[id] = true
+	case http.MethodDelete:
+		delete(s.favorites, id)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJson(w, s.userData(id))
+}
+
+// rating likes or dislikes item with POST and parameter 'likes', and clears rating with DELETE.
+func (s *Server) rating(w http.ResponseWriter, r *http.Request, id string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.hasItem(id) {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		likes, err := strconv.ParseBool(r.URL.Query().Get("likes"))
+		if err != nil {
+			http.Error(w, "invalid parameter 'likes'", http.StatusBadRequest)
+			return
+		}
+		s.likes[id] = likes
+	case http.MethodDelete:
+		delete(s.likes, id)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJson(w, s.userData(id))
+}
+
+// hasItem returns true if song or album exists. Lock must be held.
+func (s *Server) hasItem(id string) bool {
+	for _, v := range s.songs {
+		if v.Id == id || v.AlbumId == id {
+			return true
+		}
+	}
+	return false
+}
+
+// userData returns user data of item. Lock must be held.
+func (s *Server) userData(id string) map[string]interface{} {
+	data := map[string]interface{}{"IsFavorite": s.favorites[id]}
+	if likes, ok := s.likes[id]; ok {
+		data["Likes"] = likes
+	}
+	return data
+}
+
+// items serves songs or albums filtered by Ids, ParentId, ArtistIds, SearchTerm and favorite filter,
+// paged with StartIndex and Limit.
+func (s *Server) items(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	ids := map[string]bool{}
+	for _, v := range strings.Split(q.Get("Ids"), ",") {
+		if v != "" {
+			ids[v] = true
+		}
+	}
+	albumsOnly := q.Get("IncludeItemTypes") == "MusicAlbum"
+	search := strings.ToLower(q.Get("SearchTerm"))
+	favoriteOnly := false
+	for _, v := range strings.Split(q.Get("Filters"), ",") {
+		favoriteOnly = favoriteOnly || v == "IsFavorite"
+	}
+
+	s.lock.Lock()
+	items := []map[string]interface{}{}
+	seenAlbums := map[string]bool{}
+	for _, v := range s.songs {
+		if albumsOnly {
+			if seenAlbums[v.AlbumId] || (len(ids) > 0 && !ids[v.AlbumId]) ||
+				!strings.Contains(strings.ToLower(v.Album), search) || (favoriteOnly && !s.favorites[v.AlbumId]) {
+				continue
+			}
+			seenAlbums[v.AlbumId] = true
+			items = append(items, albumDto(v, s.userData(v.AlbumId)))
+			continue
+		}
+		if len(ids) > 0 && !ids[v.Id] {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(v.Name), search) || (favoriteOnly && !s.favorites[v.Id]) {
+			continue
+		}
+		if parent := q.Get("ParentId"); parent != "" && parent != v.AlbumId {
+			continue
+		}
+		if artist := q.Get("ArtistIds"); artist != "" && artist != v.ArtistId {
+			continue
+		}
+		items = append(items, songDto(v, s.userData(v.Id)))
+	}
+	s.lock.Unlock()
+
+	total := len(items)
+	start, _ := strconv.Atoi(q.Get("StartIndex"))
+	if start > total {
+		start = total
+	}
+	items = items[start:]
+	if limit, err := strconv.Atoi(q.Get("Limit")); err == nil && limit < len(items) {
+		items = items[:limit]
+	}
+	writeJson(w, map[string]interface{}{
+		"Items":            items,
+		"TotalRecordCount": total,
+	})
+}
+
+func songDto(song *Song, userData map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"Name":         song.Name,
+		"Id":           song.Id,
+		"Type":         "Audio",
+		"RunTimeTicks": int64(song.Duration) * ticksToSecond,
+		"AlbumId":      song.AlbumId,
+		"Album":        song.Album,
+		"AlbumArtist":  song.Artist,
+		"ArtistItems":  []map[string]string{{"Name": song.Artist, "Id": song.ArtistId}},
+		"UserData":     userData,
+	}
+}
+
+func albumDto(song *Song, userData map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"Name":         song.Album,
+		"Id":           song.AlbumId,
+		"Type":         "MusicAlbum",
+		"AlbumArtists": []map[string]string{{"Name": song.Artist, "Id": song.ArtistId}},
+		"UserData":     userData,
+	}
+}
+
+// stream serves /Audio/{id}/universal. Range requests are supported.
+func (s *Server) stream(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 || parts[2] != "universal" {
+		http.NotFound(w, r)
+		return
+	}
+	s.lock.Lock()
+	var song *Song
+	for _, v := range s.songs {
+		if v.Id == parts[1] {
+			song = v
+		}
+	}
+	failure := s.failures[parts[1]]
+	var fail streamFailure
+	if failure != nil && failure.times != 0 {
+		fail = *failure
+		failure.times -= 1
+	}
+	s.lock.Unlock()
+
+	if song == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if fail.status != 0 {
+		http.Error(w, "A task was canceled.", fail.status)
+		return
+	}
+	mime, data := song.Mime, song.Data
+	if mime == "" {
+		mime = "audio/mpeg"
+	}
+	if song.Transcoded != nil && r.URL.Query().Get("Container") == "mp3" {
+		mime, data = "audio/mpeg", song.Transcoded
+	}
+	w.Header().Set("Content-Type", mime)
+	if fail.cutAfter > 0 && fail.cutAfter < len(data) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(data[:fail.cutAfter])
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		// abort connection without completing response
+		panic(http.ErrAbortHandler)
+	}
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+}
+
+// report returns handler that records playback report of given event.
+func (s *Server) report(event string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := struct {
+			ItemId        string `json:"ItemId"`
+			PositionTicks int64  `json:"PositionTicks"`
+			IsPaused      bool   `json:"IsPaused"`
+			PlayMethod    string `json:"PlayMethod"`
+		}{}
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.lock.Lock()
+		s.reports = append(s.reports, Report{
+			Event:         event,
+			ItemId:        body.ItemId,
+			PositionTicks: body.PositionTicks,
+			IsPaused:      body.IsPaused,
+			PlayMethod:    body.PlayMethod,
+			Received:      time.Now(),
+		})
+		s.lock.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -19,8 +19,10 @@
 package jellyfin
 
 import (
+	"fmt"
 	"strconv"
-	// "tryffel.net/go/jellycli/interfaces" // Removed unused import
+	"strings"
+	"tryffel.net/go/jellycli/models"
 )
 
 type params map[string]string
@@ -30,7 +32,11 @@ func (p *params) ptr() map[string]string {
 	return *p
 }
 
-// setPaging removed - depends on removed models.Paging
+func (p *params) setPaging(paging models.Paging) {
+	ptr := p.ptr()
+	ptr["Limit"] = strconv.Itoa(paging.PageSize)
+	ptr["StartIndex"] = strconv.Itoa(paging.Offset())
+}
 
 func (p *params) setLimit(n int) {
 	(*p)["Limit"] = strconv.Itoa(n)
@@ -49,7 +55,75 @@ func (p *params) setParentId(id string) {
 	(*p)["ParentId"] = id
 }
 
-// setSorting removed - depends on removed models.SortMode constants/labels
-// setSortingByType removed - depends on removed models.SortMode constants/labels and models.Sort
-// setFilter removed - depends on removed models.Filter and models.FilterPlayStatus
-// appendFilter removed - only used by setFilter
+// setSorting sets sort field and order. Empty field sorts songs by artist and album and other items by name.
+func (p *params) setSorting(sort models.Sort, itemType mediaItemType) {
+	ptr := p.ptr()
+	field := ""
+	switch sort.Field {
+	case models.SortByName:
+		field = "SortName"
+	case models.SortByDate:
+		field = "PremiereDate,ProductionYear,SortName"
+	case models.SortByArtist:
+		field = "AlbumArtist,SortName"
+		if itemType == mediaTypeSong {
+			field = "AlbumArtist,Album,ParentIndexNumber,IndexNumber,SortName"
+		}
+	case models.SortByAlbum:
+		field = "Album,ParentIndexNumber,IndexNumber,SortName"
+		if itemType != mediaTypeSong {
+			field = "SortName"
+		}
+	case models.SortByPlayCount:
+		field = "PlayCount,SortName"
+	case models.SortByRandom:
+		field = "Random"
+	case models.SortByLatest:
+		field = "DateCreated,SortName"
+	case models.SortByLastPlayed:
+		field = "DatePlayed,SortName"
+	default:
+		field = "SortName"
+		if itemType == mediaTypeSong {
+			field = "AlbumArtist,Album,ParentIndexNumber,IndexNumber,SortName"
+		}
+	}
+	ptr["SortBy"] = field
+	if sort.Mode == models.SortDesc {
+		ptr["SortOrder"] = "Descending"
+	} else {
+		ptr["SortOrder"] = "Ascending"
+	}
+}
+func (p *params) setFilter(filter models.Filter) {
+	ptr := p.ptr()
+	if filter.YearFrom > 0 && filter.YearTo > 0 {
+		years := make([]string, 0, filter.YearTo-filter.YearFrom+1)
+		for y := filter.YearFrom; y <= filter.YearTo; y++ {
+			years = append(years, strconv.Itoa(y))
+		}
+		ptr["Years"] = strings.Join(years, ",")
+	} else if filter.YearFrom > 0 {
+		ptr["MinPremiereDate"] = fmt.Sprintf("%d-01-01T00:00:00Z", filter.YearFrom)
+	} else if filter.YearTo > 0 {
+		ptr["MaxPremiereDate"] = fmt.Sprintf("%d-12-31T23:59:59Z", filter.YearTo)
+	}
+	if len(filter.Genres) > 0 {
+		ptr["Genres"] = strings.Join(filter.Genres, "|")
+	}
+	if filter.FavoriteOnly {
+		p.appendFilter("IsFavorite")
+	}
+	if filter.UnplayedOnly {
+		p.appendFilter("IsUnplayed")
+	}
+}
+
+func (p *params) appendFilter(filter string) {
+	ptr := p.ptr()
+	if ptr["Filters"] == "" {
+		ptr["Filters"] = filter
+	} else {
+		ptr["Filters"] += "," + filter
+	}
+}
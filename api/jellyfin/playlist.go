@@ -0,0 +1,161 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package jellyfin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"tryffel.net/go/jellycli/models"
+)
+
+type createPlaylistRequest struct {
+	Name      string   `json:"Name"`
+	Ids       []string `json:"Ids"`
+	UserId    string   `json:"UserId"`
+	MediaType string   `json:"MediaType"`
+}
+
+type createPlaylistResponse struct {
+	Id string `json:"Id"`
+}
+
+// CreatePlaylist creates new playlist with songs and returns its id.
+func (jf *Jellyfin) CreatePlaylist(name string, songs []models.Id) (models.Id, error) {
+	ids := make([]string, len(songs))
+	for i, v := range songs {
+		ids[i] = v.String()
+	}
+	body, err := json.Marshal(&createPlaylistRequest{
+		Name:      name,
+		Ids:       ids,
+		UserId:    jf.currentUserId(),
+		MediaType: "Audio",
+	})
+	if err != nil {
+		return "", fmt.Errorf("encode json: %v", err)
+	}
+
+	resp, err := jf.post("/Playlists", &body, nil)
+	if resp != nil {
+		defer resp.Close()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	dto := createPlaylistResponse{}
+	err = json.NewDecoder(resp).Decode(&dto)
+	if err != nil {
+		return "", fmt.Errorf("decode json: %v", err)
+	}
+	return models.Id(dto.Id), nil
+}
+
+// getPlaylistItems returns songs in playlist in order.
+func (jf *Jellyfin) getPlaylistItems(playlist models.Id) ([]song, error) {
+	params := *jf.defaultParams()
+	params["Fields"] = songFields
+	resp, err := jf.get(fmt.Sprintf("/Playlists/%s/Items", playlist), &params)
+	if resp != nil {
+		defer resp.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dto := songs{}
+	err = json.NewDecoder(resp).Decode(&dto)
+	if err != nil {
+		return nil, fmt.Errorf("decode json: %v", err)
+	}
+	return dto.Songs, nil
+}
+
+// GetPlaylistSongs returns songs in playlist in order.
+func (jf *Jellyfin) GetPlaylistSongs(playlist models.Id) ([]*models.Song, error) {
+	items, err := jf.getPlaylistItems(playlist)
+	if err != nil {
+		return nil, err
+	}
+	songs := make([]*models.Song, len(items))
+	for i, v := range items {
+		logInvalidType(&v, "get playlist songs")
+		songs[i] = v.toSong()
+		songs[i].Index = i + 1
+	}
+	return songs, nil
+}
+
+// AddToPlaylist appends songs to playlist.
+func (jf *Jellyfin) AddToPlaylist(playlist models.Id, songs []models.Id) error {
+	ids := make([]string, len(songs))
+	for i, v := range songs {
+		ids[i] = v.String()
+	}
+	params := *jf.defaultParams()
+	params["Ids"] = strings.Join(ids, ",")
+	resp, err := jf.post(fmt.Sprintf("/Playlists/%s/Items", playlist), nil, &params)
+	if resp != nil {
+		resp.Close()
+	}
+	return err
+}
+
+// playlistEntryId returns id of playlist entry at index. Server identifies entries with their own ids,
+// since same song can be in playlist multiple times.
+func (jf *Jellyfin) playlistEntryId(playlist models.Id, index int) (string, error) {
+	items, err := jf.getPlaylistItems(playlist)
+	if err != nil {
+		return "", err
+	}
+	if index < 0 || index >= len(items) {
+		return "", fmt.Errorf("invalid index %d, playlist has %d songs", index+1, len(items))
+	}
+	return items[index].PlaylistItemId, nil
+}
+
+// RemoveFromPlaylist removes song at index from playlist. First index is 0.
+func (jf *Jellyfin) RemoveFromPlaylist(playlist models.Id, index int) error {
+	entry, err := jf.playlistEntryId(playlist, index)
+	if err != nil {
+		return err
+	}
+	params := *jf.defaultParams()
+	params["EntryIds"] = entry
+	resp, err := jf.delete(fmt.Sprintf("/Playlists/%s/Items", playlist), &params)
+	if resp != nil {
+		resp.Close()
+	}
+	return err
+}
+
+// MovePlaylistItem moves song at index to newIndex in playlist. First index is 0.
+func (jf *Jellyfin) MovePlaylistItem(playlist models.Id, index, newIndex int) error {
+	entry, err := jf.playlistEntryId(playlist, index)
+	if err != nil {
+		return err
+	}
+	params := *jf.defaultParams()
+	resp, err := jf.post(fmt.Sprintf("/Playlists/%s/Items/%s/Move/%d", playlist, entry, newIndex), nil, &params)
+	if resp != nil {
+		resp.Close()
+	}
+	return err
+}
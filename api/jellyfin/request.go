@@ -26,20 +26,12 @@ import (
 	"io/ioutil"
 	"net/http"
 	"time"
-)
-
-const (
-	errInvalidRequest       = "invalid request"
-	errUnexpectedStatusCode = "unexpected statuscode"
-	errServerError          = "server error"
-	errNotFound             = "page not found"
-	errUnauthorized         = "needs authorization"
-	errForbidden            = "forbidden"
+	"tryffel.net/go/jellycli/api"
 )
 
 func (jf *Jellyfin) defaultParams() *params {
 	params := *(&params{})
-	params["UserId"] = jf.userId
+	params["UserId"] = jf.currentUserId()
 	params["DeviceId"] = jf.DeviceId
 	return &params
 }
@@ -60,6 +52,14 @@ func (jf *Jellyfin) post(url string, body *[]byte, params *params) (io.ReadClose
 	return nil, err
 }
 
+func (jf *Jellyfin) delete(url string, params *params) (io.ReadCloser, error) {
+	resp, err := jf.makeRequest("DELETE", url, nil, params, nil)
+	if resp != nil {
+		return resp.Body, err
+	}
+	return nil, err
+}
+
 //Construct request
 // Set authorization header and build url query
 // Make request, parse response code and raise error if needed. Else return response body
@@ -81,7 +81,7 @@ func (jf *Jellyfin) makeRequest(method, url string, body *[]byte, params *params
 	if method == "POST" {
 		req.Header.Set("Content-Type", "application/json")
 	}
-	req.Header.Set("X-Emby-Token", jf.token)
+	req.Header.Set("X-Emby-Token", jf.currentToken())
 
 	if len(headers) > 0 {
 		for k, v := range headers {
@@ -105,7 +105,7 @@ func (jf *Jellyfin) makeRequest(method, url string, body *[]byte, params *params
 	start := time.Now()
 	resp, err := jf.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed make request: %v", err)
+		return nil, fmt.Errorf("failed make request: %w: %v", api.ErrServerUnavailable, err)
 	}
 	took := time.Since(start)
 	logrus.Debugf("%s %s: %d (%d ms)", req.Method, req.URL.Path, resp.StatusCode, took.Milliseconds())
@@ -114,24 +114,5 @@ func (jf *Jellyfin) makeRequest(method, url string, body *[]byte, params *params
 		return resp, nil
 	}
 	bytes, _ := ioutil.ReadAll(resp.Body)
-	var msg string = "no body"
-	if len(bytes) > 0 {
-		msg = string(bytes)
-	}
-	var errMsg string
-	switch resp.StatusCode {
-	case 400:
-		errMsg = errInvalidRequest
-	case 401:
-		errMsg = errUnauthorized
-	case 403:
-		errMsg = errForbidden
-	case 404:
-		errMsg = errNotFound
-	case 500:
-		errMsg = errServerError
-	default:
-		errMsg = errUnexpectedStatusCode
-	}
-	return resp, fmt.Errorf("%s, code: %d, msg: %s", errMsg, resp.StatusCode, msg)
+	return resp, api.NewRequestError(resp.StatusCode, string(bytes))
 }
@@ -25,6 +25,46 @@ import (
 	"tryffel.net/go/jellycli/models"
 )
 
+// Search returns items of given type whose name matches query.
+func (jf *Jellyfin) Search(query string, itemType models.ItemType, limit int) ([]models.Item, error) {
+	target := toItemType(itemType)
+	if target == "" {
+		return []models.Item{}, fmt.Errorf("cannot search items of type %s", itemType)
+	}
+	params := *jf.defaultParams()
+	params.setIncludeTypes(target)
+	params.enableRecursive()
+	params.setLimit(limit)
+	params["SearchTerm"] = query
+
+	results := []models.Item{}
+	for _, p := range jf.viewParams(params, target) {
+		if len(results) >= limit {
+			break
+		}
+		items, err := jf.search(&p, target)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, items...)
+	}
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (jf *Jellyfin) search(params *params, target mediaItemType) ([]models.Item, error) {
+	resp, err := jf.get(fmt.Sprintf("/Users/%s/Items", jf.currentUserId()), params)
+	if resp != nil {
+		defer resp.Close()
+	}
+	if err != nil {
+		return []models.Item{}, err
+	}
+	return searchDtoToItems(resp, target)
+}
+
 type SearchHint struct {
 	Id          string `json:"Id"`
 	Name        string `json:"Name"`
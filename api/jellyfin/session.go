@@ -0,0 +1,163 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package jellyfin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"tryffel.net/go/jellycli/models"
+)
+
+type sessionInfo struct {
+	Id                    string `json:"Id"`
+	DeviceId              string `json:"DeviceId"`
+	DeviceName            string `json:"DeviceName"`
+	Client                string `json:"Client"`
+	SupportsRemoteControl bool   `json:"SupportsRemoteControl"`
+	NowPlayingItem        *song  `json:"NowPlayingItem"`
+	PlayState             struct {
+		PositionTicks int64 `json:"PositionTicks"`
+		IsPaused      bool  `json:"IsPaused"`
+		IsMuted       bool  `json:"IsMuted"`
+		VolumeLevel   int   `json:"VolumeLevel"`
+	} `json:"PlayState"`
+	NowPlayingQueue []struct {
+		Id string `json:"Id"`
+	} `json:"NowPlayingQueue"`
+}
+
+func (s *sessionInfo) toSession() *models.Session {
+	session := &models.Session{
+		Id:         s.Id,
+		DeviceId:   s.DeviceId,
+		DeviceName: s.DeviceName,
+		Client:     s.Client,
+		Position:   models.AudioTick(s.PlayState.PositionTicks * 1000 / ticksToSecond),
+		Paused:     s.PlayState.IsPaused,
+		Muted:      s.PlayState.IsMuted,
+		Volume:     models.AudioVolume(s.PlayState.VolumeLevel),
+		Queue:      make([]models.Id, len(s.NowPlayingQueue)),
+	}
+	if s.NowPlayingItem != nil {
+		session.Song = s.NowPlayingItem.toSong()
+	}
+	for i, v := range s.NowPlayingQueue {
+		session.Queue[i] = models.Id(v.Id)
+	}
+	return session
+}
+
+// GetSessions returns sessions that user can control remotely. Jellycli's own session is excluded.
+func (jf *Jellyfin) GetSessions() ([]*models.Session, error) {
+	params := *jf.defaultParams()
+	delete(params, "DeviceId")
+	params["ControllableByUserId"] = jf.currentUserId()
+	resp, err := jf.get("/Sessions", &params)
+	if resp != nil {
+		defer resp.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dto := []sessionInfo{}
+	err = json.NewDecoder(resp).Decode(&dto)
+	if err != nil {
+		return nil, fmt.Errorf("decode json: %v", err)
+	}
+	sessions := make([]*models.Session, 0, len(dto))
+	for i, v := range dto {
+		if !v.SupportsRemoteControl || v.DeviceId == jf.DeviceId {
+			continue
+		}
+		sessions = append(sessions, dto[i].toSession())
+	}
+	return sessions, nil
+}
+
+// PlayOnSession passes songs to session.
+func (jf *Jellyfin) PlayOnSession(session string, play models.SessionPlay, songs []models.Id, startIndex int,
+	position models.AudioTick) error {
+	ids := make([]string, len(songs))
+	for i, v := range songs {
+		ids[i] = v.String()
+	}
+	params := params{}
+	params["playCommand"] = string(play)
+	params["itemIds"] = strings.Join(ids, ",")
+	if play == models.SessionPlayNow {
+		params["startIndex"] = strconv.Itoa(startIndex)
+		params["startPositionTicks"] = strconv.FormatInt(int64(position)*ticksToSecond/1000, 10)
+	}
+	resp, err := jf.post(fmt.Sprintf("/Sessions/%s/Playing", session), nil, &params)
+	if resp != nil {
+		resp.Close()
+	}
+	return err
+}
+
+// SendSessionCommand sends transport command to session.
+func (jf *Jellyfin) SendSessionCommand(session string, command models.SessionCommand,
+	position models.AudioTick) error {
+	params := params{}
+	if command == models.SessionSeek {
+		params["seekPositionTicks"] = strconv.FormatInt(int64(position)*ticksToSecond/1000, 10)
+	}
+	resp, err := jf.post(fmt.Sprintf("/Sessions/%s/Playing/%s", session, command), nil, &params)
+	if resp != nil {
+		resp.Close()
+	}
+	return err
+}
+
+type generalCommand struct {
+	Name      string            `json:"Name"`
+	Arguments map[string]string `json:"Arguments"`
+}
+
+// SetSessionVolume sets volume and mute of session.
+func (jf *Jellyfin) SetSessionVolume(session string, volume models.AudioVolume, muted bool) error {
+	body, err := json.Marshal(&generalCommand{
+		Name:      "SetVolume",
+		Arguments: map[string]string{"Volume": strconv.Itoa(int(volume))},
+	})
+	if err != nil {
+		return fmt.Errorf("encode json: %v", err)
+	}
+	resp, err := jf.post(fmt.Sprintf("/Sessions/%s/Command", session), &body, nil)
+	if resp != nil {
+		resp.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	mute := "Unmute"
+	if muted {
+		mute = "Mute"
+	}
+	resp, err = jf.post(fmt.Sprintf("/Sessions/%s/Command/%s", session, mute), nil, nil)
+	if resp != nil {
+		resp.Close()
+	}
+	return err
+}
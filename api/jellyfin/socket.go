@@ -24,12 +24,13 @@ import (
 	"fmt"
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
-	"math"
 	"net/url"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
+	"tryffel.net/go/jellycli/api"
+	"tryffel.net/go/jellycli/config"
 	// "tryffel.net/go/jellycli/interfaces" // Removed unused import
 	"tryffel.net/go/jellycli/models"
 )
@@ -40,7 +41,7 @@ const (
 )
 
 func (jf *Jellyfin) connectSocket() error {
-	if jf.token == "" {
+	if jf.currentToken() == "" {
 		return fmt.Errorf("no access token")
 	}
 	u, err := url.Parse(jf.host)
@@ -50,13 +51,22 @@ func (jf *Jellyfin) connectSocket() error {
 		logrus.Info("Websocket encryption disabled")
 		scheme = "ws"
 	}
+	proxy, err := api.ProxyFunc()
+	if err != nil {
+		return fmt.Errorf("websocket proxy: %v", err)
+	}
+	tlsConfig, err := api.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("websocket tls: %v", err)
+	}
 	dialer := websocket.Dialer{
-		Proxy:            nil,
-		HandshakeTimeout: time.Second * 10,
+		Proxy:            proxy,
+		HandshakeTimeout: api.RequestTimeout(),
+		TLSClientConfig:  tlsConfig,
 	}
 	logrus.Debug("connecting websocket to ", host)
 	socket, _, err := dialer.Dial(
-		fmt.Sprintf("%s://%s/socket?api_key=%s&deviceId=%s", scheme, host, jf.token, jf.DeviceId), nil)
+		fmt.Sprintf("%s://%s/socket?api_key=%s&deviceId=%s", scheme, host, jf.currentToken(), jf.DeviceId), nil)
 	if err != nil {
 		jf.socketState = socketDisconnected
 		return fmt.Errorf("websocket connection failed: %v", err)
@@ -87,6 +97,59 @@ func (jf *Jellyfin) handleSocketOutbount(msg interface{}) error {
 	return jf.socket.WriteJSON(msg)
 }
 
+type webSocketOutboundMsg struct {
+	MessageType string      `json:"MessageType"`
+	Data        interface{} `json:"Data,omitempty"`
+}
+
+// sendSocketMessage sends message to server if socket is connected.
+func (jf *Jellyfin) sendSocketMessage(msgType string, data interface{}) error {
+	jf.socketLock.Lock()
+	defer jf.socketLock.Unlock()
+	if jf.socketState != socketConnected {
+		return fmt.Errorf("socket not connected")
+	}
+	err := jf.socket.SetWriteDeadline(time.Now().Add(time.Second * 10))
+	if err != nil {
+		return fmt.Errorf("set write deadline: %v", err)
+	}
+	err = jf.handleSocketOutbount(webSocketOutboundMsg{MessageType: msgType, Data: data})
+	if err != nil {
+		jf.socketState = socketAwaitsReconnecting
+		return err
+	}
+	return nil
+}
+
+// setKeepAlive sets keep-alive interval requested by server with ForceKeepAlive and sends first keep-alive.
+func (jf *Jellyfin) setKeepAlive(seconds float64) {
+	jf.socketLock.Lock()
+	jf.keepAlive = time.Duration(seconds * float64(time.Second))
+	jf.socketLock.Unlock()
+	logrus.Debugf("websocket keep-alive requested every %s", jf.keepAlive)
+	jf.sendKeepAlive(true)
+}
+
+// sendKeepAlive sends keep-alive message when half of interval requested by server has passed, or
+// always if force.
+func (jf *Jellyfin) sendKeepAlive(force bool) {
+	jf.socketLock.RLock()
+	due := jf.keepAlive > 0 && time.Since(jf.lastKeepAlive) >= jf.keepAlive/2
+	jf.socketLock.RUnlock()
+	if !due && !force {
+		return
+	}
+	err := jf.sendSocketMessage("KeepAlive", nil)
+	if err != nil {
+		logrus.Debugf("send websocket keep-alive: %v", err)
+		return
+	}
+	logrus.Trace("Websocket sent keep-alive")
+	jf.socketLock.Lock()
+	jf.lastKeepAlive = time.Now()
+	jf.socketLock.Unlock()
+}
+
 // read next message from socket in blocking mode. Messages are read as long as socket connection is ok
 func (jf *Jellyfin) readMessage() {
 	if jf.WebsocketOk() {
@@ -124,11 +187,18 @@ func (jf *Jellyfin) parseInboudMessage(buff *[]byte) error {
 		return fmt.Errorf("parse json: %v, body: %s", err, str)
 	}
 
+	if msg.MessageType == "ForceKeepAlive" {
+		if seconds, ok := msg.Data.(float64); ok && seconds > 0 {
+			go jf.setKeepAlive(seconds)
+		}
+		return nil
+	} else if msg.MessageType == "KeepAlive" {
+		return nil
+	}
+
 	dataMap, ok := msg.Data.(map[string]interface{})
 	if !ok {
-		if msg.MessageType != "ForceKeepAlive" {
-			logrus.Errorf("Unknown websocket event: %v", msg)
-		}
+		logrus.Errorf("Unknown websocket event: %v", msg)
 		return nil
 	}
 
@@ -150,6 +220,14 @@ func (jf *Jellyfin) parseInboudMessage(buff *[]byte) error {
 				}
 			case "ToggleMute":
 				jf.player.ToggleMute()
+			case "Mute":
+				jf.player.SetMute(true)
+			case "Unmute":
+				jf.player.SetMute(false)
+			case "VolumeUp":
+				jf.player.ChangeVolume(1)
+			case "VolumeDown":
+				jf.player.ChangeVolume(-1)
 			default:
 				logrus.Warning("unknown socket command: ", name)
 			}
@@ -181,11 +259,13 @@ func (jf *Jellyfin) parseInboudMessage(buff *[]byte) error {
 			startIndex = int(index)
 		}
 
+		userId, _ := dataMap["ControllingUserId"].(string)
+
 		command, ok := dataMap["PlayCommand"].(string)
 		if !ok {
 			logrus.Error("Received play command, but command is not string: ", msg.Data)
 		} else {
-			go jf.pushSongsToQueue(items[startIndex:], command)
+			go jf.pushSongsToQueue(items[startIndex:], command, userId)
 		}
 	}
 	return err
@@ -207,12 +287,13 @@ func (jf *Jellyfin) pushCommand(cmd string) error {
 		jf.player.Pause()
 	case "Unpause":
 		jf.player.Continue()
-	case "StopMedia":
+	case "StopMedia", "Stop":
 		jf.player.StopMedia()
-		jf.queue.ClearQueue(true)
-	case "Stop":
-		jf.player.StopMedia()
-		jf.queue.ClearQueue(true)
+		if config.ReadOnly() {
+			logrus.Info("Read-only mode, keep queue on remote stop")
+		} else {
+			jf.queue.ClearQueue(true)
+		}
 	default:
 		logrus.Info("Unknown websocket playstate command: ", cmd)
 	}
@@ -284,48 +365,39 @@ func (jf *Jellyfin) reconnectSocket() bool {
 	return true
 }
 
-// push songs to queue.
-func (jf *Jellyfin) pushSongsToQueue(items []string, mode string) {
+// push songs to queue. Songs are annotated with name of user that sent the command.
+func (jf *Jellyfin) pushSongsToQueue(items []string, mode string, userId string) {
 	ids := []models.Id{}
 	for _, v := range items {
 		ids = append(ids, models.Id(v))
 	}
 
-	var songs []*models.Song
-	var err error
-
-	// server does not accept too long id list (> 15 ids), so we need to split large queries
-	if len(ids) > 15 {
-		rounds := int(math.Ceil(float64(len(ids)) / 15))
-		logrus.Infof("Too many songs for single query, split query: %d total, %d queries", len(ids), rounds)
-		for i := 0; i < rounds; i++ {
-			from := i * 15
-			to := (i + 1) * 15
-			if to > len(ids) {
-				to = len(ids)
-			}
-			logrus.Debugf("Download songs [%d, %d]", from, to)
-			s, err := jf.GetSongsById(ids[from:to])
-			if err != nil {
-				logrus.Errorf("download songs: %v", err)
-			}
-			songs = append(songs, s...)
-		}
-		if len(songs) != len(ids) {
-			logrus.Errorf("some songs were not downloaded: expect %d, got %d", len(ids), len(songs))
-		}
-	} else {
-		songs, err = jf.GetSongsById(ids)
-	}
-
+	songs, err := jf.GetSongsById(ids)
 	if err != nil {
 		logrus.Errorf("remote control: add songs to queue: get songs from ids: %v", err)
 		return
 	}
 	logrus.Debug("received play event: ", mode)
 
+	requester := "remote"
+	if userId != "" {
+		name, err := jf.getUserName(userId)
+		if err != nil {
+			logrus.Warningf("remote control: get user name: %v", err)
+		} else if name != "" {
+			requester = name
+		}
+	}
+	for _, v := range songs {
+		v.RequestedBy = requester
+	}
+
 	// some modes are swapped in other clients, use those for consistency
-	if mode == "PlayNow" {
+	if mode == "PlayNow" && config.ReadOnly() {
+		// don't replace queue, play songs next and skip to them
+		jf.queue.PlayNext(songs)
+		jf.player.Next()
+	} else if mode == "PlayNow" {
 		jf.player.StopMedia()
 		jf.queue.ClearQueue(true)
 		jf.queue.AddSongs(songs) // Use AddSongs for forward iteration and correct appending
@@ -85,6 +85,7 @@ type playbackStarted struct {
 	LiveStreamId        string
 	PlaylistLength      int64
 	PlaylistIndex       int
+	PlaylistItemId      string
 	ShuffleMode         string
 	Queue               []queueItem `json:"NowPlayingQueue"`
 }
@@ -108,12 +109,25 @@ func idsToQueue(ids []models.Id) []queueItem {
 	for i, v := range ids {
 		out = append(out, queueItem{
 			Id:    v.String(),
-			Index: "playlistItem" + strconv.Itoa(i),
+			Index: playlistItemId(i),
 		})
 	}
 	return out
 }
 
+// playlistItemId returns id of queue item at index, which tells server which queue item is playing.
+func playlistItemId(index int) string {
+	return "playlistItem" + strconv.Itoa(index)
+}
+
+// playMethod returns play method to report to server.
+func playMethod(transcoded bool) string {
+	if transcoded {
+		return "Transcode"
+	}
+	return "DirectPlay"
+}
+
 type playbackProgress struct {
 	playbackStarted
 	Event interfaces.ApiPlaybackEvent
@@ -134,12 +148,16 @@ func (jf *Jellyfin) ReportProgress(state *interfaces.ApiPlaybackState) error {
 		VolumeLevel:         state.Volume,
 		IsPaused:            state.IsPaused,
 		IsMuted:             state.IsMuted,
-		PlayMethod:          "DirectPlay",
-		PlaySessionId:       jf.SessionId,
+		PlayMethod:          playMethod(state.Transcoded),
+		PlaySessionId:       jf.playSession(),
 		LiveStreamId:        "",
 		PlaylistLength:      int64(state.PlaylistLength) * ticksToSecond,
 		Queue:               idsToQueue(state.Queue),
 	}
+	if state.QueueIndex >= 0 && state.QueueIndex < len(state.Queue) {
+		started.PlaylistIndex = state.QueueIndex
+		started.PlaylistItemId = playlistItemId(state.QueueIndex)
+	}
 
 	if state.Shuffle {
 		started.ShuffleMode = "Shuffle"
@@ -171,6 +189,15 @@ func (jf *Jellyfin) ReportProgress(state *interfaces.ApiPlaybackState) error {
 	if err != nil {
 		return fmt.Errorf("json marshaling failed: %v", err)
 	}
+	if jf.socketProgress && state.Event != interfaces.EventStart && state.Event != interfaces.EventStop {
+		// server reads data of socket report as json string
+		err = jf.sendSocketMessage("ReportPlaybackProgress", string(body))
+		if err == nil {
+			logrus.Debug("Progress event (websocket): ", state.Event)
+			return nil
+		}
+		logrus.Debugf("report progress over websocket, fallback to http: %v", err)
+	}
 	resp, err := jf.makeRequest(http.MethodPost, url, &body, &params,
 		map[string]string{"X-Emby-Authorization": jf.authHeader()})
 	if err != nil {
@@ -205,7 +232,7 @@ func (jf *Jellyfin) ReportCapabilities() error {
 	data["SupportsMediaControl"] = jf.remoteControlEnabled
 	data["SupportsPersistentIdentifier"] = false
 	data["ApplicationVersion"] = config.Version
-	data["Client"] = config.AppName
+	data["Client"] = jf.appName()
 
 	data["DeviceName"] = jf.deviceName()
 	data["DeviceId"] = jf.DeviceId
@@ -237,11 +264,23 @@ func (jf *Jellyfin) authHeader() string {
 	hostname := jf.deviceName()
 
 	auth := fmt.Sprintf("MediaBrowser Client=\"%s\", Device=\"%s\", DeviceId=\"%s\", Version=\"%s\"",
-		config.AppName, hostname, id, config.Version)
+		jf.appName(), hostname, id, config.Version)
 	return auth
 }
 
+// appName returns client name reported to server.
+func (jf *Jellyfin) appName() string {
+	if jf.clientName != "" {
+		return jf.clientName
+	}
+	return config.AppName
+}
+
+// deviceName returns configured device name, or hostname.
 func (jf *Jellyfin) deviceName() string {
+	if jf.device != "" {
+		return jf.device
+	}
 	hostname, err := os.Hostname()
 	if err != nil {
 		switch runtime.GOOS {
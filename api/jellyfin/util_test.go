@@ -0,0 +1,52 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package jellyfin
+
+import (
+	"testing"
+
+	"tryffel.net/go/jellycli/api/jellyfin/mockserver"
+	"tryffel.net/go/jellycli/interfaces"
+)
+
+func TestJellyfin_ReportProgressPlayMethod(t *testing.T) {
+	server := mockserver.New()
+	defer server.Close()
+	jf := newTestClient(t, server)
+
+	for _, transcoded := range []bool{false, true} {
+		err := jf.ReportProgress(&interfaces.ApiPlaybackState{
+			Event:      interfaces.EventStart,
+			ItemId:     "song-001",
+			Transcoded: transcoded,
+		})
+		if err != nil {
+			t.Fatalf("report progress: %v", err)
+		}
+	}
+	reports := server.Reports()
+	if len(reports) != 2 {
+		t.Fatalf("expected 2 reports, got %d", len(reports))
+	}
+	if reports[0].PlayMethod != "DirectPlay" {
+		t.Errorf("expected direct play, got %s", reports[0].PlayMethod)
+	}
+	if reports[1].PlayMethod != "Transcode" {
+		t.Errorf("expected transcode, got %s", reports[1].PlayMethod)
+	}
+}
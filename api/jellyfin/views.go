@@ -19,8 +19,142 @@
 package jellyfin
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/models"
 )
 
-// GetViews and GetLatestAlbums removed as they are TUI-specific browsing features.
+const collectionTypeMusic = "music"
+
+type folderItem struct {
+	Name           string `json:"Name"`
+	Id             string `json:"Id"`
+	Type           string `json:"Type"`
+	IsFolder       bool   `json:"IsFolder"`
+	CollectionType string `json:"CollectionType"`
+}
+
+type folderItems struct {
+	Items []folderItem `json:"Items"`
+}
+
+// GetViews returns music libraries of user. Views selected in config are marked selected.
+func (jf *Jellyfin) GetViews() ([]*models.View, error) {
+	resp, err := jf.get(fmt.Sprintf("/Users/%s/Views", jf.currentUserId()), jf.defaultParams())
+	if resp != nil {
+		defer resp.Close()
+	}
+	if err != nil {
+		return []*models.View{}, err
+	}
+
+	dto := folderItems{}
+	err = json.NewDecoder(resp).Decode(&dto)
+	if err != nil {
+		return []*models.View{}, fmt.Errorf("decode json: %v", err)
+	}
+
+	views := []*models.View{}
+	for _, v := range dto.Items {
+		if v.CollectionType != collectionTypeMusic {
+			continue
+		}
+		views = append(views, &models.View{
+			Id:       models.Id(v.Id),
+			Name:     v.Name,
+			Selected: jf.viewSelected(v.Id, v.Name),
+		})
+	}
+	return views, nil
+}
+
+// GetFolder returns children of library or folder, sorted by name.
+func (jf *Jellyfin) GetFolder(folder models.Id) ([]*models.FolderItem, error) {
+	params := *jf.defaultParams()
+	params.setParentId(folder.String())
+	params["SortBy"] = "IsFolder,SortName"
+
+	resp, err := jf.get(fmt.Sprintf("/Users/%s/Items", jf.currentUserId()), &params)
+	if resp != nil {
+		defer resp.Close()
+	}
+	if err != nil {
+		return []*models.FolderItem{}, err
+	}
+
+	dto := folderItems{}
+	err = json.NewDecoder(resp).Decode(&dto)
+	if err != nil {
+		return []*models.FolderItem{}, fmt.Errorf("decode json: %v", err)
+	}
+	items := make([]*models.FolderItem, len(dto.Items))
+	for i, v := range dto.Items {
+		items[i] = &models.FolderItem{
+			Id:       models.Id(v.Id),
+			Name:     v.Name,
+			Type:     v.Type,
+			IsFolder: v.IsFolder,
+		}
+	}
+	return items, nil
+}
+
+// viewSelected returns true if view is listed in config, by id or name.
+func (jf *Jellyfin) viewSelected(id, name string) bool {
+	for _, v := range jf.musicViews {
+		if v == id || strings.EqualFold(v, name) {
+			return true
+		}
+	}
+	return false
+}
 
+// selectedViews returns ids of music views selected in config, or nil if whole library is used.
+// Views are resolved once.
+func (jf *Jellyfin) selectedViews() []string {
+	if len(jf.musicViews) == 0 {
+		return nil
+	}
+	jf.viewLock.Lock()
+	defer jf.viewLock.Unlock()
+	if jf.viewIds != nil {
+		return jf.viewIds
+	}
+	views, err := jf.GetViews()
+	if err != nil {
+		logrus.Errorf("get music views: %v", err)
+		return nil
+	}
+	ids := []string{}
+	for _, v := range views {
+		if v.Selected {
+			ids = append(ids, v.Id.String())
+		}
+	}
+	if len(ids) == 0 {
+		logrus.Warningf("none of configured music views %v found, using whole library", jf.musicViews)
+	}
+	jf.viewIds = ids
+	return ids
+}
 
+// viewParams returns params for querying each selected music view, or params as is if no views are
+// selected. Artists and playlists do not belong to views, so queries of them are not limited.
+func (jf *Jellyfin) viewParams(p params, itemType mediaItemType) []params {
+	views := jf.selectedViews()
+	if len(views) == 0 || itemType == mediaTypeArtist || itemType == mediaTypePlaylist {
+		return []params{p}
+	}
+	out := make([]params, len(views))
+	for i, v := range views {
+		out[i] = params{}
+		for key, value := range p {
+			out[i][key] = value
+		}
+		out[i].setParentId(v)
+	}
+	return out
+}
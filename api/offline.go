@@ -0,0 +1,331 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/models"
+)
+
+const offlineDir = "offline"
+const offlineManifestFile = "playlists.json"
+
+// OfflineServer is a server that supports syncing playlists for offline use.
+type OfflineServer interface {
+	Library
+	PlaylistEditor
+	Streamer
+	GetSongDirect(id string, codec string) (io.ReadCloser, interfaces.AudioFormat, error)
+}
+
+// offlineSong is a song stored locally.
+type offlineSong struct {
+	Id   models.Id `json:"id"`
+	Name string    `json:"name"`
+	File string    `json:"file"`
+}
+
+// OfflineSyncSummary describes result of syncing a playlist.
+type OfflineSyncSummary struct {
+	Playlist   string
+	Downloaded int
+	Kept       int
+	Removed    int
+	Failed     []string
+}
+
+func (o *OfflineSyncSummary) String() string {
+	return fmt.Sprintf("%s: downloaded %d, up to date %d, removed %d, failed %d",
+		o.Playlist, o.Downloaded, o.Kept, o.Removed, len(o.Failed))
+}
+
+// OfflineStore keeps songs of selected playlists in local cache directory, so that they can be played
+// without connection to server. Songs are stored as <song id>.<format> and manifest lists songs
+// of each playlist.
+type OfflineStore struct {
+	dir  string
+	lock sync.RWMutex
+	// playlists maps playlist name to its songs
+	playlists map[string][]offlineSong
+	// files maps song id to file
+	files map[models.Id]string
+}
+
+// NewOfflineStore opens offline store in cache dir.
+func NewOfflineStore(cacheDir string) *OfflineStore {
+	o := &OfflineStore{
+		dir:       path.Join(cacheDir, offlineDir),
+		playlists: map[string][]offlineSong{},
+		files:     map[models.Id]string{},
+	}
+	data, err := ioutil.ReadFile(path.Join(o.dir, offlineManifestFile))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logrus.Errorf("read offline playlists: %v", err)
+		}
+		return o
+	}
+	err = json.Unmarshal(data, &o.playlists)
+	if err != nil {
+		logrus.Errorf("parse offline playlists: %v", err)
+	}
+	o.index()
+	return o
+}
+
+// Playlists returns names of playlists stored offline.
+func (o *OfflineStore) Playlists() []string {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	names := make([]string, 0, len(o.playlists))
+	for k := range o.playlists {
+		names = append(names, k)
+	}
+	return names
+}
+
+// Open opens local copy of song, if there is one.
+func (o *OfflineStore) Open(song *models.Song) (io.ReadCloser, interfaces.AudioFormat, bool) {
+	o.lock.RLock()
+	file, ok := o.files[song.Id]
+	o.lock.RUnlock()
+	if !ok {
+		return nil, interfaces.AudioFormatNil, false
+	}
+	fd, err := os.Open(path.Join(o.dir, file))
+	if err != nil {
+		logrus.Warningf("open offline song %s: %v", song.Name, err)
+		return nil, interfaces.AudioFormatNil, false
+	}
+	format := interfaces.AudioFormat(strings.TrimPrefix(path.Ext(file), "."))
+	return fd, format, true
+}
+
+// Path returns path of local copy of song, if there is one.
+func (o *OfflineStore) Path(song *models.Song) (string, bool) {
+	o.lock.RLock()
+	file, ok := o.files[song.Id]
+	o.lock.RUnlock()
+	if !ok {
+		return "", false
+	}
+	return path.Join(o.dir, file), true
+}
+
+// SyncPlaylist downloads missing songs of playlist and removes songs that are no longer in it.
+// Progress is called with each song that is downloaded.
+func (o *OfflineStore) SyncPlaylist(server OfflineServer, name string, progress func(song string)) (*OfflineSyncSummary, error) {
+	summary := &OfflineSyncSummary{Playlist: name}
+	id, err := findPlaylist(server, name)
+	if err != nil {
+		return summary, err
+	}
+	songs, err := server.GetPlaylistSongs(id)
+	if err != nil {
+		return summary, fmt.Errorf("get playlist songs: %v", err)
+	}
+	err = os.MkdirAll(o.dir, 0760)
+	if err != nil {
+		return summary, err
+	}
+
+	o.lock.RLock()
+	old := o.playlists[name]
+	o.lock.RUnlock()
+	items := make([]offlineSong, 0, len(songs))
+	for _, song := range songs {
+		o.lock.RLock()
+		file, ok := o.files[song.Id]
+		o.lock.RUnlock()
+		if ok {
+			if _, err := os.Stat(path.Join(o.dir, file)); err == nil {
+				summary.Kept++
+				items = append(items, offlineSong{Id: song.Id, Name: song.Name, File: file})
+				continue
+			}
+		}
+
+		if progress != nil {
+			progress(song.Name)
+		}
+		file, err = o.download(server, song)
+		if err != nil {
+			logrus.Errorf("offline sync: download %s: %v", song.Name, err)
+			summary.Failed = append(summary.Failed, song.Name)
+			continue
+		}
+		summary.Downloaded++
+		items = append(items, offlineSong{Id: song.Id, Name: song.Name, File: file})
+	}
+
+	current := map[models.Id]bool{}
+	for _, v := range items {
+		current[v.Id] = true
+	}
+	for _, v := range old {
+		if !current[v.Id] {
+			summary.Removed++
+		}
+	}
+
+	o.lock.Lock()
+	o.playlists[name] = items
+	o.index()
+	o.lock.Unlock()
+	err = o.save()
+	if err != nil {
+		return summary, err
+	}
+	return summary, o.prune()
+}
+
+// RemovePlaylist removes playlist and files that no other playlist uses.
+func (o *OfflineStore) RemovePlaylist(name string) error {
+	o.lock.Lock()
+	delete(o.playlists, name)
+	o.index()
+	o.lock.Unlock()
+	err := o.save()
+	if err != nil {
+		return err
+	}
+	return o.prune()
+}
+
+// download downloads song. Original file is preferred, but if it cannot be played, song is transcoded.
+func (o *OfflineStore) download(server OfflineServer, song *models.Song) (string, error) {
+	reader, format, err := server.Download(song)
+	if err == nil && !formatSupported(format) {
+		reader.Close()
+		err = fmt.Errorf("format %s not supported", format)
+	}
+	if err != nil {
+		logrus.Debugf("offline sync: download original %s: %v, transcode", song.Name, err)
+		reader, format, err = server.GetSongDirect(song.Id.String(), "")
+		if err != nil {
+			return "", err
+		}
+	}
+	defer reader.Close()
+
+	file := song.Id.String() + "." + format.String()
+	part := path.Join(o.dir, file+".part")
+	fd, err := os.Create(part)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(fd, reader)
+	closeErr := fd.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(part)
+		return "", err
+	}
+	return file, os.Rename(part, path.Join(o.dir, file))
+}
+
+// prune removes files that are not part of any playlist.
+func (o *OfflineStore) prune() error {
+	entries, err := ioutil.ReadDir(o.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	o.lock.RLock()
+	used := map[string]bool{offlineManifestFile: true}
+	for _, v := range o.files {
+		used[v] = true
+	}
+	o.lock.RUnlock()
+
+	for _, v := range entries {
+		if v.IsDir() || used[v.Name()] {
+			continue
+		}
+		logrus.Debugf("offline: remove %s", v.Name())
+		err = os.Remove(path.Join(o.dir, v.Name()))
+		if err != nil {
+			logrus.Errorf("offline: remove unused file: %v", err)
+		}
+	}
+	return nil
+}
+
+// index rebuilds file index. Caller must hold lock.
+func (o *OfflineStore) index() {
+	o.files = map[models.Id]string{}
+	for _, songs := range o.playlists {
+		for _, v := range songs {
+			o.files[v.Id] = v.File
+		}
+	}
+}
+
+func (o *OfflineStore) save() error {
+	o.lock.RLock()
+	data, err := json.MarshalIndent(o.playlists, "", "  ")
+	o.lock.RUnlock()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(o.dir, 0760)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path.Join(o.dir, offlineManifestFile), data, 0660)
+}
+
+// findPlaylist returns id of playlist with given name.
+func findPlaylist(library Library, name string) (models.Id, error) {
+	items, err := library.Search(name, models.TypePlaylist, config.SearchLimit)
+	if err != nil {
+		return "", fmt.Errorf("search playlist: %v", err)
+	}
+	for _, v := range items {
+		if strings.EqualFold(v.GetName(), name) {
+			return v.GetId(), nil
+		}
+	}
+	return "", errors.New("playlist not found: " + name)
+}
+
+func formatSupported(format interfaces.AudioFormat) bool {
+	for _, v := range interfaces.SupportedAudioFormats {
+		if v == format {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,288 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package api
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+)
+
+// PlaylistFormat is file format of exported playlist.
+type PlaylistFormat string
+
+const (
+	PlaylistFormatM3u  PlaylistFormat = "m3u"
+	PlaylistFormatXspf PlaylistFormat = "xspf"
+)
+
+// PlaylistFormatFromFile returns format by file extension, m3u if extension is not known.
+func PlaylistFormatFromFile(name string) PlaylistFormat {
+	if strings.EqualFold(path.Ext(name), ".xspf") {
+		return PlaylistFormatXspf
+	}
+	return PlaylistFormatM3u
+}
+
+// PlaylistEntry is a single entry read from playlist file.
+type PlaylistEntry struct {
+	Location string
+	Title    string
+	Artist   string
+	Duration int
+}
+
+func (p *PlaylistEntry) String() string {
+	if p.Artist != "" {
+		return p.Artist + " - " + p.Title
+	}
+	return p.Title
+}
+
+// WritePlaylist writes songs as playlist file of given format. Location returns path or url of each song.
+func WritePlaylist(w io.Writer, format PlaylistFormat, title string, songs []*models.Song,
+	location func(song *models.Song) string) error {
+	switch format {
+	case PlaylistFormatM3u:
+		return writeM3u(w, title, songs, location)
+	case PlaylistFormatXspf:
+		return writeXspf(w, title, songs, location)
+	default:
+		return fmt.Errorf("unknown playlist format '%s', expected m3u or xspf", format)
+	}
+}
+
+func writeM3u(w io.Writer, title string, songs []*models.Song, location func(song *models.Song) string) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("#EXTM3U\n")
+	if title != "" {
+		bw.WriteString("#PLAYLIST:" + title + "\n")
+	}
+	for _, v := range songs {
+		fmt.Fprintf(bw, "#EXTINF:%d,%s - %s\n%s\n", v.Duration, songArtist(v), v.Name, location(v))
+	}
+	return bw.Flush()
+}
+
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"playlist"`
+	Version string      `xml:"version,attr"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title,omitempty"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location string `xml:"location"`
+	Title    string `xml:"title,omitempty"`
+	Creator  string `xml:"creator,omitempty"`
+	Album    string `xml:"album,omitempty"`
+	TrackNum int    `xml:"trackNum,omitempty"`
+	// Duration is in milliseconds
+	Duration int `xml:"duration,omitempty"`
+}
+
+func writeXspf(w io.Writer, title string, songs []*models.Song, location func(song *models.Song) string) error {
+	playlist := xspfPlaylist{
+		Version: "1",
+		Xmlns:   "http://xspf.org/ns/0/",
+		Title:   title,
+		Tracks:  make([]xspfTrack, len(songs)),
+	}
+	for i, v := range songs {
+		playlist.Tracks[i] = xspfTrack{
+			Location: location(v),
+			Title:    v.Name,
+			Creator:  songArtist(v),
+			Album:    v.AlbumName,
+			TrackNum: v.Index,
+			Duration: v.Duration * 1000,
+		}
+	}
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(&playlist)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// ReadM3u reads entries of m3u or m3u8 playlist. Title and artist are taken from #EXTINF, if present,
+// else title is file name of entry.
+func ReadM3u(r io.Reader) ([]*PlaylistEntry, error) {
+	entries := []*PlaylistEntry{}
+	info := &PlaylistEntry{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#EXTINF:") {
+			info = parseExtInf(strings.TrimPrefix(line, "#EXTINF:"))
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		info.Location = line
+		if info.Title == "" {
+			name := filepath.Base(filepath.FromSlash(line))
+			if u, err := url.Parse(line); err == nil && u.Scheme != "" && len(u.Scheme) > 1 {
+				name = path.Base(u.Path)
+			}
+			info.Title = strings.TrimSuffix(name, path.Ext(name))
+		}
+		entries = append(entries, info)
+		info = &PlaylistEntry{}
+	}
+	return entries, scanner.Err()
+}
+
+// parseExtInf parses '<duration>[ attributes],[<artist> - ]<title>'.
+func parseExtInf(s string) *PlaylistEntry {
+	entry := &PlaylistEntry{}
+	parts := strings.SplitN(s, ",", 2)
+	fields := strings.Fields(parts[0])
+	if len(fields) > 0 {
+		if duration, err := strconv.Atoi(fields[0]); err == nil && duration > 0 {
+			entry.Duration = duration
+		}
+	}
+	if len(parts) < 2 {
+		return entry
+	}
+	entry.Title = strings.TrimSpace(parts[1])
+	if i := strings.Index(entry.Title, " - "); i > 0 {
+		entry.Artist = strings.TrimSpace(entry.Title[:i])
+		entry.Title = strings.TrimSpace(entry.Title[i+3:])
+	}
+	return entry
+}
+
+// MatchPlaylistEntries finds songs in library for playlist entries. Entries exported by jellycli are
+// matched by song id in stream url or offline file name, others by searching title and comparing artist
+// and duration. Entries that did not match any song are returned as missing.
+func MatchPlaylistEntries(library Library, entries []*PlaylistEntry) ([]*models.Song, []*PlaylistEntry, error) {
+	songs := make([]*models.Song, 0, len(entries))
+	missing := []*PlaylistEntry{}
+	for _, v := range entries {
+		song, err := matchEntry(library, v)
+		if err != nil {
+			return songs, missing, err
+		}
+		if song == nil {
+			missing = append(missing, v)
+		} else {
+			songs = append(songs, song)
+		}
+	}
+	return songs, missing, nil
+}
+
+func matchEntry(library Library, entry *PlaylistEntry) (*models.Song, error) {
+	if id := entrySongId(entry.Location); id != "" {
+		songs, err := library.GetSongsById([]models.Id{id})
+		if err == nil && len(songs) == 1 {
+			return songs[0], nil
+		}
+	}
+	if entry.Title == "" {
+		return nil, nil
+	}
+	items, err := library.Search(entry.Title, models.TypeSong, config.SearchLimit)
+	if err != nil {
+		return nil, fmt.Errorf("search %s: %v", entry.Title, err)
+	}
+
+	var best *models.Song
+	bestScore := 0
+	for _, v := range items {
+		song, ok := v.(*models.Song)
+		if !ok {
+			continue
+		}
+		// artist must match, if it's known
+		if entry.Artist != "" && !songHasArtist(song, entry.Artist) {
+			continue
+		}
+		score := 1
+		if strings.EqualFold(song.Name, entry.Title) {
+			score += 2
+		}
+		diff := song.Duration - entry.Duration
+		if entry.Duration > 0 && diff <= 3 && diff >= -3 {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = song, score
+		}
+	}
+	return best, nil
+}
+
+// entrySongId returns song id from jellyfin stream url (/Audio/<id>/) or offline file (<id>.<format>),
+// if location is either one.
+func entrySongId(location string) models.Id {
+	if i := strings.Index(location, "/Audio/"); i >= 0 {
+		id := strings.SplitN(location[i+len("/Audio/"):], "/", 2)[0]
+		return models.Id(id)
+	}
+	dir := filepath.Base(filepath.Dir(filepath.FromSlash(location)))
+	if dir == offlineDir {
+		name := filepath.Base(location)
+		return models.Id(strings.TrimSuffix(name, filepath.Ext(name)))
+	}
+	return ""
+}
+
+func songHasArtist(song *models.Song, artist string) bool {
+	if strings.EqualFold(song.AlbumArtistName, artist) {
+		return true
+	}
+	for _, v := range song.Artists {
+		if strings.EqualFold(v.Name, artist) {
+			return true
+		}
+	}
+	return false
+}
+
+// songArtist returns first artist of song, or album artist if song has no artists.
+func songArtist(song *models.Song) string {
+	if len(song.Artists) > 0 {
+		return song.Artists[0].Name
+	}
+	return song.AlbumArtistName
+}
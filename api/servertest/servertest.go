@@ -0,0 +1,258 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+// Package servertest contains conformance tests for api.MediaServer implementations. Backends run the
+// suite against a server that has known content:
+//
+//	func TestConformance(t *testing.T) {
+//		servertest.Run(t, server, servertest.Fixture{Song: "id", Album: "album id", SearchQuery: "name"})
+//	}
+//
+// Optional capabilities, e.g. api.Library and api.Favoriter, are tested only if server implements them.
+package servertest
+
+import (
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"tryffel.net/go/jellycli/api"
+	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/models"
+)
+
+// Fixture describes content that server under test has.
+type Fixture struct {
+	// Song is id of song that can be streamed.
+	Song models.Id
+	// Album is id of album that Song belongs to.
+	Album models.Id
+	// SearchQuery matches name of Song. Empty skips search tests.
+	SearchQuery string
+	// PageSize is page size for paging tests, default is 2. Library should have more songs than that.
+	PageSize int
+	// ReadOnly skips tests that modify server, e.g. favorites.
+	ReadOnly bool
+}
+
+// maxPages limits pages requested in paging tests.
+const maxPages = 1000
+
+// Run runs conformance tests against server.
+func Run(t *testing.T, server api.MediaServer, fixture Fixture) {
+	if fixture.PageSize <= 0 {
+		fixture.PageSize = 2
+	}
+	t.Run("Connection", func(t *testing.T) { testConnection(t, server) })
+	t.Run("Stream", func(t *testing.T) { testStream(t, server, fixture) })
+
+	library, ok := server.(api.Library)
+	if !ok {
+		t.Log("server does not implement api.Library, skip library tests")
+		return
+	}
+	t.Run("SongsById", func(t *testing.T) { testSongsById(t, library, fixture) })
+	t.Run("AlbumSongs", func(t *testing.T) { testAlbumSongs(t, library, fixture) })
+	t.Run("Paging", func(t *testing.T) { testPaging(t, library, fixture) })
+	if fixture.SearchQuery != "" {
+		t.Run("Search", func(t *testing.T) { testSearch(t, library, fixture) })
+	}
+	if favoriter, ok := server.(api.Favoriter); ok && !fixture.ReadOnly {
+		t.Run("Favorites", func(t *testing.T) { testFavorites(t, library, favoriter, fixture) })
+	}
+}
+
+func testConnection(t *testing.T, server api.MediaServer) {
+	if err := server.ConnectionOk(); err != nil {
+		t.Fatalf("connection not ok: %v", err)
+	}
+	info, err := server.GetInfo()
+	if err != nil {
+		t.Fatalf("get info: %v", err)
+	}
+	if info == nil {
+		t.Fatal("get info: no info returned")
+	}
+}
+
+func testStream(t *testing.T, server api.MediaServer, fixture Fixture) {
+	song := &models.Song{Id: fixture.Song}
+	if library, ok := server.(api.Library); ok {
+		songs, err := library.GetSongsById([]models.Id{fixture.Song})
+		if err == nil && len(songs) == 1 {
+			song = songs[0]
+		}
+	}
+	reader, format, err := server.Stream(song)
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	defer reader.Close()
+	if format == interfaces.AudioFormatNil {
+		t.Error("stream: no audio format")
+	}
+	n, err := io.CopyN(ioutil.Discard, reader, 1024)
+	if err != nil && err != io.EOF {
+		t.Fatalf("read stream: %v", err)
+	}
+	if n == 0 {
+		t.Error("read stream: no data")
+	}
+}
+
+func testSongsById(t *testing.T, library api.Library, fixture Fixture) {
+	songs, err := library.GetSongsById([]models.Id{fixture.Song})
+	if err != nil {
+		t.Fatalf("get songs by id: %v", err)
+	}
+	if len(songs) != 1 {
+		t.Fatalf("get songs by id: expected 1 song, got %d", len(songs))
+	}
+	if songs[0].Id != fixture.Song {
+		t.Errorf("get songs by id: expected song %s, got %s", fixture.Song, songs[0].Id)
+	}
+	if fixture.Album != "" && songs[0].Album != fixture.Album {
+		t.Errorf("get songs by id: expected album %s, got %s", fixture.Album, songs[0].Album)
+	}
+
+	items, err := library.GetItems([]models.Id{fixture.Song})
+	if err != nil {
+		t.Fatalf("get items: %v", err)
+	}
+	if len(items) != 1 || items[0].GetId() != fixture.Song || items[0].GetType() != models.TypeSong {
+		t.Errorf("get items: expected song %s, got %v", fixture.Song, items)
+	}
+}
+
+func testAlbumSongs(t *testing.T, library api.Library, fixture Fixture) {
+	if fixture.Album == "" {
+		t.Skip("no album in fixture")
+	}
+	songs, err := library.GetAlbumSongs(fixture.Album)
+	if err != nil {
+		t.Fatalf("get album songs: %v", err)
+	}
+	found := false
+	for _, v := range songs {
+		if v.Album != fixture.Album {
+			t.Errorf("get album songs: song %s has album %s, expected %s", v.Id, v.Album, fixture.Album)
+		}
+		found = found || v.Id == fixture.Song
+	}
+	if !found {
+		t.Errorf("get album songs: song %s not in album", fixture.Song)
+	}
+}
+
+// testPaging checks that pages do not overlap, are not larger than page size and together contain
+// all songs.
+func testPaging(t *testing.T, library api.Library, fixture Fixture) {
+	paging := models.DefaultPaging()
+	paging.PageSize = fixture.PageSize
+	sort := models.Sort{Field: models.SortByName, Mode: models.SortAsc}
+	seen := map[models.Id]bool{}
+	total := -1
+	for page := 0; page < maxPages; page++ {
+		paging.CurrentPage = page
+		songs, n, err := library.GetSongs(models.Filter{}, sort, paging)
+		if err != nil {
+			t.Fatalf("get songs, page %d: %v", page, err)
+		}
+		if total >= 0 && n != total {
+			t.Fatalf("get songs, page %d: total changed from %d to %d", page, total, n)
+		}
+		total = n
+		if len(songs) > paging.PageSize {
+			t.Fatalf("get songs, page %d: got %d songs, page size is %d", page, len(songs), paging.PageSize)
+		}
+		for _, v := range songs {
+			if seen[v.Id] {
+				t.Fatalf("get songs, page %d: song %s on multiple pages", page, v.Id)
+			}
+			seen[v.Id] = true
+		}
+		if len(songs) == 0 || len(seen) >= total {
+			break
+		}
+	}
+	if len(seen) != total {
+		t.Errorf("get songs: got %d songs in pages, total is %d", len(seen), total)
+	}
+	if total <= fixture.PageSize {
+		t.Logf("library has only %d songs, paging is not fully tested", total)
+	}
+}
+
+func testSearch(t *testing.T, library api.Library, fixture Fixture) {
+	items, err := library.Search(fixture.SearchQuery, models.TypeSong, 50)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	for _, v := range items {
+		if v.GetType() != models.TypeSong {
+			t.Errorf("search: expected only songs, got %s %s", v.GetType(), v.GetId())
+		}
+		if v.GetId() == fixture.Song {
+			return
+		}
+	}
+	t.Errorf("search '%s': song %s not found", fixture.SearchQuery, fixture.Song)
+}
+
+// testFavorites toggles favorite of song and restores original state.
+func testFavorites(t *testing.T, library api.Library, favoriter api.Favoriter, fixture Fixture) {
+	songs, err := library.GetSongsById([]models.Id{fixture.Song})
+	if err != nil || len(songs) != 1 {
+		t.Fatalf("get song: %v", err)
+	}
+	original := songs[0].Favorite
+	defer func() {
+		if err := favoriter.SetFavorite(fixture.Song, original); err != nil {
+			t.Errorf("restore favorite: %v", err)
+		}
+	}()
+
+	for _, favorite := range []bool{true, false} {
+		if err := favoriter.SetFavorite(fixture.Song, favorite); err != nil {
+			t.Fatalf("set favorite %t: %v", favorite, err)
+		}
+		if got := isFavorite(t, library, fixture.Song); got != favorite {
+			t.Errorf("set favorite %t: song listed as favorite: %t", favorite, got)
+		}
+	}
+}
+
+// isFavorite returns true if song is in any page of favorite songs.
+func isFavorite(t *testing.T, library api.Library, song models.Id) bool {
+	paging := models.DefaultPaging()
+	for page := 0; page < maxPages; page++ {
+		paging.CurrentPage = page
+		songs, total, err := library.GetFavoriteSongs(paging)
+		if err != nil {
+			t.Fatalf("get favorite songs: %v", err)
+		}
+		for _, v := range songs {
+			if v.Id == song {
+				return true
+			}
+		}
+		if len(songs) == 0 || paging.Offset()+len(songs) >= total {
+			break
+		}
+	}
+	return false
+}
@@ -25,9 +25,11 @@ import (
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 	"tryffel.net/go/jellycli/config"
 	"tryffel.net/go/jellycli/interfaces" // Changed from player to interfaces
@@ -49,6 +51,15 @@ type StreamBuffer struct {
 	downloadDone   bool               // Flag indicating download completion/error
 	downloadErr    error              // Stores final download error (EOF or other)
 	cancelCtx      context.CancelFunc // Function to cancel the underlying HTTP request context
+	ctx            context.Context
+	// downloaded is total bytes read from server, offset for resuming interrupted download
+	downloaded int64
+	// waiting is true while Read waits for more data
+	waiting bool
+	// consumed is signaled when Read takes data from buffer
+	consumed chan bool
+	// closeOnce closes cancelDownload, which is read by background buffering and is not reset
+	closeOnce sync.Once
 }
 
 func (s *StreamBuffer) Read(p []byte) (n int, err error) {
@@ -58,13 +69,19 @@ func (s *StreamBuffer) Read(p []byte) (n int, err error) {
 	for s.buff.Len() == 0 && !s.downloadDone {
 		// Buffer is empty and download is not finished, wait for signal
 		logrus.Trace("Read: Buffer empty, waiting for data...")
+		s.waiting = true
 		s.cond.Wait()
+		s.waiting = false
 		logrus.Trace("Read: Woke up from wait.")
 	}
 
 	// Check buffer again after waking up or if download was already done
 	if s.buff.Len() > 0 {
 		n, err = s.buff.Read(p)
+		select {
+		case s.consumed <- true:
+		default:
+		}
 		// If we read something, return that, even if download finished concurrently.
 		// The next Read call will handle the downloadDone state if buffer becomes empty.
 		return n, err // err might be io.EOF from buffer, which is fine
@@ -84,27 +101,20 @@ func (s *StreamBuffer) Read(p []byte) (n int, err error) {
 func (s *StreamBuffer) Close() error {
 	logrus.Debug("Close stream download")
 	// Signal background buffer to stop if it's running
-	// Cancel the request context first
-	if s.cancelCtx != nil {
-		s.cancelCtx()
-		s.cancelCtx = nil // Prevent double cancel
-	}
-
-	// Signal background buffer goroutine to stop
-	if s.cancelDownload != nil {
-		// Use a non-blocking send to avoid deadlock if channel is already closed or receiver isn't ready
-		select {
-		case s.cancelDownload <- true:
-			logrus.Trace("Close: Sent cancel signal to background buffer")
-		default:
-			logrus.Trace("Close: Cancel signal to background buffer already sent or channel closed")
+	s.closeOnce.Do(func() {
+		// Cancel the request context first
+		if s.cancelCtx != nil {
+			s.cancelCtx()
 		}
+		// Signal background buffer goroutine to stop
 		close(s.cancelDownload)
-		s.cancelDownload = nil // Prevent closing closed channel
-	}
+	})
 	// Close the underlying response body
-	if s.resp != nil && s.resp.Body != nil {
-		return s.resp.Body.Close()
+	s.lock.Lock()
+	resp := s.resp
+	s.lock.Unlock()
+	if resp != nil && resp.Body != nil {
+		return resp.Body.Close()
 	}
 	return nil // Nothing to close
 }
@@ -120,6 +130,21 @@ func (s *StreamBuffer) Len() int {
 	return s.buff.Len()
 }
 
+// Bitrate returns approximate bitrate of stream in kbps, or 0 if it is not known.
+func (s *StreamBuffer) Bitrate() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.bitrate * 8 / 1000
+}
+
+// Buffering returns true if playback is waiting for more data from server.
+func (s *StreamBuffer) Buffering() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.waiting
+}
+
+// SecondsBuffered returns approximate seconds of audio downloaded but not yet read.
 func (s *StreamBuffer) SecondsBuffered() int {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -149,6 +174,7 @@ func NewStreamDownload(url string, headers map[string]string, params map[string]
 		bitrate:        0, // Initialize bitrate, calculate later
 		buff:           bytes.NewBuffer(make([]byte, 0, 1024*1024)), // Start with 1MB capacity
 		cancelDownload: make(chan bool), // Add missing comma
+		consumed:       make(chan bool, 1),
 	}
 	stream.cond = sync.NewCond(stream.lock) // Move initialization here
 	if client == nil {
@@ -159,6 +185,7 @@ func NewStreamDownload(url string, headers map[string]string, params map[string]
 	// Create a cancellable context for the request
 	ctx, cancel := context.WithCancel(context.Background())
 	stream.cancelCtx = cancel // Store the cancel function
+	stream.ctx = ctx
 
 	var err error
 	// Create request with context
@@ -182,13 +209,13 @@ func NewStreamDownload(url string, headers map[string]string, params map[string]
 
 	stream.resp, err = stream.client.Do(stream.req)
 	if err != nil {
-		return nil, fmt.Errorf("make http request: %v", err) // Return nil stream on error
+		return nil, fmt.Errorf("make http request: %w: %v", ErrServerUnavailable, err) // Return nil stream on error
 	}
 	if stream.resp.StatusCode != http.StatusOK { // Use http.StatusOK constant
 		// Attempt to read body for more details, then close
 		bodyBytes, _ := io.ReadAll(stream.resp.Body)
 		stream.resp.Body.Close() // Ensure body is closed on error
-		return nil, fmt.Errorf("http request error, statuscode: %d, body: %s", stream.resp.StatusCode, string(bodyBytes))
+		return nil, fmt.Errorf("http request: %w", NewRequestError(stream.resp.StatusCode, string(bodyBytes)))
 	}
 
 	sLength := stream.resp.Header.Get("Content-Length")
@@ -206,36 +233,10 @@ func NewStreamDownload(url string, headers map[string]string, params map[string]
 		// stream.bitrate = 128000 / 8 // Example: 128 kbps
 	}
 
-	// Initial buffering logic
-	initialBufferTarget := 0
-	minBufferBytes := 64 * 1024 // Minimum 64 KiB buffer
-
-	// Prioritize InitialBufferKB if set
-	if config.AppConfig.Player.InitialBufferKB > 0 {
-		initialBufferTarget = config.AppConfig.Player.InitialBufferKB * 1024 // Convert KiB to Bytes
-		logrus.Debugf("Using InitialBufferKB config for initial target: %d bytes", initialBufferTarget)
-	} else if stream.bitrate > 0 {
-		// Fallback to HttpBufferingS if bitrate is known
-		target := stream.bitrate * config.AppConfig.Player.HttpBufferingS
-		if target < minBufferBytes {
-			initialBufferTarget = minBufferBytes
-		} else {
-			initialBufferTarget = target
-		}
-		logrus.Debugf("Using HttpBufferingS config for initial target: %d bytes", initialBufferTarget)
-	} else {
-		// Fallback to default if bitrate is unknown and InitialBufferKB not set
-		initialBufferTarget = 512 * 1024 // Default to 512 KiB
-		logrus.Warnf("Bitrate unknown and InitialBufferKB not set. Using default initial buffer target: %d bytes", initialBufferTarget)
-	}
-
-	// Ensure the target is at least the minimum
-	if initialBufferTarget < minBufferBytes {
-		logrus.Warnf("Calculated initial buffer target (%d) is less than minimum (%d). Using minimum.", initialBufferTarget, minBufferBytes)
-		initialBufferTarget = minBufferBytes
-	}
-
+	initialBufferTarget := initialBufferSize(length, stream.bitrate, networkThroughput.get())
+	logrus.Debugf("Initial buffer target: %d bytes", initialBufferTarget)
 
+	started := time.Now()
 	for {
 		// Check if buffer already meets target before reading
 		if stream.buff.Len() >= initialBufferTarget {
@@ -254,6 +255,7 @@ func NewStreamDownload(url string, headers map[string]string, params map[string]
 			break // Stop initial buffering, but proceed if some data was read
 		}
 	}
+	networkThroughput.add(stream.buff.Len(), time.Since(started))
 
 	go stream.bufferBackground()
 	return stream, nil // Return nil error on success
@@ -262,15 +264,18 @@ func NewStreamDownload(url string, headers map[string]string, params map[string]
 func (s *StreamBuffer) bufferBackground() {
 	logrus.Debug("Start background stream buffering")
 	// Use a ticker for more regular checks instead of timer resets
-	ticker := time.NewTicker(500 * time.Millisecond) // Check every 500ms
-	defer ticker.Stop()
+	ticker := time.NewTicker(streamReadInterval)
+	cancel := s.cancelDownload
+	defer func() {
+		ticker.Stop()
+	}()
 
 loop:
 	for {
 		select {
 		case <-ticker.C:
 			// Check buffer limit (use MiB for clarity)
-			bufferLimitBytes := config.AppConfig.Player.HttpBufferingLimitMem * 1024 * 1024
+			bufferLimitBytes := bufferLimit()
 			// Check if buffer is nil before accessing Len
 			currentLen := 0
 			s.lock.Lock()
@@ -283,7 +288,7 @@ loop:
 			if currentLen < bufferLimitBytes {
 				// REMOVED: s.lock.Unlock() // Unlock before calling readData (which locks internally) - This was incorrect
 				logrus.Tracef("Buffer below limit (%d / %d bytes), attempting read", currentLen, bufferLimitBytes)
-				readFinished, readErr := s.readData()
+				readFinished, readErr := s.readAhead()
 				if readFinished {
 					s.lock.Lock() // Re-lock to update shared state
 					s.downloadDone = true
@@ -295,6 +300,15 @@ loop:
 				}
 				// Signal readers that new data *might* be available (readData succeeded)
 				s.cond.Broadcast()
+			} else if config.AppConfig.Player.IdleMode {
+				// wait for reader instead of polling, e.g. while paused
+				logrus.Tracef("Buffer limit reached (%d / %d bytes), wait for reader", currentLen, bufferLimitBytes)
+				ticker.Stop()
+				select {
+				case <-s.consumed:
+				case <-cancel:
+				}
+				ticker = time.NewTicker(streamReadInterval)
 			} else {
 				logrus.Tracef("Buffer limit reached (%d / %d bytes), skipping read this tick", currentLen, bufferLimitBytes)
 				// REMOVED: s.lock.Unlock() // Unlock if not reading - This was incorrect
@@ -329,26 +343,17 @@ func (s *StreamBuffer) readData() (finished bool, err error) {
 		// Continue if not cancelled
 	}
 
-	if s.resp == nil || s.resp.Body == nil {
+	s.lock.Lock()
+	resp := s.resp
+	s.lock.Unlock()
+	if resp == nil || resp.Body == nil {
 		logrus.Error("readData called with nil response body")
 		return true, errors.New("response body is nil") // Signal stop with error
 	}
 
-	// Determine buffer size dynamically or use a fixed reasonable size
-	readChunkSize := 32 * 1024 // Read 32KB chunks
-	if s.bitrate > 0 {
-		// Read roughly 1 second of data if bitrate is known, capped at e.g., 256KB
-		readChunkSize = s.bitrate
-		if readChunkSize > 256*1024 {
-			readChunkSize = 256 * 1024
-		}
-		if readChunkSize < 4*1024 { // Ensure a minimum read size
-			readChunkSize = 4 * 1024
-		}
-	}
-	buf := make([]byte, readChunkSize)
+	buf := make([]byte, readChunkSize(s.bitrate, networkThroughput.get()))
 
-	nHttp, readErr := s.resp.Body.Read(buf)
+	nHttp, readErr := resp.Body.Read(buf)
 
 	s.lock.Lock() // Lock only when modifying the shared buffer
 	// Check if buffer is nil before writing
@@ -359,6 +364,8 @@ func (s *StreamBuffer) readData() (finished bool, err error) {
 	}
 
 	if nHttp > 0 {
+		s.downloaded += int64(nHttp)
+		atomic.AddInt64(&sessionDownloaded, int64(nHttp))
 		nBuff, writeErr := s.buff.Write(buf[:nHttp]) // Write only the bytes read
 		if writeErr != nil {
 			logrus.Errorf("Error writing to stream buffer: %v", writeErr)
@@ -389,11 +396,219 @@ func (s *StreamBuffer) readData() (finished bool, err error) {
 	if readErr != nil {
 		if readErr == io.EOF {
 			logrus.Debug("EOF reached while reading stream body")
-		} else {
-			logrus.Errorf("Error reading stream body: %v", readErr)
+		} else if s.ctx.Err() == nil {
+			// connection dropped, not closed by us
+			err := s.reconnect(readErr)
+			if err == nil {
+				return false, nil
+			}
+			logrus.Errorf("Error reading stream body: %v", err)
+			return true, err
 		}
 		return true, readErr // Signal stop on EOF or any other read error
 	}
 
 	return false, nil // Continue buffering
-}
\ No newline at end of file
+}
+
+// readAhead reads one chunk, or if less than configured http_buffering_s is buffered, keeps reading
+// back to back for one reading interval. Throughput is measured from back to back reads.
+func (s *StreamBuffer) readAhead() (finished bool, err error) {
+	started := time.Now()
+	read := 0
+	for {
+		before := s.Len()
+		finished, err = s.readData()
+		after := s.Len()
+		if after > before {
+			read += after - before
+		}
+		if finished || s.bitrate == 0 || after >= s.bitrate*config.HttpBufferingS() ||
+			after >= bufferLimit() ||
+			time.Since(started) > streamReadInterval {
+			break
+		}
+	}
+	if read > 0 && time.Since(started) > streamReadInterval/2 {
+		networkThroughput.add(read, time.Since(started))
+	}
+	return
+}
+
+// bufferLimit returns maximum size of stream buffer in bytes.
+func bufferLimit() int {
+	limit := config.HttpBufferingLimitMem() * 1024 * 1024
+	if low := config.LowMemoryBufferKB * 1024; config.AppConfig.Player.LowMemory && limit > low {
+		limit = low
+	}
+	return limit
+}
+
+// sessionDownloaded is total bytes of streams downloaded since start.
+var sessionDownloaded int64
+
+// BytesDownloaded returns total bytes of streams downloaded since start.
+func BytesDownloaded() int64 {
+	return atomic.LoadInt64(&sessionDownloaded)
+}
+
+// streamReadInterval is how often background buffering reads from network.
+const streamReadInterval = 500 * time.Millisecond
+
+// minimum initial buffer
+const minBufferBytes = 64 * 1024
+
+// initialBufferSize returns how many bytes to buffer before playback starts. If bitrate and network throughput
+// are known, this is the minimum needed for download to stay ahead of playback for the whole song, with some
+// margin: nothing more than a second of audio on a fast network, more on a network slower than the bitrate.
+// Otherwise configured initial buffer is used.
+func initialBufferSize(length, bitrate, throughput int) int {
+	var target int
+	if bitrate > 0 && throughput > 0 && length > 0 {
+		target = bitrate
+		if throughput < bitrate {
+			// download must cover remaining song while it plays: x + throughput * t >= bitrate * t
+			deficit := float64(length) * (1 - float64(throughput)/float64(bitrate))
+			target += int(deficit * 1.25)
+		}
+		logrus.Debugf("Adaptive initial buffer: throughput %d KiB/s, bitrate %d KiB/s",
+			throughput/1024, bitrate/1024)
+	} else if config.InitialBufferKB() > 0 {
+		target = config.InitialBufferKB() * 1024
+	} else if bitrate > 0 {
+		target = bitrate * config.HttpBufferingS()
+	} else {
+		target = 512 * 1024
+	}
+
+	if target < minBufferBytes {
+		target = minBufferBytes
+	}
+	if length > 0 && target > length {
+		target = length
+	}
+	return target
+}
+
+// readChunkSize returns size of single read from network: one second of audio, or what network delivers in
+// one reading interval, if that is more. Chunk is limited to [4 KiB, 1 MiB].
+func readChunkSize(bitrate, throughput int) int {
+	size := 32 * 1024
+	if bitrate > 0 {
+		size = bitrate
+	}
+	if perInterval := int(float64(throughput) * streamReadInterval.Seconds()); perInterval > size {
+		size = perInterval
+	}
+	if size > 1024*1024 {
+		size = 1024 * 1024
+	}
+	if limit := config.LowMemoryReadChunkKB * 1024; config.AppConfig.Player.LowMemory && size > limit {
+		size = limit
+	}
+	if size < 4*1024 {
+		size = 4 * 1024
+	}
+	return size
+}
+
+// throughputMeter estimates network throughput from downloads. It is shared by streams, so a new stream
+// can use measurements from previous ones.
+type throughputMeter struct {
+	lock        sync.Mutex
+	bytesPerSec float64
+}
+
+var networkThroughput = &throughputMeter{}
+
+// add adds measurement of bytes downloaded in elapsed time.
+func (t *throughputMeter) add(bytes int, elapsed time.Duration) {
+	// short reads are served from os buffers and don't tell much about network
+	if bytes <= 0 || elapsed < time.Millisecond*50 {
+		return
+	}
+	rate := float64(bytes) / elapsed.Seconds()
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.bytesPerSec == 0 {
+		t.bytesPerSec = rate
+	} else {
+		// exponential moving average
+		t.bytesPerSec = 0.7*t.bytesPerSec + 0.3*rate
+	}
+}
+
+// get returns estimated throughput in bytes per second, 0 if unknown.
+func (t *throughputMeter) get() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return int(t.bytesPerSec)
+}
+
+// maximum delay between reconnect attempts
+const maxReconnectDelay = time.Second * 16
+
+// reconnect resumes interrupted download. Attempts are retried with exponential backoff,
+// at most player.stream_retries times.
+func (s *StreamBuffer) reconnect(cause error) error {
+	retries := config.StreamRetries()
+	delay := time.Second
+	for attempt := 1; attempt <= retries; attempt++ {
+		logrus.Warningf("Stream interrupted (%v), reconnect in %v (%d/%d)", cause, delay, attempt, retries)
+		select {
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		case <-time.After(delay):
+		}
+
+		err := s.resume()
+		if err == nil {
+			logrus.Infof("Stream resumed at %d KiB", s.downloaded/1024)
+			return nil
+		}
+		cause = err
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+	return fmt.Errorf("stream interrupted: %v", cause)
+}
+
+// resume requests rest of the stream starting from already downloaded offset. If server ignores
+// range request, already downloaded bytes are skipped.
+func (s *StreamBuffer) resume() error {
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodGet, s.req.URL.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header = s.req.Header.Clone()
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", s.downloaded))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		logrus.Debug("Server does not support range requests, skip downloaded bytes")
+		_, err = io.CopyN(ioutil.Discard, resp.Body, s.downloaded)
+		if err != nil {
+			resp.Body.Close()
+			return fmt.Errorf("skip downloaded bytes: %v", err)
+		}
+	default:
+		resp.Body.Close()
+		return NewRequestError(resp.StatusCode, "")
+	}
+
+	s.lock.Lock()
+	old := s.resp
+	s.resp = resp
+	s.lock.Unlock()
+	if old != nil && old.Body != nil {
+		old.Body.Close()
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/models"
+	"tryffel.net/go/jellycli/task"
+)
+
+// SupervisedServer is a server whose connection can be monitored.
+type SupervisedServer interface {
+	interfaces.ProgressReporter
+	ConnectionOk() error
+}
+
+// Reconnecter restores session, e.g. capabilities and websocket, after server has been unreachable.
+type Reconnecter interface {
+	Reconnect() error
+}
+
+// ConnectionSupervisor checks server connection periodically. During outage playback reports are
+// kept and sent once server is reachable again. If server implements Reconnecter, session is restored
+// when connection is back.
+type ConnectionSupervisor struct {
+	task.Task
+	server SupervisedServer
+	// check triggers connection check immediately
+	check chan struct{}
+
+	lock    sync.Mutex
+	status  models.ConnectionStatus
+	pending []*interfaces.ApiPlaybackState
+}
+
+// NewConnectionSupervisor creates new supervisor. Server is assumed to be connected.
+func NewConnectionSupervisor(server SupervisedServer) *ConnectionSupervisor {
+	c := &ConnectionSupervisor{
+		server: server,
+		check:  make(chan struct{}, 1),
+		status: models.ConnectionStatus{
+			State: models.ConnectionOk,
+			Since: time.Now(),
+		},
+	}
+	c.Name = "Connection supervisor"
+	c.SetLoop(c.loop)
+	return c
+}
+
+// Status returns current connection status.
+func (c *ConnectionSupervisor) Status() models.ConnectionStatus {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	status := c.status
+	status.PendingReports = len(c.pending)
+	return status
+}
+
+// ReportProgress reports progress to server. If server is not reachable, report is kept
+// until connection is back.
+func (c *ConnectionSupervisor) ReportProgress(state *interfaces.ApiPlaybackState) error {
+	c.lock.Lock()
+	connected := c.status.State == models.ConnectionOk
+	if !connected {
+		c.addPending(state)
+	}
+	c.lock.Unlock()
+	if !connected {
+		return nil
+	}
+
+	err := c.server.ReportProgress(state)
+	if err == nil {
+		return nil
+	}
+	if connErr := c.server.ConnectionOk(); connErr == nil {
+		// server is up, so this is a real error
+		return err
+	}
+	c.lock.Lock()
+	c.addPending(state)
+	c.lock.Unlock()
+	c.setState(models.ConnectionLost, err)
+	select {
+	case c.check <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// addPending adds report to pending reports. Consecutive time updates of same song are merged.
+// Caller must hold lock.
+func (c *ConnectionSupervisor) addPending(state *interfaces.ApiPlaybackState) {
+	if n := len(c.pending); n > 0 {
+		last := c.pending[n-1]
+		if last.Event == interfaces.EventTimeUpdate && state.Event == interfaces.EventTimeUpdate &&
+			last.ItemId == state.ItemId {
+			c.pending[n-1] = state
+			return
+		}
+	}
+	if len(c.pending) >= config.MaxPendingReports {
+		c.pending = c.pending[1:]
+	}
+	c.pending = append(c.pending, state)
+}
+
+func (c *ConnectionSupervisor) setState(state models.ConnectionState, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err != nil {
+		c.status.Error = err.Error()
+	} else {
+		c.status.Error = ""
+	}
+	if c.status.State == state {
+		return
+	}
+	c.status.State = state
+	c.status.Since = time.Now()
+	switch state {
+	case models.ConnectionLost:
+		logrus.Warningf("Lost connection to server: %v", err)
+	case models.ConnectionReconnecting:
+		logrus.Info("Server is reachable again, reconnecting")
+	case models.ConnectionOk:
+		logrus.Info("Connection to server restored")
+	}
+}
+
+func (c *ConnectionSupervisor) loop() {
+	timer := time.NewTimer(config.ConnectionCheckInterval)
+	defer timer.Stop()
+	retry := config.ConnectionRetryMin
+	for {
+		select {
+		case <-c.StopChan():
+			return
+		case <-c.check:
+		case <-timer.C:
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+
+		if c.checkConnection() {
+			retry = config.ConnectionRetryMin
+			timer.Reset(config.ConnectionCheckInterval)
+		} else {
+			timer.Reset(retry)
+			retry *= 2
+			if retry > config.ConnectionRetryMax {
+				retry = config.ConnectionRetryMax
+			}
+		}
+	}
+}
+
+// checkConnection checks connection and reconnects if needed. It returns true if connection is ok.
+func (c *ConnectionSupervisor) checkConnection() bool {
+	err := c.server.ConnectionOk()
+	if err != nil {
+		c.setState(models.ConnectionLost, err)
+		return false
+	}
+
+	c.lock.Lock()
+	state := c.status.State
+	c.lock.Unlock()
+	if state == models.ConnectionOk {
+		return true
+	}
+
+	c.setState(models.ConnectionReconnecting, nil)
+	if reconnecter, ok := c.server.(Reconnecter); ok {
+		err = reconnecter.Reconnect()
+		if err != nil {
+			c.setState(models.ConnectionLost, err)
+			return false
+		}
+	}
+	err = c.flush()
+	if err != nil {
+		c.setState(models.ConnectionLost, err)
+		return false
+	}
+	c.setState(models.ConnectionOk, nil)
+	return true
+}
+
+// flush sends pending reports in order. Reports not sent are kept.
+func (c *ConnectionSupervisor) flush() error {
+	c.lock.Lock()
+	pending := c.pending
+	c.pending = nil
+	c.lock.Unlock()
+	if len(pending) > 0 {
+		logrus.Infof("Send %d pending playback reports", len(pending))
+	}
+
+	for i, v := range pending {
+		err := c.server.ReportProgress(v)
+		if err != nil {
+			c.lock.Lock()
+			c.pending = append(pending[i:], c.pending...)
+			c.lock.Unlock()
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"tryffel.net/go/jellycli/api"
+)
+
+var castDevicesCmd = &cobra.Command{
+	Use:   "list-cast-devices",
+	Short: "List devices that can be played on with --cast",
+	Long: `List devices, e.g. DLNA renderers and Chromecasts, that server can control for current user.
+Start jellycli with '--cast <name>' to play on device instead of local output. Jellycli then acts as
+a controller only: device keeps the queue and 'jellycli ctl' commands are forwarded to it.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		a, err := connectServer()
+		if err != nil {
+			exitError(err)
+		}
+		server, ok := a.server.(api.SessionController)
+		if !ok {
+			exitError(fmt.Errorf("server does not support casting"))
+		}
+		sessions, err := server.GetSessions()
+		if err != nil {
+			exitError(fmt.Errorf("get devices: %v", err))
+		}
+		if len(sessions) == 0 {
+			fmt.Println("no devices found")
+		}
+		for _, v := range sessions {
+			playing := ""
+			if v.Song != nil {
+				playing = ", playing " + v.Song.Name
+			}
+			fmt.Printf("%-30s %s (%s%s)\n", v.DeviceName, v.DeviceId, v.Client, playing)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(castDevicesCmd)
+}
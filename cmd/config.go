@@ -0,0 +1,88 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"tryffel.net/go/jellycli/config"
+)
+
+var configQuiet bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect configuration",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate config file and print effective configuration",
+	Long: `Check config file for unknown keys, invalid values and missing server settings, then
+print effective configuration, including environment variables, with tokens masked.
+Exit code is 1 if any problems are found.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		initConfig()
+		file := viper.New()
+		file.SetConfigFile(config.ConfigFile)
+		if err := file.ReadInConfig(); err != nil {
+			exitError(fmt.Errorf("read config file: %v", err))
+		}
+
+		problems := 0
+		for _, key := range config.UnknownKeys(file.AllKeys()) {
+			fmt.Fprintf(os.Stderr, "%s: unknown key\n", key)
+			problems++
+		}
+		for _, err := range config.AppConfig.Validate() {
+			fmt.Fprintln(os.Stderr, err)
+			problems++
+		}
+
+		if !configQuiet {
+			fmt.Printf("# %s\n", config.ConfigFile)
+			if config.AppConfig.Profile != "" {
+				fmt.Printf("# profile: %s\n", config.AppConfig.Profile)
+			}
+			settings := config.Settings()
+			keys := make([]string, 0, len(settings))
+			for key := range settings {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Printf("%s: %v\n", key, settings[key])
+			}
+		}
+		if problems > 0 {
+			fmt.Fprintf(os.Stderr, "%d problems found\n", problems)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	configValidateCmd.Flags().BoolVarP(&configQuiet, "quiet", "q", false, "only print problems")
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,225 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"tryffel.net/go/jellycli/ipc"
+)
+
+var ctlSocket string
+var ctlClient string
+var ctlFollow bool
+
+var ctlCmd = &cobra.Command{
+	Use:   "ctl <command> [args...]",
+	Short: "Control running instance",
+	Long: `Send command to running jellycli instance over local socket (named pipe on Windows).
+
+Commands:
+  play [id...]               continue playback, or play given items now, replacing queue
+  pause, toggle, stop        pause, toggle pause, stop playback
+  next, prev                 play next / previous song
+  forward, rewind            seek forward / backward by player.seek_step_s
+  seek <+n|-n>               seek given seconds
+  status                     show current song and player state
+  status json|waybar|plain   show status as json, waybar custom module or single line,
+                             with --follow it is printed whenever it changes
+  info [song id]             show metadata of current or given song
+  link [id]                  show link to current song or given item in server's web interface
+  stats                      show memory usage, stream format and buffering, and downloads of this session
+  queue                      list queue with time until each song starts
+  queue ids                  list song ids in queue, one per line
+  queue add <id...>          add items to the end of queue
+  queue next <id...>         play items next
+  queue remove <index>       remove song from queue
+  queue move <index> <new>   move song to new position in queue
+  queue jump <index>         play song now, removing songs before it
+  queue save <name>          save queue as new playlist in server
+  queue clear                clear queue, except current song
+  playlist <id>              list songs in playlist
+  playlist <id> add <song id...>
+  playlist <id> remove <index>
+  playlist <id> move <index> <new index>
+                             edit playlist, first index is 1
+  album <id>                 list songs of album, grouped by disc if album has many
+  album <id> info            show overview, genres, year, duration and favorite status of album
+  album <id> play|queue [disc]
+                             play album or single disc now, or add it to the end of queue
+  artist <id>                list most played songs and similar artists of artist
+  artist <id> play|queue     play top songs of artist now, or add them to the end of queue
+  mix [add]                  replace upcoming songs with instant mix of current song, or add it to queue
+  fav [on|off [song id]]     toggle or set favorite of current or given song
+  fav sync                   send favorites changed while offline to server
+  like [song id]             toggle like of current or given song
+  dislike [song id]          toggle dislike of current or given song
+  favs albums|songs [page]   list favorite albums or songs, 100 per page
+  albums|songs [filter...] [page]
+                             list albums or songs, 100 per page, filters: year=<from>[-<to>],
+                             genre=<genre>[,<genre>], fav, unplayed, sorted with
+                             sort=name|date|artist|album|plays|random|latest|played and asc|desc
+  search [artist|album|song|playlist] <query>
+                             search library, results are grouped by type and listed with ids
+  new [days]                 list albums released in last days (default 30), newest first
+  views                      list music libraries, '*' marks ones selected in jellyfin.music_views
+  browse <id>                list folders and items in library or folder
+  history [all|<session>]    list played songs, older sessions are collapsed
+  history page <n>           list played songs by pages of 100, latest first
+  history play <n>           play song number n from history
+  history queue <session>    add songs of history session to the end of queue
+  user [<name>]              list logged in users or switch to one of them
+  volume [n|+n|-n|up|down]   show or set volume, up and down change it by player.volume_step
+  mute                       toggle mute
+  shuffle [on|off]           toggle or set shuffle
+  shuffle all|<id>           play whole library, album, artist or playlist in random order
+  shuffle seed [<n>]         show or set seed, same seed shuffles same queue in same order
+  speed [rate|+n|-n]         show or set playback speed, 0.5 - 2.0
+  preview <song id>|stop     preview song on top of current audio
+  help                       list commands supported by instance
+
+Item ids given to play and queue can be songs, albums, artists or playlists, e.g. from search.
+`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		socket := ctlSocketPath()
+		client := ctlClient
+		if client == "" {
+			client = defaultClientName()
+		}
+
+		req := &ipc.Request{Command: args[0], Args: args[1:], Client: client}
+		if ctlFollow {
+			if !followable(req) {
+				fmt.Fprintf(os.Stderr, "--follow is not supported for '%s', it can only repeat commands that don't modify player\n",
+					strings.Join(args, " "))
+				os.Exit(1)
+			}
+			follow(socket, req)
+			return
+		}
+		resp, err := ipc.Send(socket, req)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if resp.Output != "" {
+			fmt.Println(resp.Output)
+		}
+		if !resp.Ok {
+			fmt.Fprintln(os.Stderr, "error:", resp.Error)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	ctlCmd.Flags().StringVar(&ctlSocket, "socket", "", "socket of running instance")
+	ctlCmd.Flags().StringVar(&ctlClient, "as", "", "name shown for songs added to queue, default user@host")
+	ctlCmd.Flags().BoolVarP(&ctlFollow, "follow", "f", false,
+		"repeat command that only shows state every second and print output when it changes, e.g. for status bars")
+	// allow e.g. 'ctl volume -5'
+	ctlCmd.Flags().SetInterspersed(false)
+	rootCmd.AddCommand(ctlCmd)
+}
+
+// ctlSocketPath returns socket given with --socket, configured socket or default socket, in that order.
+func ctlSocketPath() string {
+	socket := ctlSocket
+	if socket == "" {
+		// running instance owns config file, read it without creating or saving it
+		setConfigFile()
+		if err := viper.ReadInConfig(); err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintln(os.Stderr, "read config file:", err)
+		}
+		socket = viper.GetString("player.ipc_socket")
+	}
+	if socket == "" {
+		socket = ipc.DefaultSocketPath()
+	}
+	return socket
+}
+
+// follow sends request every second and prints output whenever it changes. If instance is not running
+// or command fails, empty line is printed and request is retried.
+func follow(socket string, req *ipc.Request) {
+	last := ""
+	first := true
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		out := ""
+		resp, err := ipc.Send(socket, req)
+		if err == nil && resp.Ok {
+			out = resp.Output
+		}
+		if first || out != last {
+			fmt.Println(out)
+			last = out
+			first = false
+		}
+		<-ticker.C
+	}
+}
+
+// followable returns true if command only reads state and can be repeated with --follow.
+func followable(req *ipc.Request) bool {
+	args := req.Args
+	switch req.Command {
+	case "status", "stats", "info", "link", "search", "new", "views", "browse", "favs", "albums", "songs", "help":
+		return true
+	case "volume", "speed", "user":
+		return len(args) == 0
+	case "queue":
+		return len(args) == 0 || (len(args) == 1 && args[0] == "ids")
+	case "shuffle":
+		return len(args) == 1 && args[0] == "seed"
+	case "history":
+		return len(args) == 0 || (args[0] != "play" && args[0] != "queue")
+	case "playlist", "artist":
+		return len(args) == 1
+	case "album":
+		return len(args) == 1 || (len(args) == 2 && args[1] == "info")
+	default:
+		return false
+	}
+}
+
+// defaultClientName returns user@host, or as much of it as is known.
+func defaultClientName() string {
+	name := ""
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return name
+	}
+	if name == "" {
+		return host
+	}
+	return name + "@" + host
+}
@@ -0,0 +1,260 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"tryffel.net/go/jellycli/api"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+	"tryffel.net/go/jellycli/util"
+)
+
+var downloadDir string
+var downloadWorkers int
+
+var downloadCmd = &cobra.Command{
+	Use:   "download <artist|album|playlist|song> <query|id>",
+	Short: "Download original files",
+	Long: `Download original audio files of artist, album, playlist or song to directory.
+Item is searched by name, best match is downloaded. If nothing is found, query is used as item id.
+Files are saved as <dir>/<album artist>/<album>/<track> <title>.<ext>, tracks after first disc are prefixed
+with disc number. Existing files are skipped.
+Original files are not transcoded, so they keep their embedded tags.`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		itemType, err := parseItemType(args[0])
+		if err != nil {
+			exitError(err)
+		}
+		a, err := connectServer()
+		if err != nil {
+			exitError(err)
+		}
+		songs, name, err := getItemSongs(a.server, itemType, strings.Join(args[1:], " "))
+		if err != nil {
+			exitError(err)
+		}
+		if len(songs) == 0 {
+			exitError(fmt.Errorf("%s '%s' has no songs", strings.ToLower(string(itemType)), name))
+		}
+		fmt.Printf("Downloading %s songs of %s '%s' to %s\n", util.FormatNumber(len(songs)),
+			strings.ToLower(string(itemType)), name, downloadDir)
+		failed := downloadSongs(a.server, songs, downloadDir, downloadWorkers)
+		if failed > 0 {
+			exitError(fmt.Errorf("%d downloads failed", failed))
+		}
+	},
+}
+
+func init() {
+	downloadCmd.Flags().StringVarP(&downloadDir, "dir", "d", ".", "target directory")
+	downloadCmd.Flags().IntVarP(&downloadWorkers, "workers", "w", 4, "concurrent downloads")
+	rootCmd.AddCommand(downloadCmd)
+}
+
+func exitError(err error) {
+	fmt.Fprintln(os.Stderr, "error:", err)
+	os.Exit(1)
+}
+
+func parseItemType(s string) (models.ItemType, error) {
+	for _, v := range []models.ItemType{models.TypeArtist, models.TypeAlbum, models.TypePlaylist, models.TypeSong} {
+		if strings.EqualFold(s, string(v)) {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("unknown item type '%s', expected artist, album, playlist or song", s)
+}
+
+// findItem searches item by name and returns its id and name. Exact match is preferred over first result.
+// If nothing is found, query is assumed to be id.
+func findItem(library api.Library, itemType models.ItemType, query string) (models.Id, string, error) {
+	items, err := library.Search(query, itemType, config.SearchLimit)
+	if err != nil {
+		return "", "", fmt.Errorf("search: %v", err)
+	}
+	if len(items) == 0 {
+		return models.Id(query), query, nil
+	}
+	for _, v := range items {
+		if strings.EqualFold(v.GetName(), query) || v.GetId().String() == query {
+			return v.GetId(), v.GetName(), nil
+		}
+	}
+	return items[0].GetId(), items[0].GetName(), nil
+}
+
+// getItemSongs returns songs of artist, album, playlist or song matching query, and name of item.
+func getItemSongs(server interface{}, itemType models.ItemType, query string) ([]*models.Song, string, error) {
+	library, ok := server.(api.Library)
+	if !ok {
+		return nil, "", errors.New("server does not support browsing library")
+	}
+	id, name, err := findItem(library, itemType, query)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var songs []*models.Song
+	switch itemType {
+	case models.TypeSong:
+		songs, err = library.GetSongsById([]models.Id{id})
+	case models.TypeAlbum:
+		songs, err = library.GetAlbumSongs(id)
+	case models.TypeArtist:
+		songs, err = library.GetArtistSongs(id)
+	case models.TypePlaylist:
+		editor, ok := server.(api.PlaylistEditor)
+		if !ok {
+			return nil, "", errors.New("server does not support playlists")
+		}
+		songs, err = editor.GetPlaylistSongs(id)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("get songs: %v", err)
+	}
+	return songs, name, nil
+}
+
+// downloadSongs downloads songs to dir with given number of workers and prints progress.
+// Number of failed downloads is returned.
+func downloadSongs(server interface{}, songs []*models.Song, dir string, workers int) int {
+	streamer, ok := server.(api.Streamer)
+	if !ok {
+		exitError(errors.New("server does not support downloading"))
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan *models.Song)
+	lock := sync.Mutex{}
+	done := 0
+	failed := 0
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for song := range jobs {
+				file, size, err := downloadSong(streamer, song, dir)
+				lock.Lock()
+				done++
+				progress := fmt.Sprintf("[%d/%d]", done, len(songs))
+				if err != nil {
+					failed++
+					fmt.Fprintf(os.Stderr, "%s %s: %v\n", progress, song.Name, err)
+				} else if size < 0 {
+					fmt.Printf("%s %s exists, skip\n", progress, file)
+				} else {
+					fmt.Printf("%s %s (%.1f MiB)\n", progress, file, float64(size)/1024/1024)
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+	for _, v := range songs {
+		jobs <- v
+	}
+	close(jobs)
+	wg.Wait()
+	return failed
+}
+
+// downloadSong downloads song to its path under dir. Partial file is written first and renamed when complete.
+// If file already exists, size is -1.
+func downloadSong(streamer api.Streamer, song *models.Song, dir string) (string, int64, error) {
+	reader, format, err := streamer.Download(song)
+	if err != nil {
+		return "", 0, err
+	}
+	defer reader.Close()
+
+	ext := format.String()
+	if ext == "" {
+		ext = "audio"
+	}
+	file := songFileName(song, ext)
+	target := filepath.Join(dir, file)
+	if _, err := os.Stat(target); err == nil {
+		return file, -1, nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return file, 0, err
+	}
+	part := target + ".part"
+	fd, err := os.Create(part)
+	if err != nil {
+		return file, 0, err
+	}
+	size, err := io.Copy(fd, reader)
+	closeErr := fd.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(part)
+		return file, 0, err
+	}
+	return file, size, os.Rename(part, target)
+}
+
+// songFileName returns relative path for song: <album artist>/<album>/<track> <title>.<ext>. Tracks of
+// discs after the first one are prefixed with disc number.
+func songFileName(song *models.Song, ext string) string {
+	artist := song.AlbumArtistName
+	if artist == "" && len(song.Artists) > 0 {
+		artist = song.Artists[0].Name
+	}
+	if artist == "" {
+		artist = "Unknown artist"
+	}
+	album := song.AlbumName
+	if album == "" {
+		album = "Unknown album"
+	}
+
+	name := fmt.Sprintf("%02d %s.%s", song.Index, song.Name, ext)
+	if song.DiscNumber > 1 {
+		name = fmt.Sprintf("%d-%s", song.DiscNumber, name)
+	}
+	return filepath.Join(safeFileName(artist), safeFileName(album), safeFileName(name))
+}
+
+// safeFileName replaces characters that are not allowed in file names on common file systems.
+func safeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < 32 {
+			return '_'
+		}
+		return r
+	}, name)
+	return strings.TrimRight(strings.TrimSpace(name), ".")
+}
@@ -35,7 +35,11 @@ JELLYCLI_JELLYFIN_TOKEN
 JELLYCLI_JELLYFIN_USERID
 JELLYCLI_JELLYFIN_DEVICE_ID
 JELLYCLI_JELLYFIN_SERVER_ID
-// JELLYCLI_JELLYFIN_MUSIC_VIEW // Removed: TUI-specific concept
+JELLYCLI_JELLYFIN_DEVICE_NAME
+JELLYCLI_JELLYFIN_CLIENT_NAME
+JELLYCLI_JELLYFIN_MUSIC_VIEWS
+
+JELLYCLI_PROFILE
 
 JELLYCLI_PLAYER_SERVER
 JELLYCLI_PLAYER_LOGFILE
@@ -46,6 +50,56 @@ JELLYCLI_PLAYER_AUDIO_BUFFERING_MS
 JELLYCLI_PLAYER_ENABLE_REMOTE_CONTROL
 JELLYCLI_PLAYER_ENABLE_LOCAL_CACHE
 JELLYCLI_PLAYER_ENABLE_LOCAL_CACHE_DIR
+JELLYCLI_PLAYER_NORMALIZE_VOLUME
+JELLYCLI_PLAYER_ESTIMATE_MISSING_GAIN
+JELLYCLI_PLAYER_DISABLE_IPC
+JELLYCLI_PLAYER_IPC_SOCKET
+JELLYCLI_PLAYER_HISTORY_SESSION_GAP_MIN
+JELLYCLI_PLAYER_READ_ONLY
+JELLYCLI_PLAYER_LOCALE
+JELLYCLI_PLAYER_OUTPUT
+JELLYCLI_PLAYER_OUTPUT_FORMAT
+JELLYCLI_PLAYER_AUDIO_OUTPUT
+JELLYCLI_PLAYER_AUDIO_DEVICE
+JELLYCLI_PLAYER_PROXY
+JELLYCLI_PLAYER_RESTORE_STATE
+JELLYCLI_PLAYER_TLS_CLIENT_CERT
+JELLYCLI_PLAYER_TLS_CLIENT_KEY
+JELLYCLI_PLAYER_TLS_CA_CERT
+JELLYCLI_PLAYER_AUTO_PAUSE
+JELLYCLI_PLAYER_AUTO_RESUME
+JELLYCLI_PLAYER_NIGHT_MODE_START
+JELLYCLI_PLAYER_NIGHT_MODE_END
+JELLYCLI_PLAYER_NIGHT_MODE_MAX_VOLUME
+JELLYCLI_PLAYER_NIGHT_MODE_LOUDNESS
+JELLYCLI_PLAYER_SEEK_STEP_S
+JELLYCLI_PLAYER_VOLUME_STEP
+JELLYCLI_PLAYER_STREAM_RETRIES
+JELLYCLI_PLAYER_OFFLINE_PLAYLISTS
+JELLYCLI_PLAYER_AUTO_QUEUE
+JELLYCLI_PLAYER_AUTO_QUEUE_MIN
+JELLYCLI_PLAYER_MAX_STREAMING_BITRATE
+JELLYCLI_PLAYER_LOG_TO_FILE
+JELLYCLI_PLAYER_LOG_MAX_SIZE_MB
+JELLYCLI_PLAYER_LOG_MAX_BACKUPS
+JELLYCLI_PLAYER_LOG_MAX_AGE_DAYS
+JELLYCLI_PLAYER_LOG_JSON
+JELLYCLI_PLAYER_LOG_LEVELS
+JELLYCLI_PLAYER_OUTPUT_SAMPLE_RATE
+JELLYCLI_PLAYER_RESAMPLE_QUALITY
+JELLYCLI_PLAYER_PERSIST_HISTORY
+JELLYCLI_PLAYER_HISTORY_LIMIT
+JELLYCLI_PLAYER_COLLECT_STATS
+JELLYCLI_PLAYER_RESUME_MIN_DURATION_MIN
+JELLYCLI_PLAYER_REQUEST_TIMEOUT_S
+JELLYCLI_PLAYER_TLS_SKIP_VERIFY
+JELLYCLI_PLAYER_TRIM_SILENCE
+JELLYCLI_PLAYER_TRIM_SILENCE_MS
+JELLYCLI_PLAYER_PREFERRED_CODEC
+JELLYCLI_PLAYER_IDLE_MODE
+JELLYCLI_PLAYER_DECODE_AHEAD_MS
+JELLYCLI_PLAYER_LOW_MEMORY
+JELLYCLI_PLAYER_MEDIA_KEYS
 
 # Additional environment variables
 JELLYCLI_JELLYFIN_PASSWORD
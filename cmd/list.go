@@ -0,0 +1,240 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"tryffel.net/go/jellycli/api"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+)
+
+var listSearch string
+var listJson bool
+var listLimit int
+
+var listCmd = &cobra.Command{
+	Use:   "list <artists|albums|playlists|songs>",
+	Short: "Print library contents",
+	Long: `Print artists, albums, playlists or songs of library, one per line with tab separated fields,
+or as json array with --json. Output is meant for scripts and tools like fzf, first field is id that can be
+passed to e.g. 'ctl play'. With --search, only items matching query are printed.
+
+Fields are:
+  artists    id, name, album count
+  albums     id, artist, name, year
+  playlists  id, name, song count
+  songs      id, artist, album, name, duration in seconds`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		itemType, err := parseItemType(strings.TrimSuffix(args[0], "s"))
+		if err != nil {
+			exitError(fmt.Errorf("unknown item type '%s', expected artists, albums, playlists or songs", args[0]))
+		}
+		a, err := connectServer()
+		if err != nil {
+			exitError(err)
+		}
+		items, err := listItems(a.server, itemType, listSearch, listLimit)
+		if err != nil {
+			exitError(err)
+		}
+		if listJson {
+			err = printItemsJson(items)
+		} else {
+			printItems(items)
+		}
+		if err != nil {
+			exitError(err)
+		}
+	},
+}
+
+func init() {
+	listCmd.Flags().StringVarP(&listSearch, "search", "s", "", "print only items matching query")
+	listCmd.Flags().BoolVar(&listJson, "json", false, "print json")
+	listCmd.Flags().IntVarP(&listLimit, "limit", "n", 0, "print at most n items, 0 prints all")
+	rootCmd.AddCommand(listCmd)
+}
+
+// listItems returns items of itemType, or items matching query if it's not empty. Limit 0 returns all items.
+func listItems(server interface{}, itemType models.ItemType, query string, limit int) ([]models.Item, error) {
+	library, ok := server.(api.Library)
+	if !ok {
+		return nil, errors.New("server does not support browsing library")
+	}
+	if query != "" {
+		if limit == 0 {
+			limit = config.ListSearchLimit
+		}
+		items, err := library.Search(query, itemType, limit)
+		if err != nil {
+			return nil, fmt.Errorf("search: %v", err)
+		}
+		return items, nil
+	}
+
+	items := []models.Item{}
+	switch itemType {
+	case models.TypeArtist, models.TypePlaylist:
+		lister, ok := server.(api.ItemLister)
+		if !ok {
+			return nil, errors.New("server does not support listing artists and playlists")
+		}
+		if itemType == models.TypeArtist {
+			artists, err := lister.GetArtists()
+			if err != nil {
+				return nil, fmt.Errorf("get artists: %v", err)
+			}
+			for _, v := range artists {
+				items = append(items, v)
+			}
+		} else {
+			playlists, err := lister.GetPlaylists()
+			if err != nil {
+				return nil, fmt.Errorf("get playlists: %v", err)
+			}
+			for _, v := range playlists {
+				items = append(items, v)
+			}
+		}
+	case models.TypeAlbum, models.TypeSong:
+		paging := models.DefaultPaging()
+		for {
+			var page []models.Item
+			var total int
+			var err error
+			if itemType == models.TypeAlbum {
+				var albums []*models.Album
+				albums, total, err = library.GetAlbums(models.Filter{}, models.Sort{}, paging)
+				page = models.AlbumsToItems(albums)
+			} else {
+				var songs []*models.Song
+				songs, total, err = library.GetSongs(models.Filter{}, models.Sort{}, paging)
+				for _, v := range songs {
+					page = append(page, v)
+				}
+			}
+			if err != nil {
+				return nil, fmt.Errorf("get %ss: %v", strings.ToLower(string(itemType)), err)
+			}
+			items = append(items, page...)
+			if len(page) == 0 || paging.Offset()+len(page) >= total || (limit > 0 && len(items) >= limit) {
+				break
+			}
+			paging.CurrentPage++
+		}
+	}
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
+
+// itemFields returns fields of item printed by list command.
+func itemFields(item models.Item) []string {
+	switch v := item.(type) {
+	case *models.Artist:
+		return []string{v.Id.String(), v.Name, fmt.Sprint(v.AlbumCount)}
+	case *models.Album:
+		artist := ""
+		if len(v.AdditionalArtists) > 0 {
+			artist = v.AdditionalArtists[0].Name
+		}
+		return []string{v.Id.String(), artist, v.Name, fmt.Sprint(v.Year)}
+	case *models.Playlist:
+		return []string{v.Id.String(), v.Name, fmt.Sprint(v.SongCount)}
+	case *models.Song:
+		return []string{v.Id.String(), songArtistName(v), v.AlbumName, v.Name, fmt.Sprint(v.Duration)}
+	default:
+		return []string{item.GetId().String(), item.GetName()}
+	}
+}
+
+func printItems(items []models.Item) {
+	// keep one item per line
+	replacer := strings.NewReplacer("\t", " ", "\n", " ")
+	for _, v := range items {
+		fields := itemFields(v)
+		for i := range fields {
+			fields[i] = replacer.Replace(fields[i])
+		}
+		fmt.Println(strings.Join(fields, "\t"))
+	}
+}
+
+// listItem is item printed as json.
+type listItem struct {
+	Id       string `json:"id"`
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Artist   string `json:"artist,omitempty"`
+	Album    string `json:"album,omitempty"`
+	Year     int    `json:"year,omitempty"`
+	Duration int    `json:"duration,omitempty"`
+	Albums   int    `json:"albums,omitempty"`
+	Songs    int    `json:"songs,omitempty"`
+}
+
+func printItemsJson(items []models.Item) error {
+	out := make([]listItem, len(items))
+	for i, item := range items {
+		out[i] = listItem{Id: item.GetId().String(), Type: strings.ToLower(string(item.GetType())),
+			Name: item.GetName()}
+		switch v := item.(type) {
+		case *models.Artist:
+			out[i].Albums = v.AlbumCount
+		case *models.Album:
+			if len(v.AdditionalArtists) > 0 {
+				out[i].Artist = v.AdditionalArtists[0].Name
+			}
+			out[i].Year = v.Year
+			out[i].Duration = v.Duration
+		case *models.Playlist:
+			out[i].Songs = v.SongCount
+			out[i].Duration = v.Duration
+		case *models.Song:
+			out[i].Artist = songArtistName(v)
+			out[i].Album = v.AlbumName
+			out[i].Year = v.Year
+			out[i].Duration = v.Duration
+		}
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
+
+// songArtistName returns artists of song separated by comma, or album artist if song has no artists.
+func songArtistName(song *models.Song) string {
+	if len(song.Artists) == 0 {
+		return song.AlbumArtistName
+	}
+	names := make([]string, len(song.Artists))
+	for i, v := range song.Artists {
+		names[i] = v.Name
+	}
+	return strings.Join(names, ", ")
+}
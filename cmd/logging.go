@@ -0,0 +1,86 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const modulePrefix = "tryffel.net/go/jellycli/"
+
+// moduleFormatter drops entries that are below log level of the module that logged them.
+// Module is the top-level package of the caller, e.g. api or player. Logger must report caller.
+type moduleFormatter struct {
+	logrus.Formatter
+	defaultLevel logrus.Level
+	levels       map[string]logrus.Level
+}
+
+// newModuleFormatter parses per-module levels. Logger level must be set to returned level, which is the most
+// verbose of given levels.
+func newModuleFormatter(formatter logrus.Formatter, defaultLevel logrus.Level,
+	levels map[string]string) (*moduleFormatter, logrus.Level, error) {
+	f := &moduleFormatter{
+		Formatter:    formatter,
+		defaultLevel: defaultLevel,
+		levels:       map[string]logrus.Level{},
+	}
+	max := defaultLevel
+	for module, name := range levels {
+		level, err := logrus.ParseLevel(name)
+		if err != nil {
+			return nil, max, fmt.Errorf("log level of %s: %v", module, err)
+		}
+		f.levels[strings.ToLower(module)] = level
+		if level > max {
+			max = level
+		}
+	}
+	return f, max, nil
+}
+
+func (f *moduleFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	level := f.defaultLevel
+	if entry.Caller != nil {
+		if l, ok := f.levels[callerModule(entry.Caller.Function)]; ok {
+			level = l
+		}
+	}
+	if entry.Level > level {
+		// logrus writes returned bytes as is, so entry is skipped
+		return nil, nil
+	}
+	return f.Formatter.Format(entry)
+}
+
+// callerModule returns top-level package of function, e.g. 'api' for
+// 'tryffel.net/go/jellycli/api/jellyfin.(*Jellyfin).loop'.
+func callerModule(function string) string {
+	if !strings.HasPrefix(function, modulePrefix) {
+		return ""
+	}
+	module := strings.TrimPrefix(function, modulePrefix)
+	if i := strings.IndexAny(module, "/."); i >= 0 {
+		module = module[:i]
+	}
+	return module
+}
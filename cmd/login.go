@@ -0,0 +1,90 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"tryffel.net/go/jellycli/api/jellyfin"
+	"tryffel.net/go/jellycli/config"
+)
+
+var loginUser string
+
+var loginCmd = &cobra.Command{
+	Use:   "login --user <name>",
+	Short: "Log in as another user",
+	Long: `Log in to server as given user and make it the current user. Password is asked, unless user has
+logged in before and stored token is still valid. Tokens of all logged in users are kept in config file,
+so a running player can switch between them with 'jellycli ctl user <name>'.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if loginUser == "" {
+			exitError(errors.New("user is required"))
+		}
+		initConfig()
+		err := initLogging()
+		if err != nil {
+			exitError(err)
+		}
+		if config.AppConfig.Player.Server != "jellyfin" {
+			exitError(fmt.Errorf("login not supported for %s", config.AppConfig.Player.Server))
+		}
+
+		conf := config.AppConfig.Jellyfin
+		conf.Token = ""
+		conf.UserId = ""
+		if user := conf.FindUser(loginUser); user != nil {
+			conf.Token = user.Token
+			conf.UserId = user.UserId
+		}
+		server, err := jellyfin.NewJellyfin(&conf, &loginProvider{user: loginUser})
+		if err != nil {
+			exitError(fmt.Errorf("login: %v", err))
+		}
+		if err := server.ConnectionOk(); err != nil {
+			exitError(fmt.Errorf("login: %v", err))
+		}
+		config.AppConfig.Jellyfin = *server.GetConfig().(*config.Jellyfin)
+		err = config.SaveConfig()
+		if err != nil {
+			exitError(err)
+		}
+		fmt.Printf("logged in as %s\n", config.AppConfig.Jellyfin.CurrentUser())
+	},
+}
+
+func init() {
+	loginCmd.Flags().StringVarP(&loginUser, "user", "u", "", "user name")
+	rootCmd.AddCommand(loginCmd)
+}
+
+// loginProvider answers username with user to log in and reads password from environment or stdin.
+type loginProvider struct {
+	user string
+}
+
+func (l *loginProvider) Get(key string, sensitive bool, label string) (string, error) {
+	if key == "jellyfin.username" {
+		return l.user, nil
+	}
+	return (&config.ViperStdConfigProvider{}).Get(key, sensitive, label)
+}
@@ -0,0 +1,136 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"tryffel.net/go/jellycli/config"
+)
+
+var manCmd = &cobra.Command{
+	Use:   "man",
+	Short: "Generate man page",
+	Long: `Print man page of jellycli and all its commands to stdout, e.g.
+  jellycli man > /usr/share/man/man1/jellycli.1`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		w := bufio.NewWriter(os.Stdout)
+		writeManPage(w, rootCmd)
+		if err := w.Flush(); err != nil {
+			exitError(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(manCmd)
+}
+
+// writeManPage writes root and its subcommands as a single roff man page.
+func writeManPage(w io.Writer, root *cobra.Command) {
+	name := strings.ToUpper(root.Name())
+	fmt.Fprintf(w, ".TH %s 1 \"%s\" \"%s %s\"\n", name, time.Now().Format("2006-01-02"), root.Name(),
+		roffEscape(config.Version))
+	fmt.Fprintf(w, ".SH NAME\n%s \\- %s\n", root.Name(), roffEscape(root.Short))
+	fmt.Fprintf(w, ".SH SYNOPSIS\n.B %s\n[command] [flags]\n", root.Name())
+	description := root.Long
+	if description == "" {
+		description = root.Short
+	}
+	fmt.Fprintf(w, ".SH DESCRIPTION\n%s\n", roffText(description))
+	writeManFlags(w, root.NonInheritedFlags())
+
+	fmt.Fprintf(w, ".SH COMMANDS\n")
+	writeManCommands(w, root)
+}
+
+func writeManCommands(w io.Writer, parent *cobra.Command) {
+	for _, cmd := range parent.Commands() {
+		if !cmd.IsAvailableCommand() || cmd.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		fmt.Fprintf(w, ".SS %s\n", roffEscape(cmd.UseLine()))
+		description := cmd.Long
+		if description == "" {
+			description = cmd.Short
+		}
+		fmt.Fprintf(w, "%s\n", roffText(description))
+		if cmd.HasAvailableLocalFlags() {
+			fmt.Fprintf(w, ".PP\nFlags:\n")
+			writeManFlagList(w, cmd.LocalFlags())
+		}
+		writeManCommands(w, cmd)
+	}
+}
+
+func writeManFlags(w io.Writer, flags *pflag.FlagSet) {
+	if !flags.HasAvailableFlags() {
+		return
+	}
+	fmt.Fprintf(w, ".SH OPTIONS\n")
+	writeManFlagList(w, flags)
+}
+
+func writeManFlagList(w io.Writer, flags *pflag.FlagSet) {
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden {
+			return
+		}
+		name := "\\-\\-" + roffEscape(flag.Name)
+		if flag.Shorthand != "" {
+			name = "\\-" + flag.Shorthand + ", " + name
+		}
+		if flag.Value.Type() != "bool" {
+			name += " " + flag.Value.Type()
+		}
+		fmt.Fprintf(w, ".TP\n\\fB%s\\fR\n%s", name, roffEscape(flag.Usage))
+		if flag.DefValue != "" && flag.DefValue != "false" && flag.DefValue != "[]" {
+			fmt.Fprintf(w, " (default %s)", roffEscape(flag.DefValue))
+		}
+		fmt.Fprintln(w)
+	})
+}
+
+// roffText formats multi-line help text, keeping its line breaks and indentation.
+func roffText(text string) string {
+	sb := strings.Builder{}
+	sb.WriteString(".nf\n")
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		sb.WriteString(roffEscape(line) + "\n")
+	}
+	sb.WriteString(".fi")
+	return sb.String()
+}
+
+// roffEscape escapes text so that it is not interpreted as roff macros.
+func roffEscape(text string) string {
+	text = strings.ReplaceAll(text, "\\", "\\e")
+	text = strings.ReplaceAll(text, "-", "\\-")
+	if strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'") {
+		text = "\\&" + text
+	}
+	return text
+}
@@ -0,0 +1,53 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"tryffel.net/go/jellycli/util"
+)
+
+var openPrint bool
+
+var openCmd = &cobra.Command{
+	Use:   "open [id]",
+	Short: "Open current song or given item in web browser",
+	Long: `Open current song of running instance, or artist, album, song or playlist with given id,
+in server's web interface with default web browser, e.g. to edit its metadata.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		link := ctlOutput(ctlSocketPath(), "link", args...)
+		if openPrint {
+			fmt.Println(link)
+			return
+		}
+		err := util.OpenBrowser(link)
+		if err != nil {
+			exitError(fmt.Errorf("open browser: %v", err))
+		}
+	},
+}
+
+func init() {
+	openCmd.Flags().StringVar(&ctlSocket, "socket", "", "socket of running instance")
+	openCmd.Flags().BoolVarP(&openPrint, "print", "p", false, "print link instead of opening it")
+	rootCmd.AddCommand(openCmd)
+}
@@ -0,0 +1,65 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"tryffel.net/go/jellycli/player"
+)
+
+var outputsCmd = &cobra.Command{
+	Use:   "list-outputs [backend]",
+	Short: "List audio outputs and devices",
+	Long: `List audio output backends and their devices. Set backend as 'player.audio_output'
+and device name as 'player.audio_device' in config file.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		backends := player.OutputBackends
+		if len(args) == 1 {
+			backends = args
+		}
+		for i, backend := range backends {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%s:\n", backend)
+			devices, err := player.ListOutputDevices(backend)
+			if err != nil {
+				fmt.Printf("  not available: %v\n", err)
+				continue
+			}
+			if len(devices) == 0 {
+				fmt.Println("  no devices found")
+			}
+			for _, v := range devices {
+				name := v.Name
+				if name == "" {
+					name = "(empty)"
+				}
+				fmt.Printf("  %-40s %s\n", name, v.Description)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(outputsCmd)
+}
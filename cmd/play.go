@@ -0,0 +1,159 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"tryffel.net/go/jellycli/models"
+	"tryffel.net/go/jellycli/util"
+)
+
+var playArtist bool
+var playAlbum bool
+var playPlaylist bool
+var playShuffle bool
+
+var playCmd = &cobra.Command{
+	Use:   "play [--artist|--album|--playlist] [--shuffle] <query|id>",
+	Short: "Play item without starting the service",
+	Long: `Search song, or artist, album or playlist with given flag, and play it. Best match is played,
+if nothing is found, query is used as item id.
+Each song is printed to stdout when it starts. Player exits when queue has been played or on Ctrl+C.
+Ipc socket and remote control are enabled as usual, so playback can be controlled with 'jellycli ctl'.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		itemType, err := playItemType()
+		if err != nil {
+			exitError(err)
+		}
+		initConfig()
+		err = initLogging()
+		if err != nil {
+			exitError(err)
+		}
+		a := &app{}
+		err = a.initServerConnection()
+		if err != nil {
+			exitError(fmt.Errorf("connect to server: %v", err))
+		}
+		songs, name, err := getItemSongs(a.server, itemType, strings.Join(args, " "))
+		if err != nil {
+			exitError(err)
+		}
+		if len(songs) == 0 {
+			exitError(fmt.Errorf("%s '%s' has no songs", strings.ToLower(string(itemType)), name))
+		}
+		if playShuffle {
+			rand.Shuffle(len(songs), func(i, j int) { songs[i], songs[j] = songs[j], songs[i] })
+		}
+		err = a.initApp()
+		if err != nil {
+			exitError(fmt.Errorf("init application: %v", err))
+		}
+
+		done := make(chan bool, 1)
+		a.playback().AddStatusCallback(nowPlayingPrinter(a, done))
+		a.start()
+		a.playback().AddSongs(songs)
+
+		select {
+		case sig := <-catchSignals():
+			logrus.Infof("Received signal: %s. Shutting down...", sig)
+		case <-done:
+			logrus.Info("Queue finished")
+		}
+		err = a.stop()
+		if err != nil {
+			exitError(err)
+		}
+	},
+}
+
+func init() {
+	playCmd.Flags().BoolVar(&playArtist, "artist", false, "play artist")
+	playCmd.Flags().BoolVar(&playAlbum, "album", false, "play album")
+	playCmd.Flags().BoolVar(&playPlaylist, "playlist", false, "play playlist")
+	playCmd.Flags().BoolVarP(&playShuffle, "shuffle", "s", false, "play songs in random order")
+	rootCmd.AddCommand(playCmd)
+}
+
+// playItemType returns item type selected with flags, song by default.
+func playItemType() (models.ItemType, error) {
+	itemType := models.TypeSong
+	selected := 0
+	for _, v := range []struct {
+		set      bool
+		itemType models.ItemType
+	}{{playArtist, models.TypeArtist}, {playAlbum, models.TypeAlbum}, {playPlaylist, models.TypePlaylist}} {
+		if v.set {
+			itemType = v.itemType
+			selected++
+		}
+	}
+	if selected > 1 {
+		return "", errors.New("only one of --artist, --album and --playlist can be given")
+	}
+	return itemType, nil
+}
+
+// nowPlayingPrinter returns status callback that prints each song when it starts. Done is signaled
+// once player stops with empty queue.
+func nowPlayingPrinter(a *app, done chan bool) func(status models.AudioStatus) {
+	lock := sync.Mutex{}
+	var current models.Id
+	started := false
+	return func(status models.AudioStatus) {
+		lock.Lock()
+		defer lock.Unlock()
+		if status.State == models.AudioStatePlaying && status.Song != nil {
+			started = true
+			if status.Song.Id != current {
+				current = status.Song.Id
+				fmt.Printf("%s - %s (%s)\n", artistNames(status.Song), status.Song.Name,
+					util.SecToString(status.Song.Duration))
+			}
+			return
+		}
+		if started && status.State == models.AudioStateStopped && len(a.playback().GetQueue()) == 0 {
+			started = false
+			select {
+			case done <- true:
+			default:
+			}
+		}
+	}
+}
+
+func artistNames(song *models.Song) string {
+	names := make([]string, len(song.Artists))
+	for i, v := range song.Artists {
+		names[i] = v.Name
+	}
+	if len(names) == 0 {
+		return song.AlbumArtistName
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,240 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"tryffel.net/go/jellycli/api"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+	"tryffel.net/go/jellycli/util"
+)
+
+var playlistFormat string
+var playlistLocal bool
+var playlistQueue bool
+var playlistName string
+
+var playlistCmd = &cobra.Command{
+	Use:   "playlist",
+	Short: "Export and import playlist files",
+}
+
+var playlistExportCmd = &cobra.Command{
+	Use:   "export <name|id> <file>",
+	Short: "Export playlist to M3U or XSPF file",
+	Long: `Export server playlist, or with --queue queue of running instance, to M3U or XSPF file.
+Format is taken from file extension unless --format is given, file '-' writes to stdout.
+Songs are linked with stream urls, which contain access token. With --local, songs synced
+for offline use are linked to files in local cache instead.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		file := args[len(args)-1]
+		if !playlistQueue && len(args) < 2 {
+			exitError(errors.New("usage: playlist export <name|id> <file>"))
+		}
+		format := api.PlaylistFormat(strings.ToLower(playlistFormat))
+		if format == "" {
+			format = api.PlaylistFormatFromFile(file)
+		}
+
+		var socket string
+		if playlistQueue {
+			socket = ctlSocketPath()
+		}
+		a, err := connectServer()
+		if err != nil {
+			exitError(err)
+		}
+		var songs []*models.Song
+		var name string
+		if playlistQueue {
+			songs, err = getQueueSongs(a.server, socket)
+			name = "Queue"
+		} else {
+			songs, name, err = getItemSongs(a.server, models.TypePlaylist, strings.Join(args[:len(args)-1], " "))
+		}
+		if err != nil {
+			exitError(err)
+		}
+
+		location, err := songLocation(a.server, playlistLocal)
+		if err != nil {
+			exitError(err)
+		}
+		var out io.Writer = os.Stdout
+		if file != "-" {
+			fd, err := os.Create(file)
+			if err != nil {
+				exitError(err)
+			}
+			defer fd.Close()
+			out = fd
+		}
+		err = api.WritePlaylist(out, format, name, songs, location)
+		if err != nil {
+			exitError(err)
+		}
+		if file != "-" {
+			fmt.Printf("Exported %s songs of '%s' to %s\n", util.FormatNumber(len(songs)), name, file)
+		}
+	},
+}
+
+var playlistImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import M3U file as playlist",
+	Long: `Import M3U file as new server playlist, or with --queue add it to queue of running instance.
+Entries are matched against library by song id, if file was exported by jellycli, else by searching
+title and comparing artist and duration. Entries without match are listed and skipped.
+Playlist is named with --name or by file name.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		fd, err := os.Open(args[0])
+		if err != nil {
+			exitError(err)
+		}
+		entries, err := api.ReadM3u(fd)
+		fd.Close()
+		if err != nil {
+			exitError(fmt.Errorf("read playlist: %v", err))
+		}
+		name := playlistName
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+		}
+
+		var socket string
+		if playlistQueue {
+			socket = ctlSocketPath()
+		}
+		a, err := connectServer()
+		if err != nil {
+			exitError(err)
+		}
+		library, ok := a.server.(api.Library)
+		if !ok {
+			exitError(errors.New("server does not support browsing library"))
+		}
+		songs, missing, err := api.MatchPlaylistEntries(library, entries)
+		if err != nil {
+			exitError(err)
+		}
+		for _, v := range missing {
+			fmt.Printf("Not found: %s\n", v.String())
+		}
+		if len(songs) == 0 {
+			exitError(errors.New("no songs found"))
+		}
+		ids := make([]models.Id, len(songs))
+		for i, v := range songs {
+			ids[i] = v.Id
+		}
+
+		if playlistQueue {
+			args := make([]string, len(ids)+1)
+			args[0] = "add"
+			for i, v := range ids {
+				args[i+1] = v.String()
+			}
+			fmt.Println(ctlOutput(socket, "queue", args...))
+			return
+		}
+		editor, ok := a.server.(api.PlaylistEditor)
+		if !ok {
+			exitError(errors.New("server does not support playlists"))
+		}
+		id, err := editor.CreatePlaylist(name, ids)
+		if err != nil {
+			exitError(fmt.Errorf("create playlist: %v", err))
+		}
+		fmt.Printf("Imported %s of %s songs to playlist '%s' (%s)\n", util.FormatNumber(len(songs)),
+			util.FormatNumber(len(entries)), name, id)
+	},
+}
+
+func init() {
+	playlistExportCmd.Flags().StringVarP(&playlistFormat, "format", "f", "", "file format: m3u or xspf")
+	playlistExportCmd.Flags().BoolVar(&playlistLocal, "local", false, "link songs synced offline to local files")
+	playlistImportCmd.Flags().StringVarP(&playlistName, "name", "n", "", "name of new playlist")
+	for _, v := range []*cobra.Command{playlistExportCmd, playlistImportCmd} {
+		v.Flags().BoolVar(&playlistQueue, "queue", false, "use queue of running instance")
+		v.Flags().StringVar(&ctlSocket, "socket", "", "socket of running instance")
+		playlistCmd.AddCommand(v)
+	}
+	rootCmd.AddCommand(playlistCmd)
+}
+
+// getQueueSongs returns songs in queue of running instance.
+func getQueueSongs(server interface{}, socket string) ([]*models.Song, error) {
+	library, ok := server.(api.Library)
+	if !ok {
+		return nil, errors.New("server does not support browsing library")
+	}
+	output := ctlOutput(socket, "queue", "ids")
+	ids := []models.Id{}
+	for _, v := range strings.Fields(output) {
+		ids = append(ids, models.Id(v))
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("queue is empty")
+	}
+	songs, err := library.GetSongsById(ids)
+	if err != nil {
+		return nil, fmt.Errorf("get songs: %v", err)
+	}
+	// keep queue order
+	byId := make(map[models.Id]*models.Song, len(songs))
+	for _, v := range songs {
+		byId[v.Id] = v
+	}
+	ordered := make([]*models.Song, 0, len(ids))
+	for _, v := range ids {
+		if song, ok := byId[v]; ok {
+			ordered = append(ordered, song)
+		}
+	}
+	return ordered, nil
+}
+
+// songLocation returns function that gives stream url of song, or path of its offline copy if local is set
+// and song is synced.
+func songLocation(server interface{}, local bool) (func(song *models.Song) string, error) {
+	linker, ok := server.(api.StreamLinker)
+	if !ok {
+		return nil, errors.New("server does not support stream urls")
+	}
+	if !local {
+		return linker.StreamUrl, nil
+	}
+	store := api.NewOfflineStore(config.AppConfig.Player.LocalCacheDir)
+	return func(song *models.Song) string {
+		if file, ok := store.Path(song); ok {
+			return file
+		}
+		return linker.StreamUrl(song)
+	}, nil
+}
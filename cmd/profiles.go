@@ -0,0 +1,91 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"tryffel.net/go/jellycli/config"
+)
+
+var profileServer string
+
+var profilesCmd = &cobra.Command{
+	Use:   "profiles [list | add <name> <url> | switch <name> | remove <name>]",
+	Short: "Manage server profiles",
+	Long: `Manage server profiles.
+Each profile has its own server settings, stored in config file under 'profiles'. Top-level
+'jellyfin' settings form profile named 'default'. Switching profile takes effect on next start.
+Flag --profile selects profile for a single run without switching it.
+After adding a profile, switch to it and start jellycli to log in.
+
+Examples:
+jellycli profiles
+jellycli profiles add home https://jellyfin.home.lan
+jellycli profiles switch home
+jellycli --profile default`,
+	Run: func(cmd *cobra.Command, args []string) {
+		initConfig()
+		if len(args) == 0 || args[0] == "list" {
+			listProfiles()
+			return
+		}
+		var err error
+		switch {
+		case args[0] == "add" && len(args) == 3:
+			err = config.AddProfile(config.Profile{Name: args[1], Url: args[2], Server: profileServer})
+		case args[0] == "switch" && len(args) == 2:
+			err = config.SwitchProfile(args[1])
+		case args[0] == "remove" && len(args) == 2:
+			err = config.RemoveProfile(args[1])
+		default:
+			err = errors.New("usage: " + cmd.Use)
+		}
+		if err != nil {
+			exitError(err)
+		}
+	},
+}
+
+func listProfiles() {
+	profiles, err := config.Profiles()
+	if err != nil {
+		exitError(err)
+	}
+	active := config.ActiveProfile()
+	mark := func(name string) string {
+		if strings.EqualFold(name, active) || (active == "" && name == config.DefaultProfile) {
+			return "*"
+		}
+		return " "
+	}
+	fmt.Printf("%s %s (%s)\n", mark(config.DefaultProfile), config.DefaultProfile, viper.GetString("jellyfin.url"))
+	for _, v := range profiles {
+		fmt.Printf("%s %s (%s, %s)\n", mark(v.Name), v.Name, v.Server, v.Url)
+	}
+}
+
+func init() {
+	profilesCmd.Flags().StringVar(&profileServer, "server", "jellyfin", "server type of added profile")
+	rootCmd.AddCommand(profilesCmd)
+}
@@ -28,6 +28,7 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -36,14 +37,24 @@ import (
 	"tryffel.net/go/jellycli/api"
 	"tryffel.net/go/jellycli/api/jellyfin"
 	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/ipc"
 	"tryffel.net/go/jellycli/player"
 	"tryffel.net/go/jellycli/task"
+	"tryffel.net/go/jellycli/util"
 	"tryffel.net/go/jellycli/interfaces"
 )
 
 var cfgFile string
 
+// castDevice, if set, is device to play on instead of local output.
+var castDevice string
+
+// logFormat is log formatter without per-module levels.
+var logFormat logrus.Formatter
+
 var rootCmd = &cobra.Command{
+	Use:   "jellycli",
+	Short: "Terminal music player for Jellyfin",
 	Long: `Jellycli is a terminal music player for Jellyfin servers.
 
 `,
@@ -67,30 +78,14 @@ func Execute() {
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file")
+	rootCmd.PersistentFlags().StringVarP(&config.ProfileOverride, "profile", "p", "",
+		"server profile to use instead of active profile")
+	rootCmd.PersistentFlags().StringVar(&castDevice, "cast", "",
+		"play on device through server instead of local output, see 'list-cast-devices'")
 }
 
 func initConfig() {
-	// default config dir is ~/.config/jellycli
-	if cfgFile != "" {
-		viper.SetConfigFile(cfgFile)
-	} else {
-		configDir, err := os.UserConfigDir()
-		if err != nil {
-			logrus.Errorf("cannot determine config directory: %v", err)
-			configDir = ""
-		} else {
-			configDir = path.Join(configDir, "jellycli")
-		}
-
-		viper.AddConfigPath(configDir)
-		viper.SetConfigFile(path.Join(configDir, "jellycli.yaml"))
-	}
-
-	// env variables
-	replacer := strings.NewReplacer(".", "_")
-	viper.SetEnvPrefix("jellycli")
-	viper.SetEnvKeyReplacer(replacer)
-	viper.AutomaticEnv()
+	setConfigFile()
 
 	if err := viper.ReadInConfig(); err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -118,46 +113,122 @@ func initConfig() {
 	config.ConfigFile = file
 }
 
-// initLogging configures logrus to output only to Stderr.
+// setConfigFile points viper to config file and environment variables without reading them.
+func setConfigFile() {
+	// default config dir is ~/.config/jellycli
+	if cfgFile != "" {
+		viper.SetConfigFile(cfgFile)
+	} else {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			logrus.Errorf("cannot determine config directory: %v", err)
+			configDir = ""
+		} else {
+			configDir = path.Join(configDir, "jellycli")
+		}
+
+		viper.AddConfigPath(configDir)
+		viper.SetConfigFile(path.Join(configDir, "jellycli.yaml"))
+	}
+
+	// env variables
+	replacer := strings.NewReplacer(".", "_")
+	viper.SetEnvPrefix("jellycli")
+	viper.SetEnvKeyReplacer(replacer)
+	viper.AutomaticEnv()
+}
+
+// initLogging configures logrus to output to stderr, or to rotated log file if enabled.
 func initLogging() error {
-	level, err := logrus.ParseLevel(config.AppConfig.Player.LogLevel)
+	conf := config.AppConfig.Player
+	level, err := logrus.ParseLevel(conf.LogLevel)
 	if err != nil {
 		// Log directly to stderr if parsing fails, before SetOutput is called
-		fmt.Fprintf(os.Stderr, "Error parsing log level '%s': %v. Defaulting to INFO.\n", config.AppConfig.Player.LogLevel, err)
+		fmt.Fprintf(os.Stderr, "Error parsing log level '%s': %v. Defaulting to INFO.\n", conf.LogLevel, err)
 		level = logrus.InfoLevel // Default to Info level if parsing fails
 	}
 
-	logrus.SetLevel(level)
-	format := &prefixed.TextFormatter{
-		ForceColors:      true, // Enable colors for terminal output
-		DisableColors:    false,
-		ForceFormatting:  true,
-		DisableTimestamp: false,
-		DisableUppercase: false,
-		FullTimestamp:    true,
-		TimestampFormat:  "15:04:05.000",
-		DisableSorting:   false,
-		QuoteEmptyFields: false,
-		QuoteCharacter:   "'",
-		SpacePadding:     0,
-		Once:             sync.Once{},
+	var format logrus.Formatter
+	if conf.LogJson {
+		format = &logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano}
+	} else {
+		format = &prefixed.TextFormatter{
+			ForceColors:      !conf.LogToFile, // Enable colors for terminal output
+			DisableColors:    conf.LogToFile,
+			ForceFormatting:  true,
+			DisableTimestamp: false,
+			DisableUppercase: false,
+			FullTimestamp:    true,
+			TimestampFormat:  "15:04:05.000",
+			DisableSorting:   false,
+			QuoteEmptyFields: false,
+			QuoteCharacter:   "'",
+			SpacePadding:     0,
+			Once:             sync.Once{},
+		}
+	}
+	logFormat = format
+	level, err = setLogLevel(level, conf.LogLevels)
+	if err != nil {
+		return err
 	}
-	logrus.SetFormatter(format)
 
-	// Set output directly to stderr
-	logrus.SetOutput(os.Stderr)
-	config.LogFile = "" // Indicate no log file is used
+	if conf.LogToFile {
+		file, err := util.OpenRotatingFile(conf.LogFile, int64(conf.LogMaxSizeMB)*1024*1024, conf.LogMaxBackups,
+			time.Duration(conf.LogMaxAgeDays)*time.Hour*24)
+		if err != nil {
+			return err
+		}
+		logrus.SetOutput(file)
+		config.LogFile = conf.LogFile
+	} else {
+		logrus.SetOutput(os.Stderr)
+		config.LogFile = "" // Indicate no log file is used
+	}
 
 	// Log confirmation message *after* setting output
-	logrus.Infof("Logging initialized to Stderr at level: %s", level.String())
-	return nil // No file descriptor to return
+	logrus.Infof("Logging initialized to %s at level: %s", logOutputName(), level.String())
+	return nil
+}
+
+// setLogLevel sets log level and per-module levels, if any. It returns the most verbose level in use.
+func setLogLevel(level logrus.Level, modules map[string]string) (logrus.Level, error) {
+	format := logFormat
+	if len(modules) > 0 {
+		var f *moduleFormatter
+		var err error
+		f, level, err = newModuleFormatter(format, level, modules)
+		if err != nil {
+			return level, err
+		}
+		format = f
+	}
+	logrus.SetReportCaller(len(modules) > 0)
+	logrus.SetLevel(level)
+	logrus.SetFormatter(format)
+	return level, nil
+}
+
+func logOutputName() string {
+	if config.LogFile == "" {
+		return "Stderr"
+	}
+	return config.LogFile
 }
 
 // --- Application Lifecycle Logic ---
 
 type app struct {
-	server      interfaces.Api // Use the common interface
-	player      *player.Player
+	server interfaces.Api // Use the common interface
+	player *player.Player
+	// cast replaces player when casting to device
+	cast       *player.CastPlayer
+	ipc        *ipc.Server
+	favorites  *api.FavoriteSync
+	autoPause  *player.AutoPause
+	hooks      *player.Hooks
+	mediaKeys  *player.MediaKeys
+	supervisor *api.ConnectionSupervisor
 	// logfile     *os.File // Removed, logging goes to Stderr
 }
 
@@ -210,6 +281,26 @@ func initApplication() (*app, error) {
 	return a, nil // Return the app instance, although it might have already stopped
 }
 
+// connectServer loads config and connects to server without starting player. Commands that only need
+// server use this.
+func connectServer() (*app, error) {
+	initConfig()
+	err := initLogging()
+	if err != nil {
+		return nil, fmt.Errorf("init logging: %w", err)
+	}
+	a := &app{}
+	err = a.initServerConnection()
+	if err != nil {
+		return nil, fmt.Errorf("connect to server: %w", err)
+	}
+	err = config.SaveConfig()
+	if err != nil {
+		logrus.Warningf("save config file: %v", err)
+	}
+	return a, nil
+}
+
 func (a *app) initServerConnection() error {
 	var err error
 	serverType := strings.ToLower(config.AppConfig.Player.Server)
@@ -241,19 +332,105 @@ func (a *app) initServerConnection() error {
 
 func (a *app) initApp() error {
 	var err error
+	err = util.SetLocale(config.AppConfig.Player.Locale)
+	if err != nil {
+		logrus.Warningf("set locale: %v", err)
+	}
+
 	logrus.Info("Initializing player...")
-	a.player, err = player.NewPlayer(a.server)
+	if castDevice != "" {
+		sessions, ok := a.server.(api.SessionController)
+		if !ok {
+			return fmt.Errorf("server type '%s' does not support casting", config.AppConfig.Player.Server)
+		}
+		a.cast, err = player.NewCastPlayer(sessions, castDevice)
+	} else {
+		a.player, err = player.NewPlayer(a.server)
+	}
 	if err != nil {
 		return fmt.Errorf("create player: %w", err)
 	}
 	logrus.Info("Player initialized.")
 
+	if config.AppConfig.Player.AutoPause && a.player != nil {
+		a.autoPause = player.NewAutoPause(a.player, config.AppConfig.Player.AutoResume)
+	}
+
+	if config.AppConfig.Player.MediaKeys {
+		a.mediaKeys = player.NewMediaKeys(a.playback())
+	}
+
+	if len(config.AppConfig.Player.Hooks) > 0 {
+		var p interfaces.Player = a.player
+		if a.cast != nil {
+			p = a.cast
+		}
+		a.hooks = player.NewHooks(p, config.AppConfig.Player.Hooks)
+	}
+
+	if server, ok := a.server.(api.SupervisedServer); ok {
+		a.supervisor = api.NewConnectionSupervisor(server)
+		if a.player != nil {
+			a.player.SetProgressReporter(a.supervisor)
+		}
+	}
+
+	if server, ok := a.server.(api.FavoriteServer); ok {
+		a.favorites = api.NewFavoriteSync(server, config.AppConfig.Player.LocalCacheDir)
+	}
+
+	if !config.AppConfig.Player.DisableIpc {
+		a.ipc = ipc.NewServer(config.AppConfig.Player.IpcSocket)
+		a.ipc.SetPlayer(a.playback())
+		a.ipc.SetQueue(a.playback())
+		if library, ok := a.server.(api.Library); ok {
+			a.ipc.SetLibrary(library)
+		}
+		if editor, ok := a.server.(api.PlaylistEditor); ok {
+			a.ipc.SetPlaylistEditor(editor)
+		}
+		if browser, ok := a.server.(api.LibraryBrowser); ok {
+			a.ipc.SetBrowser(browser)
+		}
+		if users, ok := a.server.(api.UserSwitcher); ok {
+			a.ipc.SetUserSwitcher(users)
+		}
+		if rater, ok := a.server.(api.Rater); ok {
+			a.ipc.SetRater(rater)
+		}
+		if artists, ok := a.server.(api.ArtistInfo); ok {
+			a.ipc.SetArtistInfo(artists)
+		}
+		if linker, ok := a.server.(api.WebLinker); ok {
+			a.ipc.SetWebLinker(linker)
+		}
+		if a.favorites != nil {
+			a.ipc.SetFavorites(a.favorites)
+		}
+		if a.supervisor != nil {
+			a.ipc.SetConnection(a.supervisor)
+		}
+	}
+
 	// MPRIS initialization removed.
 	return nil
 }
 
 func (a *app) run() {
-	if config.AppConfig.Player.EnableRemoteControl {
+	a.start()
+	logrus.Info("Press Ctrl+C to exit.")
+
+	// Block until signal is received
+	a.stopOnSignal()
+
+	// stop() is called by stopOnSignal, no need to call it again here.
+	logrus.Info("Application run loop finished.")
+}
+
+// start enables remote control and starts background tasks. On failure application exits.
+func (a *app) start() {
+	// device being casted to is controlled through server already
+	if config.AppConfig.Player.EnableRemoteControl && a.cast == nil {
 		remoteController, ok := a.server.(api.RemoteController)
 		if ok {
 			logrus.Info("Enabling remote control via server.")
@@ -264,7 +441,7 @@ func (a *app) run() {
 		}
 	}
 
-	tasks := []task.Tasker{a.player, a.server}
+	tasks := a.tasks()
 	logrus.Info("Starting background tasks (player, server connection)...")
 	for i, t := range tasks {
 		taskName := fmt.Sprintf("task %d (%T)", i, t) // Get a basic name for logging
@@ -279,18 +456,51 @@ func (a *app) run() {
 		logrus.Debugf("Started %s.", taskName)
 	}
 	logrus.Info("Application started successfully. Running headless.")
-	logrus.Info("Press Ctrl+C to exit.")
+}
 
-	// Block until signal is received
-	a.stopOnSignal()
+// tasks returns background tasks in the order they are started.
+func (a *app) tasks() []task.Tasker {
+	tasks := []task.Tasker{a.playback(), a.server}
+	if a.supervisor != nil {
+		tasks = append(tasks, a.supervisor)
+	}
+	if a.favorites != nil {
+		tasks = append(tasks, a.favorites)
+	}
+	if a.autoPause != nil {
+		tasks = append(tasks, a.autoPause)
+	}
+	if a.mediaKeys != nil {
+		tasks = append(tasks, a.mediaKeys)
+	}
+	if a.ipc != nil {
+		tasks = append(tasks, a.ipc)
+	}
+	return tasks
+}
 
-	// stop() is called by stopOnSignal, no need to call it again here.
-	logrus.Info("Application run loop finished.")
+// playbackController is local player or cast device.
+type playbackController interface {
+	task.Tasker
+	interfaces.Player
+	interfaces.QueueController
+}
+
+// playback returns cast player when casting, else local player.
+func (a *app) playback() playbackController {
+	if a.cast != nil {
+		return a.cast
+	}
+	return a.player
 }
 
 func (a *app) stopOnSignal() {
 	sigChan := catchSignals()
 	sig := <-sigChan // Wait for signal
+	for sig == syscall.SIGHUP {
+		a.reloadConfig()
+		sig = <-sigChan
+	}
 	logrus.Infof("Received signal: %s. Shutting down...", sig)
 	err := a.stop()
 	if err != nil {
@@ -301,17 +511,34 @@ func (a *app) stopOnSignal() {
 	// No os.Exit here, let the main function handle exit.
 }
 
+// reloadConfig applies changes in config file that can be changed at runtime, see config.Reload.
+func (a *app) reloadConfig() {
+	logrus.Info("Reloading configuration")
+	err := config.Reload()
+	if err == nil {
+		conf := config.AppConfig.Player
+		level, _ := logrus.ParseLevel(conf.LogLevel)
+		_, err = setLogLevel(level, conf.LogLevels)
+	}
+	if err != nil {
+		logrus.Errorf("reload config: %v", err)
+		return
+	}
+	logrus.Info("Configuration reloaded")
+}
+
 func (a *app) stop() error {
 	logrus.Info("Stopping application components...")
-	// Stop tasks in reverse order? Player depends on server? Check dependencies.
-	// Let's assume stopping player first is safer.
-	tasks := []task.Tasker{a.player, a.server}
+	// Player is stopped first, so that it can report playback stopped before server connection is
+	// closed. Other tasks are stopped in reverse order of start.
+	tasks := a.tasks()
+	tasks = append(tasks[1:len(tasks):len(tasks)], tasks[0])
 	var firstErr error
 
 	// MPRIS related cleanup removed.
 
 
-	for i := len(tasks) - 1; i >= 0; i-- { // Stop in reverse order of start
+	for i := len(tasks) - 1; i >= 0; i-- {
 		t := tasks[i]
 		taskName := fmt.Sprintf("task %d (%T)", i, t)
 		logrus.Debugf("Stopping %s...", taskName)
@@ -340,7 +567,8 @@ func catchSignals() chan os.Signal {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c,
 		syscall.SIGINT,  // Interrupt (Ctrl+C)
-		syscall.SIGTERM) // Termination request
-	logrus.Debug("Signal catcher initialized for SIGINT, SIGTERM.")
+		syscall.SIGTERM, // Termination request
+		syscall.SIGHUP)  // Reload config
+	logrus.Debug("Signal catcher initialized for SIGINT, SIGTERM, SIGHUP.")
 	return c
 }
@@ -0,0 +1,160 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+	"tryffel.net/go/jellycli/player"
+	"tryffel.net/go/jellycli/util"
+)
+
+var statsTop int
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show local listening statistics",
+	Long: `Show total listening time and top artists and albums of this week and month.
+Statistics are recorded locally when 'player.collect_stats' is enabled and are independent of server.
+Skipped songs count as plays if they were listened at least 30 seconds.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		initConfig()
+		records, err := player.ReadPlayRecords()
+		if err != nil {
+			exitError(fmt.Errorf("read statistics: %v", err))
+		}
+		if len(records) == 0 {
+			if !config.AppConfig.Player.CollectStats {
+				fmt.Println("no statistics, enable 'player.collect_stats' to record them")
+			} else {
+				fmt.Println("no statistics yet")
+			}
+			return
+		}
+
+		now := time.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		// week starts from monday
+		week := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+		plays, listened := statsTotal(records)
+		fmt.Printf("total: %s plays, %s listened since %s\n", util.FormatNumber(plays), util.SecToString(listened),
+			records[0].PlayedAt.Format("2006-01-02"))
+		printStatsPeriod("this week", statsSince(records, week))
+		printStatsPeriod("this month", statsSince(records, month))
+	},
+}
+
+func init() {
+	statsCmd.Flags().IntVarP(&statsTop, "top", "n", 5, "number of top artists and albums to show")
+	rootCmd.AddCommand(statsCmd)
+}
+
+// statCount is play count and listening time of artist or album.
+type statCount struct {
+	name     string
+	plays    int
+	listened int
+}
+
+func isPlay(record *models.PlayRecord) bool {
+	return record.Completed || record.Listened >= config.StatsMinPlaySeconds
+}
+
+func statsTotal(records []*models.PlayRecord) (plays int, listened int) {
+	for _, v := range records {
+		if isPlay(v) {
+			plays++
+		}
+		listened += v.Listened
+	}
+	return
+}
+
+// statsSince returns records played after t. Records are in chronological order.
+func statsSince(records []*models.PlayRecord, t time.Time) []*models.PlayRecord {
+	i := sort.Search(len(records), func(i int) bool { return !records[i].PlayedAt.Before(t) })
+	return records[i:]
+}
+
+// statsTopBy returns n most played names by key, ordered by play count and listening time.
+func statsTopBy(records []*models.PlayRecord, key func(r *models.PlayRecord) string, n int) []statCount {
+	counts := map[string]*statCount{}
+	for _, v := range records {
+		name := key(v)
+		if name == "" {
+			continue
+		}
+		count, ok := counts[name]
+		if !ok {
+			count = &statCount{name: name}
+			counts[name] = count
+		}
+		if isPlay(v) {
+			count.plays++
+		}
+		count.listened += v.Listened
+	}
+
+	top := make([]statCount, 0, len(counts))
+	for _, v := range counts {
+		top = append(top, *v)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].plays != top[j].plays {
+			return top[i].plays > top[j].plays
+		}
+		if top[i].listened != top[j].listened {
+			return top[i].listened > top[j].listened
+		}
+		return top[i].name < top[j].name
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+func printStatsPeriod(name string, records []*models.PlayRecord) {
+	plays, listened := statsTotal(records)
+	fmt.Printf("\n%s: %s plays, %s listened\n", name, util.FormatNumber(plays), util.SecToString(listened))
+	if len(records) == 0 {
+		return
+	}
+	for _, v := range []struct {
+		title string
+		key   func(r *models.PlayRecord) string
+	}{
+		{"top artists", func(r *models.PlayRecord) string { return r.Artist }},
+		{"top albums", func(r *models.PlayRecord) string { return r.Album }},
+	} {
+		fmt.Printf("  %s:\n", v.title)
+		for i, count := range statsTopBy(records, v.key, statsTop) {
+			fmt.Printf("  %2d. %s (%s plays, %s)\n", i+1, count.name, util.FormatNumber(count.plays),
+				util.SecToString(count.listened))
+		}
+	}
+}
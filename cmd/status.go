@@ -0,0 +1,135 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"tryffel.net/go/jellycli/ipc"
+	"tryffel.net/go/jellycli/util"
+)
+
+// exitNotPlaying is exit code of read-only commands when nothing is playing.
+const exitNotPlaying = 2
+
+var statusFormat string
+var nowPlayingFormat string
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show status of running instance",
+	Long: `Show current song and player state of running instance.
+Exit code is 2 if nothing is playing and 1 if instance is not running.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		socket := ctlSocketPath()
+		playing := getNowPlaying(socket)
+		if statusFormat == "" {
+			fmt.Println(ctlOutput(socket, "status"))
+		} else {
+			fmt.Println(ctlOutput(socket, "status", statusFormat))
+		}
+		exitIfStopped(playing)
+	},
+}
+
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "List queue of running instance",
+	Long: `List queue of running instance with time until each song starts.
+Exit code is 2 if nothing is playing and 1 if instance is not running.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		socket := ctlSocketPath()
+		playing := getNowPlaying(socket)
+		fmt.Println(ctlOutput(socket, "queue"))
+		exitIfStopped(playing)
+	},
+}
+
+var nowPlayingCmd = &cobra.Command{
+	Use:   "now-playing",
+	Short: "Print current song of running instance",
+	Long: `Print current song of running instance formatted with --format. Format can contain
+{title}, {artist}, {album}, {id}, {state}, {position}, {duration}, {genre}, {track}, {disc} and {art}.
+Nothing is printed and exit code is 2 if nothing is playing, exit code is 1 if instance is not running.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		playing := getNowPlaying(ctlSocketPath())
+		exitIfStopped(playing)
+		fmt.Println(formatNowPlaying(playing, nowPlayingFormat))
+	},
+}
+
+func init() {
+	for _, v := range []*cobra.Command{statusCmd, queueCmd, nowPlayingCmd} {
+		v.Flags().StringVar(&ctlSocket, "socket", "", "socket of running instance")
+		rootCmd.AddCommand(v)
+	}
+	statusCmd.Flags().StringVar(&statusFormat, "format", "", "status format: json, waybar or plain")
+	nowPlayingCmd.Flags().StringVar(&nowPlayingFormat, "format", "{artist} - {title}", "output format")
+}
+
+// ctlOutput sends command to running instance and returns its output. On failure program exits.
+func ctlOutput(socket string, command string, args ...string) string {
+	resp, err := ipc.Send(socket, &ipc.Request{Command: command, Args: args, Client: defaultClientName()})
+	if err != nil {
+		exitError(err)
+	}
+	if !resp.Ok {
+		exitError(fmt.Errorf("%s", resp.Error))
+	}
+	return resp.Output
+}
+
+// getNowPlaying returns status of running instance. On failure program exits.
+func getNowPlaying(socket string) *ipc.NowPlaying {
+	playing := &ipc.NowPlaying{}
+	err := json.Unmarshal([]byte(ctlOutput(socket, "status", "json")), playing)
+	if err != nil {
+		exitError(fmt.Errorf("parse status: %v", err))
+	}
+	return playing
+}
+
+func exitIfStopped(playing *ipc.NowPlaying) {
+	if playing.State == "stopped" || playing.Id == "" {
+		os.Exit(exitNotPlaying)
+	}
+}
+
+func formatNowPlaying(playing *ipc.NowPlaying, format string) string {
+	return strings.NewReplacer(
+		"{title}", playing.Title,
+		"{artist}", strings.Join(playing.Artists, ", "),
+		"{album}", playing.Album,
+		"{id}", playing.Id,
+		"{state}", playing.State,
+		"{position}", util.SecToString(playing.Position),
+		"{duration}", util.SecToString(playing.Duration),
+		"{genre}", playing.Genre,
+		"{track}", strconv.Itoa(playing.Track),
+		"{disc}", strconv.Itoa(playing.Disc),
+		"{art}", playing.ArtUrl,
+	).Replace(format)
+}
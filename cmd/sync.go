@@ -0,0 +1,96 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"tryffel.net/go/jellycli/api"
+	"tryffel.net/go/jellycli/config"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync [playlist <name>]",
+	Short: "Sync playlists for offline use",
+	Long: `Download songs of playlists to local cache, so they play even when server is not reachable.
+Without arguments, playlists listed in 'player.offline_playlists' are synced and playlists no longer listed
+are removed. With 'playlist <name>', given playlist is synced. Songs removed from playlists are deleted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 1 || (len(args) > 1 && args[0] != "playlist") {
+			exitError(errors.New("usage: sync [playlist <name>]"))
+		}
+		a, err := connectServer()
+		if err != nil {
+			exitError(err)
+		}
+		server, ok := a.server.(api.OfflineServer)
+		if !ok {
+			exitError(errors.New("server does not support offline playlists"))
+		}
+		store := api.NewOfflineStore(config.AppConfig.Player.LocalCacheDir)
+
+		playlists := config.AppConfig.Player.OfflinePlaylists
+		if len(args) > 1 {
+			playlists = []string{strings.Join(args[1:], " ")}
+		} else {
+			configured := map[string]bool{}
+			for _, v := range playlists {
+				configured[v] = true
+			}
+			for _, v := range store.Playlists() {
+				if !configured[v] {
+					fmt.Printf("Remove %s\n", v)
+					if err := store.RemovePlaylist(v); err != nil {
+						exitError(err)
+					}
+				}
+			}
+		}
+		if len(playlists) == 0 {
+			fmt.Println("No playlists to sync, set 'player.offline_playlists' in config file")
+			return
+		}
+
+		failed := false
+		for _, v := range playlists {
+			summary, err := store.SyncPlaylist(server, v, func(song string) {
+				fmt.Printf("%s: download %s\n", v, song)
+			})
+			if err != nil {
+				fmt.Printf("%s: %v\n", v, err)
+				failed = true
+				continue
+			}
+			fmt.Println(summary.String())
+			if len(summary.Failed) > 0 {
+				failed = true
+			}
+		}
+		if failed {
+			exitError(errors.New("some songs were not synced"))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+}
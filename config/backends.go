@@ -18,7 +18,11 @@
 
 package config
 
-import "github.com/spf13/viper"
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
 
 type Backend interface {
 	DumpConfig() interface{}
@@ -31,7 +35,54 @@ type Jellyfin struct {
 	UserId    string `yaml:"user_id"`
 	DeviceId  string `yaml:"device_id"`
 	ServerId string `yaml:"server_id"`
-	// MusicView string `yaml:"music_view"` // Removed: TUI-specific concept
+	// DeviceName overrides device name (hostname) shown in server dashboard.
+	DeviceName string `yaml:"device_name"`
+	// ClientName overrides client name shown in server dashboard.
+	ClientName string `yaml:"client_name"`
+	// MusicViews limits browsing and searching to given music libraries, by name or id. Empty means all.
+	MusicViews []string `yaml:"music_views"`
+	// Users are users that have logged in, so that user can be switched without logging in again.
+	Users []User `yaml:"users"`
+	// SocketProgress sends playback progress over websocket when it is connected instead of http.
+	SocketProgress bool `yaml:"socket_progress"`
+}
+
+// User is a logged in server user.
+type User struct {
+	Name   string `yaml:"name" mapstructure:"name"`
+	UserId string `yaml:"user_id" mapstructure:"user_id"`
+	Token  string `yaml:"token" mapstructure:"token"`
+}
+
+// FindUser returns stored user by name, or nil if user has not logged in.
+func (j *Jellyfin) FindUser(name string) *User {
+	for i, v := range j.Users {
+		if strings.EqualFold(v.Name, name) {
+			return &j.Users[i]
+		}
+	}
+	return nil
+}
+
+// SetUser stores user, replacing one with same name, and makes it current user.
+func (j *Jellyfin) SetUser(user User) {
+	j.Token = user.Token
+	j.UserId = user.UserId
+	if existing := j.FindUser(user.Name); existing != nil {
+		*existing = user
+		return
+	}
+	j.Users = append(j.Users, user)
+}
+
+// CurrentUser returns name of current user, if it is stored.
+func (j *Jellyfin) CurrentUser() string {
+	for _, v := range j.Users {
+		if v.UserId == j.UserId {
+			return v.Name
+		}
+	}
+	return ""
 }
 
 func (j *Jellyfin) DumpConfig() interface{} {
@@ -44,6 +44,8 @@ type Config struct {
 	Jellyfin Jellyfin `yaml:"jellyfin"`
 	Player   Player `yaml:"player"`
 	ClientID string `yaml:"client_id"`
+	// Profile is name of the server profile in use, empty if default.
+	Profile string `yaml:"-"`
 }
 
 
@@ -59,8 +61,146 @@ type Player struct {
 	DisablePlaybackReporting bool `yaml:"disable_playback_reporting"`
 
 	LocalCacheDir    string `yaml:"local_cache_dir"`
-	// InitialBufferKB defines the initial buffer size in KiB before playback starts. Overrides HttpBufferingS for initial buffering if > 0.
+	// InitialBufferKB defines the initial buffer size in KiB before playback starts, until network throughput
+	// has been measured. After that initial buffer is calculated from throughput and bitrate.
 	InitialBufferKB  int    `yaml:"initial_buffer_kb"`
+
+	// NormalizeVolume applies track gain provided by server.
+	NormalizeVolume bool `yaml:"normalize_volume"`
+	// EstimateMissingGain estimates track gain for songs that have none, if NormalizeVolume is enabled.
+	EstimateMissingGain bool `yaml:"estimate_missing_gain"`
+
+	// DisableIpc disables local control socket used by 'jellycli ctl'.
+	DisableIpc bool `yaml:"disable_ipc"`
+	// IpcSocket overrides default socket path (named pipe on Windows).
+	IpcSocket string `yaml:"ipc_socket"`
+
+	// HistorySessionGapMin is idle time in minutes after which history starts a new session.
+	HistorySessionGapMin int `yaml:"history_session_gap_min"`
+
+	// ReadOnly disables actions that modify queue or library, except playback controls and adding songs.
+	ReadOnly bool `yaml:"read_only"`
+
+	// Locale for formatting durations and numbers, e.g. 'de'. Empty uses system locale.
+	Locale string `yaml:"locale"`
+
+	// Output is where audio is played: speaker, stdout or pipe:<path>.
+	Output string `yaml:"output"`
+	// OutputFormat is format for stdout and pipe outputs: pcm or wav.
+	OutputFormat string `yaml:"output_format"`
+	// AudioOutput is speaker backend: default, pulse, pipewire or alsa.
+	AudioOutput string `yaml:"audio_output"`
+	// AudioDevice is device for AudioOutput, see 'jellycli list-outputs'. Empty uses default device.
+	AudioDevice string `yaml:"audio_device"`
+
+	// Proxy for server connections, e.g. http://proxy:8080 or socks5://localhost:1080. Empty uses
+	// HTTP(S)_PROXY environment variables, 'none' disables proxy.
+	Proxy string `yaml:"proxy"`
+
+	// RestoreState saves queue, history and playback position on shutdown and restores them on start.
+	RestoreState bool `yaml:"restore_state"`
+
+	// TLSClientCert and TLSClientKey are PEM files for client certificate authentication.
+	TLSClientCert string `yaml:"tls_client_cert"`
+	TLSClientKey  string `yaml:"tls_client_key"`
+	// TLSCACert is PEM file of additional CA certificates to trust.
+	TLSCACert string `yaml:"tls_ca_cert"`
+	// TLSSkipVerify disables verifying server certificate. Insecure, prefer TLSCACert.
+	TLSSkipVerify bool `yaml:"tls_skip_verify"`
+
+	// AutoPause pauses playback when another application starts playing audio (PulseAudio / PipeWire).
+	AutoPause bool `yaml:"auto_pause"`
+	// AutoResume continues playback after other audio stops, if it was paused by AutoPause.
+	AutoResume bool `yaml:"auto_resume"`
+
+	// NightModeStart and NightModeEnd are times of day (HH:MM) when night mode is active.
+	// Empty disables night mode.
+	NightModeStart string `yaml:"night_mode_start"`
+	NightModeEnd   string `yaml:"night_mode_end"`
+	// NightModeMaxVolume is maximum volume [0,100] during night mode.
+	NightModeMaxVolume int `yaml:"night_mode_max_volume"`
+	// NightModeLoudness enables loudness compensation (bass and treble boost) during night mode.
+	NightModeLoudness bool `yaml:"night_mode_loudness"`
+
+	// SeekStepS is how many seconds forward and rewind seek.
+	SeekStepS int `yaml:"seek_step_s"`
+	// VolumeStep is how much volume up / down changes volume, in range [1,100].
+	VolumeStep int `yaml:"volume_step"`
+
+	// StreamRetries is how many times interrupted stream is resumed before giving up. Negative disables retrying.
+	StreamRetries int `yaml:"stream_retries"`
+
+	// OfflinePlaylists are playlists that 'jellycli sync' keeps in local cache for offline playback.
+	OfflinePlaylists []string `yaml:"offline_playlists"`
+
+	// AutoQueue appends songs similar to recently played ones when queue is about to run out.
+	AutoQueue bool `yaml:"auto_queue"`
+	// AutoQueueMin is number of upcoming songs below which songs are appended.
+	AutoQueueMin int `yaml:"auto_queue_min"`
+
+	// MaxStreamingBitrate limits stream bitrate in kbps. Songs with higher bitrate are transcoded. 0 is unlimited.
+	MaxStreamingBitrate int `yaml:"max_streaming_bitrate"`
+
+	// LogToFile writes log to LogFile instead of stderr.
+	LogToFile bool `yaml:"log_to_file"`
+	// LogMaxSizeMB is size in MiB after which log file is rotated.
+	LogMaxSizeMB int `yaml:"log_max_size_mb"`
+	// LogMaxBackups is number of rotated log files to keep.
+	LogMaxBackups int `yaml:"log_max_backups"`
+	// LogMaxAgeDays is age after which rotated log files are removed, 0 keeps them.
+	LogMaxAgeDays int `yaml:"log_max_age_days"`
+	// LogJson formats log entries as json.
+	LogJson bool `yaml:"log_json"`
+	// LogLevels overrides log level per module: api, player, ipc, config or cmd.
+	LogLevels map[string]string `yaml:"log_levels"`
+
+	// OutputSampleRate is fixed sample rate of audio output in Hz. Songs are resampled to it.
+	OutputSampleRate int `yaml:"output_sample_rate"`
+	// ResampleQuality is quality of resampling in range [1,6]. Higher uses more cpu.
+	ResampleQuality int `yaml:"resample_quality"`
+
+	// PersistHistory saves play history to local cache directory after every song and loads it on start.
+	PersistHistory bool `yaml:"persist_history"`
+	// HistoryLimit is maximum number of songs kept in history. Oldest songs are removed first.
+	HistoryLimit int `yaml:"history_limit"`
+
+	// CollectStats records every played song to local cache directory. See 'jellycli stats'.
+	CollectStats bool `yaml:"collect_stats"`
+
+	// ResumeMinDurationMin is minimum duration in minutes of items, e.g. audiobooks and podcasts, that
+	// resume from where they were left. Negative disables resuming.
+	ResumeMinDurationMin int `yaml:"resume_min_duration_min"`
+
+	// RequestTimeoutS limits connecting and waiting for response headers from server, in seconds.
+	// Reading response body, e.g. stream, is not limited.
+	RequestTimeoutS int `yaml:"request_timeout_s"`
+
+	// TrimSilence removes silence at the beginning and end of songs, up to TrimSilenceMs.
+	TrimSilence bool `yaml:"trim_silence"`
+	// TrimSilenceMs is maximum silence removed from each end of song.
+	TrimSilenceMs int `yaml:"trim_silence_ms"`
+
+	// Hooks are commands run on playback events, by event name: track_start, track_end, pause, resume,
+	// stop and seek.
+	Hooks map[string]string `yaml:"hooks"`
+
+	// PreferredCodec forces stream format: flac, mp3, ogg or wav. Songs in other formats are transcoded.
+	// Empty accepts any supported format.
+	PreferredCodec string `yaml:"preferred_codec"`
+
+	// IdleMode suspends periodic status updates while stopped or paused and stream buffering polls while
+	// buffer is full, to reduce cpu wakeups.
+	IdleMode bool `yaml:"idle_mode"`
+
+	// DecodeAheadMs is how much audio is decoded ahead of playback in background, 0 decodes on demand.
+	DecodeAheadMs int `yaml:"decode_ahead_ms"`
+
+	// LowMemory limits stream buffer and decode ahead to small windows and preloads next song to local
+	// cache directory instead of memory.
+	LowMemory bool `yaml:"low_memory"`
+
+	// MediaKeys controls playback with hardware media keys on Windows.
+	MediaKeys bool `yaml:"media_keys"`
 }
 
 
@@ -87,6 +227,64 @@ func (p *Player) sanitize() {
 		// Default to 512 KiB initial buffer if not set, can be overridden by HttpBufferingS logic later if needed.
 		p.InitialBufferKB = 512
 	}
+	if p.Output == "" {
+		p.Output = "speaker"
+	}
+	if p.OutputFormat == "" {
+		p.OutputFormat = "pcm"
+	}
+	if p.AudioOutput == "" {
+		p.AudioOutput = "default"
+	}
+	if p.HistorySessionGapMin == 0 {
+		p.HistorySessionGapMin = 30
+	}
+	if p.NightModeMaxVolume == 0 {
+		p.NightModeMaxVolume = 30
+	}
+	if p.SeekStepS <= 0 {
+		p.SeekStepS = 10
+	}
+	if p.VolumeStep <= 0 || p.VolumeStep > 100 {
+		p.VolumeStep = 5
+	}
+	if p.StreamRetries == 0 {
+		p.StreamRetries = 3
+	}
+	if p.AutoQueueMin <= 0 {
+		p.AutoQueueMin = 3
+	}
+	if p.MaxStreamingBitrate < 0 {
+		p.MaxStreamingBitrate = 0
+	}
+	if p.LogMaxSizeMB <= 0 {
+		p.LogMaxSizeMB = 10
+	}
+	if p.LogMaxBackups <= 0 {
+		p.LogMaxBackups = 3
+	}
+	if p.OutputSampleRate <= 0 {
+		p.OutputSampleRate = AudioSamplingRate
+	}
+	if p.ResampleQuality <= 0 || p.ResampleQuality > 6 {
+		p.ResampleQuality = 4
+	}
+	if p.HistoryLimit <= 0 {
+		p.HistoryLimit = 1000
+	}
+	if p.ResumeMinDurationMin == 0 {
+		p.ResumeMinDurationMin = 20
+	}
+	if p.RequestTimeoutS <= 0 {
+		p.RequestTimeoutS = 30
+	}
+	if p.TrimSilenceMs <= 0 {
+		p.TrimSilenceMs = 2000
+	}
+	p.PreferredCodec = strings.ToLower(p.PreferredCodec)
+	if p.DecodeAheadMs < 0 {
+		p.DecodeAheadMs = 0
+	}
 
 	if p.LocalCacheDir == "" {
 		baseCacheDir, err := os.UserCacheDir()
@@ -143,29 +341,15 @@ func ReadUserInput(name string, mask bool) (string, error) {
 
 // ConfigFromViper reads full application configuration from viper.
 func ConfigFromViper() error {
+	AppConfig = configFromViper(viper.GetViper())
 
-	AppConfig = &Config{
-		Jellyfin: Jellyfin{
-			Url:       viper.GetString("jellyfin.url"),
-			Token:     viper.GetString("jellyfin.token"),
-			UserId:    viper.GetString("jellyfin.userid"),
-			DeviceId:  viper.GetString("jellyfin.device_id"),
-			ServerId: viper.GetString("jellyfin.server_id"),
-			// MusicView: viper.GetString("jellyfin.music_view"), // Removed: TUI-specific concept
-		},
-		Player: Player{
-			Server:                   viper.GetString("player.server"),
-			LogFile:                  viper.GetString("player.logfile"),
-			LogLevel:                 viper.GetString("player.loglevel"),
-			AudioBufferingMs:         viper.GetInt("player.audio_buffering_ms"),
-			HttpBufferingS:           viper.GetInt("player.http_buffering_s"),
-			HttpBufferingLimitMem:    viper.GetInt("player.http_buffering_limit_mem"),
-			EnableRemoteControl:      viper.GetBool("player.enable_remote_control"),
-			DisablePlaybackReporting: viper.GetBool("player.disable_playback_reporting"), // Read new field
-			LocalCacheDir:            viper.GetString("player.local_cache_dir"),
-			InitialBufferKB:          viper.GetInt("player.initial_buffer_kb"), // Read new field
-		},
-		ClientID: viper.GetString("client_id"),
+	err := viper.UnmarshalKey("jellyfin.users", &AppConfig.Jellyfin.Users)
+	if err != nil {
+		return fmt.Errorf("read users: %v", err)
+	}
+	err = AppConfig.applyProfile()
+	if err != nil {
+		return err
 	}
 
 	if AppConfig.Jellyfin.Url == "" {
@@ -174,8 +358,10 @@ func ConfigFromViper() error {
 	} else {
 		AppConfig.Player.sanitize()
 	}
-	AudioBufferPeriod = time.Millisecond * time.Duration(AppConfig.Player.AudioBufferingMs)
-	// VolumeStepSize calculation removed, will be set in settings.go
+	runtimeLock.Lock()
+	audioBufferPeriod = time.Millisecond * time.Duration(AppConfig.Player.AudioBufferingMs)
+	volumeStepSize = AppConfig.Player.VolumeStep
+	runtimeLock.Unlock()
 
 	// Add debug logging for effective config values
 	logrus.Debugf("Effective Config - Player LogLevel: %s", AppConfig.Player.LogLevel)
@@ -184,6 +370,124 @@ func ConfigFromViper() error {
 	return nil
 }
 
+// Reload reads config file again and applies settings that can be changed at runtime: logging, buffering,
+// playback reporting, read-only mode and step sizes. Other settings take effect on next start.
+func Reload() error {
+	// global viper has all settings overridden when config was saved, so file is read to new instance
+	v := viper.New()
+	v.SetConfigFile(viper.ConfigFileUsed())
+	v.SetEnvPrefix("jellycli")
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("read config file: %v", err)
+	}
+	conf := configFromViper(v)
+	conf.Player.sanitize()
+	if _, err := logrus.ParseLevel(conf.Player.LogLevel); err != nil {
+		return fmt.Errorf("log level: %v", err)
+	}
+
+	runtimeLock.Lock()
+	defer runtimeLock.Unlock()
+	p := &AppConfig.Player
+	p.LogLevel = conf.Player.LogLevel
+	p.LogLevels = conf.Player.LogLevels
+	p.AudioBufferingMs = conf.Player.AudioBufferingMs
+	p.HttpBufferingS = conf.Player.HttpBufferingS
+	p.HttpBufferingLimitMem = conf.Player.HttpBufferingLimitMem
+	p.InitialBufferKB = conf.Player.InitialBufferKB
+	p.DisablePlaybackReporting = conf.Player.DisablePlaybackReporting
+	p.ReadOnly = conf.Player.ReadOnly
+	p.SeekStepS = conf.Player.SeekStepS
+	p.VolumeStep = conf.Player.VolumeStep
+	p.StreamRetries = conf.Player.StreamRetries
+	audioBufferPeriod = time.Millisecond * time.Duration(p.AudioBufferingMs)
+	volumeStepSize = p.VolumeStep
+	return nil
+}
+
+// configFromViper reads configuration from v without applying profile or defaults.
+func configFromViper(v *viper.Viper) *Config {
+	return &Config{
+		Jellyfin: Jellyfin{
+			Url:            v.GetString("jellyfin.url"),
+			Token:          v.GetString("jellyfin.token"),
+			UserId:         v.GetString("jellyfin.userid"),
+			DeviceId:       v.GetString("jellyfin.device_id"),
+			ServerId:       v.GetString("jellyfin.server_id"),
+			DeviceName:     v.GetString("jellyfin.device_name"),
+			ClientName:     v.GetString("jellyfin.client_name"),
+			MusicViews:     v.GetStringSlice("jellyfin.music_views"),
+			SocketProgress: v.GetBool("jellyfin.socket_progress"),
+		},
+		Player: Player{
+			Server:                   v.GetString("player.server"),
+			LogFile:                  v.GetString("player.logfile"),
+			LogLevel:                 v.GetString("player.loglevel"),
+			AudioBufferingMs:         v.GetInt("player.audio_buffering_ms"),
+			HttpBufferingS:           v.GetInt("player.http_buffering_s"),
+			HttpBufferingLimitMem:    v.GetInt("player.http_buffering_limit_mem"),
+			EnableRemoteControl:      v.GetBool("player.enable_remote_control"),
+			DisablePlaybackReporting: v.GetBool("player.disable_playback_reporting"), // Read new field
+			LocalCacheDir:            v.GetString("player.local_cache_dir"),
+			InitialBufferKB:          v.GetInt("player.initial_buffer_kb"), // Read new field
+			NormalizeVolume:          v.GetBool("player.normalize_volume"),
+			EstimateMissingGain:      v.GetBool("player.estimate_missing_gain"),
+			DisableIpc:               v.GetBool("player.disable_ipc"),
+			IpcSocket:                v.GetString("player.ipc_socket"),
+			HistorySessionGapMin:     v.GetInt("player.history_session_gap_min"),
+			ReadOnly:                 v.GetBool("player.read_only"),
+			Locale:                   v.GetString("player.locale"),
+			Output:                   v.GetString("player.output"),
+			OutputFormat:             v.GetString("player.output_format"),
+			AudioOutput:              v.GetString("player.audio_output"),
+			AudioDevice:              v.GetString("player.audio_device"),
+			Proxy:                    v.GetString("player.proxy"),
+			RestoreState:             v.GetBool("player.restore_state"),
+			TLSClientCert:            v.GetString("player.tls_client_cert"),
+			TLSClientKey:             v.GetString("player.tls_client_key"),
+			TLSCACert:                v.GetString("player.tls_ca_cert"),
+			AutoPause:                v.GetBool("player.auto_pause"),
+			AutoResume:               v.GetBool("player.auto_resume"),
+			NightModeStart:           v.GetString("player.night_mode_start"),
+			NightModeEnd:             v.GetString("player.night_mode_end"),
+			NightModeMaxVolume:       v.GetInt("player.night_mode_max_volume"),
+			NightModeLoudness:        v.GetBool("player.night_mode_loudness"),
+			SeekStepS:                v.GetInt("player.seek_step_s"),
+			VolumeStep:               v.GetInt("player.volume_step"),
+			StreamRetries:            v.GetInt("player.stream_retries"),
+			OfflinePlaylists:         v.GetStringSlice("player.offline_playlists"),
+			AutoQueue:                v.GetBool("player.auto_queue"),
+			AutoQueueMin:             v.GetInt("player.auto_queue_min"),
+			MaxStreamingBitrate:      v.GetInt("player.max_streaming_bitrate"),
+			LogToFile:                v.GetBool("player.log_to_file"),
+			LogMaxSizeMB:             v.GetInt("player.log_max_size_mb"),
+			LogMaxBackups:            v.GetInt("player.log_max_backups"),
+			LogMaxAgeDays:            v.GetInt("player.log_max_age_days"),
+			LogJson:                  v.GetBool("player.log_json"),
+			LogLevels:                v.GetStringMapString("player.log_levels"),
+			OutputSampleRate:         v.GetInt("player.output_sample_rate"),
+			ResampleQuality:          v.GetInt("player.resample_quality"),
+			PersistHistory:           v.GetBool("player.persist_history"),
+			HistoryLimit:             v.GetInt("player.history_limit"),
+			CollectStats:             v.GetBool("player.collect_stats"),
+			ResumeMinDurationMin:     v.GetInt("player.resume_min_duration_min"),
+			RequestTimeoutS:          v.GetInt("player.request_timeout_s"),
+			TLSSkipVerify:            v.GetBool("player.tls_skip_verify"),
+			TrimSilence:              v.GetBool("player.trim_silence"),
+			TrimSilenceMs:            v.GetInt("player.trim_silence_ms"),
+			Hooks:                    v.GetStringMapString("player.hooks"),
+			PreferredCodec:           v.GetString("player.preferred_codec"),
+			IdleMode:                 v.GetBool("player.idle_mode"),
+			DecodeAheadMs:            v.GetInt("player.decode_ahead_ms"),
+			LowMemory:                v.GetBool("player.low_memory"),
+			MediaKeys:                v.GetBool("player.media_keys"),
+		},
+		ClientID: v.GetString("client_id"),
+	}
+}
+
 func SaveConfig() error {
 	UpdateViper()
 	err := viper.WriteConfig()
@@ -209,24 +513,91 @@ func configFrom(conf *Config) {
 }
 
 func UpdateViper() {
-	viper.Set("jellyfin.url", AppConfig.Jellyfin.Url)
-	viper.Set("jellyfin.token", AppConfig.Jellyfin.Token)
-	viper.Set("jellyfin.userid", AppConfig.Jellyfin.UserId)
-	viper.Set("jellyfin.device_id", AppConfig.Jellyfin.DeviceId)
-	viper.Set("jellyfin.server_id", AppConfig.Jellyfin.ServerId)
-	// viper.Set("jellyfin.music_view", AppConfig.Jellyfin.MusicView) // Removed: TUI-specific concept
-
-	viper.Set("player.server", AppConfig.Player.Server)
-	viper.Set("player.logfile", AppConfig.Player.LogFile)
-	viper.Set("player.loglevel", AppConfig.Player.LogLevel)
-	viper.Set("player.http_buffering_s", AppConfig.Player.HttpBufferingS)
-	viper.Set("player.http_buffering_limit_mem", AppConfig.Player.HttpBufferingLimitMem)
-	viper.Set("player.enable_remote_control", AppConfig.Player.EnableRemoteControl)
-	viper.Set("player.disable_playback_reporting", AppConfig.Player.DisablePlaybackReporting) // Save new field
-	viper.Set("player.audio_buffering_ms", AppConfig.Player.AudioBufferingMs)
-	viper.Set("player.local_cache_dir", AppConfig.Player.LocalCacheDir)
-	viper.Set("player.initial_buffer_kb", AppConfig.Player.InitialBufferKB) // Save new field
-	viper.Set("client_id", AppConfig.ClientID)
+	runtimeLock.RLock()
+	defer runtimeLock.RUnlock()
+	// with profile in use, server settings belong to profile and top-level settings are left untouched
+	if !AppConfig.updateProfile() {
+		updateViperServer(viper.Set)
+	}
+	updateViperPlayer(viper.Set)
+}
+
+func updateViperServer(set func(key string, value interface{})) {
+	set("jellyfin.url", AppConfig.Jellyfin.Url)
+	set("jellyfin.token", AppConfig.Jellyfin.Token)
+	set("jellyfin.userid", AppConfig.Jellyfin.UserId)
+	set("jellyfin.device_id", AppConfig.Jellyfin.DeviceId)
+	set("jellyfin.server_id", AppConfig.Jellyfin.ServerId)
+	set("jellyfin.device_name", AppConfig.Jellyfin.DeviceName)
+	set("jellyfin.client_name", AppConfig.Jellyfin.ClientName)
+	set("jellyfin.music_views", AppConfig.Jellyfin.MusicViews)
+	set("jellyfin.users", AppConfig.Jellyfin.Users)
+	set("jellyfin.socket_progress", AppConfig.Jellyfin.SocketProgress)
+	set("player.server", AppConfig.Player.Server)
+}
+
+func updateViperPlayer(set func(key string, value interface{})) {
+	set("player.logfile", AppConfig.Player.LogFile)
+	set("player.loglevel", AppConfig.Player.LogLevel)
+	set("player.http_buffering_s", AppConfig.Player.HttpBufferingS)
+	set("player.http_buffering_limit_mem", AppConfig.Player.HttpBufferingLimitMem)
+	set("player.enable_remote_control", AppConfig.Player.EnableRemoteControl)
+	set("player.disable_playback_reporting", AppConfig.Player.DisablePlaybackReporting) // Save new field
+	set("player.audio_buffering_ms", AppConfig.Player.AudioBufferingMs)
+	set("player.local_cache_dir", AppConfig.Player.LocalCacheDir)
+	set("player.initial_buffer_kb", AppConfig.Player.InitialBufferKB) // Save new field
+	set("player.normalize_volume", AppConfig.Player.NormalizeVolume)
+	set("player.estimate_missing_gain", AppConfig.Player.EstimateMissingGain)
+	set("player.disable_ipc", AppConfig.Player.DisableIpc)
+	set("player.ipc_socket", AppConfig.Player.IpcSocket)
+	set("player.history_session_gap_min", AppConfig.Player.HistorySessionGapMin)
+	set("player.read_only", AppConfig.Player.ReadOnly)
+	set("player.locale", AppConfig.Player.Locale)
+	set("player.output", AppConfig.Player.Output)
+	set("player.output_format", AppConfig.Player.OutputFormat)
+	set("player.audio_output", AppConfig.Player.AudioOutput)
+	set("player.audio_device", AppConfig.Player.AudioDevice)
+	set("player.proxy", AppConfig.Player.Proxy)
+	set("player.restore_state", AppConfig.Player.RestoreState)
+	set("player.tls_client_cert", AppConfig.Player.TLSClientCert)
+	set("player.tls_client_key", AppConfig.Player.TLSClientKey)
+	set("player.tls_ca_cert", AppConfig.Player.TLSCACert)
+	set("player.auto_pause", AppConfig.Player.AutoPause)
+	set("player.auto_resume", AppConfig.Player.AutoResume)
+	set("player.night_mode_start", AppConfig.Player.NightModeStart)
+	set("player.night_mode_end", AppConfig.Player.NightModeEnd)
+	set("player.night_mode_max_volume", AppConfig.Player.NightModeMaxVolume)
+	set("player.night_mode_loudness", AppConfig.Player.NightModeLoudness)
+	set("player.seek_step_s", AppConfig.Player.SeekStepS)
+	set("player.volume_step", AppConfig.Player.VolumeStep)
+	set("player.stream_retries", AppConfig.Player.StreamRetries)
+	set("player.offline_playlists", AppConfig.Player.OfflinePlaylists)
+	set("player.auto_queue", AppConfig.Player.AutoQueue)
+	set("player.auto_queue_min", AppConfig.Player.AutoQueueMin)
+	set("player.max_streaming_bitrate", AppConfig.Player.MaxStreamingBitrate)
+	set("player.log_to_file", AppConfig.Player.LogToFile)
+	set("player.log_max_size_mb", AppConfig.Player.LogMaxSizeMB)
+	set("player.log_max_backups", AppConfig.Player.LogMaxBackups)
+	set("player.log_max_age_days", AppConfig.Player.LogMaxAgeDays)
+	set("player.log_json", AppConfig.Player.LogJson)
+	set("player.log_levels", AppConfig.Player.LogLevels)
+	set("player.output_sample_rate", AppConfig.Player.OutputSampleRate)
+	set("player.resample_quality", AppConfig.Player.ResampleQuality)
+	set("player.persist_history", AppConfig.Player.PersistHistory)
+	set("player.history_limit", AppConfig.Player.HistoryLimit)
+	set("player.collect_stats", AppConfig.Player.CollectStats)
+	set("player.resume_min_duration_min", AppConfig.Player.ResumeMinDurationMin)
+	set("player.request_timeout_s", AppConfig.Player.RequestTimeoutS)
+	set("player.tls_skip_verify", AppConfig.Player.TLSSkipVerify)
+	set("player.trim_silence", AppConfig.Player.TrimSilence)
+	set("player.trim_silence_ms", AppConfig.Player.TrimSilenceMs)
+	set("player.hooks", AppConfig.Player.Hooks)
+	set("player.preferred_codec", AppConfig.Player.PreferredCodec)
+	set("player.idle_mode", AppConfig.Player.IdleMode)
+	set("player.decode_ahead_ms", AppConfig.Player.DecodeAheadMs)
+	set("player.low_memory", AppConfig.Player.LowMemory)
+	set("player.media_keys", AppConfig.Player.MediaKeys)
+	set("client_id", AppConfig.ClientID)
 }
 
 // GetClientID retrieves the unique client ID for this instance.
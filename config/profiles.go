@@ -0,0 +1,212 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultProfile is the name of the implicit profile that uses top-level 'jellyfin' settings.
+const DefaultProfile = "default"
+
+// ProfileOverride, if set, selects profile for this run only, without changing the active profile
+// that is saved in config file.
+var ProfileOverride string
+
+// Profile is a named server configuration. Profiles are stored in config file under 'profiles' and
+// the active one is selected with 'profile'. When a profile is active, its settings replace
+// top-level 'jellyfin' settings and 'player.server'.
+type Profile struct {
+	Name           string   `yaml:"name" mapstructure:"name"`
+	Server         string   `yaml:"server" mapstructure:"server"`
+	Url            string   `yaml:"url" mapstructure:"url"`
+	Token          string   `yaml:"token" mapstructure:"token"`
+	UserId         string   `yaml:"userid" mapstructure:"userid"`
+	DeviceId       string   `yaml:"device_id" mapstructure:"device_id"`
+	ServerId       string   `yaml:"server_id" mapstructure:"server_id"`
+	DeviceName     string   `yaml:"device_name" mapstructure:"device_name"`
+	ClientName     string   `yaml:"client_name" mapstructure:"client_name"`
+	MusicViews     []string `yaml:"music_views" mapstructure:"music_views"`
+	Users          []User   `yaml:"users" mapstructure:"users"`
+	SocketProgress bool     `yaml:"socket_progress" mapstructure:"socket_progress"`
+}
+
+func (p *Profile) jellyfin() Jellyfin {
+	return Jellyfin{
+		Url:            p.Url,
+		Token:          p.Token,
+		UserId:         p.UserId,
+		DeviceId:       p.DeviceId,
+		ServerId:       p.ServerId,
+		DeviceName:     p.DeviceName,
+		ClientName:     p.ClientName,
+		MusicViews:     p.MusicViews,
+		Users:          p.Users,
+		SocketProgress: p.SocketProgress,
+	}
+}
+
+func (p *Profile) setJellyfin(j *Jellyfin) {
+	p.Url = j.Url
+	p.Token = j.Token
+	p.UserId = j.UserId
+	p.DeviceId = j.DeviceId
+	p.ServerId = j.ServerId
+	p.DeviceName = j.DeviceName
+	p.ClientName = j.ClientName
+	p.MusicViews = j.MusicViews
+	p.Users = j.Users
+	p.SocketProgress = j.SocketProgress
+}
+
+// Profiles returns all configured profiles.
+func Profiles() ([]Profile, error) {
+	profiles := []Profile{}
+	err := viper.UnmarshalKey("profiles", &profiles)
+	if err != nil {
+		return nil, fmt.Errorf("read profiles: %v", err)
+	}
+	return profiles, nil
+}
+
+// ActiveProfile returns name of the profile saved as active in config file. Empty means default profile.
+func ActiveProfile() string {
+	name := viper.GetString("profile")
+	if strings.EqualFold(name, DefaultProfile) {
+		return ""
+	}
+	return name
+}
+
+// AddProfile adds new profile and saves config file.
+func AddProfile(profile Profile) error {
+	if profile.Name == "" {
+		return fmt.Errorf("profile name cannot be empty")
+	}
+	if strings.EqualFold(profile.Name, DefaultProfile) {
+		return fmt.Errorf("profile name '%s' is reserved", DefaultProfile)
+	}
+	if profile.Server == "" {
+		profile.Server = "jellyfin"
+	}
+	profiles, err := Profiles()
+	if err != nil {
+		return err
+	}
+	if findProfile(profiles, profile.Name) >= 0 {
+		return fmt.Errorf("profile '%s' already exists", profile.Name)
+	}
+	viper.Set("profiles", append(profiles, profile))
+	return SaveConfig()
+}
+
+// RemoveProfile removes profile and saves config file. Removing active profile switches to default profile.
+func RemoveProfile(name string) error {
+	profiles, err := Profiles()
+	if err != nil {
+		return err
+	}
+	i := findProfile(profiles, name)
+	if i < 0 {
+		return fmt.Errorf("profile '%s' not found", name)
+	}
+	if strings.EqualFold(ActiveProfile(), profiles[i].Name) {
+		viper.Set("profile", "")
+	}
+	if strings.EqualFold(AppConfig.Profile, profiles[i].Name) {
+		// don't write removed profile back
+		AppConfig.Profile = ""
+	}
+	viper.Set("profiles", append(profiles[:i], profiles[i+1:]...))
+	return SaveConfig()
+}
+
+// SwitchProfile sets given profile active and saves config file. Settings take effect on next start.
+func SwitchProfile(name string) error {
+	if strings.EqualFold(name, DefaultProfile) {
+		viper.Set("profile", "")
+		return SaveConfig()
+	}
+	profiles, err := Profiles()
+	if err != nil {
+		return err
+	}
+	i := findProfile(profiles, name)
+	if i < 0 {
+		return fmt.Errorf("profile '%s' not found", name)
+	}
+	viper.Set("profile", profiles[i].Name)
+	return SaveConfig()
+}
+
+// applyProfile replaces server settings with ones from profile in use, if any.
+func (c *Config) applyProfile() error {
+	name := ProfileOverride
+	if name == "" {
+		name = ActiveProfile()
+	}
+	if name == "" || strings.EqualFold(name, DefaultProfile) {
+		return nil
+	}
+	profiles, err := Profiles()
+	if err != nil {
+		return err
+	}
+	i := findProfile(profiles, name)
+	if i < 0 {
+		return fmt.Errorf("profile '%s' not found", name)
+	}
+	c.Profile = profiles[i].Name
+	c.Jellyfin = profiles[i].jellyfin()
+	if profiles[i].Server != "" {
+		c.Player.Server = profiles[i].Server
+	}
+	return nil
+}
+
+// updateProfile writes server settings back to profile in use, e.g. after logging in. It returns false
+// if default profile is in use.
+func (c *Config) updateProfile() bool {
+	if c.Profile == "" {
+		return false
+	}
+	profiles, err := Profiles()
+	if err != nil {
+		return false
+	}
+	i := findProfile(profiles, c.Profile)
+	if i < 0 {
+		return false
+	}
+	profiles[i].setJellyfin(&c.Jellyfin)
+	viper.Set("profiles", profiles)
+	return true
+}
+
+func findProfile(profiles []Profile, name string) int {
+	for i, v := range profiles {
+		if strings.EqualFold(v.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,54 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestProfile_SocketProgress(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("profile", "work")
+	viper.Set("profiles", []Profile{{Name: "work", Server: "jellyfin", Url: "http://work", SocketProgress: true}})
+
+	conf := &Config{}
+	if err := conf.applyProfile(); err != nil {
+		t.Fatalf("apply profile: %v", err)
+	}
+	if !conf.Jellyfin.SocketProgress {
+		t.Error("expected socket progress from profile")
+	}
+
+	configFrom(conf)
+	defer configFrom(nil)
+	conf.Jellyfin.SocketProgress = false
+	UpdateViper()
+	profiles, err := Profiles()
+	if err != nil {
+		t.Fatalf("read profiles: %v", err)
+	}
+	if len(profiles) != 1 || profiles[0].SocketProgress {
+		t.Errorf("expected socket progress to be saved to profile, got %+v", profiles)
+	}
+	if viper.IsSet("jellyfin.socket_progress") {
+		t.Error("expected top-level settings to be left untouched")
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package config
+
+import (
+	"sync"
+	"time"
+)
+
+// runtimeLock guards settings that Reload changes while application is running. Other goroutines
+// read them with functions below instead of AppConfig.
+var runtimeLock sync.RWMutex
+
+// AudioBufferPeriod returns target buffer duration of audio output.
+func AudioBufferPeriod() time.Duration {
+	runtimeLock.RLock()
+	defer runtimeLock.RUnlock()
+	return audioBufferPeriod
+}
+
+// VolumeStepSize returns how much volume up / down changes volume, in range [1,100].
+func VolumeStepSize() int {
+	runtimeLock.RLock()
+	defer runtimeLock.RUnlock()
+	return volumeStepSize
+}
+
+// ReadOnly returns true if actions that modify queue or library are disabled, see Player.ReadOnly.
+func ReadOnly() bool {
+	runtimeLock.RLock()
+	defer runtimeLock.RUnlock()
+	return AppConfig.Player.ReadOnly
+}
+
+// PlaybackReportingDisabled returns true if playback is not reported to server.
+func PlaybackReportingDisabled() bool {
+	runtimeLock.RLock()
+	defer runtimeLock.RUnlock()
+	return AppConfig.Player.DisablePlaybackReporting
+}
+
+// SeekStep returns how many seconds forward and rewind seek.
+func SeekStep() int {
+	runtimeLock.RLock()
+	defer runtimeLock.RUnlock()
+	return AppConfig.Player.SeekStepS
+}
+
+// StreamRetries returns how many times interrupted stream is resumed, see Player.StreamRetries.
+func StreamRetries() int {
+	runtimeLock.RLock()
+	defer runtimeLock.RUnlock()
+	return AppConfig.Player.StreamRetries
+}
+
+// HttpBufferingS returns how many seconds of stream is buffered ahead.
+func HttpBufferingS() int {
+	runtimeLock.RLock()
+	defer runtimeLock.RUnlock()
+	return AppConfig.Player.HttpBufferingS
+}
+
+// HttpBufferingLimitMem returns maximum size of stream buffer in MiB.
+func HttpBufferingLimitMem() int {
+	runtimeLock.RLock()
+	defer runtimeLock.RUnlock()
+	return AppConfig.Player.HttpBufferingLimitMem
+}
+
+// InitialBufferKB returns size of initial stream buffer in KiB, see Player.InitialBufferKB.
+func InitialBufferKB() int {
+	runtimeLock.RLock()
+	defer runtimeLock.RUnlock()
+	return AppConfig.Player.InitialBufferKB
+}
@@ -0,0 +1,86 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestReload_ConcurrentReads(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "jellycli.yaml")
+	data := []byte(`player:
+  local_cache_dir: ` + dir + `
+  read_only: true
+  volume_step: 10
+  audio_buffering_ms: 200
+  stream_retries: 5
+`)
+	if err := ioutil.WriteFile(file, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	viper.SetConfigFile(file)
+	configFrom(&Config{})
+
+	stop := make(chan bool)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				ReadOnly()
+				VolumeStepSize()
+				AudioBufferPeriod()
+				StreamRetries()
+				HttpBufferingS()
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		if err := Reload(); err != nil {
+			t.Fatalf("reload: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	if !ReadOnly() {
+		t.Error("expected read-only mode to be reloaded")
+	}
+	if step := VolumeStepSize(); step != 10 {
+		t.Errorf("expected volume step 10, got %d", step)
+	}
+	if period := AudioBufferPeriod(); period != time.Millisecond*200 {
+		t.Errorf("expected buffer period 200ms, got %v", period)
+	}
+	if retries := StreamRetries(); retries != 5 {
+		t.Errorf("expected 5 stream retries, got %d", retries)
+	}
+}
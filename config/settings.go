@@ -29,16 +29,17 @@ const (
 	Version      = "0.9.1"
 )
 
+// Settings that are changed with Reload, read them with accessors in runtime.go.
 var (
-	// AudioBufferPeriod defines the target buffer duration for the audio player.
-	AudioBufferPeriod = time.Millisecond * 100
-	// VolumeStepSize defines the increment/decrement value for volume control (0-100).
-	VolumeStepSize = 5
+	// audioBufferPeriod defines the target buffer duration for the audio player.
+	audioBufferPeriod = time.Millisecond * 100
+	// volumeStepSize defines the increment/decrement value for volume control (0-100).
+	volumeStepSize = 5
 )
 
 // audio configuration
 const (
-	// AudioSamplingRate is default sampling rate. This may vary depending on song being played.
+	// AudioSamplingRate is default sampling rate of audio output. Songs are resampled to output rate.
 	AudioSamplingRate = 44100
 
 	// Volume range in decibels
@@ -51,9 +52,133 @@ const (
 	// Audio volume is logarithmic, which base to use
 	AudioVolumeLogBase = 2
 
+	// AudioPreviewVolumedB is volume of song preview channel, relative to player volume.
+	AudioPreviewVolumedB = -2
+	// PreviewDuration is how long song preview plays from the beginning of song.
+	PreviewDuration = time.Second * 30
+
+	// AudioNormalizationTarget is target loudness in LUFS when normalizing volume.
+	AudioNormalizationTarget = -18
+	// AudioMaxGaindB limits track gain when normalizing volume.
+	AudioMaxGaindB = 12
+	// AudioGainAnalysisDuration is how much of song is analyzed when estimating track gain.
+	AudioGainAnalysisDuration = time.Second * 10
+
+	// Playback rate range
+	AudioMinPlaybackRate = 0.5
+	AudioMaxPlaybackRate = 2.0
+
+	// AudioNightBassBoostdB and AudioNightTrebleBoostdB are boosts of night mode loudness compensation.
+	AudioNightBassBoostdB   = 6
+	AudioNightTrebleBoostdB = 3
+
+	// AudioSilenceThresholddB is level below which samples count as silence when trimming silence.
+	AudioSilenceThresholddB = -60
+
 	CacheTimeout = time.Minute * 5
+
+	// PlayedToCompletionRatio is the portion of song that must be played for it to count as played.
+	PlayedToCompletionRatio = 0.9
+
+	// AudioOutputMaxRateRatio is how many times faster or slower than sample rate output may pull samples
+	// before it is considered failed.
+	AudioOutputMaxRateRatio = 3
+	// AudioOutputMaxFailures is number of consecutive failed checks after which output is re-initialized.
+	AudioOutputMaxFailures = 2
+
+	// ShutdownTimeout is how long player may take to close current stream on shutdown.
+	ShutdownTimeout = time.Second * 3
+)
+
+// metadata prefetching of queued songs
+const (
+	// PrefetchSongs is number of upcoming songs whose album and artist are prefetched.
+	PrefetchSongs = 50
+	// PrefetchWorkers is number of concurrent requests.
+	PrefetchWorkers = 3
+	// PrefetchBatchSize is number of items per request.
+	PrefetchBatchSize = 15
+	// PrefetchQueueSize is number of requests that can wait for a worker.
+	PrefetchQueueSize = 10
+	// PrefetchInterval is minimum interval between requests.
+	PrefetchInterval = time.Millisecond * 200
+)
+
+// server connection monitoring
+const (
+	// ConnectionCheckInterval is how often server connection is checked while connected.
+	ConnectionCheckInterval = time.Second * 30
+	// ConnectionRetryMin and ConnectionRetryMax bound interval of connection checks during outage.
+	ConnectionRetryMin = time.Second * 2
+	ConnectionRetryMax = time.Minute
+	// MaxPendingReports limits playback reports kept during outage. Oldest reports are dropped first.
+	MaxPendingReports = 100
+)
+
+// playback reporting
+const (
+	// ReportInterval is minimum interval between time updates, server expects one every 10 seconds.
+	ReportInterval = time.Millisecond * 9500
+	// ReportRepeatInterval is interval identical time updates are still sent at, e.g. while paused.
+	ReportRepeatInterval = time.Minute
+	// ReportQueueSize is number of reports waiting to be sent.
+	ReportQueueSize = 20
+	// ReportRetries is number of times failed report is retried, first after ReportRetryDelay,
+	// then with doubling delay.
+	ReportRetries    = 3
+	ReportRetryDelay = time.Second * 2
+	// ReportHistorySize is number of played songs reported before current song in queue.
+	ReportHistorySize = 20
+)
+
+// Limits in low memory mode, see Player.LowMemory.
+const (
+	// LowMemoryBufferKB is maximum size of stream buffer.
+	LowMemoryBufferKB = 512
+	// LowMemoryReadChunkKB is maximum size of single read from server.
+	LowMemoryReadChunkKB = 32
+	// LowMemoryDecodeAheadMs is maximum duration of audio decoded ahead.
+	LowMemoryDecodeAheadMs = 250
+	// LowMemoryDecodeChunk is number of samples decoded at once when decoding ahead.
+	LowMemoryDecodeChunk = 128
 )
 
+// HookTimeout is how long hook command may run before it is killed.
+const HookTimeout = time.Second * 30
+
+// ArtMaxSize is maximum height of album art in pixels.
+const ArtMaxSize = 512
+
+// ArtDownloadTimeout is how long downloading album art to local cache may take.
+const ArtDownloadTimeout = time.Second * 10
+
+// InstantMixLimit is maximum number of songs in instant mix.
+const InstantMixLimit = 50
+
+// AutoQueueSongs is how many songs auto queue adds at a time.
+const AutoQueueSongs = 10
+
+// ShuffleLimit is maximum number of songs queued when shuffling library, artist or album.
+const ShuffleLimit = 500
+
+// SearchLimit is maximum number of search results per item type.
+const SearchLimit = 20
+
+// ListSearchLimit is maximum number of search results of list command, unless limit is given.
+const ListSearchLimit = 100
+
+// ArtistTopSongsLimit is number of most played songs shown for artist.
+const ArtistTopSongsLimit = 10
+
+// SimilarArtistsLimit is maximum number of similar artists shown for artist.
+const SimilarArtistsLimit = 10
+
+// NewReleasesDays is default period for listing new releases.
+const NewReleasesDays = 30
+
+// StatsMinPlaySeconds is minimum listening time for skipped song to count as a play in local statistics.
+const StatsMinPlaySeconds = 30
+
 // AppNameVersion returns string containing application name and current version
 func AppNameVersion() string {
 	return fmt.Sprintf("%s v%s", AppName, Version)
@@ -0,0 +1,196 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// secretKey is the last part of keys whose values are masked in Settings.
+const secretKey = "token"
+
+// Settings returns effective configuration by config file key, with server tokens masked.
+// AppConfig must be loaded.
+func Settings() map[string]interface{} {
+	settings := map[string]interface{}{}
+	set := func(key string, value interface{}) {
+		if strings.HasSuffix(key, "."+secretKey) && value != "" {
+			value = "***"
+		}
+		settings[key] = value
+	}
+	updateViperServer(set)
+	updateViperPlayer(set)
+	users := make([]string, len(AppConfig.Jellyfin.Users))
+	for i, v := range AppConfig.Jellyfin.Users {
+		users[i] = v.Name
+	}
+	settings["jellyfin.users"] = users
+	settings["profile"] = AppConfig.Profile
+	return settings
+}
+
+// UnknownKeys returns keys that are not config keys. Keys are in viper format, e.g. player.log_levels.api.
+// Sub keys of map settings are accepted.
+func UnknownKeys(keys []string) []string {
+	known := Settings()
+	known["profiles"] = nil
+	unknown := []string{}
+	for _, key := range keys {
+		key = strings.ToLower(key)
+		if _, ok := known[key]; ok {
+			continue
+		}
+		parent := false
+		for k, v := range known {
+			if _, isMap := v.(map[string]string); isMap && strings.HasPrefix(key, k+".") {
+				parent = true
+				break
+			}
+		}
+		if !parent {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// Validate returns all invalid values of configuration.
+func (c *Config) Validate() []error {
+	errs := []error{}
+	invalid := func(key string, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if c.Jellyfin.Url == "" {
+		invalid("jellyfin.url", "server url is missing")
+	} else if u, err := url.Parse(c.Jellyfin.Url); err != nil || u.Scheme == "" || u.Host == "" {
+		invalid("jellyfin.url", "invalid url '%s'", c.Jellyfin.Url)
+	}
+	if c.Jellyfin.Url != "" && (c.Jellyfin.Token == "" || c.Jellyfin.UserId == "") {
+		invalid("jellyfin.token", "not logged in, run jellycli to log in")
+	}
+	if strings.ToLower(c.Player.Server) != "jellyfin" {
+		invalid("player.server", "unsupported server '%s', supported: jellyfin", c.Player.Server)
+	}
+
+	if _, err := logrus.ParseLevel(c.Player.LogLevel); err != nil {
+		invalid("player.loglevel", "%v", err)
+	}
+	for module, level := range c.Player.LogLevels {
+		if _, err := logrus.ParseLevel(level); err != nil {
+			invalid("player.log_levels."+module, "%v", err)
+		}
+	}
+
+	for _, v := range []struct {
+		key   string
+		value int
+	}{
+		{"player.audio_buffering_ms", c.Player.AudioBufferingMs},
+		{"player.http_buffering_s", c.Player.HttpBufferingS},
+		{"player.http_buffering_limit_mem", c.Player.HttpBufferingLimitMem},
+		{"player.initial_buffer_kb", c.Player.InitialBufferKB},
+		{"player.history_session_gap_min", c.Player.HistorySessionGapMin},
+		{"player.night_mode_max_volume", c.Player.NightModeMaxVolume},
+		{"player.log_max_age_days", c.Player.LogMaxAgeDays},
+	} {
+		if v.value < 0 {
+			invalid(v.key, "must not be negative, got %d", v.value)
+		}
+	}
+	if c.Player.NightModeMaxVolume > 100 {
+		invalid("player.night_mode_max_volume", "must be in range [0,100], got %d", c.Player.NightModeMaxVolume)
+	}
+
+	switch {
+	case c.Player.Output == "speaker", c.Player.Output == "stdout":
+	case strings.HasPrefix(c.Player.Output, "pipe:") && len(c.Player.Output) > len("pipe:"):
+	default:
+		invalid("player.output", "unknown output '%s', supported: speaker, stdout, pipe:<path>", c.Player.Output)
+	}
+	if c.Player.OutputFormat != "pcm" && c.Player.OutputFormat != "wav" {
+		invalid("player.output_format", "unknown format '%s', supported: pcm, wav", c.Player.OutputFormat)
+	}
+	switch c.Player.AudioOutput {
+	case "default", "pulse", "pipewire", "alsa":
+	default:
+		invalid("player.audio_output", "unknown backend '%s', supported: default, pulse, pipewire, alsa",
+			c.Player.AudioOutput)
+	}
+
+	for _, v := range []struct {
+		key   string
+		value string
+	}{
+		{"player.night_mode_start", c.Player.NightModeStart},
+		{"player.night_mode_end", c.Player.NightModeEnd},
+	} {
+		if _, err := time.Parse("15:04", v.value); v.value != "" && err != nil {
+			invalid(v.key, "invalid time '%s', expected HH:MM", v.value)
+		}
+	}
+	if (c.Player.NightModeStart == "") != (c.Player.NightModeEnd == "") {
+		invalid("player.night_mode_start", "both night_mode_start and night_mode_end must be set")
+	}
+
+	for _, v := range []struct {
+		key   string
+		value string
+	}{
+		{"player.tls_client_cert", c.Player.TLSClientCert},
+		{"player.tls_client_key", c.Player.TLSClientKey},
+		{"player.tls_ca_cert", c.Player.TLSCACert},
+	} {
+		if _, err := os.Stat(v.value); v.value != "" && err != nil {
+			invalid(v.key, "%v", err)
+		}
+	}
+	if (c.Player.TLSClientCert == "") != (c.Player.TLSClientKey == "") {
+		invalid("player.tls_client_cert", "both tls_client_cert and tls_client_key must be set")
+	}
+	if c.Player.Proxy != "" && c.Player.Proxy != "none" {
+		if u, err := url.Parse(c.Player.Proxy); err != nil || u.Scheme == "" {
+			invalid("player.proxy", "invalid proxy url '%s'", c.Player.Proxy)
+		}
+	}
+
+	switch c.Player.PreferredCodec {
+	case "", "flac", "mp3", "ogg", "wav":
+	default:
+		invalid("player.preferred_codec", "unsupported codec '%s', supported: flac, mp3, ogg, wav",
+			c.Player.PreferredCodec)
+	}
+
+	for event := range c.Player.Hooks {
+		switch strings.ToLower(event) {
+		case "track_start", "track_end", "pause", "resume", "stop":
+		default:
+			invalid("player.hooks."+event, "unknown event, supported: track_start, track_end, pause, resume, stop")
+		}
+	}
+	return errs
+}
@@ -48,7 +48,7 @@ const (
 
 type Api interface {
 	ReportProgress(state *ApiPlaybackState) error
-	GetSongDirect(id string, codec string) (io.ReadCloser, error)
+	GetSongDirect(id string, codec string) (io.ReadCloser, AudioFormat, error)
 	Stream(song *models.Song) (io.ReadCloser, AudioFormat, error)
 	GetConfig() config.Backend
 	ConnectionOk() error
@@ -56,6 +56,11 @@ type Api interface {
 	Stop() error
 }
 
+// ProgressReporter reports playback progress to server.
+type ProgressReporter interface {
+	ReportProgress(state *ApiPlaybackState) error
+}
+
 //Playbackstate reports playback back to server
 type ApiPlaybackState struct {
 	Event    ApiPlaybackEvent
@@ -71,6 +76,12 @@ type ApiPlaybackState struct {
 
 	Shuffle bool
 
+	// Queue contains recently played songs and songs in queue in play order, shuffled if shuffle is enabled.
 	Queue []models.Id
+	// QueueIndex is index of current song in Queue.
+	QueueIndex int
+	// PlayedToCompletion is set on stop if enough of song was played for it to count as played.
 	PlayedToCompletion bool
+	// Transcoded is set if server transcoded stream, e.g. opus or aac that cannot be decoded.
+	Transcoded bool
 }
@@ -18,7 +18,12 @@
 
 package interfaces
 
-import "fmt"
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"strings"
+)
 
 // AudioFormat represents supported audio formats.
 type AudioFormat string
@@ -32,6 +37,11 @@ const (
 	AudioFormatMp3  AudioFormat = "mp3"
 	AudioFormatOgg  AudioFormat = "ogg"
 	AudioFormatWav  AudioFormat = "wav"
+	// Opus and AAC / ALAC (m4a) are recognized but cannot be decoded. Server is asked to transcode them,
+	// see AudioFormat.Container and AudioFormat.NeedsTranscoding.
+	AudioFormatOpus AudioFormat = "opus"
+	AudioFormatAac  AudioFormat = "aac"
+	AudioFormatM4a  AudioFormat = "m4a"
 	// AudioFormatNil represents an empty format, used for errors or unknown types.
 	AudioFormatNil AudioFormat = ""
 )
@@ -44,19 +54,80 @@ var SupportedAudioFormats = []AudioFormat{
 	AudioFormatWav,
 }
 
+// Container returns container description for server. Ogg can carry both vorbis and opus, but only vorbis
+// can be decoded, so it's qualified with codec.
+func (a AudioFormat) Container() string {
+	if a == AudioFormatOgg {
+		return "ogg|vorbis"
+	}
+	return string(a)
+}
+
+// NeedsTranscoding returns true if format is recognized but cannot be decoded, so server must transcode it.
+func (a AudioFormat) NeedsTranscoding() bool {
+	return a == AudioFormatOpus || a == AudioFormatAac || a == AudioFormatM4a
+}
+
+// Codec returns audio codec of format for server, e.g. vorbis for ogg.
+func (a AudioFormat) Codec() string {
+	if a == AudioFormatOgg {
+		return "vorbis"
+	}
+	return string(a)
+}
+
+// SniffAudioFormat detects format from beginning of file. It returns AudioFormatNil if format is not
+// recognized. Mp3 is only detected if it starts with ID3 tag or frame sync.
+func SniffAudioFormat(header []byte) AudioFormat {
+	switch {
+	case bytes.HasPrefix(header, []byte("fLaC")):
+		return AudioFormatFlac
+	case bytes.HasPrefix(header, []byte("OggS")):
+		if bytes.Contains(header, []byte("OpusHead")) {
+			return AudioFormatOpus
+		}
+		return AudioFormatOgg
+	case len(header) >= 12 && bytes.HasPrefix(header, []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WAVE")):
+		return AudioFormatWav
+	case bytes.HasPrefix(header, []byte("ID3")):
+		return AudioFormatMp3
+	case len(header) >= 2 && header[0] == 0xff && header[1]&0xe0 == 0xe0 && header[1]&0x06 != 0:
+		// mpeg frame sync with layer set, aac (adts) has layer 0
+		return AudioFormatMp3
+	case len(header) >= 2 && header[0] == 0xff && header[1]&0xf6 == 0xf0:
+		return AudioFormatAac
+	case len(header) >= 8 && bytes.Equal(header[4:8], []byte("ftyp")):
+		return AudioFormatM4a
+	}
+	return AudioFormatNil
+}
+
 // MimeToAudioFormat converts a MIME type string to an AudioFormat.
 // Returns AudioFormatNil and an error if the MIME type is not recognized.
 func MimeToAudioFormat(mimeType string) (format AudioFormat, err error) {
 	format = AudioFormatNil
-	switch mimeType {
+	mediaType, params, parseErr := mime.ParseMediaType(mimeType)
+	if parseErr != nil {
+		mediaType = mimeType
+	}
+	switch mediaType {
 	case "audio/mpeg":
 		format = AudioFormatMp3
 	case "audio/flac":
 		format = AudioFormatFlac
 	case "audio/ogg":
 		format = AudioFormatOgg
+		if strings.Contains(params["codecs"], "opus") {
+			format = AudioFormatOpus
+		}
 	case "audio/wav":
 		format = AudioFormatWav
+	case "audio/opus":
+		format = AudioFormatOpus
+	case "audio/aac":
+		format = AudioFormatAac
+	case "audio/mp4", "audio/m4a", "audio/x-m4a":
+		format = AudioFormatM4a
 	default:
 		err = fmt.Errorf("unidentified audio format: %s", mimeType)
 	}
@@ -45,8 +45,13 @@ type QueueController interface {
 	// Reorder shifts item in current index to left or right (earlier / later) by one depending on left.
 	// If down, play it earlier, else play it later. Returns true if reorder was made.
 	Reorder(currentIndex int, down bool) bool
+	// MoveSong moves song at index to newIndex. First song, which is current song, cannot be moved.
+	// Returns true if song was moved.
+	MoveSong(index, newIndex int) bool
 	//GetHistory get's n past songs that has been played.
 	GetHistory(n int) []*models.Song
+	// GetHistoryItems gets n past songs with time played and session. Use n < 0 to get all items.
+	GetHistoryItems(n int) []*models.HistoryItem
 	//AddQueueChangedCallback sets function that is called every time queue changes.
 	AddQueueChangedCallback(func(content []*models.Song))
 
@@ -55,6 +60,14 @@ type QueueController interface {
 
 	// SetHistoryChangedCallback sets a function that gets called every time history items update
 	SetHistoryChangedCallback(func(songs []*models.Song))
+
+	// GetShuffleSeed returns seed used for shuffling queue.
+	GetShuffleSeed() int64
+	// SetShuffleSeed sets seed used for shuffling queue. Same seed results in same order for same queue.
+	SetShuffleSeed(seed int64)
+
+	// SaveQueueAsPlaylist creates new playlist in server from songs in queue and returns id of playlist.
+	SaveQueueAsPlaylist(name string) (models.Id, error)
 }
 
 //MediaManager manages media: artists, albums, songs
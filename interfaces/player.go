@@ -35,20 +35,33 @@ type Player interface {
 	Next()
 	//Previous plays last played song (first in history) if there is one.
 	Previous()
-	//Seek seeks forward given seconds
+	// JumpTo plays song at queue index right away, removing songs before it.
+	JumpTo(index int) bool
+	//Seek seeks forward given ticks, or backward if ticks is negative
 	Seek(ticks models.AudioTick)
-	//SeekBackwards seeks backwards given seconds
+	// SeekRelative seeks given seconds, forward if positive, else backward
+	SeekRelative(seconds int)
 	//AddStatusCallback adds callback that get's called every time status has changed,
-	//including playback progress
-	AddStatusCallback(func(status models.AudioStatus))
+	//including playback progress. Returned function removes callback.
+	AddStatusCallback(func(status models.AudioStatus)) func()
 	//SetVolume sets volume to given level in range of [0,100]
 	SetVolume(volume models.AudioVolume)
+	// ChangeVolume changes volume by given number of volume steps, decreases if steps is negative.
+	ChangeVolume(steps int)
 	// SetMute mutes or un-mutes audio
 	SetMute(muted bool)
 	// ToggleMute toggles current mute.
 	ToggleMute()
 
 	SetShuffle(enabled bool)
+
+	// SetPlaybackRate sets playback speed in range [0.5, 2.0], 1 being normal speed.
+	SetPlaybackRate(rate float64)
+
+	// PreviewSong plays beginning of song at reduced volume without touching queue or current song.
+	PreviewSong(song *models.Song)
+	// StopPreview stops ongoing preview, if any.
+	StopPreview()
 }
 
 // Queuer contains read-only methods for song queue.
@@ -0,0 +1,1927 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ipc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"tryffel.net/go/jellycli/api"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/models"
+	"tryffel.net/go/jellycli/util"
+)
+
+var errNoPlayer = errors.New("player not available")
+
+// controller runs player and queue commands.
+type controller struct {
+	lock       sync.RWMutex
+	player     interfaces.Player
+	queue      interfaces.QueueController
+	library    api.Library
+	editor     api.PlaylistEditor
+	favorites  *api.FavoriteSync
+	connection *api.ConnectionSupervisor
+	browser    api.LibraryBrowser
+	users      api.UserSwitcher
+	rater      api.Rater
+	artists    api.ArtistInfo
+	linker     api.WebLinker
+	status     models.AudioStatus
+	// statusAt is when status was received
+	statusAt time.Time
+}
+
+// SetPlayer connects player to server, which can then be controlled with ipc commands.
+func (s *Server) SetPlayer(player interfaces.Player) {
+	s.ctrl.lock.Lock()
+	s.ctrl.player = player
+	s.ctrl.lock.Unlock()
+	player.AddStatusCallback(s.ctrl.statusChanged)
+}
+
+// SetQueue connects queue to server.
+func (s *Server) SetQueue(queue interfaces.QueueController) {
+	s.ctrl.lock.Lock()
+	defer s.ctrl.lock.Unlock()
+	s.ctrl.queue = queue
+}
+
+// SetLibrary sets library, which is used to fetch songs by id.
+func (s *Server) SetLibrary(library api.Library) {
+	s.ctrl.lock.Lock()
+	defer s.ctrl.lock.Unlock()
+	s.ctrl.library = library
+}
+
+// SetPlaylistEditor sets editor, which is used to modify playlists.
+func (s *Server) SetPlaylistEditor(editor api.PlaylistEditor) {
+	s.ctrl.lock.Lock()
+	defer s.ctrl.lock.Unlock()
+	s.ctrl.editor = editor
+}
+
+// SetFavorites sets favorite sync, which is used to mark favorites.
+func (s *Server) SetFavorites(favorites *api.FavoriteSync) {
+	s.ctrl.lock.Lock()
+	defer s.ctrl.lock.Unlock()
+	s.ctrl.favorites = favorites
+}
+
+// SetConnection sets connection supervisor, which is used to show server connection state.
+func (s *Server) SetConnection(connection *api.ConnectionSupervisor) {
+	s.ctrl.lock.Lock()
+	defer s.ctrl.lock.Unlock()
+	s.ctrl.connection = connection
+}
+
+// SetBrowser sets library browser, which is used to list library folders.
+func (s *Server) SetBrowser(browser api.LibraryBrowser) {
+	s.ctrl.lock.Lock()
+	defer s.ctrl.lock.Unlock()
+	s.ctrl.browser = browser
+}
+
+// SetUserSwitcher sets user switcher, which is used to change user at runtime.
+func (s *Server) SetUserSwitcher(users api.UserSwitcher) {
+	s.ctrl.lock.Lock()
+	defer s.ctrl.lock.Unlock()
+	s.ctrl.users = users
+}
+
+// SetRater sets rater, which is used to like and dislike songs.
+func (s *Server) SetRater(rater api.Rater) {
+	s.ctrl.lock.Lock()
+	defer s.ctrl.lock.Unlock()
+	s.ctrl.rater = rater
+}
+
+// SetArtistInfo sets artist info, which is used to list top songs and similar artists.
+func (s *Server) SetArtistInfo(artists api.ArtistInfo) {
+	s.ctrl.lock.Lock()
+	defer s.ctrl.lock.Unlock()
+	s.ctrl.artists = artists
+}
+
+// SetWebLinker sets web linker, which is used to link items in server's web interface.
+func (s *Server) SetWebLinker(linker api.WebLinker) {
+	s.ctrl.lock.Lock()
+	defer s.ctrl.lock.Unlock()
+	s.ctrl.linker = linker
+}
+
+func (s *Server) handlePlayerCommands() {
+	c := s.ctrl
+	s.HandleRequest("play", c.play)
+	s.Handle("pause", c.transport(interfaces.Player.Pause))
+	s.Handle("toggle", c.transport(interfaces.Player.PlayPause))
+	s.Handle("stop", c.transport(interfaces.Player.StopMedia))
+	s.Handle("next", c.transport(interfaces.Player.Next))
+	s.Handle("prev", c.transport(interfaces.Player.Previous))
+	s.Handle("mute", c.transport(interfaces.Player.ToggleMute))
+	s.Handle("forward", c.seek(1))
+	s.Handle("rewind", c.seek(-1))
+	s.Handle("seek", c.seekBy)
+	s.Handle("volume", c.volume)
+	s.Handle("shuffle", c.shuffle)
+	s.Handle("speed", c.speed)
+	s.Handle("status", c.getStatus)
+	s.Handle("stats", c.stats)
+	s.HandleRequest("queue", c.queueCmd)
+	s.Handle("history", c.history)
+	s.Handle("user", c.user)
+	s.Handle("like", c.rate(models.RatingLike))
+	s.Handle("dislike", c.rate(models.RatingDislike))
+	s.Handle("preview", c.preview)
+	s.Handle("playlist", c.playlist)
+	s.HandleRequest("album", c.album)
+	s.HandleRequest("artist", c.artist)
+	s.Handle("mix", c.instantMix)
+	s.Handle("fav", c.favorite)
+	s.Handle("favs", c.listFavorites)
+	s.Handle("albums", c.listFiltered("albums"))
+	s.Handle("songs", c.listFiltered("songs"))
+	s.Handle("info", c.songInfo)
+	s.Handle("link", c.link)
+	s.Handle("search", c.search)
+	s.Handle("new", c.newReleases)
+	s.Handle("views", c.views)
+	s.Handle("browse", c.browse)
+}
+
+func (c *controller) statusChanged(status models.AudioStatus) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.status = status
+	c.statusAt = time.Now()
+}
+
+// currentStatus returns latest status with position advanced by time played since it was received, so that
+// position stays accurate between status updates.
+func (c *controller) currentStatus() models.AudioStatus {
+	c.lock.RLock()
+	status, at := c.status, c.statusAt
+	c.lock.RUnlock()
+	if status.State != models.AudioStatePlaying || status.Paused || status.Buffering || status.Song == nil {
+		return status
+	}
+	rate := status.PlaybackRate
+	if rate == 0 {
+		rate = 1
+	}
+	past := status.SongPast + models.AudioTick(float64(time.Since(at).Milliseconds())*rate)
+	if end := models.AudioTick(status.Song.Duration * 1000); past > end {
+		past = end
+	}
+	status.SongPast = past
+	return status
+}
+
+func (c *controller) getPlayer() (interfaces.Player, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if c.player == nil {
+		return nil, errNoPlayer
+	}
+	return c.player, nil
+}
+
+func (c *controller) getQueue() (interfaces.QueueController, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if c.queue == nil {
+		return nil, errors.New("queue not available")
+	}
+	return c.queue, nil
+}
+
+// transport wraps player method that takes no arguments.
+func (c *controller) transport(action func(p interfaces.Player)) Handler {
+	return func(args []string) (string, error) {
+		p, err := c.getPlayer()
+		if err != nil {
+			return "", err
+		}
+		action(p)
+		return "", nil
+	}
+}
+
+// play continues playback, or if song ids are given, replaces queue with them.
+func (c *controller) play(req *Request) (string, error) {
+	args := req.Args
+	p, err := c.getPlayer()
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		p.Continue()
+		return "", nil
+	}
+	if config.ReadOnly() {
+		return "", models.ErrReadOnly
+	}
+	q, err := c.getQueue()
+	if err != nil {
+		return "", err
+	}
+	songs, err := c.getSongs(args)
+	if err != nil {
+		return "", err
+	}
+	setRequester(songs, req.Client)
+	p.StopMedia()
+	q.ClearQueue(true)
+	q.AddSongs(songs)
+	return fmt.Sprintf("playing %d songs", len(songs)), nil
+}
+
+// seek returns handler that seeks configured step to given direction.
+func (c *controller) seek(direction int) Handler {
+	return func(args []string) (string, error) {
+		p, err := c.getPlayer()
+		if err != nil {
+			return "", err
+		}
+		p.SeekRelative(direction * config.SeekStep())
+		return "", nil
+	}
+}
+
+// seekBy seeks given seconds: seek <+n|-n>.
+func (c *controller) seekBy(args []string) (string, error) {
+	p, err := c.getPlayer()
+	if err != nil {
+		return "", err
+	}
+	if len(args) != 1 {
+		return "", fmt.Errorf("usage: seek <+n|-n>")
+	}
+	seconds, err := strconv.Atoi(args[0])
+	if err != nil {
+		return "", fmt.Errorf("invalid seconds: %s", args[0])
+	}
+	p.SeekRelative(seconds)
+	return "", nil
+}
+
+// volume prints current volume, sets it to given level, changes it with +n / -n or
+// by player.volume_step with up / down.
+func (c *controller) volume(args []string) (string, error) {
+	p, err := c.getPlayer()
+	if err != nil {
+		return "", err
+	}
+	c.lock.RLock()
+	current := c.status.Volume
+	c.lock.RUnlock()
+	if len(args) == 0 {
+		return fmt.Sprintf("volume: %d%%", current), nil
+	}
+
+	arg := args[0]
+	switch arg {
+	case "up":
+		p.ChangeVolume(1)
+		return fmt.Sprintf("volume: %d%%", current.Add(config.VolumeStepSize())), nil
+	case "down":
+		p.ChangeVolume(-1)
+		return fmt.Sprintf("volume: %d%%", current.Add(-config.VolumeStepSize())), nil
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return "", fmt.Errorf("invalid volume: %s", arg)
+	}
+	volume := models.AudioVolume(0).Add(n)
+	if strings.HasPrefix(arg, "+") || strings.HasPrefix(arg, "-") {
+		volume = current.Add(n)
+	}
+	p.SetVolume(volume)
+	return fmt.Sprintf("volume: %d%%", volume), nil
+}
+
+// speed prints or sets playback rate: speed [rate|+n|-n].
+func (c *controller) speed(args []string) (string, error) {
+	p, err := c.getPlayer()
+	if err != nil {
+		return "", err
+	}
+	c.lock.RLock()
+	current := c.status.PlaybackRate
+	c.lock.RUnlock()
+	if len(args) == 0 {
+		return fmt.Sprintf("speed: %.2fx", current), nil
+	}
+
+	rate, err := strconv.ParseFloat(args[0], 64)
+	if err != nil {
+		return "", fmt.Errorf("usage: speed [rate|+n|-n]")
+	}
+	if strings.HasPrefix(args[0], "+") || strings.HasPrefix(args[0], "-") {
+		rate += current
+	}
+	rate = math.Max(config.AudioMinPlaybackRate, math.Min(config.AudioMaxPlaybackRate, rate))
+	p.SetPlaybackRate(rate)
+	return fmt.Sprintf("speed: %.2fx", rate), nil
+}
+
+func (c *controller) shuffle(args []string) (string, error) {
+	p, err := c.getPlayer()
+	if err != nil {
+		return "", err
+	}
+	c.lock.RLock()
+	enabled := !c.status.Shuffle
+	c.lock.RUnlock()
+	if len(args) > 0 {
+		switch args[0] {
+		case "on":
+			enabled = true
+		case "off":
+			enabled = false
+		case "seed":
+			return c.shuffleSeed(args[1:])
+		default:
+			return c.playShuffled(p, args[0])
+		}
+	}
+	p.SetShuffle(enabled)
+	return "shuffle: " + onOff(enabled), nil
+}
+
+// shuffleSeed prints current shuffle seed or sets it.
+func (c *controller) shuffleSeed(args []string) (string, error) {
+	queue, err := c.getQueue()
+	if err != nil {
+		return "", err
+	}
+	if len(args) > 0 {
+		if config.ReadOnly() {
+			return "", models.ErrReadOnly
+		}
+		seed, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid seed: %v", err)
+		}
+		queue.SetShuffleSeed(seed)
+	}
+	return fmt.Sprintf("shuffle seed: %d", queue.GetShuffleSeed()), nil
+}
+
+// playShuffled replaces queue with songs of whole library ('all'), album, artist or playlist
+// in random order.
+func (c *controller) playShuffled(p interfaces.Player, id string) (string, error) {
+	if config.ReadOnly() {
+		return "", models.ErrReadOnly
+	}
+	q, err := c.getQueue()
+	if err != nil {
+		return "", err
+	}
+	c.lock.RLock()
+	library := c.library
+	editor := c.editor
+	c.lock.RUnlock()
+	if library == nil {
+		return "", errors.New("server does not support fetching songs")
+	}
+
+	name := "library"
+	var songs []*models.Song
+	if id == "all" {
+		songs, err = library.GetRandomSongs(nil, config.ShuffleLimit)
+	} else {
+		items, err := library.GetItems([]models.Id{models.Id(id)})
+		if err != nil {
+			return "", fmt.Errorf("get item: %v", err)
+		}
+		if len(items) == 0 {
+			return "", fmt.Errorf("usage: shuffle [on|off|all|<album, artist or playlist id>]")
+		}
+		name = items[0].GetName()
+		if items[0].GetType() == models.TypePlaylist {
+			if editor == nil {
+				return "", errors.New("server does not support playlists")
+			}
+			songs, err = editor.GetPlaylistSongs(items[0].GetId())
+			rand.Shuffle(len(songs), func(i, j int) { songs[i], songs[j] = songs[j], songs[i] })
+		} else {
+			songs, err = library.GetRandomSongs(items[0], config.ShuffleLimit)
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("get songs: %v", err)
+	}
+	if len(songs) == 0 {
+		return "", errors.New("no songs found")
+	}
+	p.StopMedia()
+	q.ClearQueue(true)
+	q.AddSongs(songs)
+	return fmt.Sprintf("shuffled %s songs of %s", util.FormatNumber(len(songs)), name), nil
+}
+
+// getStatus shows player status: status [json|waybar|plain]. Without format, status is shown in full
+// for humans.
+func (c *controller) getStatus(args []string) (string, error) {
+	if _, err := c.getPlayer(); err != nil {
+		return "", err
+	}
+	status := c.currentStatus()
+	c.lock.RLock()
+	connection := c.connection
+	c.lock.RUnlock()
+	if len(args) > 1 {
+		return "", fmt.Errorf("usage: status [json|waybar|plain]")
+	}
+	if len(args) == 1 {
+		return formatStatus(status, args[0])
+	}
+
+	state := "stopped"
+	if status.State == models.AudioStatePlaying {
+		state = "playing"
+		if status.Paused {
+			state = "paused"
+		} else if status.Buffering {
+			state = "buffering…"
+		}
+	} else if status.Action == models.AudioActionSeek && status.Song != nil {
+		state = "buffering…"
+	}
+
+	sb := strings.Builder{}
+	if status.Song != nil {
+		sb.WriteString(songString(status.Song) + "\n")
+		sb.WriteString(fmt.Sprintf("[%s] %s/%s\n", state, util.SecToString(status.SongPast.Seconds()),
+			util.SecToString(status.Song.Duration)))
+	} else {
+		sb.WriteString(fmt.Sprintf("[%s]\n", state))
+	}
+	volume := fmt.Sprintf("%d%%", status.Volume)
+	if status.Muted {
+		volume += " (muted)"
+	}
+	if status.NightMode {
+		volume += " (night mode)"
+	}
+	sb.WriteString(fmt.Sprintf("volume: %s, shuffle: %s", volume, onOff(status.Shuffle)))
+	if status.PlaybackRate != 0 && status.PlaybackRate != 1 {
+		sb.WriteString(fmt.Sprintf(", speed: %.2fx", status.PlaybackRate))
+	}
+	if status.Song != nil && status.Bitrate > 0 {
+		sb.WriteString(fmt.Sprintf(", bitrate: %d kbps", status.Bitrate))
+	}
+	if status.Song != nil && status.Buffered > 0 {
+		sb.WriteString(fmt.Sprintf(", buffered: %s", util.SecToString(status.Buffered)))
+	}
+	if queue, err := c.getQueue(); err == nil {
+		if n := len(queue.GetQueue()); n > 0 {
+			if _, remaining, ok := c.queueDuration(); ok {
+				sb.WriteString(fmt.Sprintf("\nqueue: %d songs, %s", n, remainingString(remaining)))
+			}
+		}
+	}
+	if connection != nil {
+		if conn := connection.Status(); conn.State != models.ConnectionOk {
+			sb.WriteString(fmt.Sprintf("\nserver: %s since %s", conn.State, conn.Since.Format("15:04:05")))
+			if conn.PendingReports > 0 {
+				sb.WriteString(fmt.Sprintf(", %d reports pending", conn.PendingReports))
+			}
+			if conn.Error != "" {
+				sb.WriteString(fmt.Sprintf(" (%s)", conn.Error))
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+// cacheCounter is a player that counts songs played from offline copies.
+type cacheCounter interface {
+	CacheStats() (hits, misses int)
+}
+
+// stats shows memory usage, current stream and download statistics of this session.
+func (c *controller) stats(args []string) (string, error) {
+	c.lock.RLock()
+	status := c.status
+	player := c.player
+	connection := c.connection
+	c.lock.RUnlock()
+
+	mem := runtime.MemStats{}
+	runtime.ReadMemStats(&mem)
+	stats := &models.Stats{
+		Heap:       int(mem.HeapAlloc),
+		LogFile:    config.LogFile,
+		ConfigFile: config.ConfigFile,
+		Downloaded: int(api.BytesDownloaded()),
+	}
+	if connection != nil {
+		stats.Connection = connection.Status()
+	}
+	if counter, ok := player.(cacheCounter); ok {
+		stats.CacheHits, stats.CacheMisses = counter.CacheStats()
+	}
+	if status.State == models.AudioStatePlaying && status.Song != nil {
+		stats.Stream = models.StreamInfo{
+			Codec:      status.Codec,
+			Bitrate:    status.Bitrate,
+			Transcoded: status.Transcoded,
+			Buffered:   status.Buffered,
+		}
+	}
+
+	logFile := stats.LogFile
+	if logFile == "" {
+		logFile = "stderr"
+	}
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("heap: %s\nconfig file: %s\nlog file: %s\n", stats.HeapString(), stats.ConfigFile,
+		logFile))
+	sb.WriteString(fmt.Sprintf("server: %s\n", stats.Connection.State))
+	if stats.Stream.Codec != "" {
+		mode := "direct play"
+		if stats.Stream.Transcoded {
+			mode = "transcoded"
+		}
+		sb.WriteString(fmt.Sprintf("stream: %s, %s", stats.Stream.Codec, mode))
+		if stats.Stream.Bitrate > 0 {
+			sb.WriteString(fmt.Sprintf(", %d kbps", stats.Stream.Bitrate))
+		}
+		sb.WriteString(fmt.Sprintf(", buffered %s\n", util.SecToString(stats.Stream.Buffered)))
+	} else {
+		sb.WriteString("stream: -\n")
+	}
+	sb.WriteString(fmt.Sprintf("offline cache: %d hits, %d misses (%.0f%%)\n", stats.CacheHits, stats.CacheMisses,
+		stats.CacheHitRate()*100))
+	sb.WriteString(fmt.Sprintf("downloaded: %s", stats.DownloadedString()))
+	return sb.String(), nil
+}
+
+// queueCmd lists queue or modifies it: queue [add|next|remove|clear].
+func (c *controller) queueCmd(req *Request) (string, error) {
+	args := req.Args
+	q, err := c.getQueue()
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return c.listQueue(q.GetQueue()), nil
+	}
+
+	switch args[0] {
+	case "ids":
+		ids := make([]string, len(q.GetQueue()))
+		for i, v := range q.GetQueue() {
+			ids[i] = v.Id.String()
+		}
+		return strings.Join(ids, "\n"), nil
+	case "add", "next":
+		if len(args) < 2 {
+			return "", fmt.Errorf("usage: queue %s <id>...", args[0])
+		}
+		songs, err := c.getSongs(args[1:])
+		if err != nil {
+			return "", err
+		}
+		setRequester(songs, req.Client)
+		if args[0] == "add" {
+			q.AddSongs(songs)
+		} else {
+			q.PlayNext(songs)
+		}
+		return fmt.Sprintf("added %s songs", util.FormatNumber(len(songs))), nil
+	case "remove":
+		if config.ReadOnly() {
+			return "", models.ErrReadOnly
+		}
+		if len(args) < 2 {
+			return "", fmt.Errorf("usage: queue remove <index>")
+		}
+		index, err := strconv.Atoi(args[1])
+		if err != nil || index < 1 || index >= len(q.GetQueue()) {
+			return "", fmt.Errorf("invalid index: %s", args[1])
+		}
+		q.RemoveSong(index)
+		return "", nil
+	case "move":
+		if config.ReadOnly() {
+			return "", models.ErrReadOnly
+		}
+		if len(args) < 3 {
+			return "", fmt.Errorf("usage: queue move <index> <new index>")
+		}
+		index, err := strconv.Atoi(args[1])
+		if err != nil {
+			return "", fmt.Errorf("invalid index: %s", args[1])
+		}
+		newIndex, err := strconv.Atoi(args[2])
+		if err != nil {
+			return "", fmt.Errorf("invalid index: %s", args[2])
+		}
+		if !q.MoveSong(index, newIndex) {
+			return "", fmt.Errorf("cannot move song %d to %d", index, newIndex)
+		}
+		return "", nil
+	case "jump":
+		if config.ReadOnly() {
+			return "", models.ErrReadOnly
+		}
+		if len(args) < 2 {
+			return "", fmt.Errorf("usage: queue jump <index>")
+		}
+		p, err := c.getPlayer()
+		if err != nil {
+			return "", err
+		}
+		index, err := strconv.Atoi(args[1])
+		if err != nil || !p.JumpTo(index) {
+			return "", fmt.Errorf("invalid index: %s", args[1])
+		}
+		return "", nil
+	case "save":
+		if len(args) < 2 {
+			return "", fmt.Errorf("usage: queue save <playlist name>")
+		}
+		name := strings.Join(args[1:], " ")
+		id, err := q.SaveQueueAsPlaylist(name)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("saved playlist '%s' (%s)", name, id), nil
+	case "clear":
+		if config.ReadOnly() {
+			return "", models.ErrReadOnly
+		}
+		q.ClearQueue(false)
+		return "", nil
+	default:
+		return "", fmt.Errorf("usage: queue [ids|add|next|remove|move|jump|save|clear]")
+	}
+}
+
+// listQueue lists songs with time until each one starts. First song is the one currently playing.
+func (c *controller) listQueue(songs []*models.Song) string {
+	if len(songs) == 0 {
+		return "queue is empty"
+	}
+	status := c.currentStatus()
+
+	sb := strings.Builder{}
+	startsIn := 0
+	for i, v := range songs {
+		starts := util.SecToStartsIn(startsIn)
+		if i == 0 {
+			if status.State == models.AudioStatePlaying && status.Song != nil && status.Song.Id == v.Id {
+				starts = "playing"
+				startsIn -= status.SongPast.Seconds()
+			}
+		} else {
+			sb.WriteString("\n")
+		}
+		startsIn += v.Duration
+		sb.WriteString(fmt.Sprintf("%3d. %s (%s) %s", i, songString(v), util.SecToString(v.Duration), starts))
+		if v.RequestedBy != "" {
+			sb.WriteString("\n     requested by " + v.RequestedBy)
+		}
+	}
+	if total, remaining, ok := c.queueDuration(); ok {
+		sb.WriteString(fmt.Sprintf("\ntotal %s, %s", util.SecToString(total), remainingString(remaining)))
+	}
+	return sb.String()
+}
+
+// queueDuration returns total and remaining duration of queue in seconds. Remaining duration takes
+// progress of current song and playback rate into account.
+func (c *controller) queueDuration() (total, remaining int, ok bool) {
+	q, err := c.getQueue()
+	if err != nil {
+		return 0, 0, false
+	}
+	queuer, ok := q.(interfaces.Queuer)
+	if !ok {
+		return 0, 0, false
+	}
+	status := c.currentStatus()
+
+	total = queuer.GetTotalDuration().Seconds()
+	remaining = total
+	if status.State == models.AudioStatePlaying {
+		remaining -= status.SongPast.Seconds()
+	}
+	if status.PlaybackRate > 0 {
+		remaining = int(float64(remaining) / status.PlaybackRate)
+	}
+	if remaining < 0 {
+		remaining = 0
+	}
+	return total, remaining, true
+}
+
+// remainingString formats remaining time and estimated clock time when it ends.
+func remainingString(sec int) string {
+	ends := time.Now().Add(time.Duration(sec) * time.Second)
+	return fmt.Sprintf("%s remaining, ends at %s", util.SecToString(sec), ends.Format("15:04"))
+}
+
+// history lists played songs grouped by session. Only latest session is expanded, unless
+// 'all' or session number is given. History can also be listed by pages, and songs or whole sessions
+// can be queued again.
+func (c *controller) history(args []string) (string, error) {
+	usage := fmt.Errorf("usage: history [all|<session>|page <n>|play <n>|queue <session>]")
+	q, err := c.getQueue()
+	if err != nil {
+		return "", err
+	}
+	items := q.GetHistoryItems(-1)
+	if len(items) == 0 {
+		return "history is empty", nil
+	}
+
+	expand := items[0].Session
+	if len(args) > 0 {
+		switch args[0] {
+		case "all":
+			expand = -1
+		case "page", "play", "queue":
+			if len(args) != 2 {
+				return "", usage
+			}
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return "", usage
+			}
+			switch args[0] {
+			case "page":
+				return historyPage(items, n-1)
+			case "play":
+				return c.historyPlay(q, items, n)
+			default:
+				return c.historyQueue(q, items, n)
+			}
+		default:
+			expand, err = strconv.Atoi(args[0])
+			if err != nil {
+				return "", usage
+			}
+		}
+	}
+
+	sb := strings.Builder{}
+	for start := 0; start < len(items); {
+		session := items[start].Session
+		end := start
+		for end < len(items) && items[end].Session == session {
+			end++
+		}
+		group := items[start:end]
+		first := group[len(group)-1].PlayedAt
+		last := group[0].PlayedAt
+
+		if start > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(fmt.Sprintf("session %d: %s songs, %s - %s", session, util.FormatNumber(len(group)),
+			first.Format("2006-01-02 15:04"), last.Format("15:04")))
+		if expand == -1 || expand == session {
+			for i, v := range group {
+				sb.WriteString(fmt.Sprintf("\n%4d. %s %s", start+i+1, v.PlayedAt.Format("15:04"), songString(v.Song)))
+			}
+		}
+		start = end
+	}
+	return sb.String(), nil
+}
+
+// user lists users that have logged in, or switches to one of them. New users log in with 'jellycli login'.
+func (c *controller) user(args []string) (string, error) {
+	conf := &config.AppConfig.Jellyfin
+	if len(args) == 0 {
+		if len(conf.Users) == 0 {
+			return "no stored users, log in with 'jellycli login --user <name>'", nil
+		}
+		sb := strings.Builder{}
+		for i, v := range conf.Users {
+			if i > 0 {
+				sb.WriteString("\n")
+			}
+			mark := " "
+			if v.UserId == conf.UserId {
+				mark = "*"
+			}
+			sb.WriteString(mark + " " + v.Name)
+		}
+		return sb.String(), nil
+	}
+	if len(args) > 1 {
+		return "", fmt.Errorf("usage: user [<name>]")
+	}
+	if config.ReadOnly() {
+		return "", models.ErrReadOnly
+	}
+	c.lock.RLock()
+	users := c.users
+	c.lock.RUnlock()
+	if users == nil {
+		return "", errors.New("server does not support switching user")
+	}
+	user := conf.FindUser(args[0])
+	if user == nil {
+		return "", fmt.Errorf("user '%s' has not logged in, log in with 'jellycli login --user %s'", args[0], args[0])
+	}
+	err := users.SwitchUser(*user)
+	if err != nil {
+		return "", err
+	}
+	conf.SetUser(*user)
+	err = config.SaveConfig()
+	if err != nil {
+		return "", err
+	}
+	return "user: " + user.Name, nil
+}
+
+// historyPage lists one page of history items without grouping. First page is 0.
+func historyPage(items []*models.HistoryItem, page int) (string, error) {
+	paging := models.DefaultPaging()
+	paging.CurrentPage = page
+	paging.SetTotalItems(len(items))
+	if paging.CurrentPage >= paging.TotalPages {
+		return "", fmt.Errorf("page %d out of range, %d pages", paging.CurrentPage+1, paging.TotalPages)
+	}
+	start := paging.Offset()
+	end := start + paging.PageSize
+	if end > len(items) {
+		end = len(items)
+	}
+	sb := strings.Builder{}
+	for i, v := range items[start:end] {
+		sb.WriteString(fmt.Sprintf("%4d. %s [%d] %s\n", start+i+1, v.PlayedAt.Format("2006-01-02 15:04"),
+			v.Session, songString(v.Song)))
+	}
+	sb.WriteString(fmt.Sprintf("page %d/%d, %d songs", paging.CurrentPage+1, paging.TotalPages, len(items)))
+	return sb.String(), nil
+}
+
+// historyPlay plays history item n right away. Items are numbered from 1, latest first.
+func (c *controller) historyPlay(q interfaces.QueueController, items []*models.HistoryItem, n int) (string, error) {
+	if config.ReadOnly() {
+		return "", models.ErrReadOnly
+	}
+	if n > len(items) {
+		return "", fmt.Errorf("no history item %d, history has %d songs", n, len(items))
+	}
+	p, err := c.getPlayer()
+	if err != nil {
+		return "", err
+	}
+	song := items[n-1].Song
+	if len(q.GetQueue()) == 0 {
+		q.AddSongs([]*models.Song{song})
+	} else {
+		q.PlayNext([]*models.Song{song})
+		p.Next()
+	}
+	return "playing " + songString(song), nil
+}
+
+// historyQueue adds songs of history session to the end of queue in the order they were played.
+func (c *controller) historyQueue(q interfaces.QueueController, items []*models.HistoryItem, session int) (string, error) {
+	if config.ReadOnly() {
+		return "", models.ErrReadOnly
+	}
+	var songs []*models.Song
+	for i := len(items) - 1; i >= 0; i-- {
+		if items[i].Session == session {
+			songs = append(songs, items[i].Song)
+		}
+	}
+	if len(songs) == 0 {
+		return "", fmt.Errorf("no history session %d", session)
+	}
+	q.AddSongs(songs)
+	return fmt.Sprintf("queued %s songs of session %d", util.FormatNumber(len(songs)), session), nil
+}
+
+func (c *controller) preview(args []string) (string, error) {
+	p, err := c.getPlayer()
+	if err != nil {
+		return "", err
+	}
+	if len(args) != 1 {
+		return "", fmt.Errorf("usage: preview <song id>|stop")
+	}
+	if args[0] == "stop" {
+		p.StopPreview()
+		return "", nil
+	}
+	songs, err := c.getSongs(args)
+	if err != nil {
+		return "", err
+	}
+	p.PreviewSong(songs[0])
+	return "previewing " + songString(songs[0]), nil
+}
+
+// instantMix starts instant mix from current song. By default upcoming songs are replaced with mix,
+// with 'add' mix is added to the end of queue.
+func (c *controller) instantMix(args []string) (string, error) {
+	replace := true
+	if len(args) > 0 {
+		if args[0] != "add" {
+			return "", fmt.Errorf("usage: mix [add]")
+		}
+		replace = false
+	}
+	if replace && config.ReadOnly() {
+		return "", models.ErrReadOnly
+	}
+	q, err := c.getQueue()
+	if err != nil {
+		return "", err
+	}
+	c.lock.RLock()
+	library := c.library
+	song := c.status.Song
+	c.lock.RUnlock()
+	if library == nil {
+		return "", errors.New("server does not support instant mix")
+	}
+	if song == nil {
+		return "", errors.New("nothing is playing")
+	}
+
+	mix, err := library.GetInstantMix(song.Id)
+	if err != nil {
+		return "", fmt.Errorf("get instant mix: %v", err)
+	}
+	songs := make([]*models.Song, 0, len(mix))
+	for _, v := range mix {
+		if v.Id != song.Id {
+			songs = append(songs, v)
+		}
+	}
+	if len(songs) == 0 {
+		return "", errors.New("no similar songs found")
+	}
+
+	if replace {
+		q.ClearQueue(false)
+	}
+	q.AddSongs(songs)
+	return fmt.Sprintf("added %s songs from instant mix of %s", util.FormatNumber(len(songs)), songString(song)), nil
+}
+
+// favorite toggles or sets favorite of current or given song: fav [on|off [song id]]. With 'sync', changes
+// made while offline are sent to server.
+func (c *controller) favorite(args []string) (string, error) {
+	c.lock.RLock()
+	favorites := c.favorites
+	song := c.status.Song
+	c.lock.RUnlock()
+	if favorites == nil {
+		return "", errors.New("server does not support favorites")
+	}
+
+	if len(args) > 0 && args[0] == "sync" {
+		summary, err := favorites.Sync()
+		if err != nil {
+			return "", err
+		}
+		out := summary.String()
+		if len(summary.Conflicts) > 0 {
+			out += "\nchanged in server meanwhile, kept server state: " + strings.Join(summary.Conflicts, ", ")
+		}
+		return out, nil
+	}
+
+	if config.ReadOnly() {
+		return "", models.ErrReadOnly
+	}
+	if len(args) > 2 || (len(args) > 0 && args[0] != "on" && args[0] != "off") {
+		return "", fmt.Errorf("usage: fav [on|off [song id]] | fav sync")
+	}
+	if len(args) == 2 {
+		songs, err := c.getSongs(args[1:])
+		if err != nil {
+			return "", err
+		}
+		song = songs[0]
+	}
+	if song == nil {
+		return "", errors.New("nothing is playing")
+	}
+
+	favorite := !song.Favorite
+	if len(args) > 0 {
+		favorite = args[0] == "on"
+	}
+	queued, err := favorites.Set(song, favorite)
+	if err != nil {
+		return "", fmt.Errorf("set favorite: %v", err)
+	}
+	out := fmt.Sprintf("favorite %s: %s", onOff(favorite), songString(song))
+	if queued {
+		out += " (offline, will sync when server is reachable)"
+	}
+	return out, nil
+}
+
+// songInfo shows metadata of current or given song: info [song id].
+func (c *controller) songInfo(args []string) (string, error) {
+	c.lock.RLock()
+	song := c.status.Song
+	c.lock.RUnlock()
+	if len(args) > 1 {
+		return "", fmt.Errorf("usage: info [song id]")
+	}
+	if len(args) == 1 {
+		songs, err := c.getSongs(args)
+		if err != nil {
+			return "", err
+		}
+		song = songs[0]
+	}
+	if song == nil {
+		return "", errors.New("nothing is playing")
+	}
+
+	artists := make([]string, len(song.Artists))
+	for i, v := range song.Artists {
+		artists[i] = v.Name
+	}
+	type field struct {
+		name  string
+		value string
+	}
+	fields := []field{
+		{"title", song.Name},
+		{"artists", strings.Join(artists, ", ")},
+		{"album", song.AlbumName},
+		{"album artist", song.AlbumArtistName},
+		{"composer", song.Composer},
+		{"genre", song.Genre},
+		{"duration", util.SecToString(song.Duration)},
+		{"id", song.Id.String()},
+	}
+	if song.Year > 0 {
+		fields = append(fields, field{"year", strconv.Itoa(song.Year)})
+	}
+	if song.DiscNumber > 0 || song.Index > 0 {
+		fields = append(fields, field{"track", fmt.Sprintf("%d/%d", song.DiscNumber, song.Index)})
+	}
+	format := song.Container
+	if song.Bitrate > 0 {
+		format = strings.TrimSpace(fmt.Sprintf("%s %d kbps", format, song.Bitrate))
+	}
+	fields = append(fields, field{"format", format})
+
+	sb := strings.Builder{}
+	for _, v := range fields {
+		if v.value == "" {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(fmt.Sprintf("%-13s %s", v.name+":", v.value))
+	}
+	return sb.String(), nil
+}
+
+// link returns web link of current song or given item: link [id].
+func (c *controller) link(args []string) (string, error) {
+	c.lock.RLock()
+	linker := c.linker
+	song := c.status.Song
+	c.lock.RUnlock()
+	if linker == nil {
+		return "", errors.New("server does not support web links")
+	}
+	if len(args) > 1 {
+		return "", fmt.Errorf("usage: link [id]")
+	}
+	if len(args) == 1 {
+		return linker.GetLink(models.Id(args[0])), nil
+	}
+	if song == nil {
+		return "", errors.New("nothing is playing")
+	}
+	return linker.GetLink(song.Id), nil
+}
+
+// rate returns handler that toggles rating of current or given song: like|dislike [song id].
+func (c *controller) rate(rating models.Rating) Handler {
+	name := "like"
+	if rating == models.RatingDislike {
+		name = "dislike"
+	}
+	return func(args []string) (string, error) {
+		c.lock.RLock()
+		rater := c.rater
+		song := c.status.Song
+		c.lock.RUnlock()
+		if rater == nil {
+			return "", errors.New("server does not support ratings")
+		}
+		if config.ReadOnly() {
+			return "", models.ErrReadOnly
+		}
+		if len(args) > 1 {
+			return "", fmt.Errorf("usage: %s [song id]", name)
+		}
+		if len(args) == 1 {
+			songs, err := c.getSongs(args)
+			if err != nil {
+				return "", err
+			}
+			song = songs[0]
+		}
+		if song == nil {
+			return "", errors.New("nothing is playing")
+		}
+
+		newRating := rating
+		if song.Rating == rating {
+			newRating = models.RatingNone
+		}
+		err := rater.SetRating(song.Id, newRating)
+		if err != nil {
+			return "", fmt.Errorf("set rating: %v", err)
+		}
+		song.Rating = newRating
+		return fmt.Sprintf("%s %s: %s", name, onOff(newRating == rating), songString(song)), nil
+	}
+}
+
+// listFavorites lists page of favorite albums or songs: favs albums|songs [page]. First page is 1.
+func (c *controller) listFavorites(args []string) (string, error) {
+	usage := fmt.Errorf("usage: favs albums|songs [page]")
+	c.lock.RLock()
+	library := c.library
+	c.lock.RUnlock()
+	if library == nil {
+		return "", errors.New("server does not support listing favorites")
+	}
+	if len(args) == 0 || len(args) > 2 {
+		return "", usage
+	}
+	paging := models.DefaultPaging()
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return "", usage
+		}
+		paging.CurrentPage = n - 1
+	}
+
+	var items []models.Item
+	var total int
+	switch args[0] {
+	case "albums":
+		albums, n, err := library.GetFavoriteAlbums(paging)
+		if err != nil {
+			return "", fmt.Errorf("get favorite albums: %v", err)
+		}
+		for _, v := range albums {
+			items = append(items, v)
+		}
+		total = n
+	case "songs":
+		songs, n, err := library.GetFavoriteSongs(paging)
+		if err != nil {
+			return "", fmt.Errorf("get favorite songs: %v", err)
+		}
+		for _, v := range songs {
+			items = append(items, v)
+		}
+		total = n
+	default:
+		return "", usage
+	}
+
+	paging.SetTotalItems(total)
+	if total == 0 {
+		return "no favorite " + args[0], nil
+	}
+	if len(items) == 0 {
+		return "", fmt.Errorf("page %d out of range, %d pages", paging.CurrentPage+1, paging.TotalPages)
+	}
+	sb := strings.Builder{}
+	for _, v := range items {
+		sb.WriteString(fmt.Sprintf("%s  %s\n", v.GetId(), itemString(v)))
+	}
+	sb.WriteString(fmt.Sprintf("page %d/%d, %d %s", paging.CurrentPage+1, paging.TotalPages, total, args[0]))
+	return sb.String(), nil
+}
+
+// sortFields maps sort names accepted by list commands to sort fields.
+var sortFields = map[string]models.SortField{
+	"name":   models.SortByName,
+	"date":   models.SortByDate,
+	"artist": models.SortByArtist,
+	"album":  models.SortByAlbum,
+	"plays":  models.SortByPlayCount,
+	"random": models.SortByRandom,
+	"latest": models.SortByLatest,
+	"played": models.SortByLastPlayed,
+}
+
+// listFiltered returns handler that lists page of albums or songs matching filter:
+// albums|songs [year=<from>[-<to>]] [genre=<genre>[,<genre>]] [fav] [unplayed] [sort=<field>] [asc|desc] [page].
+func (c *controller) listFiltered(kind string) Handler {
+	return func(args []string) (string, error) {
+		usage := fmt.Errorf("usage: %s [year=<from>[-<to>]] [genre=<genre>[,<genre>]] [fav] [unplayed] "+
+			"[sort=name|date|artist|album|plays|random|latest|played] [asc|desc] [page]", kind)
+		c.lock.RLock()
+		library := c.library
+		c.lock.RUnlock()
+		if library == nil {
+			return "", errors.New("server does not support listing " + kind)
+		}
+
+		filter := models.Filter{}
+		sort := models.Sort{Mode: models.SortAsc}
+		paging := models.DefaultPaging()
+		for _, arg := range args {
+			switch {
+			case arg == "asc":
+				sort.Mode = models.SortAsc
+			case arg == "desc":
+				sort.Mode = models.SortDesc
+			case strings.HasPrefix(arg, "sort="):
+				field, ok := sortFields[strings.TrimPrefix(arg, "sort=")]
+				if !ok {
+					return "", usage
+				}
+				sort.Field = field
+			case arg == "fav":
+				filter.FavoriteOnly = true
+			case arg == "unplayed":
+				filter.UnplayedOnly = true
+			case strings.HasPrefix(arg, "genre="):
+				for _, g := range strings.Split(strings.TrimPrefix(arg, "genre="), ",") {
+					if g = strings.TrimSpace(g); g != "" {
+						filter.Genres = append(filter.Genres, g)
+					}
+				}
+			case strings.HasPrefix(arg, "year="):
+				from, to, err := parseYearRange(strings.TrimPrefix(arg, "year="))
+				if err != nil {
+					return "", usage
+				}
+				filter.YearFrom, filter.YearTo = from, to
+			default:
+				n, err := strconv.Atoi(arg)
+				if err != nil || n < 1 {
+					return "", usage
+				}
+				paging.CurrentPage = n - 1
+			}
+		}
+		if err := filter.Validate(); err != nil {
+			return "", err
+		}
+
+		var items []models.Item
+		var total int
+		if kind == "albums" {
+			albums, n, err := library.GetAlbums(filter, sort, paging)
+			if err != nil {
+				return "", fmt.Errorf("get albums: %v", err)
+			}
+			for _, v := range albums {
+				items = append(items, v)
+			}
+			total = n
+		} else {
+			songs, n, err := library.GetSongs(filter, sort, paging)
+			if err != nil {
+				return "", fmt.Errorf("get songs: %v", err)
+			}
+			for _, v := range songs {
+				items = append(items, v)
+			}
+			total = n
+		}
+
+		paging.SetTotalItems(total)
+		if total == 0 {
+			return "no matching " + kind, nil
+		}
+		if len(items) == 0 {
+			return "", fmt.Errorf("page %d out of range, %d pages", paging.CurrentPage+1, paging.TotalPages)
+		}
+		sb := strings.Builder{}
+		for _, v := range items {
+			sb.WriteString(fmt.Sprintf("%s  %s\n", v.GetId(), itemString(v)))
+		}
+		sb.WriteString(fmt.Sprintf("page %d/%d, %d %s", paging.CurrentPage+1, paging.TotalPages, total, kind))
+		return sb.String(), nil
+	}
+}
+
+// parseYearRange parses year or year range: 1990, 1990-1999, 1990- or -1999.
+func parseYearRange(s string) (from, to int, err error) {
+	parts := strings.SplitN(s, "-", 2)
+	if parts[0] != "" {
+		from, err = strconv.Atoi(parts[0])
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if len(parts) == 1 {
+		return from, from, nil
+	}
+	if parts[1] != "" {
+		to, err = strconv.Atoi(parts[1])
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if from == 0 && to == 0 {
+		return 0, 0, models.ErrInvalidFilter
+	}
+	return from, to, nil
+}
+
+// playlist lists or edits playlist: playlist <id> [add|remove|move]. Indices start from 1.
+func (c *controller) playlist(args []string) (string, error) {
+	usage := fmt.Errorf("usage: playlist <id> [add <song id>...|remove <index>|move <index> <new index>]")
+	c.lock.RLock()
+	editor := c.editor
+	c.lock.RUnlock()
+	if editor == nil {
+		return "", errors.New("server does not support playlists")
+	}
+	if len(args) == 0 {
+		return "", usage
+	}
+
+	id := models.Id(args[0])
+	if len(args) == 1 {
+		songs, err := editor.GetPlaylistSongs(id)
+		if err != nil {
+			return "", fmt.Errorf("get playlist: %v", err)
+		}
+		if len(songs) == 0 {
+			return "playlist is empty", nil
+		}
+		sb := strings.Builder{}
+		for i, v := range songs {
+			if i > 0 {
+				sb.WriteString("\n")
+			}
+			sb.WriteString(fmt.Sprintf("%3d. %s (%s)", i+1, songString(v), util.SecToString(v.Duration)))
+		}
+		return sb.String(), nil
+	}
+
+	if config.ReadOnly() {
+		return "", models.ErrReadOnly
+	}
+	indices := func(args []string) ([]int, error) {
+		out := make([]int, len(args))
+		for i, v := range args {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid index: %s", v)
+			}
+			out[i] = n - 1
+		}
+		return out, nil
+	}
+
+	var err error
+	switch {
+	case args[1] == "add" && len(args) > 2:
+		ids := make([]models.Id, len(args)-2)
+		for i, v := range args[2:] {
+			ids[i] = models.Id(v)
+		}
+		err = editor.AddToPlaylist(id, ids)
+	case args[1] == "remove" && len(args) == 3:
+		var index []int
+		index, err = indices(args[2:])
+		if err == nil {
+			err = editor.RemoveFromPlaylist(id, index[0])
+		}
+	case args[1] == "move" && len(args) == 4:
+		var index []int
+		index, err = indices(args[2:])
+		if err == nil {
+			err = editor.MovePlaylistItem(id, index[0], index[1])
+		}
+	default:
+		return "", usage
+	}
+	if err != nil {
+		return "", fmt.Errorf("edit playlist: %v", err)
+	}
+	return "", nil
+}
+
+// album lists songs of album grouped by disc, shows its info, or plays or queues whole album or single disc:
+// album <id> [info|play|queue [disc]].
+func (c *controller) album(req *Request) (string, error) {
+	usage := fmt.Errorf("usage: album <id> [info|play|queue [disc]]")
+	args := req.Args
+	c.lock.RLock()
+	library := c.library
+	c.lock.RUnlock()
+	if library == nil {
+		return "", errors.New("server does not support browsing library")
+	}
+	if len(args) == 0 || len(args) > 3 {
+		return "", usage
+	}
+	if len(args) == 2 && args[1] == "info" {
+		return albumInfo(library, models.Id(args[0]))
+	}
+
+	songs, err := library.GetAlbumSongs(models.Id(args[0]))
+	if err != nil {
+		return "", fmt.Errorf("get album: %v", err)
+	}
+	if len(songs) == 0 {
+		return "album is empty", nil
+	}
+	discs := models.GroupByDisc(songs)
+	if len(args) == 1 {
+		sb := strings.Builder{}
+		for i, disc := range discs {
+			if len(discs) > 1 {
+				if i > 0 {
+					sb.WriteString("\n")
+				}
+				sb.WriteString(fmt.Sprintf("Disc %d (%s)\n", disc.Number, util.SecToString(songsDuration(disc.Songs))))
+			}
+			for j, v := range disc.Songs {
+				index := v.Index
+				if index < 1 {
+					index = j + 1
+				}
+				if i > 0 || j > 0 {
+					sb.WriteString("\n")
+				}
+				sb.WriteString(fmt.Sprintf("%3d. %s (%s)", index, songString(v), util.SecToString(v.Duration)))
+			}
+		}
+		return sb.String(), nil
+	}
+
+	if args[1] != "play" && args[1] != "queue" {
+		return "", usage
+	}
+	if len(args) == 3 {
+		number, err := strconv.Atoi(args[2])
+		if err != nil {
+			return "", fmt.Errorf("invalid disc: %s", args[2])
+		}
+		songs = nil
+		for _, v := range discs {
+			if v.Number == number {
+				songs = v.Songs
+			}
+		}
+		if songs == nil {
+			return "", fmt.Errorf("album has no disc %d", number)
+		}
+	}
+	q, err := c.getQueue()
+	if err != nil {
+		return "", err
+	}
+	setRequester(songs, req.Client)
+	if args[1] == "queue" {
+		q.AddSongs(songs)
+		return fmt.Sprintf("added %s songs", util.FormatNumber(len(songs))), nil
+	}
+	if config.ReadOnly() {
+		return "", models.ErrReadOnly
+	}
+	p, err := c.getPlayer()
+	if err != nil {
+		return "", err
+	}
+	p.StopMedia()
+	q.ClearQueue(true)
+	q.AddSongs(songs)
+	return fmt.Sprintf("playing %d songs", len(songs)), nil
+}
+
+// artist lists most played songs and similar artists of artist, or plays or queues its top songs:
+// artist <id> [play|queue].
+func (c *controller) artist(req *Request) (string, error) {
+	args := req.Args
+	c.lock.RLock()
+	artists := c.artists
+	c.lock.RUnlock()
+	if artists == nil {
+		return "", errors.New("server does not support artist info")
+	}
+	if len(args) == 0 || len(args) > 2 || (len(args) == 2 && args[1] != "play" && args[1] != "queue") {
+		return "", fmt.Errorf("usage: artist <id> [play|queue]")
+	}
+
+	id := models.Id(args[0])
+	songs, err := artists.GetArtistTopSongs(id, config.ArtistTopSongsLimit)
+	if err != nil {
+		return "", fmt.Errorf("get top songs: %v", err)
+	}
+	if len(args) == 2 {
+		if len(songs) == 0 {
+			return "", errors.New("artist has no played songs")
+		}
+		q, err := c.getQueue()
+		if err != nil {
+			return "", err
+		}
+		setRequester(songs, req.Client)
+		if args[1] == "queue" {
+			q.AddSongs(songs)
+			return fmt.Sprintf("added %s songs", util.FormatNumber(len(songs))), nil
+		}
+		if config.ReadOnly() {
+			return "", models.ErrReadOnly
+		}
+		p, err := c.getPlayer()
+		if err != nil {
+			return "", err
+		}
+		p.StopMedia()
+		q.ClearQueue(true)
+		q.AddSongs(songs)
+		return fmt.Sprintf("playing %d songs", len(songs)), nil
+	}
+
+	similar, err := artists.GetSimilarArtists(id, config.SimilarArtistsLimit)
+	if err != nil {
+		return "", fmt.Errorf("get similar artists: %v", err)
+	}
+	sb := strings.Builder{}
+	sb.WriteString("Top songs:")
+	if len(songs) == 0 {
+		sb.WriteString("\n  none played yet")
+	}
+	for i, v := range songs {
+		sb.WriteString(fmt.Sprintf("\n  %s  %d. %s (%s)", v.Id, i+1, songString(v), util.SecToString(v.Duration)))
+	}
+	sb.WriteString("\nSimilar artists:")
+	if len(similar) == 0 {
+		sb.WriteString("\n  none")
+	}
+	for _, v := range similar {
+		sb.WriteString(fmt.Sprintf("\n  %s  %s", v.Id, itemString(v)))
+	}
+	return sb.String(), nil
+}
+
+// albumInfo shows overview, genres, year, duration and favorite status of album.
+func albumInfo(library api.Library, id models.Id) (string, error) {
+	items, err := library.GetItems([]models.Id{id})
+	if err != nil {
+		return "", fmt.Errorf("get album: %v", err)
+	}
+	var album *models.Album
+	for _, v := range items {
+		if a, ok := v.(*models.Album); ok {
+			album = a
+		}
+	}
+	if album == nil {
+		return "", fmt.Errorf("album not found: %s", id)
+	}
+
+	artists := make([]string, len(album.AdditionalArtists))
+	for i, v := range album.AdditionalArtists {
+		artists[i] = v.Name
+	}
+	year := ""
+	if album.Year > 0 {
+		year = strconv.Itoa(album.Year)
+	}
+	favorite := "no"
+	if album.Favorite {
+		favorite = "yes"
+	}
+	type field struct {
+		name  string
+		value string
+	}
+	fields := []field{
+		{"title", album.Name},
+		{"artists", strings.Join(artists, ", ")},
+		{"year", year},
+		{"genres", strings.Join(album.Genres, ", ")},
+		{"duration", util.SecToString(album.Duration)},
+		{"favorite", favorite},
+		{"id", album.Id.String()},
+	}
+	sb := strings.Builder{}
+	for _, v := range fields {
+		if v.value == "" {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(fmt.Sprintf("%-13s %s", v.name+":", v.value))
+	}
+	if album.Overview != "" {
+		sb.WriteString("\n\n" + strings.TrimSpace(album.Overview))
+	}
+	return sb.String(), nil
+}
+
+func songsDuration(songs []*models.Song) int {
+	total := 0
+	for _, v := range songs {
+		total += v.Duration
+	}
+	return total
+}
+
+// search searches artists, albums, songs and playlists: search [artist|album|song|playlist] <query>.
+// Results are grouped by type and printed with ids, which can be passed to e.g. queue add or playlist.
+func (c *controller) search(args []string) (string, error) {
+	c.lock.RLock()
+	library := c.library
+	c.lock.RUnlock()
+	if library == nil {
+		return "", errors.New("server does not support search")
+	}
+
+	types := []models.ItemType{models.TypeArtist, models.TypeAlbum, models.TypeSong, models.TypePlaylist}
+	if len(args) > 1 {
+		for _, v := range types {
+			if strings.EqualFold(args[0], string(v)) {
+				types = []models.ItemType{v}
+				args = args[1:]
+				break
+			}
+		}
+	}
+	query := strings.Join(args, " ")
+	if query == "" {
+		return "", fmt.Errorf("usage: search [artist|album|song|playlist] <query>")
+	}
+
+	sb := strings.Builder{}
+	for _, t := range types {
+		items, err := library.Search(query, t, config.SearchLimit)
+		if err != nil {
+			return "", fmt.Errorf("search %ss: %v", strings.ToLower(string(t)), err)
+		}
+		if len(items) == 0 {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString(fmt.Sprintf("%ss:", t))
+		for _, v := range items {
+			sb.WriteString(fmt.Sprintf("\n  %s  %s", v.GetId(), itemString(v)))
+		}
+	}
+	if sb.Len() == 0 {
+		return "no results", nil
+	}
+	return sb.String(), nil
+}
+
+// newReleases lists albums released in last n days: new [days]. This differs from recently added
+// in that it uses release date.
+func (c *controller) newReleases(args []string) (string, error) {
+	c.lock.RLock()
+	library := c.library
+	c.lock.RUnlock()
+	if library == nil {
+		return "", errors.New("server does not support listing albums")
+	}
+	days := config.NewReleasesDays
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || len(args) > 1 {
+			return "", fmt.Errorf("usage: new [days]")
+		}
+		days = n
+	}
+
+	albums, err := library.GetNewReleases(days)
+	if err != nil {
+		return "", fmt.Errorf("get new releases: %v", err)
+	}
+	if len(albums) == 0 {
+		return fmt.Sprintf("no albums released in last %d days", days), nil
+	}
+	sb := strings.Builder{}
+	for i, v := range albums {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		artists := make([]string, len(v.AdditionalArtists))
+		for i, artist := range v.AdditionalArtists {
+			artists[i] = artist.Name
+		}
+		sb.WriteString(fmt.Sprintf("%s  %s  %s - %s", v.PremiereDate.Format("2006-01-02"), v.Id,
+			strings.Join(artists, ", "), v.Name))
+	}
+	return sb.String(), nil
+}
+
+// views lists music libraries. Selected libraries are marked with '*'.
+func (c *controller) views(args []string) (string, error) {
+	browser, err := c.getBrowser()
+	if err != nil {
+		return "", err
+	}
+	views, err := browser.GetViews()
+	if err != nil {
+		return "", fmt.Errorf("get views: %v", err)
+	}
+	if len(views) == 0 {
+		return "no music libraries", nil
+	}
+	sb := strings.Builder{}
+	for i, v := range views {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		mark := " "
+		if v.Selected {
+			mark = "*"
+		}
+		sb.WriteString(fmt.Sprintf("%s %s  %s", mark, v.Id, v.Name))
+	}
+	return sb.String(), nil
+}
+
+// browse lists children of library or folder.
+func (c *controller) browse(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("usage: browse <library or folder id>")
+	}
+	browser, err := c.getBrowser()
+	if err != nil {
+		return "", err
+	}
+	items, err := browser.GetFolder(models.Id(args[0]))
+	if err != nil {
+		return "", fmt.Errorf("get folder: %v", err)
+	}
+	if len(items) == 0 {
+		return "folder is empty", nil
+	}
+	sb := strings.Builder{}
+	for i, v := range items {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		name := v.Name
+		if v.IsFolder {
+			name += "/"
+		}
+		sb.WriteString(fmt.Sprintf("%-12s %s  %s", v.Type, v.Id, name))
+	}
+	return sb.String(), nil
+}
+
+func (c *controller) getBrowser() (api.LibraryBrowser, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if c.browser == nil {
+		return nil, errors.New("server does not support browsing libraries")
+	}
+	return c.browser, nil
+}
+
+func itemString(item models.Item) string {
+	switch v := item.(type) {
+	case *models.Song:
+		return fmt.Sprintf("%s (%s)", songString(v), util.SecToString(v.Duration))
+	case *models.Album:
+		if v.Year > 0 {
+			return fmt.Sprintf("%s (%d)", v.Name, v.Year)
+		}
+		return v.Name
+	case *models.Playlist:
+		return fmt.Sprintf("%s (%d songs)", v.Name, v.SongCount)
+	default:
+		return item.GetName()
+	}
+}
+
+// getSongs returns songs of given items in order. Items can be songs, albums, artists or playlists.
+func (c *controller) getSongs(ids []string) ([]*models.Song, error) {
+	c.lock.RLock()
+	library := c.library
+	editor := c.editor
+	c.lock.RUnlock()
+	if library == nil {
+		return nil, errors.New("server does not support fetching songs")
+	}
+
+	itemIds := make([]models.Id, len(ids))
+	for i, v := range ids {
+		itemIds[i] = models.Id(v)
+	}
+	items, err := library.GetItems(itemIds)
+	if err != nil {
+		return nil, fmt.Errorf("get items: %v", err)
+	}
+
+	songs := []*models.Song{}
+	for _, item := range items {
+		var itemSongs []*models.Song
+		switch v := item.(type) {
+		case *models.Song:
+			itemSongs = []*models.Song{v}
+		case *models.Album:
+			itemSongs, err = library.GetAlbumSongs(v.Id)
+		case *models.Artist:
+			itemSongs, err = library.GetArtistSongs(v.Id)
+		case *models.Playlist:
+			if editor == nil {
+				return nil, errors.New("server does not support playlists")
+			}
+			itemSongs, err = editor.GetPlaylistSongs(v.Id)
+		default:
+			err = fmt.Errorf("unsupported item type %s", item.GetType())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get songs of %s: %v", item.GetName(), err)
+		}
+		songs = append(songs, itemSongs...)
+	}
+	if len(songs) == 0 {
+		return nil, errors.New("no songs found")
+	}
+	return songs, nil
+}
+
+// setRequester annotates songs with client that added them.
+func setRequester(songs []*models.Song, client string) {
+	for _, v := range songs {
+		v.RequestedBy = client
+	}
+}
+
+func songString(song *models.Song) string {
+	artists := make([]string, len(song.Artists))
+	for i, v := range song.Artists {
+		artists[i] = v.Name
+	}
+	name := song.Name + songMarks(song)
+	if len(artists) == 0 {
+		return name
+	}
+	return strings.Join(artists, ", ") + " - " + name
+}
+
+// songMarks returns markers for favorite and rating of song, e.g. " ♥ +".
+func songMarks(song *models.Song) string {
+	marks := ""
+	if song.Favorite {
+		marks += " ♥"
+	}
+	switch song.Rating {
+	case models.RatingLike:
+		marks += " +"
+	case models.RatingDislike:
+		marks += " -"
+	}
+	return marks
+}
+
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,82 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package ipc implements local control interface for running jellycli instance. Server listens on
+// unix socket (named pipe on Windows) and client sends commands to it. Protocol is line-delimited json:
+// client writes one Request and server answers with one Response.
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Request is a command sent to running instance.
+type Request struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+	// Client identifies sender, e.g. user@host. Songs added to queue are annotated with it.
+	Client string `json:"client,omitempty"`
+}
+
+// Response is a result of a command.
+type Response struct {
+	Ok     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+	Output string `json:"output,omitempty"`
+}
+
+// Handler handles single command. Returned string is printed to user.
+type Handler func(args []string) (string, error)
+
+// RequestHandler is a Handler that needs whole request, e.g. to know the client.
+type RequestHandler func(req *Request) (string, error)
+
+// timeout for a single request
+const requestTimeout = time.Second * 10
+
+// Send sends request to instance listening on socket and returns its response.
+func Send(socket string, req *Request) (*Response, error) {
+	conn, err := dial(socket)
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s: %v (is jellycli running?)", socket, err)
+	}
+	defer conn.Close()
+	err = conn.SetDeadline(time.Now().Add(requestTimeout))
+	if err != nil {
+		return nil, fmt.Errorf("set deadline: %v", err)
+	}
+
+	err = json.NewEncoder(conn).Encode(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %v", err)
+	}
+
+	resp := &Response{}
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read response: %v", err)
+	}
+	err = json.Unmarshal(line, resp)
+	if err != nil {
+		return nil, fmt.Errorf("decode response: %v", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,69 @@
+//go:build !windows
+// +build !windows
+
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ipc
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"time"
+)
+
+// DefaultSocketPath returns socket in user's runtime directory, or temp directory if there is none.
+func DefaultSocketPath() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		return path.Join(os.TempDir(), fmt.Sprintf("jellycli-%d.sock", os.Getuid()))
+	}
+	return path.Join(dir, "jellycli.sock")
+}
+
+func listen(socket string) (net.Listener, error) {
+	if _, err := os.Stat(socket); err == nil {
+		// socket is stale unless there's someone listening to it
+		conn, err := net.DialTimeout("unix", socket, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("another instance is already running")
+		}
+		err = os.Remove(socket)
+		if err != nil {
+			return nil, fmt.Errorf("remove stale socket: %v", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	err = os.Chmod(socket, 0600)
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("set socket permissions: %v", err)
+	}
+	return listener, nil
+}
+
+func dial(socket string) (net.Conn, error) {
+	return net.DialTimeout("unix", socket, time.Second*2)
+}
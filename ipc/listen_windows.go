@@ -0,0 +1,41 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ipc
+
+import (
+	"net"
+	"time"
+
+	"github.com/Microsoft/go-winio"
+)
+
+// DefaultSocketPath returns named pipe for jellycli.
+func DefaultSocketPath() string {
+	return `\\.\pipe\jellycli`
+}
+
+func listen(socket string) (net.Listener, error) {
+	// allow only current user to connect
+	return winio.ListenPipe(socket, &winio.PipeConfig{SecurityDescriptor: "D:P(A;;GA;;;OW)"})
+}
+
+func dial(socket string) (net.Conn, error) {
+	timeout := time.Second * 2
+	return winio.DialPipe(socket, &timeout)
+}
@@ -0,0 +1,170 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"tryffel.net/go/jellycli/task"
+)
+
+// Server listens to local socket and runs commands it receives. Commands are registered with Handle.
+type Server struct {
+	task.Task
+	socket string
+
+	lock     sync.RWMutex
+	listener net.Listener
+	handlers map[string]RequestHandler
+	ctrl     *controller
+}
+
+// NewServer creates new server that listens to given socket once started. If socket is empty,
+// DefaultSocketPath is used.
+func NewServer(socket string) *Server {
+	if socket == "" {
+		socket = DefaultSocketPath()
+	}
+	s := &Server{
+		socket:   socket,
+		handlers: map[string]RequestHandler{},
+		ctrl:     &controller{},
+	}
+	s.Name = "Ipc"
+	s.SetLoop(s.loop)
+	s.Handle("help", s.help)
+	s.handlePlayerCommands()
+	return s
+}
+
+// Handle registers handler for command. Any existing handler for command is replaced.
+func (s *Server) Handle(command string, handler Handler) {
+	s.HandleRequest(command, func(req *Request) (string, error) {
+		return handler(req.Args)
+	})
+}
+
+// HandleRequest registers handler that receives whole request.
+func (s *Server) HandleRequest(command string, handler RequestHandler) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.handlers[command] = handler
+}
+
+// Start starts listening to socket.
+func (s *Server) Start() error {
+	listener, err := listen(s.socket)
+	if err != nil {
+		return fmt.Errorf("listen %s: %v", s.socket, err)
+	}
+	s.lock.Lock()
+	s.listener = listener
+	s.lock.Unlock()
+	logrus.Infof("Listening for commands on %s", s.socket)
+	return s.Task.Start()
+}
+
+func (s *Server) loop() {
+	go s.accept()
+	<-s.StopChan()
+	s.lock.Lock()
+	err := s.listener.Close()
+	s.lock.Unlock()
+	if err != nil {
+		logrus.Errorf("close ipc socket: %v", err)
+	}
+}
+
+func (s *Server) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !s.IsRunning() || isClosedErr(err) {
+				return
+			}
+			logrus.Errorf("accept ipc connection: %v", err)
+			continue
+		}
+		go s.handleConn(conn)
+	}
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+	err := conn.SetDeadline(time.Now().Add(requestTimeout))
+	if err != nil {
+		logrus.Errorf("set ipc deadline: %v", err)
+		return
+	}
+
+	resp := &Response{}
+	req := &Request{}
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err == nil {
+		err = json.Unmarshal(line, req)
+	}
+	if err != nil {
+		resp.Error = fmt.Sprintf("invalid request: %v", err)
+	} else {
+		resp.Output, err = s.run(req)
+		if err != nil {
+			resp.Error = err.Error()
+		} else {
+			resp.Ok = true
+		}
+	}
+
+	err = json.NewEncoder(conn).Encode(resp)
+	if err != nil {
+		logrus.Errorf("send ipc response: %v", err)
+	}
+}
+
+func (s *Server) run(req *Request) (string, error) {
+	s.lock.RLock()
+	handler, ok := s.handlers[req.Command]
+	s.lock.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("unknown command '%s', see 'help'", req.Command)
+	}
+	logrus.Debugf("ipc command: %s %s", req.Command, strings.Join(req.Args, " "))
+	return handler(req)
+}
+
+func (s *Server) help(args []string) (string, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	commands := make([]string, 0, len(s.handlers))
+	for k := range s.handlers {
+		commands = append(commands, k)
+	}
+	sort.Strings(commands)
+	return "commands: " + strings.Join(commands, ", "), nil
+}
+
+func isClosedErr(err error) bool {
+	return strings.Contains(err.Error(), "use of closed network connection")
+}
@@ -0,0 +1,137 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ipc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"tryffel.net/go/jellycli/models"
+	"tryffel.net/go/jellycli/util"
+)
+
+// NowPlaying is machine-readable status of player, as printed by 'status json'.
+type NowPlaying struct {
+	// State is one of stopped, playing, paused or buffering.
+	State   string   `json:"state"`
+	Id      string   `json:"id,omitempty"`
+	Title   string   `json:"title,omitempty"`
+	Artists []string `json:"artists,omitempty"`
+	Album   string   `json:"album,omitempty"`
+	Genre   string   `json:"genre,omitempty"`
+	Track   int      `json:"track,omitempty"`
+	Disc    int      `json:"disc,omitempty"`
+	// ArtUrl is file url of cached album art, or remote url if art is not cached yet
+	ArtUrl   string `json:"art_url,omitempty"`
+	Position int    `json:"position"`
+	// PositionMs is position in milliseconds, for progress bars that update more often than once a second
+	PositionMs int  `json:"position_ms"`
+	Duration   int  `json:"duration"`
+	Volume     int  `json:"volume"`
+	Muted      bool `json:"muted"`
+	Shuffle    bool `json:"shuffle"`
+	Favorite   bool `json:"favorite"`
+}
+
+func newNowPlaying(status models.AudioStatus) *NowPlaying {
+	n := &NowPlaying{
+		State:   "stopped",
+		Volume:  int(status.Volume),
+		Muted:   status.Muted,
+		Shuffle: status.Shuffle,
+	}
+	if status.State == models.AudioStatePlaying {
+		n.State = "playing"
+		if status.Paused {
+			n.State = "paused"
+		} else if status.Buffering {
+			n.State = "buffering"
+		}
+	} else if status.Action == models.AudioActionSeek && status.Song != nil {
+		// song is restarting from new position
+		n.State = "buffering"
+	}
+	if song := status.Song; song != nil {
+		n.Id = song.Id.String()
+		n.Title = song.Name
+		n.Album = song.AlbumName
+		n.Genre = song.Genre
+		n.Track = song.Index
+		n.Disc = song.DiscNumber
+		n.ArtUrl = status.AlbumImageUrl
+		n.Duration = song.Duration
+		n.Position = status.SongPast.Seconds()
+		n.PositionMs = status.SongPast.MilliSeconds()
+		n.Favorite = song.Favorite
+		for _, v := range song.Artists {
+			n.Artists = append(n.Artists, v.Name)
+		}
+	}
+	return n
+}
+
+// plain returns single line status, empty if nothing is playing.
+func (n *NowPlaying) plain() string {
+	if n.Title == "" {
+		return ""
+	}
+	text := n.Title
+	if len(n.Artists) > 0 {
+		text = strings.Join(n.Artists, ", ") + " - " + n.Title
+	}
+	if n.State != "playing" {
+		text = fmt.Sprintf("[%s] %s", n.State, text)
+	}
+	return fmt.Sprintf("%s %s/%s", text, util.SecToString(n.Position), util.SecToString(n.Duration))
+}
+
+// waybar returns status in waybar custom module format. Class is player state.
+func (n *NowPlaying) waybar() (string, error) {
+	out := struct {
+		Text    string `json:"text"`
+		Tooltip string `json:"tooltip"`
+		Alt     string `json:"alt"`
+		Class   string `json:"class"`
+	}{
+		Text:  n.plain(),
+		Alt:   n.State,
+		Class: n.State,
+	}
+	if n.Title != "" {
+		out.Tooltip = fmt.Sprintf("%s\n%s\n%s", n.Title, strings.Join(n.Artists, ", "), n.Album)
+	}
+	data, err := json.Marshal(out)
+	return string(data), err
+}
+
+// formatStatus formats status as json, waybar or plain.
+func formatStatus(status models.AudioStatus, format string) (string, error) {
+	n := newNowPlaying(status)
+	switch format {
+	case "json":
+		data, err := json.Marshal(n)
+		return string(data), err
+	case "waybar":
+		return n.waybar()
+	case "plain":
+		return n.plain(), nil
+	default:
+		return "", fmt.Errorf("unknown status format '%s', expected json, waybar or plain", format)
+	}
+}
@@ -18,6 +18,8 @@
 
 package models
 
+import "time"
+
 // Album has multiple songs. It has one primary artist and multiple additional artists.
 type Album struct {
 	Id       Id     `db:"id"`
@@ -37,6 +39,13 @@ type Album struct {
 	DiscCount int    `db:"disc_count"`
 
 	Favorite bool `db:"favorite"`
+
+	// PremiereDate is release date, zero if unknown. Year is known more often.
+	PremiereDate time.Time `db:"premiere_date"`
+
+	// Overview is description of album, if server has one.
+	Overview string
+	Genres   []string
 }
 
 func (a *Album) GetId() Id {
@@ -74,3 +83,30 @@ func AlbumsToItems(albums []*Album) []Item {
 	}
 	return items
 }
+
+// Disc contains songs of single disc of album.
+type Disc struct {
+	Number int
+	Songs  []*Song
+}
+
+// GroupByDisc groups songs by disc number in order of first appearance, keeping order of songs within
+// each disc. Songs without disc number belong to disc 1.
+func GroupByDisc(songs []*Song) []*Disc {
+	discs := []*Disc{}
+	byNumber := map[int]*Disc{}
+	for _, v := range songs {
+		number := v.DiscNumber
+		if number < 1 {
+			number = 1
+		}
+		disc, ok := byNumber[number]
+		if !ok {
+			disc = &Disc{Number: number}
+			byNumber[number] = disc
+			discs = append(discs, disc)
+		}
+		disc.Songs = append(disc.Songs, v)
+	}
+	return discs
+}
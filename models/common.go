@@ -22,7 +22,32 @@ import (
 	"errors"
 )
 
-// Paging struct and methods removed - UI specific.
+// Paging describes a page of items to request from server. First page is 0.
+type Paging struct {
+	TotalItems  int
+	TotalPages  int
+	CurrentPage int
+	PageSize    int
+}
+
+// DefaultPaging returns first page with default page size.
+func DefaultPaging() Paging {
+	return Paging{PageSize: 100}
+}
+
+// SetTotalItems sets total items and updates total pages accordingly.
+func (p *Paging) SetTotalItems(count int) {
+	p.TotalItems = count
+	p.TotalPages = 0
+	if p.PageSize > 0 {
+		p.TotalPages = (count + p.PageSize - 1) / p.PageSize
+	}
+}
+
+// Offset returns index of first item on current page.
+func (p *Paging) Offset() int {
+	return p.CurrentPage * p.PageSize
+}
 
 type SortMode string
 
@@ -36,8 +61,11 @@ const (
 // ErrInvalidSort occurs if backend does not support given sorting.
 var ErrInvalidSort = errors.New("invalid sort")
 
-// ErrInvalidFilter removed - Filter struct is removed.
-// var ErrInvalidFilter = errors.New("invalid filter") // Keep ErrInvalidSort for now
+// ErrReadOnly occurs if action would modify queue or library while read-only mode is enabled.
+var ErrReadOnly = errors.New("not allowed in read-only mode")
+
+// ErrInvalidFilter occurs if filter has invalid values.
+var ErrInvalidFilter = errors.New("invalid filter")
 
 type SortField string
 
@@ -62,7 +90,27 @@ type Sort struct {
 
 // FilterPlayStatus type and constants removed - UI specific.
 
-// Filter struct and methods removed - UI specific.
+// Filter limits albums and songs that are listed. Zero filter matches all items.
+type Filter struct {
+	// YearFrom and YearTo limit release year, inclusive. Zero means no limit.
+	YearFrom int
+	YearTo   int
+	// Genres matches items that have any of given genres.
+	Genres       []string
+	FavoriteOnly bool
+	UnplayedOnly bool
+}
+
+// Validate returns ErrInvalidFilter if year range is invalid.
+func (f *Filter) Validate() error {
+	if f.YearFrom < 0 || f.YearTo < 0 {
+		return ErrInvalidFilter
+	}
+	if f.YearFrom > 0 && f.YearTo > 0 && f.YearFrom > f.YearTo {
+		return ErrInvalidFilter
+	}
+	return nil
+}
 
 // QueryOpts struct and DefaultQueryOpts func removed - UI specific.
 
@@ -98,6 +146,8 @@ const (
 	AudioActionSetVolume
 
 	AudioActionShuffleChanged
+	// AudioActionQueueChanged means queue was edited
+	AudioActionQueueChanged
 )
 
 // AudioTick is alias for millisecond
@@ -151,10 +201,26 @@ type AudioStatus struct {
 	AlbumImageUrl string
 
 	SongPast AudioTick
-	Volume   AudioVolume
-	Muted    bool
-	Paused   bool
-	Shuffle  bool
+	// Volume is volume level set by user, kept while muted
+	Volume AudioVolume
+	// EffectiveVolume is volume that is applied when not muted, e.g. limited by night mode
+	EffectiveVolume AudioVolume
+	Muted           bool
+	Paused          bool
+	Shuffle         bool
+	// PlaybackRate is playback speed, 1 being normal
+	PlaybackRate float64
+	// NightMode is true when volume is limited by night mode
+	NightMode bool
+	// Bitrate is bitrate of current stream in kbps, 0 if not known
+	Bitrate int
+	// Codec is format of current stream, e.g. flac, and Transcoded is true if it differs from original file
+	Codec      string
+	Transcoded bool
+	// Buffering is true when playback is waiting for data from server
+	Buffering bool
+	// Buffered is seconds of audio downloaded ahead of playback, 0 if not known
+	Buffered int
 }
 
 func (a *AudioStatus) Clear() {
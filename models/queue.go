@@ -18,6 +18,32 @@
 
 package models
 
+import "time"
+
 type Queue struct {
 	Items []Item
 }
+
+// HistoryItem is a song that has been played.
+type HistoryItem struct {
+	Song *Song
+	// PlayedAt is the time song finished playing.
+	PlayedAt time.Time
+	// Session groups songs played in one go. New session starts when application starts or
+	// after playback has been idle long enough.
+	Session int
+}
+
+// PlayRecord is a single play of song, recorded for local statistics.
+type PlayRecord struct {
+	SongId Id     `json:"song_id"`
+	Song   string `json:"song"`
+	Artist string `json:"artist"`
+	Album  string `json:"album"`
+	// PlayedAt is the time song finished or was skipped.
+	PlayedAt time.Time `json:"played_at"`
+	// Listened is listening time in seconds.
+	Listened int `json:"listened"`
+	// Completed is true if song was played to the end.
+	Completed bool `json:"completed"`
+}
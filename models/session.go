@@ -0,0 +1,62 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package models
+
+// Session is a playback device of server that can be controlled remotely, e.g. DLNA renderer or
+// Chromecast.
+type Session struct {
+	Id         string
+	DeviceId   string
+	DeviceName string
+	Client     string
+
+	// Song is currently playing song, nil if nothing is playing.
+	Song     *Song
+	Position AudioTick
+	Paused   bool
+	Muted    bool
+	Volume   AudioVolume
+	// Queue contains songs queued in device, including current song.
+	Queue []Id
+}
+
+// SessionPlay tells how songs are passed to session.
+type SessionPlay string
+
+const (
+	// SessionPlayNow replaces queue of session and starts playing.
+	SessionPlayNow SessionPlay = "PlayNow"
+	// SessionPlayNext adds songs after current song.
+	SessionPlayNext SessionPlay = "PlayNext"
+	// SessionPlayLast adds songs to the end of queue.
+	SessionPlayLast SessionPlay = "PlayLast"
+)
+
+// SessionCommand is transport command for session.
+type SessionCommand string
+
+const (
+	SessionStop      SessionCommand = "Stop"
+	SessionPause     SessionCommand = "Pause"
+	SessionUnpause   SessionCommand = "Unpause"
+	SessionPlayPause SessionCommand = "PlayPause"
+	SessionNext      SessionCommand = "NextTrack"
+	SessionPrevious  SessionCommand = "PreviousTrack"
+	SessionSeek      SessionCommand = "Seek"
+)
@@ -18,6 +18,8 @@
 
 package models
 
+import "time"
+
 // Song always belongs to album (even if single) and has artist.
 // There might be multiple artists.
 type Song struct {
@@ -33,10 +35,44 @@ type Song struct {
 	Artists []IdName
 	// AlbumArtist is primary artist
 	AlbumArtist Id `db:"artist"`
+	// AlbumName and AlbumArtistName are names of album and its primary artist, if known
+	AlbumName       string
+	AlbumArtistName string
 
 	Favorite bool `db:"favorite"`
+
+	// RequestedBy is the user or client that added song to queue remotely, if any.
+	RequestedBy string
+
+	// NormalizationGain is track gain in decibels, if server provides one.
+	NormalizationGain *float64
+
+	// Composer and Genre are comma separated lists of composers and genres, if known.
+	Composer string
+	Genre    string
+	Year     int
+	// Container is file format of original audio, e.g. flac.
+	Container string
+	// Bitrate of original audio in kbps, 0 if unknown.
+	Bitrate int
+
+	// ResumePosition is position in seconds where server remembers item was left, 0 if none.
+	ResumePosition int
+	// LastPlayed is when server last saw song played, zero if not known.
+	LastPlayed time.Time
+
+	Rating Rating
 }
 
+// Rating is user's like or dislike of item.
+type Rating int
+
+const (
+	RatingNone Rating = iota
+	RatingLike
+	RatingDislike
+)
+
 func (s *Song) GetId() Id {
 	return s.Id
 }
@@ -38,6 +38,43 @@ type Stats struct {
 	ServerInfo *ServerInfo
 
 	StorageInfo StorageInfo
+
+	// Connection describes state of connection to server
+	Connection ConnectionStatus
+
+	// Stream describes stream of current song, empty if nothing is playing.
+	Stream StreamInfo
+	// CacheHits and CacheMisses are number of songs played from offline copies and from server
+	// during this session.
+	CacheHits   int
+	CacheMisses int
+	// Downloaded is bytes downloaded from server during this session.
+	Downloaded int
+}
+
+// StreamInfo describes stream of current song.
+type StreamInfo struct {
+	// Codec is format of stream, e.g. flac.
+	Codec string
+	// Bitrate is bitrate of stream in kbps, 0 if not known.
+	Bitrate int
+	// Transcoded is true if stream is in different format than original file.
+	Transcoded bool
+	// Buffered is seconds of audio downloaded ahead of playback.
+	Buffered int
+}
+
+// CacheHitRate returns share of songs played from offline copies in range [0,1].
+func (s *Stats) CacheHitRate() float64 {
+	if s.CacheHits+s.CacheMisses == 0 {
+		return 0
+	}
+	return float64(s.CacheHits) / float64(s.CacheHits+s.CacheMisses)
+}
+
+// DownloadedString returns downloaded bytes in human-readable format
+func (s *Stats) DownloadedString() string {
+	return byteToString(s.Downloaded)
 }
 
 // HeapString returns heap usage in human-readable format
@@ -66,6 +103,41 @@ type ServerInfo struct {
 	Misc map[string]string
 }
 
+// ConnectionState is state of connection to server.
+type ConnectionState int
+
+const (
+	ConnectionOk ConnectionState = iota
+	// ConnectionLost means server is not reachable
+	ConnectionLost
+	// ConnectionReconnecting means server is reachable again and session is being restored
+	ConnectionReconnecting
+)
+
+func (c ConnectionState) String() string {
+	switch c {
+	case ConnectionOk:
+		return "connected"
+	case ConnectionLost:
+		return "disconnected"
+	case ConnectionReconnecting:
+		return "reconnecting"
+	default:
+		return "unknown"
+	}
+}
+
+// ConnectionStatus describes connection to server.
+type ConnectionStatus struct {
+	State ConnectionState
+	// Since is when state last changed
+	Since time.Time
+	// Error is last connection error, if disconnected.
+	Error string
+	// PendingReports is number of playback reports waiting for connection.
+	PendingReports int
+}
+
 type StorageInfo struct {
 	DbSize      int
 	DbFile      string
@@ -95,8 +167,5 @@ func byteToString(bytes int) string {
 	if bytes < 1024*1024*1024 {
 		return fmt.Sprintf("%.2f MiB", f/1024/1024)
 	}
-	if bytes < 1024*1024*1024 {
-		return fmt.Sprintf("%.2f GiB", f/1024/1024/1024)
-	}
-	return ""
+	return fmt.Sprintf("%.2f GiB", f/1024/1024/1024)
 }
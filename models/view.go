@@ -0,0 +1,36 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package models
+
+// View is a library in server, e.g. music library.
+type View struct {
+	Id   Id
+	Name string
+	// Selected is true if view is used for browsing and searching
+	Selected bool
+}
+
+// FolderItem is a child of library folder.
+type FolderItem struct {
+	Id   Id
+	Name string
+	// Type is server's item type, e.g. folder, album or song.
+	Type     string
+	IsFolder bool
+}
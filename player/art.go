@@ -0,0 +1,120 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/api"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+)
+
+const artDir = "art"
+
+// artCache keeps album art in local cache directory, so that art can be shown by players that only
+// read local files. Art that is not cached yet is downloaded in background.
+type artCache struct {
+	lock    sync.Mutex
+	linker  api.ImageLinker
+	client  *http.Client
+	dir     string
+	pending map[string]bool
+}
+
+func newArtCache(linker api.ImageLinker) *artCache {
+	return &artCache{
+		linker:  linker,
+		client:  &http.Client{Timeout: config.ArtDownloadTimeout},
+		dir:     filepath.Join(config.AppConfig.Player.LocalCacheDir, artDir),
+		pending: map[string]bool{},
+	}
+}
+
+// url returns file url of cached art of album, or its remote url if it's not cached yet. Tag identifies
+// image version, cached file is replaced when it changes.
+func (a *artCache) url(album models.Id, tag string) string {
+	if album == "" {
+		return ""
+	}
+	name := album.String()
+	if tag != "" {
+		name += "-" + tag
+	}
+	file := filepath.Join(a.dir, name+".jpg")
+	if _, err := os.Stat(file); err == nil {
+		return "file://" + filepath.ToSlash(file)
+	}
+	remote := a.linker.GetImageUrl(album, tag)
+	a.lock.Lock()
+	if !a.pending[file] {
+		a.pending[file] = true
+		go a.download(remote, file)
+	}
+	a.lock.Unlock()
+	return remote
+}
+
+func (a *artCache) download(url, file string) {
+	defer func() {
+		a.lock.Lock()
+		delete(a.pending, file)
+		a.lock.Unlock()
+	}()
+	err := a.save(url, file)
+	if err != nil {
+		logrus.Debugf("cache album art: %v", err)
+	}
+}
+
+func (a *artCache) save(url, file string) error {
+	resp, err := a.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("get %s: %s", url, resp.Status)
+	}
+	err = os.MkdirAll(a.dir, 0760)
+	if err != nil {
+		return err
+	}
+	part := file + ".part"
+	fd, err := os.Create(part)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fd, resp.Body)
+	closeErr := fd.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(part)
+		return err
+	}
+	return os.Rename(part, file)
+}
@@ -19,6 +19,7 @@
 package player
 
 import (
+	"bufio"
 	"fmt"
 	"github.com/faiface/beep"
 	"github.com/faiface/beep/effects"
@@ -29,6 +30,8 @@ import (
 	"github.com/faiface/beep/wav"
 	"github.com/sirupsen/logrus"
 	"io"
+	"strings"
+	"sync"
 	"time"
 	"tryffel.net/go/jellycli/config"
 	"tryffel.net/go/jellycli/interfaces" // Added interfaces import
@@ -37,25 +40,71 @@ import (
 
 // songMetadata struct moved to player/player.go
 
+// bitrateReader is a stream that knows its bitrate.
+type bitrateReader interface {
+	Bitrate() int
+}
+
+// bufferedReader is a stream that downloads audio ahead of playback.
+type bufferedReader interface {
+	SecondsBuffered() int
+	Buffering() bool
+}
+
 // Audio manages playing song and implements interfaces.Player
 type Audio struct {
 	status models.AudioStatus // Updated to models.AudioStatus
 
 	// todo: we need multiple streamers to allow seamlessly running next song
 	streamer beep.StreamSeekCloser
+	// streamerRate is sample rate of streamer, which may differ from output rate
+	streamerRate beep.SampleRate
+	// clock tracks position in current song
+	clock *playbackClock
+	// resampler converts streamer to output rate and applies playback rate
+	resampler *beep.Resampler
+	// playbackRate is speed of playback, 1 being normal speed
+	playbackRate float64
 
 	// ctrl allows pause
 	ctrl *beep.Ctrl
 	// volume
-	volume *effects.Volume
+	volume *volumeController
 	// mixer allows adding multiple streams sequentially
 	mixer *beep.Mixer
 
+	// preview channel, mixed on top of main channel and not affected by pause
+	preview         *effects.Volume
+	previewMixer    *beep.Mixer
+	previewStreamer beep.StreamSeekCloser
+
+	// output contains both main and preview channels, it is fed to backend through volume and eq
+	output *beep.Mixer
+	// backend plays output
+	backend Output
+	// counter counts samples pulled by backend
+	counter *countingStreamer
+	// watch detects lost output, accessed only from player loop
+	watch outputWatch
+
 	songCompleteFunc func()
 
-	statusCallbacks []func(status models.AudioStatus) // Updated to models.AudioStatus
+	statusCallbacks statusCallbacks
 
 	currentSampleRate int
+
+	gains *gainCache
+
+	// night mode, nil if disabled
+	night *nightMode
+	// eq is loudness compensation of night mode
+	eq *loudnessEq
+
+	// bufferLock guards buffer and lastStatus, which are accessed without speaker lock,
+	// since speaker holds its lock while waiting for data from buffer.
+	bufferLock sync.Mutex
+	buffer     bufferedReader
+	lastStatus models.AudioStatus
 }
 
 // initialize new player. This also initializes faiface.Speaker, which should be initialized only once.
@@ -65,31 +114,44 @@ func newAudio() *Audio {
 			Streamer: nil,
 			Paused:   false,
 		},
-		volume: &effects.Volume{
+		mixer: &beep.Mixer{},
+		preview: &effects.Volume{
 			Streamer: nil,
 			Base:     config.AudioVolumeLogBase,
-			Volume:   config.AudioMaxVolumedB,
+			Volume:   config.AudioPreviewVolumedB,
 			Silent:   false,
 		},
-		mixer:           &beep.Mixer{},
-		statusCallbacks: make([]func(status models.AudioStatus), 0), // Updated to models.AudioStatus
+		previewMixer: &beep.Mixer{},
+		output:       &beep.Mixer{},
 	}
 	a.ctrl.Streamer = a.mixer
 	a.ctrl.Paused = false
-	a.volume.Streamer = a.ctrl
-	a.volume.Silent = false
-	a.status.Volume = 100 // Assuming models.AudioVolume is compatible
-
-	a.currentSampleRate = config.AudioSamplingRate
+	a.preview.Streamer = a.previewMixer
+	a.output.Add(a.ctrl, a.preview)
+	a.volume = newVolumeController(a.output)
+	a.eq = newLoudnessEq(a.volume)
+	a.updateVolumeStatus()
+	a.playbackRate = 1
+	a.status.PlaybackRate = 1
+
+	a.currentSampleRate = config.AppConfig.Player.OutputSampleRate
+	a.gains = newGainCache(config.AppConfig.Player.LocalCacheDir)
+	a.night = newNightMode(&config.AppConfig.Player)
 	return a
 }
 
-func initAudio() error {
-	err := speaker.Init(config.AudioSamplingRate, config.AudioSamplingRate/1000*
-		int(config.AudioBufferPeriod.Milliseconds()))
+// initOutput initializes output backend with given sample rate and starts feeding output to it.
+func (a *Audio) initOutput(sampleRate beep.SampleRate) error {
+	err := a.backend.Init(sampleRate)
 	if err != nil {
-		return fmt.Errorf("init speaker: %v", err)
+		return err
 	}
+	speaker.Lock()
+	a.eq.setSampleRate(sampleRate)
+	speaker.Unlock()
+	a.counter = &countingStreamer{Streamer: a.eq}
+	a.watch = outputWatch{}
+	a.backend.Play(a.counter)
 	return nil
 }
 
@@ -166,17 +228,27 @@ func (a *Audio) Continue() {
 // StopMedia stops music. If there is no audio to play, do nothing.
 func (a *Audio) StopMedia() {
 	logrus.Infof("Stop audio")
+	a.stop(models.AudioActionStop)
+}
+
+// stopForSeek stops audio so that song can be restarted from new position. Status has seek action,
+// so that listeners can tell it from stopping playback.
+func (a *Audio) stopForSeek() {
+	a.stop(models.AudioActionSeek)
+}
+
+func (a *Audio) stop(action models.AudioAction) {
 	speaker.Lock()
 	a.status.State = models.AudioStateStopped // Updated to models.AudioState
-	a.status.Action = models.AudioActionStop // Updated to models.AudioAction
+	a.status.Action = action
 	a.ctrl.Paused = false
 	a.status.Paused = false
-	speaker.Unlock()
-	speaker.Clear()
-
-	speaker.Lock()
+	a.mixer.Clear()
 	err := a.closeOldStream()
 	speaker.Unlock()
+	a.bufferLock.Lock()
+	a.buffer = nil
+	a.bufferLock.Unlock()
 	if err != nil {
 		logrus.Errorf("stop: %v", err)
 	}
@@ -201,54 +273,64 @@ func (a *Audio) Previous() {
 	go a.flushStatus()
 }
 
-// Seek seeks given ticks. If there is no audio, do nothing.
-// TODO: Implement Seek functionality using streamer.Seek()
-func (a *Audio) Seek(ticks models.AudioTick) { // Updated parameter type
-	logrus.Warnf("Seek functionality not yet implemented (seek %d ms)", ticks.MilliSeconds())
-	// Example (needs proper calculation and locking):
-	// speaker.Lock()
-	// if a.streamer != nil {
-	// 	 newPos := a.streamer.Position() + a.currentSampleRate.N(ticks * time.Millisecond)
-	//   if newPos < a.streamer.Len() && newPos >= 0 {
-	//	    a.streamer.Seek(newPos)
-	//   }
-	// }
-	// a.status.Action = models.AudioActionSeek // Updated to models.AudioAction
-	// speaker.Unlock()
-	// go a.flushStatus()
-}
-
 // AddStatusCallback adds a callback that gets called every time audio status is changed, or after certain time.
-func (a *Audio) AddStatusCallback(cb func(status models.AudioStatus)) { // Updated parameter type
-	a.statusCallbacks = append(a.statusCallbacks, cb)
+// Returned function removes callback.
+func (a *Audio) AddStatusCallback(cb func(status models.AudioStatus)) func() {
+	return a.statusCallbacks.add(cb)
 }
 
 // SetVolume sets volume to given level.
 func (a *Audio) SetVolume(volume models.AudioVolume) { // Updated parameter type
-	decibels := float64(volumeTodB(int(volume)))
-	logrus.Debugf("Set volume to %d %s -> %.2f Db", volume, "%", decibels)
 	speaker.Lock()
+	a.volume.setLevel(volume)
+	a.updateVolumeStatus()
+	a.status.Action = models.AudioActionSetVolume // Updated to models.AudioAction
+	speaker.Unlock()
+	go a.flushStatus()
+}
 
-	// settings volume to 0 does not mute audio, set silent to true
-	if decibels <= config.AudioMinVolumedB {
-		a.volume.Silent = true
-		a.volume.Volume = config.AudioMinVolumedB
-		a.status.Volume = models.AudioVolumeMin // Updated const
-	} else if decibels >= config.AudioMaxVolumedB {
-		a.volume.Volume = config.AudioMaxVolumedB
-		a.volume.Silent = false
-		a.status.Volume = models.AudioVolumeMax // Updated const
-	} else {
-		a.volume.Silent = false
-		a.volume.Volume = decibels
-		a.status.Volume = volume
+// ChangeVolume changes volume by steps of config.VolumeStepSize.
+func (a *Audio) ChangeVolume(steps int) {
+	speaker.Lock()
+	a.volume.setLevel(a.volume.level.Add(steps * config.VolumeStepSize()))
+	a.updateVolumeStatus()
+	a.status.Action = models.AudioActionSetVolume
+	speaker.Unlock()
+	go a.flushStatus()
+}
+
+// SetPlaybackRate sets playback speed, 1 being normal speed. Rate is limited to
+// [config.AudioMinPlaybackRate, config.AudioMaxPlaybackRate]. Pitch changes with speed.
+func (a *Audio) SetPlaybackRate(rate float64) {
+	if rate < config.AudioMinPlaybackRate {
+		rate = config.AudioMinPlaybackRate
+	} else if rate > config.AudioMaxPlaybackRate {
+		rate = config.AudioMaxPlaybackRate
+	}
+	logrus.Infof("Set playback rate to %.2f", rate)
+	speaker.Lock()
+	a.playbackRate = rate
+	a.status.PlaybackRate = rate
+	if a.resampler != nil {
+		a.resampler.SetRatio(resampleRatio(a.streamerRate, a.currentSampleRate, rate))
 	}
-	a.status.Action = models.AudioActionSetVolume // Updated to models.AudioAction
 	speaker.Unlock()
 	go a.flushStatus()
 }
 
-// SetMute mutes and un-mutes audio
+// resampleRatio returns ratio for resampling stream to output sample rate at given playback rate.
+func resampleRatio(streamRate beep.SampleRate, outputRate int, playbackRate float64) float64 {
+	return float64(streamRate) / float64(outputRate) * playbackRate
+}
+
+// updateVolumeStatus copies volume to status. Caller must hold speaker lock.
+func (a *Audio) updateVolumeStatus() {
+	a.status.Volume = a.volume.level
+	a.status.EffectiveVolume = a.volume.effective()
+	a.status.Muted = a.volume.muted
+}
+
+// SetMute mutes and un-mutes audio. Volume level is kept while muted.
 func (a *Audio) SetMute(muted bool) {
 
 	if muted {
@@ -257,14 +339,9 @@ func (a *Audio) SetMute(muted bool) {
 		logrus.Info("Unmute audio")
 	}
 	speaker.Lock()
-	if a.ctrl == nil {
-		speaker.Unlock()
-		return
-	}
-	// Don't pause when muting/unmuting
-	// a.ctrl.Paused = false
-	a.volume.Silent = muted
-	a.status.Muted = muted
+	a.volume.setMuted(muted)
+	a.updateVolumeStatus()
+	a.status.Action = models.AudioActionSetVolume
 	speaker.Unlock()
 	go a.flushStatus()
 }
@@ -272,7 +349,7 @@ func (a *Audio) SetMute(muted bool) {
 func (a *Audio) ToggleMute() {
 	logrus.Info("Toggle mute")
 	speaker.Lock()
-	muted := a.status.Muted
+	muted := a.volume.muted
 	speaker.Unlock()
 	a.SetMute(!muted)
 }
@@ -318,6 +395,7 @@ func (a *Audio) closeOldStream() error {
 			logrus.Debug("closed old streamer")
 		}
 		a.streamer = nil
+		a.clock = nil
 	} else {
 		// This might not be an error if StopMedia was called before completion
 		logrus.Debug("audio stream completed but streamer is already nil")
@@ -325,97 +403,132 @@ func (a *Audio) closeOldStream() error {
 	return err
 }
 
-// gather latest status and flush it to callbacks
+// gather latest status and flush it to callbacks. While buffering, last status is flushed with
+// buffering set, since speaker is blocked until more data arrives.
 func (a *Audio) updateStatus() {
+	buffering, buffered := a.bufferStatus()
+	if buffering {
+		a.bufferLock.Lock()
+		status := a.lastStatus
+		a.bufferLock.Unlock()
+		status.Action = models.AudioActionTimeUpdate
+		status.Buffering = true
+		status.Buffered = 0
+		a.callStatusCallbacks(status)
+		return
+	}
+
 	past := a.getPastTicks()
 	speaker.Lock()
 	a.status.SongPast = past
+	a.status.Buffered = buffered
 	a.status.Action = models.AudioActionTimeUpdate // Updated to models.AudioAction
 	speaker.Unlock()
 	a.flushStatus()
 }
 
+// bufferStatus returns whether current stream is waiting for data and how many seconds
+// are buffered ahead.
+func (a *Audio) bufferStatus() (buffering bool, buffered int) {
+	a.bufferLock.Lock()
+	buffer := a.buffer
+	a.bufferLock.Unlock()
+	if buffer == nil {
+		return false, 0
+	}
+	return buffer.Buffering(), buffer.SecondsBuffered()
+}
+
+// idle returns true if playback is stopped or paused.
+func (a *Audio) idle() bool {
+	a.bufferLock.Lock()
+	defer a.bufferLock.Unlock()
+	return a.lastStatus.State == models.AudioStateStopped || a.lastStatus.Paused
+}
+
+// isBuffering returns true if playback is waiting for data from server.
+func (a *Audio) isBuffering() bool {
+	buffering, _ := a.bufferStatus()
+	return buffering
+}
+
 func (a *Audio) flushStatus() {
 	speaker.Lock()
 	status := a.status
 	speaker.Unlock()
-	for _, v := range a.statusCallbacks {
-		v(status)
-	}
+	a.callStatusCallbacks(status)
+}
+
+func (a *Audio) callStatusCallbacks(status models.AudioStatus) {
+	a.bufferLock.Lock()
+	a.lastStatus = status
+	a.bufferLock.Unlock()
+	a.statusCallbacks.call(status)
 }
 
 // play song from io reader. Only song/album/artist/imageurl are used from status.
 func (a *Audio) playSongFromReader(metadata songMetadata) error {
-	// decode
-	var songFormat beep.Format
-	var streamer beep.StreamSeekCloser
-	var err error
-	switch metadata.format { // Use interfaces.AudioFormat
-	case interfaces.AudioFormatMp3: // Use interfaces const
-		streamer, songFormat, err = mp3.Decode(metadata.reader)
-	case interfaces.AudioFormatFlac: // Use interfaces const
-		streamer, songFormat, err = flac.Decode(metadata.reader)
-	case interfaces.AudioFormatWav: // Use interfaces const
-		streamer, songFormat, err = wav.Decode(metadata.reader)
-	case interfaces.AudioFormatOgg: // Use interfaces const
-		streamer, songFormat, err = vorbis.Decode(metadata.reader)
-	default:
-		// Close the reader if format is unknown
-		if metadata.reader != nil {
-			metadata.reader.Close()
-		}
-		return fmt.Errorf("unknown audio format: %s", metadata.format)
-	}
+	streamer, songFormat, err := decodeAudio(metadata.reader, metadata.format)
 	if err != nil {
-		// Close the reader if decoding failed
-		if metadata.reader != nil {
-			metadata.reader.Close()
-		}
-		return fmt.Errorf("decode audio stream: %v", err)
+		return err
 	}
 
 	logrus.Debugf("Song %s samplerate: %d Hz", metadata.song.Name, songFormat.SampleRate.N(time.Second))
 	sampleRate := songFormat.SampleRate
-	if a.currentSampleRate != sampleRate.N(time.Second) {
-		logrus.Debugf("Set samplerate to %d Hz", sampleRate.N(time.Second))
-		// Re-initialize speaker with the new sample rate
-		// Note: This might cause a small gap or click in audio playback
-		speaker.Clear() // Clear buffer before re-init
-		err = speaker.Init(sampleRate, sampleRate.N(time.Second)/1000*
-			int(config.AudioBufferPeriod.Milliseconds()))
+	logrus.Debug("Setting new streamer from ", metadata.format.String())
+
+	skipped := 0
+	if metadata.startAt > 0 {
+		skipped = songFormat.SampleRate.N(time.Duration(metadata.startAt) * time.Millisecond)
+		err = skipSamples(streamer, skipped)
 		if err != nil {
-			logrus.Errorf("Update sample rate (%d -> %d): %v", a.currentSampleRate, sampleRate.N(time.Second), err)
-			// Attempt to continue with old sample rate? Or return error?
-			// For now, log error and continue, but audio might be distorted.
-			// streamer.Close() // Close the successfully decoded streamer
-			// return fmt.Errorf("failed to re-initialize speaker for sample rate %d: %v", sampleRate.N(time.Second), err)
-		} else {
-			a.currentSampleRate = sampleRate.N(time.Second)
+			logrus.Errorf("start song at %d s: %v", metadata.startAt.Seconds(), err)
 		}
 	}
-	logrus.Debug("Setting new streamer from ", metadata.format.String())
-	if streamer == nil {
-		return fmt.Errorf("empty streamer after decode") // Should not happen if err is nil
+	if ms := config.AppConfig.Player.DecodeAheadMs; ms > 0 {
+		chunk := decodeAheadChunk
+		if config.AppConfig.Player.LowMemory {
+			chunk = config.LowMemoryDecodeChunk
+			if ms > config.LowMemoryDecodeAheadMs {
+				ms = config.LowMemoryDecodeAheadMs
+			}
+		}
+		streamer = newDecodeAhead(streamer, sampleRate.N(time.Duration(ms)*time.Millisecond), chunk)
+	}
+	clock := newPlaybackClock(streamer, songFormat.SampleRate, skipped,
+		models.AudioTick(metadata.song.Duration*1000))
+	var source beep.Streamer = clock
+	if config.AppConfig.Player.TrimSilence {
+		source = newSilenceTrimmer(clock, sampleRate, skipped == 0)
 	}
 
 	// streamer variable holds the original StreamSeekCloser (mp3.Decode, etc.)
 	// finalStreamer will hold the stream to be played (potentially resampled)
 	var finalStreamer beep.Streamer = streamer // Start with the original streamer
 
-	// Ensure the streamer is resampled to the speaker's current sample rate if they differ
-	if songFormat.SampleRate != beep.SampleRate(a.currentSampleRate) {
-		logrus.Warnf("Resampling stream from %d Hz to %d Hz", songFormat.SampleRate.N(time.Second), a.currentSampleRate)
-		// Assign the *beep.Resampler (which is a beep.Streamer) to finalStreamer
-		finalStreamer = beep.Resample(4, songFormat.SampleRate, beep.SampleRate(a.currentSampleRate), streamer)
+	// output runs at fixed sample rate, stream is resampled to it
+	if sampleRate != beep.SampleRate(a.currentSampleRate) {
+		logrus.Debugf("Resampling stream from %d Hz to %d Hz", sampleRate.N(time.Second), a.currentSampleRate)
 	}
+	// resampler also applies playback rate, so it's always needed
+	speaker.Lock()
+	rate := a.playbackRate
+	speaker.Unlock()
+	resampler := beep.ResampleRatio(config.AppConfig.Player.ResampleQuality,
+		resampleRatio(sampleRate, a.currentSampleRate, rate), source)
+	finalStreamer = resampler
+
+	finalStreamer = a.normalize(metadata.song, finalStreamer, beep.SampleRate(a.currentSampleRate))
 
 	// Use finalStreamer (which is always a beep.Streamer) for playback sequence
 	stream := beep.Seq(finalStreamer, beep.Callback(a.streamCompleted))
-	speaker.Clear()
 	speaker.Lock()
 	old := a.streamer
 	a.mixer.Clear()
 	a.streamer = streamer // Store the original streamer for seeking? Or resampled? Let's store original for now.
+	a.streamerRate = songFormat.SampleRate
+	a.clock = clock
+	a.resampler = resampler
 	a.mixer.Add(stream)
 	// Start playback unpaused
 	a.ctrl.Paused = false
@@ -434,20 +547,189 @@ func (a *Audio) playSongFromReader(metadata songMetadata) error {
 		}
 	}
 
-	speaker.Play(a.volume)
 	speaker.Lock()
 
 	a.status.Song = metadata.song
 	a.status.Album = metadata.album
 	a.status.Artist = metadata.artist
 	a.status.AlbumImageUrl = metadata.albumImageUrl
+	a.status.Bitrate = 0
+	a.status.Codec = metadata.format.String()
+	a.status.Transcoded = metadata.song.Container != "" && !strings.EqualFold(metadata.song.Container, a.status.Codec)
+	if stream, ok := metadata.reader.(bitrateReader); ok {
+		a.status.Bitrate = stream.Bitrate()
+	}
+	buffer, _ := metadata.reader.(bufferedReader)
+	a.bufferLock.Lock()
+	a.buffer = buffer
+	a.bufferLock.Unlock()
 	a.status.State = models.AudioStatePlaying // Updated to models.AudioState
 	a.status.Action = models.AudioActionPlay // Updated to models.AudioAction
+	// song may start from seek or resume position, which is reported with start
+	a.status.SongPast = clock.position()
+	if metadata.seek {
+		a.status.Action = models.AudioActionSeek
+	}
 	speaker.Unlock()
 	a.flushStatus()
 	return err
 }
 
+// normalize applies track gain to streamer if volume normalization is enabled. Gain is taken from song, or
+// from cache of estimated gains. If song has no gain and estimating is enabled, loudness is measured during
+// playback and stored for the next time song is played.
+func (a *Audio) normalize(song *models.Song, s beep.Streamer, sampleRate beep.SampleRate) beep.Streamer {
+	if !config.AppConfig.Player.NormalizeVolume || song == nil {
+		return s
+	}
+	if song.NormalizationGain != nil {
+		logrus.Debugf("Normalize song %s with gain %.2f dB", song.Name, *song.NormalizationGain)
+		return withGain(s, *song.NormalizationGain)
+	}
+	if gain, ok := a.gains.Get(song.Id); ok {
+		logrus.Debugf("Normalize song %s with estimated gain %.2f dB", song.Name, gain)
+		return withGain(s, gain)
+	}
+	if !config.AppConfig.Player.EstimateMissingGain {
+		return s
+	}
+
+	id := song.Id
+	name := song.Name
+	return newLoudnessMeter(s, sampleRate, func(loudness float64) {
+		gain := loudnessToGain(loudness)
+		logrus.Debugf("Estimated loudness of song %s: %.2f LUFS, gain %.2f dB", name, loudness, gain)
+		// called from speaker, don't block it
+		go a.gains.Set(id, gain)
+	})
+}
+
+// decodeAudio decodes reader with given format. If content of reader is in other format, it is decoded
+// with that format instead. On failure reader is closed.
+func decodeAudio(reader io.ReadCloser, format interfaces.AudioFormat) (streamer beep.StreamSeekCloser,
+	songFormat beep.Format, err error) {
+	if reader != nil {
+		peeked := &peekReader{Reader: bufio.NewReader(reader), Closer: reader}
+		header, _ := peeked.Peek(audioHeaderSize)
+		if sniffed := interfaces.SniffAudioFormat(header); sniffed != interfaces.AudioFormatNil && sniffed != format {
+			logrus.Warningf("audio format is %s, not %s as reported", sniffed, format)
+			format = sniffed
+		}
+		reader = peeked
+	}
+	defer func() {
+		// decoders may panic on malformed data
+		if r := recover(); r != nil {
+			reader.Close()
+			streamer, err = nil, fmt.Errorf("decode %s audio stream: %v", format, r)
+		}
+	}()
+
+	switch format {
+	case interfaces.AudioFormatMp3:
+		streamer, songFormat, err = mp3.Decode(reader)
+	case interfaces.AudioFormatFlac:
+		streamer, songFormat, err = flac.Decode(reader)
+	case interfaces.AudioFormatWav:
+		streamer, songFormat, err = wav.Decode(reader)
+	case interfaces.AudioFormatOgg:
+		streamer, songFormat, err = vorbis.Decode(reader)
+	case interfaces.AudioFormatOpus, interfaces.AudioFormatAac, interfaces.AudioFormatM4a:
+		if reader != nil {
+			reader.Close()
+		}
+		return nil, songFormat, fmt.Errorf("no decoder for %s, server did not transcode it", format)
+	default:
+		if reader != nil {
+			reader.Close()
+		}
+		return nil, songFormat, fmt.Errorf("unknown audio format: %s", format)
+	}
+	if err != nil {
+		if reader != nil {
+			reader.Close()
+		}
+		return nil, songFormat, fmt.Errorf("decode audio stream: %v", err)
+	}
+	if streamer == nil {
+		return nil, songFormat, fmt.Errorf("empty streamer after decode")
+	}
+	return streamer, songFormat, nil
+}
+
+// audioHeaderSize is number of bytes read to detect audio format.
+const audioHeaderSize = 64
+
+// peekReader allows peeking beginning of stream before decoding it.
+type peekReader struct {
+	*bufio.Reader
+	io.Closer
+}
+
+// playPreview plays first config.PreviewDuration of song on preview channel. Any previous preview is replaced.
+// Main channel, queue and status are not touched.
+func (a *Audio) playPreview(metadata songMetadata) error {
+	streamer, songFormat, err := decodeAudio(metadata.reader, metadata.format)
+	if err != nil {
+		return err
+	}
+
+	speaker.Lock()
+	sampleRate := beep.SampleRate(a.currentSampleRate)
+	speaker.Unlock()
+
+	var preview beep.Streamer = streamer
+	if songFormat.SampleRate != sampleRate {
+		preview = beep.Resample(config.AppConfig.Player.ResampleQuality, songFormat.SampleRate, sampleRate, streamer)
+	}
+	preview = beep.Take(sampleRate.N(config.PreviewDuration), preview)
+
+	logrus.Infof("Preview song %s", metadata.song.Name)
+	speaker.Lock()
+	old := a.previewStreamer
+	a.previewMixer.Clear()
+	a.previewStreamer = streamer
+	a.previewMixer.Add(beep.Seq(preview, beep.Callback(a.previewCompleted)))
+	speaker.Unlock()
+
+	if old != nil {
+		err = old.Close()
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("close old preview: %v", err)
+		}
+	}
+	return nil
+}
+
+// StopPreview stops ongoing preview, if any.
+func (a *Audio) StopPreview() {
+	speaker.Lock()
+	old := a.previewStreamer
+	a.previewStreamer = nil
+	a.previewMixer.Clear()
+	speaker.Unlock()
+
+	if old != nil {
+		logrus.Info("Stop preview")
+		err := old.Close()
+		if err != nil && err != io.EOF {
+			logrus.Errorf("close preview: %v", err)
+		}
+	}
+}
+
+func (a *Audio) previewCompleted() {
+	// speaker calls this with lock held
+	logrus.Debug("preview complete")
+	if a.previewStreamer != nil {
+		err := a.previewStreamer.Close()
+		if err != nil && err != io.EOF {
+			logrus.Errorf("close preview: %v", err)
+		}
+		a.previewStreamer = nil
+	}
+}
+
 // linear scaling with a & b coefficients
 var volumeTodBA = float32(config.AudioMaxVolumedB-config.AudioMinVolumedB) /
 	(config.AudioMaxVolume - config.AudioMinVolume)
@@ -462,16 +744,28 @@ func volumeTodB(volume int) float32 {
 func (a *Audio) getPastTicks() models.AudioTick { // Updated return type
 	speaker.Lock()
 	defer speaker.Unlock()
-	if a.streamer == nil || a.currentSampleRate == 0 {
+	if a.streamer == nil || a.clock == nil {
 		return 0
 	}
-	// Use currentSampleRate for position calculation
-	position := a.streamer.Position()
-	if position < 0 { // Position might be -1 if streamer is invalid/closed
-		return 0
+	return a.clock.position()
+}
+
+// skipSamples reads and discards n samples from streamer. Sources are not seekable, so this is the way
+// to start song from given position.
+func skipSamples(s beep.Streamer, n int) error {
+	buf := make([][2]float64, 4096)
+	for n > 0 {
+		size := len(buf)
+		if n < size {
+			size = n
+		}
+		read, ok := s.Stream(buf[:size])
+		n -= read
+		if !ok {
+			return s.Err()
+		}
 	}
-	duration := time.Duration(position) * time.Second / time.Duration(a.currentSampleRate)
-	return models.AudioTick(duration.Milliseconds())
+	return nil
 }
 
 // AudioFormat definitions moved to interfaces/audio_format.go
@@ -0,0 +1,96 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package player
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"testing"
+
+	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/models"
+)
+
+// toneWav returns wav of given sample rate and length with every sample at half of full scale.
+func toneWav(sampleRate, seconds int) []byte {
+	data := wavSong("tone", 0).Data[:44]
+	size := sampleRate * seconds * 4
+	binary.LittleEndian.PutUint32(data[4:], uint32(36+size))
+	binary.LittleEndian.PutUint32(data[24:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(data[28:], uint32(sampleRate*4))
+	binary.LittleEndian.PutUint32(data[40:], uint32(size))
+	samples := make([]byte, size)
+	for i := 0; i < size; i += 2 {
+		binary.LittleEndian.PutUint16(samples[i:], math.MaxInt16/2)
+	}
+	return append(data, samples...)
+}
+
+func TestAudio_PreviewVolume(t *testing.T) {
+	tests := []struct {
+		name   string
+		volume models.AudioVolume
+		muted  bool
+		// rate is sample rate of previewed song
+		rate   int
+		silent bool
+	}{
+		{name: "full volume", volume: models.AudioVolumeMax, rate: testSampleRate},
+		{name: "resampled", volume: models.AudioVolumeMax, rate: testSampleRate / 2},
+		{name: "low volume", volume: 20, rate: testSampleRate},
+		{name: "muted", volume: models.AudioVolumeMax, muted: true, rate: testSampleRate, silent: true},
+		{name: "zero volume", volume: 0, rate: testSampleRate, silent: true},
+	}
+	var full float64
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAudio()
+			a.SetVolume(tt.volume)
+			a.SetMute(tt.muted)
+			err := a.playPreview(songMetadata{
+				song:   &models.Song{Id: "tone", Name: "tone"},
+				reader: ioutil.NopCloser(bytes.NewReader(toneWav(tt.rate, 1))),
+				format: interfaces.AudioFormatWav,
+			})
+			if err != nil {
+				t.Fatalf("play preview: %v", err)
+			}
+			defer a.StopPreview()
+
+			samples := make([][2]float64, 2048)
+			a.eq.Stream(samples)
+			peak := 0.0
+			for _, v := range samples[1024:] {
+				peak = math.Max(peak, math.Abs(v[0]))
+			}
+			switch {
+			case tt.silent && peak != 0:
+				t.Errorf("expected silent preview, got peak %.3f", peak)
+			case !tt.silent && peak == 0:
+				t.Error("expected preview to be audible")
+			case tt.volume == models.AudioVolumeMax && full == 0:
+				full = peak
+			case tt.volume < models.AudioVolumeMax && !tt.silent && peak >= full:
+				t.Errorf("expected preview to follow volume, got peak %.3f at full volume and %.3f at %d",
+					full, peak, tt.volume)
+			}
+		})
+	}
+}
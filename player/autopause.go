@@ -0,0 +1,186 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/models"
+	"tryffel.net/go/jellycli/task"
+)
+
+// AutoPause pauses player when another application starts playing audio and optionally resumes once
+// other audio stops. It follows PulseAudio (or PipeWire with pipewire-pulse) events with pactl.
+type AutoPause struct {
+	task.Task
+	player interfaces.Player
+	resume bool
+
+	lock sync.Mutex
+	// pausedByUs is true if player was paused by auto pause and not by user
+	pausedByUs bool
+	status     models.AudioStatus
+}
+
+// NewAutoPause creates new auto pause for player. If resume, playback continues after other audio stops.
+func NewAutoPause(player interfaces.Player, resume bool) *AutoPause {
+	a := &AutoPause{
+		player: player,
+		resume: resume,
+	}
+	a.Name = "Auto pause"
+	a.SetLoop(a.loop)
+	player.AddStatusCallback(a.statusChanged)
+	return a
+}
+
+func (a *AutoPause) statusChanged(status models.AudioStatus) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.pausedByUs && !status.Paused {
+		// user continued playback
+		a.pausedByUs = false
+	}
+	a.status = status
+}
+
+func (a *AutoPause) loop() {
+	for {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- a.follow(ctx)
+		}()
+
+		select {
+		case <-a.StopChan():
+			cancel()
+			return
+		case err := <-done:
+			cancel()
+			logrus.Errorf("auto pause: follow audio events: %v", err)
+		}
+
+		// pactl missing or sound server restarted
+		select {
+		case <-a.StopChan():
+			return
+		case <-time.After(time.Second * 30):
+		}
+	}
+}
+
+// follow reads sink input events until pactl exits or context is cancelled.
+func (a *AutoPause) follow(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "pactl", "subscribe")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return fmt.Errorf("run pactl: %v", err)
+	}
+	a.update()
+
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		// e.g. Event 'new' on sink-input #123
+		if strings.Contains(scanner.Text(), "sink-input") {
+			a.update()
+		}
+	}
+	return cmd.Wait()
+}
+
+// update pauses or resumes player depending on other audio.
+func (a *AutoPause) update() {
+	others, err := otherActiveStreams()
+	if err != nil {
+		logrus.Errorf("auto pause: list audio streams: %v", err)
+		return
+	}
+
+	a.lock.Lock()
+	status := a.status
+	pausedByUs := a.pausedByUs
+	a.lock.Unlock()
+	playing := status.State == models.AudioStatePlaying
+
+	if others > 0 && playing && !status.Paused {
+		logrus.Infof("Another application started playing audio, pause")
+		a.player.Pause()
+		a.lock.Lock()
+		a.pausedByUs = true
+		a.lock.Unlock()
+	} else if others == 0 && pausedByUs {
+		a.lock.Lock()
+		a.pausedByUs = false
+		a.lock.Unlock()
+		if a.resume && playing && status.Paused {
+			logrus.Infof("Other audio stopped, continue")
+			a.player.Continue()
+		}
+	}
+}
+
+// otherActiveStreams returns number of playing (not corked) streams from other processes.
+func otherActiveStreams() (int, error) {
+	out, err := exec.Command("pactl", "list", "sink-inputs").Output()
+	if err != nil {
+		return 0, err
+	}
+	pid := strconv.Itoa(os.Getpid())
+	count := 0
+	inStream := false
+	corked := false
+	own := false
+	flush := func() {
+		if inStream && !corked && !own {
+			count++
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "Sink Input #"):
+			flush()
+			inStream, corked, own = true, false, false
+		case strings.HasPrefix(line, "Corked:"):
+			corked = strings.TrimSpace(strings.TrimPrefix(line, "Corked:")) == "yes"
+		case strings.HasPrefix(line, "application.process.id"):
+			own = strings.Contains(line, `"`+pid+`"`)
+		}
+	}
+	flush()
+	return count, nil
+}
@@ -0,0 +1,116 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"math/rand"
+
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/api"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+)
+
+// autoQueueRequester is shown as requester of automatically queued songs.
+const autoQueueRequester = "auto queue"
+
+// autoQueueSeeds is how many recently played songs are considered as seed for similar songs.
+const autoQueueSeeds = 5
+
+// autoQueueExclude is how many recently played songs are not queued again.
+const autoQueueExclude = 100
+
+// fillQueue appends songs similar to current or recently played songs if auto queue is enabled
+// and queue is running out. It returns immediately if queue is being filled already.
+func (p *Player) fillQueue(queue []*models.Song) {
+	if !config.AppConfig.Player.AutoQueue {
+		return
+	}
+	// Queue is filled while last songs are playing. Empty queue means playback was stopped on purpose.
+	if len(queue) == 0 || len(queue)-1 >= config.AppConfig.Player.AutoQueueMin {
+		return
+	}
+	library, ok := p.api.(api.Library)
+	if !ok {
+		return
+	}
+
+	p.lock.Lock()
+	if p.fillingQueue {
+		p.lock.Unlock()
+		return
+	}
+	p.fillingQueue = true
+	p.lock.Unlock()
+
+	go func() {
+		defer func() {
+			p.lock.Lock()
+			p.fillingQueue = false
+			p.lock.Unlock()
+		}()
+		songs, err := p.similarSongs(library, queue)
+		if err != nil {
+			logrus.Errorf("auto queue: %v", err)
+			return
+		}
+		if len(songs) == 0 {
+			logrus.Info("auto queue: no similar songs found")
+			return
+		}
+		logrus.Infof("auto queue: add %d songs", len(songs))
+		p.Queue.AddSongs(songs)
+	}()
+}
+
+// similarSongs returns songs similar to a random song among current and recently played songs.
+// Songs in queue or played recently are skipped.
+func (p *Player) similarSongs(library api.Library, queue []*models.Song) ([]*models.Song, error) {
+	history := p.Queue.GetHistory(autoQueueExclude)
+	seeds := []*models.Song{queue[0]}
+	for i := 0; i < len(history) && len(seeds) < autoQueueSeeds; i++ {
+		seeds = append(seeds, history[i])
+	}
+	seed := seeds[rand.Intn(len(seeds))]
+
+	mix, err := library.GetInstantMix(seed.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	skip := map[models.Id]bool{}
+	for _, v := range queue {
+		skip[v.Id] = true
+	}
+	for _, v := range history {
+		skip[v.Id] = true
+	}
+	songs := []*models.Song{}
+	for _, v := range mix {
+		if skip[v.Id] {
+			continue
+		}
+		v.RequestedBy = autoQueueRequester
+		songs = append(songs, v)
+		if len(songs) >= config.AutoQueueSongs {
+			break
+		}
+	}
+	return songs, nil
+}
@@ -0,0 +1,71 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package player
+
+import (
+	"sync"
+
+	"tryffel.net/go/jellycli/models"
+)
+
+// statusCallbacks is a set of status callbacks. Callbacks can be added and removed from any goroutine,
+// also while status is being delivered. Callbacks are called in order they were added.
+type statusCallbacks struct {
+	lock      sync.Mutex
+	nextId    int
+	callbacks []statusCallback
+}
+
+type statusCallback struct {
+	id int
+	cb func(status models.AudioStatus)
+}
+
+// add adds callback and returns function that removes it.
+func (s *statusCallbacks) add(cb func(status models.AudioStatus)) func() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	id := s.nextId
+	s.nextId++
+	s.callbacks = append(s.callbacks, statusCallback{id: id, cb: cb})
+	return func() { s.remove(id) }
+}
+
+func (s *statusCallbacks) remove(id int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i, v := range s.callbacks {
+		if v.id == id {
+			// copy, so that slice being iterated in call is not modified
+			callbacks := make([]statusCallback, 0, len(s.callbacks)-1)
+			callbacks = append(callbacks, s.callbacks[:i]...)
+			s.callbacks = append(callbacks, s.callbacks[i+1:]...)
+			return
+		}
+	}
+}
+
+// call calls callbacks with status. Callbacks are called without holding lock.
+func (s *statusCallbacks) call(status models.AudioStatus) {
+	s.lock.Lock()
+	callbacks := s.callbacks
+	s.lock.Unlock()
+	for _, v := range callbacks {
+		v.cb(status)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package player
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"tryffel.net/go/jellycli/models"
+)
+
+func TestStatusCallbacks_Order(t *testing.T) {
+	callbacks := statusCallbacks{}
+	called := []int{}
+	for i := 0; i < 3; i++ {
+		i := i
+		remove := callbacks.add(func(status models.AudioStatus) { called = append(called, i) })
+		if i == 1 {
+			remove()
+		}
+	}
+	callbacks.call(models.AudioStatus{})
+	if len(called) != 2 || called[0] != 0 || called[1] != 2 {
+		t.Errorf("expected callbacks 0 and 2 to be called in order, got %v", called)
+	}
+}
+
+// TestStatusCallbacks_Concurrent adds and removes callbacks while status is delivered. Run with -race.
+func TestStatusCallbacks_Concurrent(t *testing.T) {
+	callbacks := statusCallbacks{}
+	var calls int32
+	// permanent callback must see every status
+	callbacks.add(func(status models.AudioStatus) { atomic.AddInt32(&calls, 1) })
+
+	// callback that removes itself and adds another one while being called
+	var remove func()
+	lock := sync.Mutex{}
+	lock.Lock()
+	remove = callbacks.add(func(status models.AudioStatus) {
+		lock.Lock()
+		defer lock.Unlock()
+		if remove != nil {
+			remove()
+			remove = nil
+			callbacks.add(func(status models.AudioStatus) {})
+		}
+	})
+	lock.Unlock()
+
+	const workers = 8
+	const rounds = 200
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				remove := callbacks.add(func(status models.AudioStatus) {})
+				remove()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				callbacks.call(models.AudioStatus{State: models.AudioStatePlaying})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&calls); n != workers*rounds {
+		t.Errorf("expected %d calls, got %d", workers*rounds, n)
+	}
+	callbacks.lock.Lock()
+	defer callbacks.lock.Unlock()
+	// permanent callback and the one added by self-removing callback
+	if len(callbacks.callbacks) != 2 {
+		t.Errorf("expected 2 callbacks left, got %d", len(callbacks.callbacks))
+	}
+}
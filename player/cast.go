@@ -0,0 +1,565 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/api"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+	"tryffel.net/go/jellycli/task"
+)
+
+// castSession is history session of songs played on cast device.
+const castSession = 1
+
+// FindCastDevice returns session that matches device by name, device id or session id.
+func FindCastDevice(server api.SessionController, device string) (*models.Session, error) {
+	sessions, err := server.GetSessions()
+	if err != nil {
+		return nil, fmt.Errorf("get devices: %v", err)
+	}
+	for _, v := range sessions {
+		if strings.EqualFold(v.DeviceName, device) || v.DeviceId == device || v.Id == device {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("device '%s' not found, list devices with 'jellycli list-cast-devices'", device)
+}
+
+// CastPlayer plays songs on another device, e.g. DLNA renderer or Chromecast, through server instead of
+// local output. Device keeps the queue and CastPlayer only forwards transport controls and queue changes
+// to it, polling device status once a second. CastPlayer implements interfaces.Player and
+// interfaces.QueueController, so it can replace Player.
+type CastPlayer struct {
+	task.Task
+	server  api.SessionController
+	library api.Library
+	editor  api.PlaylistEditor
+
+	// refreshLock serializes polling device
+	refreshLock sync.Mutex
+
+	lock     sync.RWMutex
+	device   models.Session
+	status   models.AudioStatus
+	queueIds []models.Id
+	// queue starts from current song
+	queue   []*models.Song
+	history []*models.HistoryItem
+	// songs are known songs by id
+	songs map[models.Id]*models.Song
+	lost  bool
+
+	statusCallbacks statusCallbacks
+	queueCallbacks  []func([]*models.Song)
+	historyCallback func([]*models.Song)
+}
+
+// NewCastPlayer creates player that controls device. Device is matched with FindCastDevice.
+func NewCastPlayer(server api.SessionController, device string) (*CastPlayer, error) {
+	session, err := FindCastDevice(server, device)
+	if err != nil {
+		return nil, err
+	}
+	c := &CastPlayer{
+		server: server,
+		device: *session,
+		songs:  map[models.Id]*models.Song{},
+	}
+	c.library, _ = server.(api.Library)
+	c.editor, _ = server.(api.PlaylistEditor)
+	c.status.PlaybackRate = 1
+	c.Name = "cast"
+	c.SetLoop(c.loop)
+	logrus.Infof("Casting to %s (%s)", session.DeviceName, session.Client)
+	return c, nil
+}
+
+func (c *CastPlayer) loop() {
+	ticker := time.NewTicker(time.Second)
+	c.refresh()
+	for {
+		select {
+		case <-c.StopChan():
+			ticker.Stop()
+			return
+		case <-ticker.C:
+			c.refresh()
+		}
+	}
+}
+
+// refresh polls device and updates status, queue and history.
+func (c *CastPlayer) refresh() {
+	c.refreshLock.Lock()
+	defer c.refreshLock.Unlock()
+
+	sessions, err := c.server.GetSessions()
+	if err != nil {
+		logrus.Errorf("get cast device status: %v", err)
+		return
+	}
+	c.lock.RLock()
+	deviceId := c.device.DeviceId
+	c.lock.RUnlock()
+	var session *models.Session
+	for _, v := range sessions {
+		// session id changes if device reconnects to server
+		if v.DeviceId == deviceId {
+			session = v
+			break
+		}
+	}
+
+	c.lock.Lock()
+	if session == nil {
+		if !c.lost {
+			logrus.Warningf("Cast device %s disconnected", c.device.DeviceName)
+		}
+		c.lost = true
+		session = &models.Session{Id: c.device.Id, DeviceId: deviceId, DeviceName: c.device.DeviceName}
+	} else if c.lost {
+		logrus.Infof("Cast device %s connected", session.DeviceName)
+		c.lost = false
+	}
+	c.lock.Unlock()
+
+	ids := session.Queue
+	if session.Song != nil {
+		ids = upcomingIds(session.Queue, session.Song.Id)
+		c.rememberSongs([]*models.Song{session.Song})
+	}
+	queue := c.resolveSongs(ids)
+
+	c.lock.Lock()
+	previous := c.status.Song
+	status := c.status
+	status.Action = models.AudioActionTimeUpdate
+	status.State = models.AudioStateStopped
+	status.Song = nil
+	status.SongPast = 0
+	if session.Song != nil {
+		status.State = models.AudioStatePlaying
+		status.Song = c.songs[session.Song.Id]
+		status.SongPast = session.Position
+	}
+	status.Paused = session.Paused
+	status.Muted = session.Muted
+	status.Volume = session.Volume
+	status.EffectiveVolume = session.Volume
+
+	historyChanged := previous != nil && (status.Song == nil || status.Song.Id != previous.Id)
+	if historyChanged {
+		item := &models.HistoryItem{Song: previous, PlayedAt: time.Now(), Session: castSession}
+		c.history = append([]*models.HistoryItem{item}, c.history...)
+		if limit := config.AppConfig.Player.HistoryLimit; limit > 0 && len(c.history) > limit {
+			c.history = c.history[:limit]
+		}
+	}
+	queueChanged := !sameIds(c.queueIds, ids)
+	c.device = *session
+	c.status = status
+	c.queueIds = ids
+	c.queue = queue
+	history := historySongs(c.history)
+	queueCallbacks := c.queueCallbacks
+	historyCallback := c.historyCallback
+	c.lock.Unlock()
+
+	c.statusCallbacks.call(status)
+	if queueChanged {
+		for _, cb := range queueCallbacks {
+			cb(queue)
+		}
+	}
+	if historyChanged && historyCallback != nil {
+		historyCallback(history)
+	}
+}
+
+// upcomingIds returns ids of queue starting from current song. If current song is not in queue,
+// it is only song.
+func upcomingIds(queue []models.Id, current models.Id) []models.Id {
+	for i, v := range queue {
+		if v == current {
+			return queue[i:]
+		}
+	}
+	return []models.Id{current}
+}
+
+func sameIds(a, b []models.Id) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *CastPlayer) rememberSongs(songs []*models.Song) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, v := range songs {
+		if _, ok := c.songs[v.Id]; !ok || v.Duration > 0 {
+			c.songs[v.Id] = v
+		}
+	}
+}
+
+// resolveSongs returns songs for ids, fetching unknown songs from server.
+func (c *CastPlayer) resolveSongs(ids []models.Id) []*models.Song {
+	c.lock.RLock()
+	missing := []models.Id{}
+	for _, v := range ids {
+		if _, ok := c.songs[v]; !ok {
+			missing = append(missing, v)
+		}
+	}
+	c.lock.RUnlock()
+
+	if len(missing) > 0 && c.library != nil {
+		songs, err := c.library.GetSongsById(missing)
+		if err != nil {
+			logrus.Errorf("get songs of cast queue: %v", err)
+		} else {
+			c.rememberSongs(songs)
+		}
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	songs := make([]*models.Song, len(ids))
+	for i, v := range ids {
+		song, ok := c.songs[v]
+		if !ok {
+			song = &models.Song{Id: v, Name: v.String()}
+		}
+		songs[i] = song
+	}
+	return songs
+}
+
+func (c *CastPlayer) sessionId() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.device.Id
+}
+
+func (c *CastPlayer) command(command models.SessionCommand, position models.AudioTick) {
+	err := c.server.SendSessionCommand(c.sessionId(), command, position)
+	if err != nil {
+		logrus.Errorf("cast %s: %v", command, err)
+		return
+	}
+	c.refresh()
+}
+
+func (c *CastPlayer) play(play models.SessionPlay, songs []*models.Song, startIndex int, position models.AudioTick) {
+	c.rememberSongs(songs)
+	ids := make([]models.Id, len(songs))
+	for i, v := range songs {
+		ids[i] = v.Id
+	}
+	err := c.server.PlayOnSession(c.sessionId(), play, ids, startIndex, position)
+	if err != nil {
+		logrus.Errorf("cast %s: %v", play, err)
+		return
+	}
+	c.refresh()
+}
+
+func (c *CastPlayer) unsupported(action string) {
+	logrus.Warningf("%s is not supported when casting", action)
+}
+
+// PlayPause toggles pause.
+func (c *CastPlayer) PlayPause() {
+	c.command(models.SessionPlayPause, 0)
+}
+
+// Pause pauses device.
+func (c *CastPlayer) Pause() {
+	c.command(models.SessionPause, 0)
+}
+
+// Continue continues paused device.
+func (c *CastPlayer) Continue() {
+	c.command(models.SessionUnpause, 0)
+}
+
+// StopMedia stops device.
+func (c *CastPlayer) StopMedia() {
+	c.command(models.SessionStop, 0)
+}
+
+// Next plays next song in device queue.
+func (c *CastPlayer) Next() {
+	c.command(models.SessionNext, 0)
+}
+
+// Previous plays previous song in device queue.
+func (c *CastPlayer) Previous() {
+	c.command(models.SessionPrevious, 0)
+}
+
+// JumpTo plays song at queue index right away.
+func (c *CastPlayer) JumpTo(index int) bool {
+	queue := c.GetQueue()
+	if index < 1 || index >= len(queue) {
+		return false
+	}
+	c.play(models.SessionPlayNow, queue[index:], 0, 0)
+	return true
+}
+
+// Seek seeks forward given ticks, or backward if ticks is negative.
+func (c *CastPlayer) Seek(ticks models.AudioTick) {
+	c.lock.RLock()
+	position := c.status.SongPast + ticks
+	c.lock.RUnlock()
+	if position < 0 {
+		position = 0
+	}
+	c.command(models.SessionSeek, position)
+}
+
+// SeekRelative seeks given seconds, forward if positive, else backward.
+func (c *CastPlayer) SeekRelative(seconds int) {
+	c.Seek(models.AudioTick(seconds * 1000))
+}
+
+// AddStatusCallback adds callback that is called every time device status is polled. Returned function
+// removes callback.
+func (c *CastPlayer) AddStatusCallback(cb func(status models.AudioStatus)) func() {
+	return c.statusCallbacks.add(cb)
+}
+
+func (c *CastPlayer) setVolume(volume models.AudioVolume, muted bool) {
+	if volume < config.AudioMinVolume {
+		volume = config.AudioMinVolume
+	} else if volume > config.AudioMaxVolume {
+		volume = config.AudioMaxVolume
+	}
+	err := c.server.SetSessionVolume(c.sessionId(), volume, muted)
+	if err != nil {
+		logrus.Errorf("cast set volume: %v", err)
+		return
+	}
+	c.refresh()
+}
+
+// SetVolume sets device volume in range [0,100].
+func (c *CastPlayer) SetVolume(volume models.AudioVolume) {
+	c.lock.RLock()
+	muted := c.status.Muted
+	c.lock.RUnlock()
+	c.setVolume(volume, muted)
+}
+
+// ChangeVolume changes volume by steps of config.VolumeStepSize.
+func (c *CastPlayer) ChangeVolume(steps int) {
+	c.lock.RLock()
+	status := c.status
+	c.lock.RUnlock()
+	c.setVolume(status.Volume+models.AudioVolume(steps*config.VolumeStepSize()), status.Muted)
+}
+
+// SetMute mutes or un-mutes device.
+func (c *CastPlayer) SetMute(muted bool) {
+	c.lock.RLock()
+	volume := c.status.Volume
+	c.lock.RUnlock()
+	c.setVolume(volume, muted)
+}
+
+// ToggleMute toggles mute of device.
+func (c *CastPlayer) ToggleMute() {
+	c.lock.RLock()
+	status := c.status
+	c.lock.RUnlock()
+	c.setVolume(status.Volume, !status.Muted)
+}
+
+func (c *CastPlayer) SetShuffle(enabled bool) {
+	c.unsupported("shuffle")
+}
+
+func (c *CastPlayer) SetPlaybackRate(rate float64) {
+	c.unsupported("playback rate")
+}
+
+func (c *CastPlayer) PreviewSong(song *models.Song) {
+	c.unsupported("preview")
+}
+
+func (c *CastPlayer) StopPreview() {}
+
+// GetQueue returns device queue starting from current song.
+func (c *CastPlayer) GetQueue() []*models.Song {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	songs := make([]*models.Song, len(c.queue))
+	copy(songs, c.queue)
+	return songs
+}
+
+// replaceQueue replaces device queue with songs. If first song is current song, it continues from
+// current position, but device restarts playback.
+func (c *CastPlayer) replaceQueue(songs []*models.Song) {
+	c.lock.RLock()
+	current := c.status.Song
+	position := c.status.SongPast
+	c.lock.RUnlock()
+	if len(songs) == 0 {
+		c.StopMedia()
+		return
+	}
+	if current == nil || songs[0].Id != current.Id {
+		position = 0
+	}
+	c.play(models.SessionPlayNow, songs, 0, position)
+}
+
+// ClearQueue clears device queue. If first is false, current song keeps playing.
+func (c *CastPlayer) ClearQueue(first bool) {
+	queue := c.GetQueue()
+	if first || len(queue) == 0 {
+		c.StopMedia()
+		return
+	}
+	c.replaceQueue(queue[:1])
+}
+
+// AddSongs adds songs to the end of device queue, or starts playing them if device is stopped.
+func (c *CastPlayer) AddSongs(songs []*models.Song) {
+	if len(c.GetQueue()) == 0 {
+		c.play(models.SessionPlayNow, songs, 0, 0)
+	} else {
+		c.play(models.SessionPlayLast, songs, 0, 0)
+	}
+}
+
+// PlayNext adds songs after current song.
+func (c *CastPlayer) PlayNext(songs []*models.Song) {
+	if len(c.GetQueue()) == 0 {
+		c.play(models.SessionPlayNow, songs, 0, 0)
+	} else {
+		c.play(models.SessionPlayNext, songs, 0, 0)
+	}
+}
+
+// Reorder shifts song by one, earlier if down. Current song cannot be moved.
+func (c *CastPlayer) Reorder(index int, down bool) bool {
+	newIndex := index + 1
+	if down {
+		newIndex = index - 1
+	}
+	return c.MoveSong(index, newIndex)
+}
+
+// MoveSong moves song at index to newIndex. Current song cannot be moved, nor can other song be moved
+// before it.
+func (c *CastPlayer) MoveSong(index, newIndex int) bool {
+	queue := c.GetQueue()
+	n := len(queue)
+	if index < 1 || index >= n || newIndex < 1 || newIndex >= n || index == newIndex {
+		return false
+	}
+	song := queue[index]
+	queue = append(queue[:index], queue[index+1:]...)
+	queue = append(queue[:newIndex], append([]*models.Song{song}, queue[newIndex:]...)...)
+	c.replaceQueue(queue)
+	return true
+}
+
+// RemoveSong removes song at index. Current song cannot be removed.
+func (c *CastPlayer) RemoveSong(index int) {
+	queue := c.GetQueue()
+	if index < 1 || index >= len(queue) {
+		return
+	}
+	c.replaceQueue(append(queue[:index], queue[index+1:]...))
+}
+
+// GetHistory returns n songs played on device since start, latest first.
+func (c *CastPlayer) GetHistory(n int) []*models.Song {
+	return historySongs(c.GetHistoryItems(n))
+}
+
+// GetHistoryItems returns n songs played on device since start. If n < 0, return all items.
+func (c *CastPlayer) GetHistoryItems(n int) []*models.HistoryItem {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if n < 0 || n > len(c.history) {
+		n = len(c.history)
+	}
+	items := make([]*models.HistoryItem, n)
+	copy(items, c.history)
+	return items
+}
+
+// AddQueueChangedCallback adds function that is called every time device queue changes.
+func (c *CastPlayer) AddQueueChangedCallback(cb func(content []*models.Song)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.queueCallbacks = append(c.queueCallbacks, cb)
+}
+
+// SetHistoryChangedCallback sets function that is called every time history changes.
+func (c *CastPlayer) SetHistoryChangedCallback(cb func([]*models.Song)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.historyCallback = cb
+}
+
+// SaveQueueAsPlaylist creates new playlist from device queue.
+func (c *CastPlayer) SaveQueueAsPlaylist(name string) (models.Id, error) {
+	if c.editor == nil {
+		return "", errors.New("server does not support playlists")
+	}
+	queue := c.GetQueue()
+	if len(queue) == 0 {
+		return "", errors.New("queue is empty")
+	}
+	ids := make([]models.Id, len(queue))
+	for i, v := range queue {
+		ids[i] = v.Id
+	}
+	return c.editor.CreatePlaylist(name, ids)
+}
+
+// GetShuffleSeed returns 0, since device shuffles its own queue.
+func (c *CastPlayer) GetShuffleSeed() int64 {
+	return 0
+}
+
+func (c *CastPlayer) SetShuffleSeed(seed int64) {
+	c.unsupported("shuffle")
+}
@@ -0,0 +1,83 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package player
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"tryffel.net/go/jellycli/models"
+)
+
+// fakeDevice is cast device that plays song set with play.
+type fakeDevice struct {
+	lock sync.Mutex
+	song *models.Song
+}
+
+func (f *fakeDevice) play(song *models.Song) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.song = song
+}
+
+func (f *fakeDevice) GetSessions() ([]*models.Session, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	session := &models.Session{Id: "session", DeviceId: "device", DeviceName: "device", Song: f.song}
+	if f.song != nil {
+		session.Queue = []models.Id{f.song.Id}
+	}
+	return []*models.Session{session}, nil
+}
+
+func (f *fakeDevice) PlayOnSession(session string, play models.SessionPlay, songs []models.Id, startIndex int,
+	position models.AudioTick) error {
+	return nil
+}
+
+func (f *fakeDevice) SendSessionCommand(session string, command models.SessionCommand, position models.AudioTick) error {
+	return nil
+}
+
+func (f *fakeDevice) SetSessionVolume(session string, volume models.AudioVolume, muted bool) error {
+	return nil
+}
+
+func TestCastPlayer_HistoryLimit(t *testing.T) {
+	device := &fakeDevice{}
+	c, err := NewCastPlayer(device, "device")
+	if err != nil {
+		t.Fatalf("new cast player: %v", err)
+	}
+
+	played := testHistoryLimit * 2
+	for i := 0; i < played; i++ {
+		device.play(&models.Song{Id: models.Id(fmt.Sprintf("song-%d", i)), Duration: 100})
+		c.refresh()
+	}
+	history := c.GetHistoryItems(-1)
+	if len(history) != testHistoryLimit {
+		t.Fatalf("expected %d songs in history, got %d", testHistoryLimit, len(history))
+	}
+	// latest first, current song is not in history yet
+	if want := models.Id(fmt.Sprintf("song-%d", played-2)); history[0].Song.Id != want {
+		t.Errorf("expected latest song %s, got %s", want, history[0].Song.Id)
+	}
+}
@@ -0,0 +1,67 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"sync/atomic"
+
+	"github.com/faiface/beep"
+	"tryffel.net/go/jellycli/models"
+)
+
+// playbackClock counts samples pulled from song stream at the stream's own sample rate. Position is
+// thus independent of output sample rate, resampling, playback rate and re-initializing output.
+type playbackClock struct {
+	beep.Streamer
+	sampleRate beep.SampleRate
+	// offset is number of samples skipped before playback started
+	offset int64
+	played int64
+	// duration of song, 0 if unknown
+	duration models.AudioTick
+}
+
+func newPlaybackClock(s beep.Streamer, sampleRate beep.SampleRate, offset int, duration models.AudioTick) *playbackClock {
+	return &playbackClock{
+		Streamer:   s,
+		sampleRate: sampleRate,
+		offset:     int64(offset),
+		duration:   duration,
+	}
+}
+
+func (c *playbackClock) Stream(samples [][2]float64) (int, bool) {
+	n, ok := c.Streamer.Stream(samples)
+	atomic.AddInt64(&c.played, int64(n))
+	return n, ok
+}
+
+// position returns position in song. Stream may be a bit longer than duration reported by server,
+// position is clamped to duration.
+func (c *playbackClock) position() models.AudioTick {
+	if c.sampleRate <= 0 {
+		return 0
+	}
+	samples := c.offset + atomic.LoadInt64(&c.played)
+	position := models.AudioTick(c.sampleRate.D(int(samples)).Milliseconds())
+	if c.duration > 0 && position > c.duration {
+		return c.duration
+	}
+	return position
+}
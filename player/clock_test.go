@@ -0,0 +1,94 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package player
+
+import (
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+	"tryffel.net/go/jellycli/models"
+)
+
+// stream pulls n samples from streamer.
+func stream(s beep.Streamer, n int) {
+	buf := make([][2]float64, 512)
+	for n > 0 {
+		size := len(buf)
+		if n < size {
+			size = n
+		}
+		s.Stream(buf[:size])
+		n -= size
+	}
+}
+
+func TestPlaybackClock(t *testing.T) {
+	const rate = beep.SampleRate(44100)
+	const duration = models.AudioTick(10000)
+
+	tests := []struct {
+		name string
+		// offset is position song starts from, e.g. after seek
+		offset time.Duration
+		// output is sample rate stream is resampled to, if set
+		output beep.SampleRate
+		paused bool
+		// played is duration of output pulled from stream
+		played   time.Duration
+		position models.AudioTick
+	}{
+		{name: "start", position: 0},
+		{name: "playing", played: time.Second * 2, position: 2000},
+		{name: "resampled", output: 48000, played: time.Second * 2, position: 2000},
+		{name: "paused", paused: true, played: time.Second * 2, position: 0},
+		{name: "seek", offset: time.Second * 5, played: time.Second, position: 6000},
+		{name: "seek paused", offset: time.Second * 5, paused: true, played: time.Second, position: 5000},
+		{name: "end of track", offset: time.Second * 9, played: time.Second * 3, position: duration},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newPlaybackClock(beep.Silence(-1), rate, rate.N(tt.offset), duration)
+			var s beep.Streamer = clock
+			output := rate
+			if tt.output != 0 {
+				s = beep.Resample(1, rate, tt.output, s)
+				output = tt.output
+			}
+			s = &beep.Ctrl{Streamer: s, Paused: tt.paused}
+			stream(s, output.N(tt.played))
+
+			// resampler reads a few samples ahead
+			position := clock.position()
+			if diff := position - tt.position; diff < 0 || diff > 10 {
+				t.Errorf("expected position %d ms, got %d ms", tt.position, position)
+			}
+			if remaining := duration - position; remaining < 0 || remaining > duration-tt.position {
+				t.Errorf("expected remaining %d ms, got %d ms", duration-tt.position, remaining)
+			}
+		})
+	}
+}
+
+func TestPlaybackClock_UnknownRate(t *testing.T) {
+	clock := newPlaybackClock(beep.Silence(-1), 0, 0, 0)
+	stream(clock, 1000)
+	if position := clock.position(); position != 0 {
+		t.Errorf("expected position 0 without sample rate, got %d", position)
+	}
+}
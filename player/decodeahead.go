@@ -0,0 +1,149 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package player
+
+import (
+	"sync"
+
+	"github.com/faiface/beep"
+	"github.com/sirupsen/logrus"
+)
+
+// decodeAheadChunk is default number of samples decoded at once.
+const decodeAheadChunk = 512
+
+// decodeAhead decodes song in background into ring buffer, so that short stalls in decoding do not starve
+// output. Source is owned by decodeAhead and closed by it. Seeking is not supported, song must be skipped
+// to start position before decoding ahead.
+type decodeAhead struct {
+	beep.StreamSeekCloser
+	lock *sync.Mutex
+	cond *sync.Cond
+	ring [][2]float64
+	// start is index of first buffered sample and size number of buffered samples
+	start int
+	size  int
+	// done is true when source is drained
+	done bool
+	// closed is true after Close, running while background decoding is active
+	closed  bool
+	running bool
+}
+
+// newDecodeAhead starts decoding source into buffer of given samples, chunk samples at a time.
+func newDecodeAhead(source beep.StreamSeekCloser, samples int, chunk int) *decodeAhead {
+	d := &decodeAhead{
+		StreamSeekCloser: source,
+		lock:             &sync.Mutex{},
+		ring:             make([][2]float64, samples),
+		running:          true,
+	}
+	d.cond = sync.NewCond(d.lock)
+	go d.decode(chunk)
+	return d
+}
+
+func (d *decodeAhead) decode(chunkSize int) {
+	chunk := make([][2]float64, chunkSize)
+	for {
+		d.lock.Lock()
+		for d.size == len(d.ring) && !d.closed {
+			d.cond.Wait()
+		}
+		if d.closed {
+			d.running = false
+			d.lock.Unlock()
+			d.closeSource()
+			return
+		}
+		free := len(d.ring) - d.size
+		d.lock.Unlock()
+
+		if free > len(chunk) {
+			free = len(chunk)
+		}
+		n, ok := d.StreamSeekCloser.Stream(chunk[:free])
+
+		d.lock.Lock()
+		end := (d.start + d.size) % len(d.ring)
+		copied := copy(d.ring[end:], chunk[:n])
+		copy(d.ring, chunk[copied:n])
+		d.size += n
+		if !ok {
+			d.done = true
+			d.running = false
+		}
+		closed := d.closed
+		d.cond.Broadcast()
+		d.lock.Unlock()
+		if !ok {
+			if closed {
+				d.closeSource()
+			}
+			return
+		}
+	}
+}
+
+// Stream returns decoded samples, waiting for decoder only if buffer has run empty.
+func (d *decodeAhead) Stream(samples [][2]float64) (int, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for d.size == 0 && !d.done && !d.closed {
+		d.cond.Wait()
+	}
+	if d.size == 0 {
+		return 0, false
+	}
+	n := 0
+	for n < len(samples) && d.size > 0 {
+		end := d.start + d.size
+		if end > len(d.ring) {
+			end = len(d.ring)
+		}
+		count := copy(samples[n:], d.ring[d.start:end])
+		n += count
+		d.start = (d.start + count) % len(d.ring)
+		d.size -= count
+	}
+	d.cond.Broadcast()
+	return n, true
+}
+
+// Close stops decoding. Source is closed right away if decoder is idle, else when decoder returns.
+func (d *decodeAhead) Close() error {
+	d.lock.Lock()
+	if d.closed {
+		d.lock.Unlock()
+		return nil
+	}
+	d.closed = true
+	running := d.running
+	d.cond.Broadcast()
+	d.lock.Unlock()
+	if running {
+		return nil
+	}
+	return d.StreamSeekCloser.Close()
+}
+
+func (d *decodeAhead) closeSource() {
+	if err := d.StreamSeekCloser.Close(); err != nil {
+		logrus.Errorf("close decoder: %v", err)
+	}
+}
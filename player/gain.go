@@ -0,0 +1,242 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/effects"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"sync"
+	"time"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+)
+
+// gainCache stores estimated track gains in decibels per song. Gains are persisted to local cache directory
+// so that estimation needs to be done only once per song.
+type gainCache struct {
+	lock   sync.Mutex
+	file   string
+	loaded bool
+	gains  map[models.Id]float64
+}
+
+func newGainCache(dir string) *gainCache {
+	return &gainCache{
+		file:  path.Join(dir, "gain.json"),
+		gains: map[models.Id]float64{},
+	}
+}
+
+// Get returns gain for song, if there is one.
+func (g *gainCache) Get(id models.Id) (float64, bool) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.load()
+	gain, ok := g.gains[id]
+	return gain, ok
+}
+
+// Set sets gain for song and saves cache file.
+func (g *gainCache) Set(id models.Id, gain float64) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.load()
+	g.gains[id] = gain
+	err := g.save()
+	if err != nil {
+		logrus.Errorf("save gain cache: %v", err)
+	}
+}
+
+func (g *gainCache) load() {
+	if g.loaded {
+		return
+	}
+	g.loaded = true
+	data, err := ioutil.ReadFile(g.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logrus.Errorf("read gain cache: %v", err)
+		}
+		return
+	}
+	err = json.Unmarshal(data, &g.gains)
+	if err != nil {
+		logrus.Errorf("parse gain cache '%s': %v", g.file, err)
+	}
+}
+
+func (g *gainCache) save() error {
+	data, err := json.Marshal(g.gains)
+	if err != nil {
+		return fmt.Errorf("encode json: %v", err)
+	}
+	err = os.MkdirAll(path.Dir(g.file), 0760)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(g.file, data, 0660)
+}
+
+// withGain returns streamer amplified by given gain in decibels.
+func withGain(s beep.Streamer, gaindB float64) beep.Streamer {
+	return &effects.Gain{
+		Streamer: s,
+		Gain:     math.Pow(10, gaindB/20) - 1,
+	}
+}
+
+// loudnessToGain returns gain needed to reach normalization target from given loudness.
+func loudnessToGain(loudness float64) float64 {
+	gain := config.AudioNormalizationTarget - loudness
+	return math.Max(-config.AudioMaxGaindB, math.Min(config.AudioMaxGaindB, gain))
+}
+
+// biquad is a second order iir filter.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	z1, z2             [2]float64
+}
+
+func (b *biquad) process(channel int, x float64) float64 {
+	y := b.b0*x + b.z1[channel]
+	b.z1[channel] = b.b1*x - b.a1*y + b.z2[channel]
+	b.z2[channel] = b.b2*x - b.a2*y
+	return y
+}
+
+// loudnessMeter passes audio through unmodified and measures loudness of the first samples. Measurement is
+// a rough approximation of EBU R128 integrated loudness: K-weighted, 400 ms blocks and absolute gate of -70 LUFS,
+// but without relative gating. Once enough samples are analyzed or stream ends, done is called
+// once with loudness in LUFS.
+type loudnessMeter struct {
+	streamer beep.Streamer
+	done     func(loudness float64)
+
+	shelf    biquad
+	highPass biquad
+
+	blockSize   int
+	blockPos    int
+	blockSum    float64
+	gatedSum    float64
+	gatedBlocks int
+
+	remaining int
+	finished  bool
+}
+
+func newLoudnessMeter(s beep.Streamer, sampleRate beep.SampleRate, done func(loudness float64)) *loudnessMeter {
+	fs := float64(sampleRate.N(time.Second))
+	m := &loudnessMeter{
+		streamer:  s,
+		done:      done,
+		blockSize: sampleRate.N(time.Millisecond * 400),
+		remaining: sampleRate.N(config.AudioGainAnalysisDuration),
+	}
+
+	// K-weighting filter coefficients for arbitrary sample rate, see ITU-R BS.1770
+	f0 := 1681.974450955533
+	gain := 3.999843853973347
+	q := 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / fs)
+	vh := math.Pow(10, gain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	m.shelf = biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	f0 = 38.13547087602444
+	q = 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / fs)
+	a0 = 1 + k/q + k*k
+	m.highPass = biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+	return m
+}
+
+func (m *loudnessMeter) Stream(samples [][2]float64) (n int, ok bool) {
+	n, ok = m.streamer.Stream(samples)
+	if m.finished {
+		return
+	}
+	for _, sample := range samples[:n] {
+		for c := range sample {
+			y := m.highPass.process(c, m.shelf.process(c, sample[c]))
+			m.blockSum += y * y
+		}
+		m.blockPos++
+		if m.blockPos == m.blockSize {
+			m.endBlock()
+		}
+	}
+	m.remaining -= n
+	if m.remaining <= 0 || !ok {
+		m.finish()
+	}
+	return
+}
+
+func (m *loudnessMeter) Err() error {
+	return m.streamer.Err()
+}
+
+func (m *loudnessMeter) endBlock() {
+	meanSquare := m.blockSum / float64(m.blockPos)
+	if blockLoudness(meanSquare) > -70 {
+		m.gatedSum += meanSquare
+		m.gatedBlocks++
+	}
+	m.blockSum = 0
+	m.blockPos = 0
+}
+
+func (m *loudnessMeter) finish() {
+	m.finished = true
+	if m.gatedBlocks == 0 {
+		logrus.Debug("loudness analysis: no audible blocks")
+		return
+	}
+	loudness := blockLoudness(m.gatedSum / float64(m.gatedBlocks))
+	m.done(loudness)
+}
+
+func blockLoudness(meanSquare float64) float64 {
+	if meanSquare <= 0 {
+		return math.Inf(-1)
+	}
+	return -0.691 + 10*math.Log10(meanSquare)
+}
@@ -0,0 +1,80 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+)
+
+const historyFile = "history.json"
+
+func historyPath() string {
+	return path.Join(config.AppConfig.Player.LocalCacheDir, historyFile)
+}
+
+// historyChanged saves history in background, if config.Player.PersistHistory is enabled.
+func (p *Player) historyChanged() {
+	if !config.AppConfig.Player.PersistHistory {
+		return
+	}
+	go func() {
+		err := p.saveHistory()
+		if err != nil {
+			logrus.Errorf("save history: %v", err)
+		}
+	}()
+}
+
+// saveHistory writes play history to history file.
+func (p *Player) saveHistory() error {
+	p.historyLock.Lock()
+	defer p.historyLock.Unlock()
+	return writeCacheFile(historyPath(), p.Queue.GetHistoryItems(-1))
+}
+
+// restoreHistory reads history file and replaces history with it. Missing file is not an error.
+func (p *Player) restoreHistory() error {
+	data, err := ioutil.ReadFile(historyPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var items []*models.HistoryItem
+	err = json.Unmarshal(data, &items)
+	if err != nil {
+		return fmt.Errorf("decode json: %v", err)
+	}
+	if limit := config.AppConfig.Player.HistoryLimit; len(items) > limit {
+		items = items[:limit]
+	}
+	logrus.Debugf("Restore history of %d songs", len(items))
+	p.Queue.setHistory(items)
+	return nil
+}
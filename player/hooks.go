@@ -0,0 +1,172 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package player
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/models"
+)
+
+// Hook events
+const (
+	HookTrackStart = "track_start"
+	HookTrackEnd   = "track_end"
+	HookPause      = "pause"
+	HookResume     = "resume"
+	HookStop       = "stop"
+	HookSeek       = "seek"
+)
+
+// Hooks runs user commands on playback events with song metadata in environment variables.
+type Hooks struct {
+	commands map[string]string
+
+	lock sync.Mutex
+	// current is song that track_start was last run for, nil if none
+	current *models.Song
+	last    models.AudioStatus
+}
+
+// NewHooks creates hooks for player. Commands are by event name, unknown events are logged and ignored.
+func NewHooks(player interfaces.Player, commands map[string]string) *Hooks {
+	h := &Hooks{commands: map[string]string{}}
+	for event, command := range commands {
+		event = strings.ToLower(event)
+		switch event {
+		case HookTrackStart, HookTrackEnd, HookPause, HookResume, HookStop, HookSeek:
+			if command != "" {
+				h.commands[event] = command
+			}
+		default:
+			logrus.Warningf("unknown hook event '%s'", event)
+		}
+	}
+	player.AddStatusCallback(h.statusChanged)
+	return h
+}
+
+func (h *Hooks) statusChanged(status models.AudioStatus) {
+	h.lock.Lock()
+	previous, last := h.current, h.last
+	h.last = status
+	events := []struct {
+		event  string
+		status models.AudioStatus
+	}{}
+	add := func(event string, s models.AudioStatus) {
+		events = append(events, struct {
+			event  string
+			status models.AudioStatus
+		}{event, s})
+	}
+
+	playing := status.State == models.AudioStatePlaying && status.Song != nil
+	seeking := status.Action == models.AudioActionSeek
+	if seeking && !playing {
+		// song is restarted from new position, it has not ended
+		h.lock.Unlock()
+		return
+	}
+	if seeking && previous != nil && status.Song.Id == previous.Id {
+		add(HookSeek, status)
+	}
+	if previous != nil && (!playing || status.Song.Id != previous.Id) {
+		ended := last
+		ended.Song = previous
+		add(HookTrackEnd, ended)
+		h.current = nil
+	}
+	if status.Action == models.AudioActionStop {
+		add(HookStop, last)
+	}
+	if playing && (previous == nil || status.Song.Id != previous.Id) {
+		add(HookTrackStart, status)
+		h.current = status.Song
+	} else if playing && status.Paused != last.Paused {
+		if status.Paused {
+			add(HookPause, status)
+		} else {
+			add(HookResume, status)
+		}
+	}
+	h.lock.Unlock()
+
+	for _, v := range events {
+		if command := h.commands[v.event]; command != "" {
+			go runHook(v.event, command, v.status)
+		}
+	}
+}
+
+// runHook runs command with shell and waits at most config.HookTimeout for it to complete.
+func runHook(event, command string, status models.AudioStatus) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.HookTimeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), hookEnv(event, status)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		logrus.Errorf("hook %s: %v: %s", event, err, strings.TrimSpace(string(out)))
+		return
+	}
+	logrus.Debugf("Ran hook %s", event)
+}
+
+// hookEnv returns environment variables describing status.
+func hookEnv(event string, status models.AudioStatus) []string {
+	env := []string{"EVENT=" + event, "POSITION=" + strconv.Itoa(status.SongPast.Seconds())}
+	song := status.Song
+	if song == nil {
+		return env
+	}
+	artists := make([]string, len(song.Artists))
+	for i, v := range song.Artists {
+		artists[i] = v.Name
+	}
+	artist := strings.Join(artists, ", ")
+	if artist == "" {
+		artist = song.AlbumArtistName
+	}
+	album := song.AlbumName
+	if album == "" && status.Album != nil {
+		album = status.Album.Name
+	}
+	return append(env,
+		"TITLE="+song.Name,
+		"ARTIST="+artist,
+		"ALBUM="+album,
+		"ID="+song.Id.String(),
+		"ART_URL="+status.AlbumImageUrl,
+		"DURATION="+strconv.Itoa(song.Duration),
+	)
+}
@@ -0,0 +1,114 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+)
+
+// countingStreamer counts samples pulled by output. Output pulls samples at its sample rate, also when
+// paused or stopped, so deviations from it tell that output has failed.
+type countingStreamer struct {
+	beep.Streamer
+	samples int64
+}
+
+func (c *countingStreamer) Stream(samples [][2]float64) (int, bool) {
+	n, ok := c.Streamer.Stream(samples)
+	atomic.AddInt64(&c.samples, int64(n))
+	return n, ok
+}
+
+func (c *countingStreamer) count() int64 {
+	return atomic.LoadInt64(&c.samples)
+}
+
+// outputWatch detects lost speaker output. When device disappears, writes to it fail immediately and
+// samples are consumed much faster than sample rate.
+type outputWatch struct {
+	lastCheck   time.Time
+	lastSamples int64
+	// failures is number of consecutive checks that failed
+	failures int
+	// lost is true when output has failed and is waiting to be re-initialized
+	lost bool
+	// stalled is true while output does not pull samples, logged only once
+	stalled bool
+}
+
+// checkOutput compares samples pulled since last check to sample rate. If output has been lost, playback is
+// paused and speaker is re-initialized on current default device.
+func (a *Audio) checkOutput(now time.Time) {
+	if _, ok := a.backend.(*speakerOutput); !ok || a.counter == nil {
+		return
+	}
+	w := &a.watch
+	samples := a.counter.count()
+	elapsed := now.Sub(w.lastCheck)
+	pulled := samples - w.lastSamples
+	first := w.lastCheck.IsZero()
+	w.lastCheck, w.lastSamples = now, samples
+	if first || elapsed <= 0 {
+		return
+	}
+
+	if w.lost {
+		a.reinitOutput()
+		return
+	}
+
+	expected := float64(a.currentSampleRate) * elapsed.Seconds()
+	switch {
+	case float64(pulled) > expected*config.AudioOutputMaxRateRatio:
+		w.failures++
+		if w.failures >= config.AudioOutputMaxFailures {
+			logrus.Warningf("Audio output lost (%d samples in %s), pause playback", pulled, elapsed)
+			w.lost = true
+			if a.getStatus().State == models.AudioStatePlaying {
+				a.Pause()
+			}
+			a.reinitOutput()
+		}
+	case float64(pulled) < expected/config.AudioOutputMaxRateRatio && !a.isBuffering():
+		if !w.stalled {
+			logrus.Warningf("Audio output stalled, %d samples in %s", pulled, elapsed)
+			w.stalled = true
+		}
+	default:
+		w.failures = 0
+		w.stalled = false
+	}
+}
+
+// reinitOutput initializes speaker again, which opens current default device.
+func (a *Audio) reinitOutput() {
+	err := a.initOutput(beep.SampleRate(a.currentSampleRate))
+	if err != nil {
+		logrus.Errorf("re-initialize audio output: %v", err)
+		return
+	}
+	// initOutput resets watch
+	logrus.Info("Audio output re-initialized")
+}
@@ -0,0 +1,172 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package player
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"tryffel.net/go/jellycli/api/jellyfin"
+	"tryffel.net/go/jellycli/api/jellyfin/mockserver"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+)
+
+// newIntegrationPlayer starts player that streams from mock server and writes audio to file.
+func newIntegrationPlayer(t *testing.T) (*Player, *mockserver.Server) {
+	t.Helper()
+	server := mockserver.New()
+	t.Cleanup(server.Close)
+
+	client, err := jellyfin.NewJellyfin(&config.Jellyfin{
+		Url:      server.URL,
+		Token:    mockserver.Token,
+		UserId:   mockserver.UserId,
+		ServerId: mockserver.ServerId,
+	}, nil)
+	if err != nil {
+		t.Fatalf("connect mock server: %v", err)
+	}
+	p, err := NewPlayer(client)
+	if err != nil {
+		t.Fatalf("new player: %v", err)
+	}
+	if err := p.Start(); err != nil {
+		t.Fatalf("start player: %v", err)
+	}
+	t.Cleanup(func() { p.Stop() })
+	return p, server
+}
+
+// wavSong returns song of silence, encoded as 16-bit stereo wav.
+func wavSong(id string, seconds int) *mockserver.Song {
+	size := seconds * testSampleRate * 4
+	data := make([]byte, 44+size)
+	copy(data[0:], "RIFF")
+	binary.LittleEndian.PutUint32(data[4:], uint32(36+size))
+	copy(data[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(data[16:], 16)
+	binary.LittleEndian.PutUint16(data[20:], 1)
+	binary.LittleEndian.PutUint16(data[22:], 2)
+	binary.LittleEndian.PutUint32(data[24:], testSampleRate)
+	binary.LittleEndian.PutUint32(data[28:], testSampleRate*4)
+	binary.LittleEndian.PutUint16(data[32:], 4)
+	binary.LittleEndian.PutUint16(data[34:], 16)
+	copy(data[36:], "data")
+	binary.LittleEndian.PutUint32(data[40:], uint32(size))
+	return &mockserver.Song{
+		Id:       id,
+		Name:     "song " + id,
+		Album:    "album",
+		AlbumId:  "album-1",
+		Artist:   "artist",
+		ArtistId: "artist-1",
+		Duration: seconds,
+		Mime:     "audio/wav",
+		Data:     data,
+	}
+}
+
+func toModel(song *mockserver.Song) *models.Song {
+	return &models.Song{Id: models.Id(song.Id), Name: song.Name, Duration: song.Duration}
+}
+
+// waitReport waits until server has received n reports of event for item and returns all reports.
+func waitReport(t *testing.T, server *mockserver.Server, event, item string, n int) []mockserver.Report {
+	t.Helper()
+	deadline := time.Now().Add(time.Second * 15)
+	for time.Now().Before(deadline) {
+		reports := server.Reports()
+		found := 0
+		for _, v := range reports {
+			if v.Event == event && v.ItemId == item {
+				found += 1
+			}
+		}
+		if found >= n {
+			return reports
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+	t.Fatalf("server did not receive %d %s reports of %s, got %v", n, event, item, server.Reports())
+	return nil
+}
+
+func TestIntegration_StreamServerError(t *testing.T) {
+	p, server := newIntegrationPlayer(t)
+	song := wavSong("a", 2)
+	server.AddSongs(song)
+	// server task fails once, e.g. 'A task was canceled'
+	server.FailStream(song.Id, 503, 1)
+
+	p.Queue.AddSongs([]*models.Song{toModel(song)})
+	waitReport(t, server, "Start", song.Id, 1)
+	waitReport(t, server, "Stopped", song.Id, 1)
+	if n := server.Requests("/Audio/a/universal"); n != 2 {
+		t.Errorf("expected 2 stream requests, got %d", n)
+	}
+}
+
+func TestIntegration_StreamInterrupted(t *testing.T) {
+	p, server := newIntegrationPlayer(t)
+	song := wavSong("a", 2)
+	server.AddSongs(song)
+	// connection drops after half of the song
+	server.CutStream(song.Id, len(song.Data)/2, 1)
+
+	p.Queue.AddSongs([]*models.Song{toModel(song)})
+	reports := waitReport(t, server, "Stopped", song.Id, 1)
+	if n := server.Requests("/Audio/a/universal"); n != 2 {
+		t.Errorf("expected 2 stream requests, got %d", n)
+	}
+	// without resuming download, playback would stop after one second
+	played := reports[len(reports)-1].Received.Sub(reports[0].Received)
+	if reports[0].Event != "Start" || played < time.Millisecond*1800 {
+		t.Errorf("expected song to be played to end, played %v: %v", played, reports)
+	}
+}
+
+func TestIntegration_SeekReported(t *testing.T) {
+	p, server := newIntegrationPlayer(t)
+	song := wavSong("a", 4)
+	server.AddSongs(song)
+
+	p.Queue.AddSongs([]*models.Song{toModel(song)})
+	waitReport(t, server, "Start", song.Id, 1)
+	p.SeekRelative(2)
+	reports := waitReport(t, server, "Start", song.Id, 2)
+	if n := server.Requests("/Audio/a/universal"); n != 2 {
+		t.Errorf("expected song to be streamed again for seek, got %d requests", n)
+	}
+
+	events := []string{}
+	var position int64
+	for _, v := range reports {
+		events = append(events, v.Event)
+		if v.Event == "Start" {
+			position = v.PositionTicks
+		}
+	}
+	if len(events) < 3 || events[len(events)-2] != "Stopped" || events[len(events)-1] != "Start" {
+		t.Errorf("expected seek to be reported as stop and start, got %v", events)
+	}
+	if position < 2*10000000 || position >= 3*10000000 {
+		t.Errorf("expected playback to start from 2 seconds, got %d ticks", position)
+	}
+}
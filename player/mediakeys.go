@@ -0,0 +1,87 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package player
+
+import (
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/task"
+)
+
+// mediaKey is a hardware media key.
+type mediaKey int
+
+const (
+	mediaKeyPlayPause mediaKey = iota
+	mediaKeyStop
+	mediaKeyNext
+	mediaKeyPrevious
+)
+
+// MediaKeys controls player with hardware media keys. It is supported only on platforms that have
+// listenMediaKeys implemented.
+type MediaKeys struct {
+	task.Task
+	player interfaces.Player
+}
+
+// NewMediaKeys creates media key listener for player.
+func NewMediaKeys(player interfaces.Player) *MediaKeys {
+	m := &MediaKeys{player: player}
+	m.Name = "Media keys"
+	m.SetLoop(m.loop)
+	return m
+}
+
+func (m *MediaKeys) loop() {
+	keys := make(chan mediaKey, 4)
+	stop := make(chan bool)
+	done := make(chan error, 1)
+	go func() {
+		done <- listenMediaKeys(keys, stop)
+	}()
+
+	for {
+		select {
+		case <-m.StopChan():
+			close(stop)
+			<-done
+			return
+		case key := <-keys:
+			m.press(key)
+		case err := <-done:
+			logrus.Errorf("media keys: %v", err)
+			<-m.StopChan()
+			return
+		}
+	}
+}
+
+func (m *MediaKeys) press(key mediaKey) {
+	logrus.Debugf("Media key %d pressed", key)
+	switch key {
+	case mediaKeyPlayPause:
+		m.player.PlayPause()
+	case mediaKeyStop:
+		m.player.StopMedia()
+	case mediaKeyNext:
+		m.player.Next()
+	case mediaKeyPrevious:
+		m.player.Previous()
+	}
+}
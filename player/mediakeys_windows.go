@@ -0,0 +1,109 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package player
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	user32               = syscall.NewLazyDLL("user32.dll")
+	kernel32             = syscall.NewLazyDLL("kernel32.dll")
+	procRegisterHotKey   = user32.NewProc("RegisterHotKey")
+	procUnregisterHotKey = user32.NewProc("UnregisterHotKey")
+	procGetMessage       = user32.NewProc("GetMessageW")
+	procPostThreadMsg    = user32.NewProc("PostThreadMessageW")
+	procGetThreadId      = kernel32.NewProc("GetCurrentThreadId")
+)
+
+const (
+	wmHotkey    = 0x0312
+	wmQuit      = 0x0012
+	modNoRepeat = 0x4000
+)
+
+// virtual key codes of media keys, hotkey id is index + 1
+var mediaKeyCodes = []struct {
+	key  mediaKey
+	code uintptr
+}{
+	{mediaKeyPlayPause, 0xB3},
+	{mediaKeyStop, 0xB2},
+	{mediaKeyNext, 0xB0},
+	{mediaKeyPrevious, 0xB1},
+}
+
+type winMsg struct {
+	hwnd    uintptr
+	message uint32
+	wParam  uintptr
+	lParam  uintptr
+	time    uint32
+	x, y    int32
+}
+
+// listenMediaKeys registers media keys as global hotkeys and sends them to keys until stop is closed.
+func listenMediaKeys(keys chan<- mediaKey, stop <-chan bool) error {
+	// hotkey messages are posted to the thread that registered them
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	threadId, _, _ := procGetThreadId.Call()
+
+	registered := 0
+	for i, v := range mediaKeyCodes {
+		ok, _, err := procRegisterHotKey.Call(0, uintptr(i+1), modNoRepeat, v.code)
+		if ok == 0 {
+			// e.g. another media player has registered it
+			logrus.Warningf("register media key %d: %v", v.key, err)
+			continue
+		}
+		registered++
+		defer procUnregisterHotKey.Call(0, uintptr(i+1))
+	}
+	if registered == 0 {
+		return fmt.Errorf("no media keys could be registered")
+	}
+
+	go func() {
+		<-stop
+		procPostThreadMsg.Call(threadId, wmQuit, 0, 0)
+	}()
+
+	msg := winMsg{}
+	for {
+		ret, _, err := procGetMessage.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+		switch int32(ret) {
+		case 0:
+			// WM_QUIT
+			return nil
+		case -1:
+			return fmt.Errorf("get message: %v", err)
+		}
+		if msg.message == wmHotkey && msg.wParam >= 1 && int(msg.wParam) <= len(mediaKeyCodes) {
+			select {
+			case keys <- mediaKeyCodes[msg.wParam-1].key:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/speaker"
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+)
+
+// nightMode caps volume between configured hours and optionally boosts bass and treble, which are
+// perceived quieter at low volume.
+type nightMode struct {
+	// minutes since midnight
+	start, end int
+	maxVolume  models.AudioVolume
+	loudness   bool
+	active     bool
+}
+
+// newNightMode parses night mode from config. Nil is returned if night mode is disabled.
+func newNightMode(conf *config.Player) *nightMode {
+	if conf.NightModeStart == "" || conf.NightModeEnd == "" {
+		return nil
+	}
+	start, err := parseTimeOfDay(conf.NightModeStart)
+	if err == nil {
+		var end int
+		end, err = parseTimeOfDay(conf.NightModeEnd)
+		if err == nil {
+			return &nightMode{
+				start:     start,
+				end:       end,
+				maxVolume: models.AudioVolume(conf.NightModeMaxVolume),
+				loudness:  conf.NightModeLoudness,
+			}
+		}
+	}
+	logrus.Errorf("night mode disabled: %v", err)
+	return nil
+}
+
+// parseTimeOfDay parses HH:MM to minutes since midnight.
+func parseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time '%s', expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// activeAt returns true if night mode should be active at given time. Period may span midnight.
+func (n *nightMode) activeAt(t time.Time) bool {
+	now := t.Hour()*60 + t.Minute()
+	if n.start <= n.end {
+		return now >= n.start && now < n.end
+	}
+	return now >= n.start || now < n.end
+}
+
+// loudnessEq is a loudness compensating equalizer: low shelf boost at 100 Hz and high shelf boost at 8 kHz.
+// When disabled, audio passes through unmodified.
+type loudnessEq struct {
+	streamer beep.Streamer
+	enabled  bool
+	low      biquad
+	high     biquad
+}
+
+func newLoudnessEq(s beep.Streamer) *loudnessEq {
+	eq := &loudnessEq{streamer: s}
+	eq.setSampleRate(beep.SampleRate(config.AppConfig.Player.OutputSampleRate))
+	return eq
+}
+
+// setSampleRate recalculates filters for sample rate.
+func (e *loudnessEq) setSampleRate(sampleRate beep.SampleRate) {
+	fs := float64(sampleRate.N(time.Second))
+	e.low = shelfFilter(fs, 100, config.AudioNightBassBoostdB, false)
+	e.high = shelfFilter(fs, 8000, config.AudioNightTrebleBoostdB, true)
+}
+
+// shelfFilter returns low or high shelf filter with given corner frequency and gain, see
+// RBJ audio EQ cookbook.
+func shelfFilter(fs, f0, gaindB float64, high bool) biquad {
+	a := math.Pow(10, gaindB/40)
+	w0 := 2 * math.Pi * f0 / fs
+	cos := math.Cos(w0)
+	// shelf slope S = 1
+	alpha := math.Sin(w0) / 2 * math.Sqrt2
+	sq := 2 * math.Sqrt(a) * alpha
+
+	var b0, b1, b2, a0, a1, a2 float64
+	if high {
+		b0 = a * ((a + 1) + (a-1)*cos + sq)
+		b1 = -2 * a * ((a - 1) + (a+1)*cos)
+		b2 = a * ((a + 1) + (a-1)*cos - sq)
+		a0 = (a + 1) - (a-1)*cos + sq
+		a1 = 2 * ((a - 1) - (a+1)*cos)
+		a2 = (a + 1) - (a-1)*cos - sq
+	} else {
+		b0 = a * ((a + 1) - (a-1)*cos + sq)
+		b1 = 2 * a * ((a - 1) - (a+1)*cos)
+		b2 = a * ((a + 1) - (a-1)*cos - sq)
+		a0 = (a + 1) + (a-1)*cos + sq
+		a1 = -2 * ((a - 1) + (a+1)*cos)
+		a2 = (a + 1) + (a-1)*cos - sq
+	}
+	return biquad{b0: b0 / a0, b1: b1 / a0, b2: b2 / a0, a1: a1 / a0, a2: a2 / a0}
+}
+
+func (e *loudnessEq) Stream(samples [][2]float64) (n int, ok bool) {
+	n, ok = e.streamer.Stream(samples)
+	if !e.enabled {
+		return
+	}
+	for i := range samples[:n] {
+		for c := range samples[i] {
+			samples[i][c] = e.high.process(c, e.low.process(c, samples[i][c]))
+		}
+	}
+	return
+}
+
+func (e *loudnessEq) Err() error {
+	return e.streamer.Err()
+}
+
+// checkNightMode activates or deactivates night mode depending on time.
+func (a *Audio) checkNightMode(t time.Time) {
+	if a.night == nil {
+		return
+	}
+	active := a.night.activeAt(t)
+	speaker.Lock()
+	if active == a.night.active {
+		speaker.Unlock()
+		return
+	}
+	if active {
+		logrus.Infof("Night mode on, limit volume to %d%%", a.night.maxVolume)
+	} else {
+		logrus.Info("Night mode off")
+	}
+	a.night.active = active
+	a.status.NightMode = active
+	a.eq.enabled = active && a.night.loudness
+	a.volume.setCap(a.night.maxVolume, active)
+	a.updateVolumeStatus()
+	speaker.Unlock()
+	go a.flushStatus()
+}
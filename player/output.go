@@ -0,0 +1,194 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/speaker"
+	"tryffel.net/go/jellycli/config"
+)
+
+const (
+	OutputDefault  = "default"
+	OutputPulse    = "pulse"
+	OutputPipewire = "pipewire"
+	OutputAlsa     = "alsa"
+)
+
+// OutputBackends lists supported values for config.Player.AudioOutput.
+var OutputBackends = []string{OutputDefault, OutputPulse, OutputPipewire, OutputAlsa}
+
+// Output is a backend that plays mixed audio. Audio state is guarded with speaker lock, so output must hold
+// speaker.Lock while it pulls samples from streamer.
+type Output interface {
+	// Init prepares output for given sample rate. It is called again if output is re-initialized.
+	Init(sampleRate beep.SampleRate) error
+	// Play starts pulling audio from streamer.
+	Play(s beep.Streamer)
+}
+
+// OutputDevice is a device that can be set as config.Player.AudioDevice.
+type OutputDevice struct {
+	Name        string
+	Description string
+}
+
+// speakerOutput plays audio with beep speaker. Speaker always opens default ALSA device (or native device
+// on other platforms), so device is selected with environment variables that sound servers and ALSA read.
+type speakerOutput struct {
+	backend string
+	device  string
+}
+
+// newOutput returns output configured with config.Player.Output and, for speaker, backend and device.
+func newOutput(conf *config.Player) (Output, error) {
+	var wav bool
+	switch conf.OutputFormat {
+	case "", "pcm":
+	case "wav":
+		wav = true
+	default:
+		return nil, fmt.Errorf("unknown output format '%s', supported: pcm, wav", conf.OutputFormat)
+	}
+
+	switch {
+	case conf.Output == "" || conf.Output == "speaker":
+		return newSpeakerOutput(conf.AudioOutput, conf.AudioDevice)
+	case conf.Output == "stdout":
+		return &pcmOutput{name: "stdout", wav: wav}, nil
+	case strings.HasPrefix(conf.Output, "pipe:"):
+		file := strings.TrimPrefix(conf.Output, "pipe:")
+		if file == "" {
+			return nil, fmt.Errorf("output pipe path cannot be empty")
+		}
+		return &pcmOutput{name: file, wav: wav}, nil
+	default:
+		return nil, fmt.Errorf("unknown output '%s', supported: speaker, stdout, pipe:<path>", conf.Output)
+	}
+}
+
+// newSpeakerOutput returns speaker output for backend. Device is optional.
+func newSpeakerOutput(backend, device string) (Output, error) {
+	switch backend {
+	case "", OutputDefault, OutputPulse, OutputPipewire, OutputAlsa:
+	default:
+		return nil, fmt.Errorf("unknown audio output '%s', supported: %s", backend,
+			strings.Join(OutputBackends, ", "))
+	}
+	if backend == "" || backend == OutputDefault {
+		if device != "" {
+			return nil, fmt.Errorf("audio device requires audio output to be set")
+		}
+	}
+	return &speakerOutput{backend: backend, device: device}, nil
+}
+
+func (s *speakerOutput) Init(sampleRate beep.SampleRate) error {
+	if s.device != "" {
+		var err error
+		switch s.backend {
+		case OutputPulse:
+			err = os.Setenv("PULSE_SINK", s.device)
+		case OutputPipewire:
+			err = os.Setenv("PIPEWIRE_NODE", s.device)
+		case OutputAlsa:
+			// used by ALSA default device, card id or index
+			err = os.Setenv("ALSA_CARD", s.device)
+		}
+		if err != nil {
+			return fmt.Errorf("select device: %v", err)
+		}
+	}
+
+	err := speaker.Init(sampleRate, sampleRate.N(time.Second)/1000*
+		int(config.AudioBufferPeriod().Milliseconds()))
+	if err != nil {
+		return fmt.Errorf("init speaker: %v", err)
+	}
+	return nil
+}
+
+func (s *speakerOutput) Play(streamer beep.Streamer) {
+	speaker.Play(streamer)
+}
+
+// ListOutputDevices lists devices for backend.
+func ListOutputDevices(backend string) ([]OutputDevice, error) {
+	switch backend {
+	case OutputPulse, OutputPipewire:
+		return listPulseSinks()
+	case OutputAlsa:
+		return listAlsaCards()
+	case OutputDefault:
+		return []OutputDevice{{Name: "", Description: "System default device"}}, nil
+	default:
+		return nil, fmt.Errorf("unknown audio output '%s'", backend)
+	}
+}
+
+// list sinks with pactl, which works with both pulseaudio and pipewire-pulse.
+func listPulseSinks() ([]OutputDevice, error) {
+	out, err := exec.Command("pactl", "list", "short", "sinks").Output()
+	if err != nil {
+		return nil, fmt.Errorf("run pactl: %v", err)
+	}
+	devices := []OutputDevice{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		// index, name, driver, format, state
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		device := OutputDevice{Name: fields[1]}
+		if len(fields) >= 5 {
+			device.Description = strings.Join(fields[3:5], ", ")
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+// e.g. ' 0 [PCH            ]: HDA-Intel - HDA Intel PCH'
+var alsaCardRegex = regexp.MustCompile(`^\s*\d+\s+\[(\S+)\s*\]:\s*(.*)$`)
+
+func listAlsaCards() ([]OutputDevice, error) {
+	data, err := ioutil.ReadFile("/proc/asound/cards")
+	if err != nil {
+		return nil, fmt.Errorf("read sound cards: %v", err)
+	}
+	devices := []OutputDevice{}
+	for _, line := range strings.Split(string(data), "\n") {
+		match := alsaCardRegex.FindStringSubmatch(line)
+		if match != nil {
+			devices = append(devices, OutputDevice{Name: match[1], Description: match[2]})
+		}
+	}
+	return devices, nil
+}
@@ -0,0 +1,170 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/speaker"
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/config"
+)
+
+// pcmOutput writes raw PCM (signed 16-bit little-endian, stereo) or wav to stdout or file, e.g. named pipe
+// for snapcast. Output runs in real time, writing silence when nothing is playing. Sample rate is fixed to
+// the rate output was initialized with.
+type pcmOutput struct {
+	// name is 'stdout' or path of file
+	name string
+	// wav writes wav header before audio
+	wav bool
+
+	lock       sync.Mutex
+	sampleRate beep.SampleRate
+	streamer   beep.Streamer
+	started    bool
+}
+
+func (p *pcmOutput) Init(sampleRate beep.SampleRate) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.sampleRate == 0 {
+		p.sampleRate = sampleRate
+	}
+	return nil
+}
+
+func (p *pcmOutput) Play(s beep.Streamer) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.streamer = s
+	if !p.started {
+		p.started = true
+		go p.run()
+	}
+}
+
+func (p *pcmOutput) open() (io.WriteCloser, error) {
+	if p.name == "stdout" {
+		return os.Stdout, nil
+	}
+	// opening named pipe blocks until there's a reader
+	return os.OpenFile(p.name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0660)
+}
+
+// run writes audio until writing to stdout fails. Pipe is reopened if reader goes away.
+func (p *pcmOutput) run() {
+	for {
+		writer, err := p.open()
+		if err != nil {
+			logrus.Errorf("open pcm output %s: %v", p.name, err)
+		} else {
+			logrus.Infof("Writing audio to %s", p.name)
+			err = p.write(writer)
+			writer.Close()
+			logrus.Errorf("write pcm output %s: %v", p.name, err)
+		}
+		if p.name == "stdout" {
+			return
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func (p *pcmOutput) write(writer io.Writer) error {
+	p.lock.Lock()
+	sampleRate := p.sampleRate
+	p.lock.Unlock()
+
+	period := config.AudioBufferPeriod()
+	samples := make([][2]float64, sampleRate.N(period))
+	data := make([]byte, len(samples)*4)
+	if p.wav {
+		_, err := writer.Write(wavHeader(sampleRate))
+		if err != nil {
+			return err
+		}
+	}
+
+	start := time.Now()
+	written := 0
+
+	for {
+		p.lock.Lock()
+		streamer := p.streamer
+		p.lock.Unlock()
+
+		speaker.Lock()
+		n, _ := streamer.Stream(samples)
+		speaker.Unlock()
+		for i := n; i < len(samples); i++ {
+			samples[i] = [2]float64{}
+		}
+
+		for i, v := range samples {
+			binary.LittleEndian.PutUint16(data[i*4:], uint16(toInt16(v[0])))
+			binary.LittleEndian.PutUint16(data[i*4+2:], uint16(toInt16(v[1])))
+		}
+		_, err := writer.Write(data)
+		if err != nil {
+			return err
+		}
+
+		// don't get ahead of real time, consumer may not limit speed (e.g. regular file)
+		written += len(samples)
+		ahead := sampleRate.D(written) - time.Since(start)
+		if ahead > period {
+			time.Sleep(ahead - period)
+		}
+	}
+}
+
+// wavHeader returns header for 16-bit stereo wav of unknown length. Sizes are set to maximum, which
+// most readers accept for streams.
+func wavHeader(sampleRate beep.SampleRate) []byte {
+	const channels = 2
+	const bytesPerSample = 2
+	rate := sampleRate.N(time.Second)
+	header := make([]byte, 44)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], math.MaxUint32)
+	copy(header[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	// pcm
+	binary.LittleEndian.PutUint16(header[20:], 1)
+	binary.LittleEndian.PutUint16(header[22:], channels)
+	binary.LittleEndian.PutUint32(header[24:], uint32(rate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(rate*channels*bytesPerSample))
+	binary.LittleEndian.PutUint16(header[32:], channels*bytesPerSample)
+	binary.LittleEndian.PutUint16(header[34:], bytesPerSample*8)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], math.MaxUint32)
+	return header
+}
+
+func toInt16(sample float64) int16 {
+	sample = math.Max(-1, math.Min(1, sample))
+	return int16(sample * math.MaxInt16)
+}
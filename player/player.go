@@ -21,17 +21,21 @@
 package player
 
 import (
+	"errors"
 	"fmt"
+	"github.com/faiface/beep"
 	"github.com/sirupsen/logrus"
 	"io"
-	"strings"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 	"tryffel.net/go/jellycli/api"
 	"tryffel.net/go/jellycli/config"
 	"tryffel.net/go/jellycli/interfaces"
 	"tryffel.net/go/jellycli/models"
 	"tryffel.net/go/jellycli/task"
+	"tryffel.net/go/jellycli/util"
 )
 
 type songMetadata struct {
@@ -42,6 +46,10 @@ type songMetadata struct {
 	albumImageId  string
 	reader        io.ReadCloser
 	format        interfaces.AudioFormat
+	// startAt is position to start playing from
+	startAt models.AudioTick
+	// seek is set when song is restarted from new position
+	seek bool
 }
 
 // Player wraps all controllers and implements interfaces.QueueController, interfaces.Player and
@@ -52,19 +60,58 @@ type Player struct {
 	*Queue
 
 	lock *sync.RWMutex
+	// historyLock serializes writing history file
+	historyLock sync.Mutex
+	// statsLock serializes writing stats file
+	statsLock sync.Mutex
 
 	downloadingSong bool
+	// fillingQueue is true while auto queue is fetching songs
+	fillingQueue bool
 
 	songComplete   chan bool
 	audioUpdated   chan models.AudioStatus
 	songDownloaded chan songMetadata
 
-	nextSong *songMetadata
-
-	api              interfaces.Api // Use the interface from the interfaces package
+	// preloaded is next song opened in advance, nil if none
+	preloaded *songMetadata
+	// preloading is true while next song is being opened
+	preloading bool
+	// prefetch fetches metadata of upcoming songs, nil if server does not support it
+	prefetch *prefetcher
+	// resume keeps positions of long items
+	resume *resumePoints
+
+	api interfaces.Api // Use the interface from the interfaces package
+	// reporter reports progress instead of api, if set
+	reporter         interfaces.ProgressReporter
 	remoteController api.RemoteController
 
-	lastApiReport time.Time
+	// progress sends reports to reporter
+	progress *progressReporter
+	// reportedSong is song that server was last told to be playing, nil if stopped.
+	reportedSong     *models.Song
+	reportedPosition int
+	// songFinished is set when song played to end
+	songFinished bool
+	// shuttingDown is set once final stop report has been sent
+	shuttingDown bool
+
+	// offline contains local copies of songs
+	offline *api.OfflineStore
+	// art caches album art, nil if server does not provide images
+	art *artCache
+	// cacheHits and cacheMisses count songs opened from offline store and from server
+	cacheHits   int32
+	cacheMisses int32
+
+	// wake resumes periodic status updates after idle
+	wake chan bool
+
+	// startPosition is position to start first song in queue from, after restoring state or seeking
+	startPosition models.AudioTick
+	// seeking is set when first song in queue is restarted from startPosition because of seek
+	seeking bool
 }
 
 // initialize new player. This also initializes faiface.Speaker, which should be initialized only once.
@@ -75,35 +122,143 @@ func NewPlayer(browser interfaces.Api) (*Player, error) { // Use the interface f
 		songComplete:   make(chan bool, 3),
 		audioUpdated:   make(chan models.AudioStatus, 3),
 		songDownloaded: make(chan songMetadata, 3),
+		wake:           make(chan bool, 1),
 		api:            browser,
 	}
 	p.Name = "Player"
+	p.progress = newProgressReporter(p.progressTarget)
+	if getter, ok := browser.(itemGetter); ok {
+		p.prefetch = newPrefetcher(getter)
+	}
+	if linker, ok := browser.(api.ImageLinker); ok {
+		p.art = newArtCache(linker)
+	}
 	p.Task.SetLoop(p.loop)
 
 	p.Audio = newAudio()
 	p.Queue = newQueue()
+	p.resume = newResumePoints()
+	p.offline = api.NewOfflineStore(config.AppConfig.Player.LocalCacheDir)
+	// remove songs left preloaded to disk
+	if err := os.RemoveAll(preloadDir()); err != nil {
+		logrus.Warningf("remove preloaded songs: %v", err)
+	}
 	if remoteController, ok := browser.(api.RemoteController); ok {
 		p.remoteController = remoteController
 		p.remoteController.SetPlayer(p)
 	}
 
-	err = initAudio()
+	p.Audio.backend, err = newOutput(&config.AppConfig.Player)
+	if err != nil {
+		return p, fmt.Errorf("audio output: %v", err)
+	}
+	err = p.Audio.initOutput(beep.SampleRate(config.AppConfig.Player.OutputSampleRate))
 	if err != nil {
 		return p, fmt.Errorf("init audio backend: %v", err)
 	}
 
 	p.Audio.songCompleteFunc = p.songCompleted
 	p.Audio.AddStatusCallback(p.audioCallback)
+	p.Audio.AddStatusCallback(p.resumeCallback)
+	p.Audio.AddStatusCallback(p.wakeCallback)
 
 	p.Queue.AddQueueChangedCallback(p.queueChanged)
 	return p, nil
 }
 
+// Start starts player and restores previous state, if enabled.
+func (p *Player) Start() error {
+	err := p.Task.Start()
+	if err != nil {
+		return err
+	}
+	if config.AppConfig.Player.PersistHistory {
+		err = p.restoreHistory()
+		if err != nil {
+			logrus.Errorf("restore history: %v", err)
+		}
+	}
+	if config.AppConfig.Player.RestoreState {
+		err = p.restoreState()
+		if err != nil {
+			logrus.Errorf("restore player state: %v", err)
+		}
+	}
+	return nil
+}
+
+// Stop saves current state, if enabled, reports current song as stopped and stops player.
+func (p *Player) Stop() error {
+	if config.AppConfig.Player.RestoreState {
+		err := p.saveState()
+		if err != nil {
+			logrus.Errorf("save player state: %v", err)
+		}
+	}
+	if err := p.resume.save(true); err != nil {
+		logrus.Errorf("save resume points: %v", err)
+	}
+	p.reportShutdown()
+	err := p.Task.Stop()
+	if err != nil {
+		return err
+	}
+	if !p.Task.Wait(config.ShutdownTimeout) {
+		return fmt.Errorf("player did not stop in %s", config.ShutdownTimeout)
+	}
+	return nil
+}
+
+// reportShutdown reports current song stopped at current position, so that server does not keep
+// showing it as playing. Song is not marked as played, since it was interrupted. Further reports are
+// not sent.
+func (p *Player) reportShutdown() {
+	p.lock.Lock()
+	p.shuttingDown = true
+	p.lock.Unlock()
+	if config.PlaybackReportingDisabled() {
+		return
+	}
+	status := p.Audio.getStatus()
+	if status.Song == nil || status.State != models.AudioStatePlaying {
+		return
+	}
+	queue, index := p.reportedQueue(status.Song)
+	err := p.progressTarget().ReportProgress(&interfaces.ApiPlaybackState{
+		Event:          interfaces.EventStop,
+		ItemId:         status.Song.Id.String(),
+		IsPaused:       status.Paused,
+		IsMuted:        status.Muted,
+		PlaylistLength: status.Song.Duration,
+		Position:       status.SongPast.Seconds(),
+		Volume:         int(status.EffectiveVolume),
+		Shuffle:        status.Shuffle,
+		Queue:          queue,
+		QueueIndex:     index,
+	})
+	if err != nil {
+		logrus.Errorf("report playback stopped: %v", err)
+	}
+}
+
 // notify song has completed
 func (p *Player) songCompleted() {
+	p.lock.Lock()
+	p.songFinished = true
+	p.lock.Unlock()
 	p.songComplete <- true
 }
 
+// wakeCallback wakes player from idle when playback starts.
+func (p *Player) wakeCallback(status models.AudioStatus) {
+	if status.State == models.AudioStatePlaying && !status.Paused {
+		select {
+		case p.wake <- true:
+		default:
+		}
+	}
+}
+
 // is download pending / ongoing
 func (p *Player) isDownloadingSong() bool {
 	p.lock.RLock()
@@ -114,51 +269,65 @@ func (p *Player) isDownloadingSong() bool {
 func (p *Player) loop() {
 	// interval to refresh status. This is the interval the status will be updated.
 	ticker := time.NewTicker(time.Second)
+	// tick is nil while idle
+	tick := ticker.C
 
 	for true {
 		select {
 		case <-p.StopChan():
-			// stop application
+			// stop application, stream is closed
+			ticker.Stop()
 			p.Audio.StopMedia()
-			break
+			p.setPreload(nil)
+			return
 		case <-p.songComplete:
 			// stream / song complete, get next song
 			logrus.Debug("song complete")
+			if queue := p.Queue.GetQueue(); len(queue) > 0 {
+				p.recordPlay(queue[0], queue[0].Duration, true)
+				p.rememberPosition(queue[0], queue[0].Duration, true)
+			}
 			p.Queue.songComplete()
-			if len(p.Queue.GetQueue()) == 0 {
+			p.historyChanged()
+			queue := p.Queue.GetQueue()
+			if len(queue) == 0 {
 				p.Audio.StopMedia()
+			} else if p.resume.position(queue[0]) > 0 {
+				// preloaded song starts from beginning
+				p.downloadSong(0)
+			} else if next := p.takePreload(queue[0]); next != nil {
+				p.playSong(*next)
 			} else {
-				if p.nextSong != nil {
-					err := p.Audio.playSongFromReader(*p.nextSong)
-					if err != nil {
-						logrus.Errorf("play track: %v", err)
-					}
-					p.nextSong = nil
-				} else {
-					p.downloadSong(0)
-				}
+				p.downloadSong(0)
 			}
 		case status := <-p.audioUpdated:
 			logrus.Infof("got audio status: %v", status)
-		case <-ticker.C:
+		case <-tick:
 			// periodically update status, this will push status to p.audioUpdated
+			if !p.Audio.isBuffering() {
+				p.Audio.checkNightMode(time.Now())
+			}
+			p.Audio.checkOutput(time.Now())
 			p.Audio.updateStatus()
-			if p.status.Song != nil && p.status.State == models.AudioStatePlaying {
-				if (p.status.Song.Duration-p.status.SongPast.Seconds()) < 5 &&
-					!p.isDownloadingSong() && p.nextSong == nil && len(p.Queue.GetQueue()) >= 2 {
-					p.downloadSong(1)
-				}
+			if config.AppConfig.Player.IdleMode && p.Audio.idle() {
+				logrus.Debug("Player idle, suspend status updates")
+				ticker.Stop()
+				tick = nil
+			}
+		case <-p.wake:
+			if tick == nil {
+				logrus.Debug("Player active, resume status updates")
+				ticker = time.NewTicker(time.Second)
+				tick = ticker.C
 			}
 		case metadata := <-p.songDownloaded:
 			if p.status.State == models.AudioStateStopped {
 				// download complete, send to audio
-				err := p.Audio.playSongFromReader(metadata)
-				if err != nil {
-					logrus.Errorf("play track: %v", err)
-				}
-				p.nextSong = nil
+				p.playSong(metadata)
 			} else {
-				p.nextSong = &metadata
+				// keep it in case it is played next, else it is discarded when queue changes
+				p.setPreload(&metadata)
+				p.checkPreload(p.Queue.GetQueue())
 			}
 		}
 	}
@@ -170,50 +339,57 @@ func (p *Player) downloadSong(index int) {
 		return
 	}
 	song := p.Queue.GetQueue()[index]
+	var startAt models.AudioTick
+	seek := false
+	if index == 0 {
+		p.lock.Lock()
+		startAt, seek = p.startPosition, p.seeking
+		p.startPosition, p.seeking = 0, false
+		p.lock.Unlock()
+		if startAt == 0 {
+			startAt = p.resumeAt(song)
+		}
+	}
+	if startAt == 0 {
+		if metadata := p.takePreload(song); metadata != nil {
+			p.songDownloaded <- *metadata
+			return
+		}
+	}
 
 	p.lock.Lock()
 	p.downloadingSong = true
 	p.lock.Unlock()
 	ok := false
 
-	reader, format, err := p.api.Stream(song)
-	if err != nil {
-		if strings.Contains(err.Error(), "A task was canceled") {
-			// server task may fail sometimes, retry
-			logrus.Warningf("Failed to download song, retrying: %v", err)
-			time.Sleep(time.Second)
-			reader, format, err = p.api.Stream(song)
-			if err == nil {
-				ok = true
-			} else {
-				logrus.Errorf("retry downloading song: %v", err)
-			}
-		} else {
-			logrus.Errorf("download song: %v", err)
-		}
-	} else {
+	reader, format, err := p.stream(song)
+	if errors.Is(err, api.ErrServerUnavailable) {
+		// server task may fail sometimes, e.g. 'A task was canceled', retry
+		logrus.Warningf("Failed to download song, retrying: %v", err)
+		time.Sleep(time.Second)
+		reader, format, err = p.stream(song)
+	}
+	switch {
+	case err == nil:
 		ok = true
+	case errors.Is(err, api.ErrUnauthorized):
+		logrus.Errorf("download song: %v, log in again with 'jellycli login'", err)
+	case errors.Is(err, api.ErrNotFound), errors.Is(err, api.ErrTranscodeNeeded):
+		logrus.Errorf("download song: %v, skip %s", err, song.Name)
+		if index == 0 {
+			defer p.Queue.dropFirst()
+		}
+	default:
+		logrus.Errorf("download song: %v", err)
 	}
 	if ok {
-		// Metadata fetching removed for headless operation
-		album := &models.Album{Name: "unknown album"}
-		artist := &models.Artist{Name: "unknown artist"}
-		var imageId string
-		var imageUrl string
-			f := func() {
-				metadata := songMetadata{
-					song:          song,
-					album:         album, // Use placeholder
-					artist:        artist, // Use placeholder
-					albumImageUrl: imageUrl, // Empty
-					albumImageId:  imageId, // Empty
-					reader:        reader,
-					format:        format,
-				}
-				p.songDownloaded <- metadata
-			}
-			defer f()
-		}
+		metadata := p.newSongMetadata(song, reader, format)
+		metadata.startAt = startAt
+		metadata.seek = seek
+		defer func() {
+			p.songDownloaded <- metadata
+		}()
+	}
 
 	p.lock.Lock()
 	p.downloadingSong = false
@@ -222,11 +398,34 @@ func (p *Player) downloadSong(index int) {
 	// push song to audio
 }
 
+// stream opens song from offline copy, if there is one, else from server.
+func (p *Player) stream(song *models.Song) (io.ReadCloser, interfaces.AudioFormat, error) {
+	if reader, format, ok := p.offline.Open(song); ok && format.NeedsTranscoding() {
+		// copy saved before it was transcoded
+		logrus.Warningf("Offline copy of %s is %s, which cannot be decoded, stream it from server", song.Name, format)
+		reader.Close()
+	} else if ok {
+		logrus.Debugf("Play %s from offline copy", song.Name)
+		atomic.AddInt32(&p.cacheHits, 1)
+		return reader, format, nil
+	}
+	atomic.AddInt32(&p.cacheMisses, 1)
+	return p.api.Stream(song)
+}
+
+// CacheStats returns number of songs opened from offline copies and from server.
+func (p *Player) CacheStats() (hits, misses int) {
+	return int(atomic.LoadInt32(&p.cacheHits)), int(atomic.LoadInt32(&p.cacheMisses))
+}
+
 // Next plays next song from queue. Override Audio next to ensure there is track to play and download it
 func (p *Player) Next() {
-	if len(p.Queue.GetQueue()) > 1 {
+	if queue := p.Queue.GetQueue(); len(queue) > 1 {
+		p.recordPlay(queue[0], p.Audio.getPastTicks().Seconds(), false)
+		p.rememberPosition(queue[0], p.Audio.getPastTicks().Seconds(), true)
 		p.StopMedia()
 		p.Queue.songComplete()
+		p.historyChanged()
 		go p.downloadSong(0)
 	}
 }
@@ -236,24 +435,103 @@ func (p *Player) Previous() {
 	if len(p.Queue.GetHistory(10)) > 0 {
 		p.StopMedia()
 		p.Queue.playLastSong()
+		p.historyChanged()
 		p.Audio.Previous()
 		go p.downloadSong(0)
 	}
 }
 
+// Seek seeks forward given ticks, or backward if ticks is negative.
+func (p *Player) Seek(ticks models.AudioTick) {
+	status := p.Audio.getStatus()
+	if status.Song == nil || status.State != models.AudioStatePlaying {
+		return
+	}
+	position := status.SongPast + ticks
+	if position < 0 {
+		position = 0
+	}
+	if position.Seconds() >= status.Song.Duration {
+		p.Next()
+		return
+	}
+
+	// stream is not seekable, restart song from position
+	logrus.Infof("Seek to %s", util.SecToString(position.Seconds()))
+	p.lock.Lock()
+	p.startPosition = position
+	p.seeking = true
+	p.lock.Unlock()
+	p.Audio.stopForSeek()
+	go p.downloadSong(0)
+}
+
+// SeekRelative seeks given seconds, forward if positive, else backward.
+func (p *Player) SeekRelative(seconds int) {
+	p.Seek(models.AudioTick(seconds * 1000))
+}
+
+// PreviewSong plays beginning of song at reduced volume on top of current audio. Queue and current song are
+// not affected. Song is streamed without play session, so it's not reported to server either.
+func (p *Player) PreviewSong(song *models.Song) {
+	go func() {
+		reader, format, err := p.api.GetSongDirect(song.Id.String(), "")
+		if err != nil {
+			logrus.Errorf("preview song: %v", err)
+			return
+		}
+		err = p.Audio.playPreview(songMetadata{
+			song:   song,
+			reader: reader,
+			format: format,
+		})
+		if err != nil {
+			logrus.Errorf("preview song: %v", err)
+		}
+	}()
+}
+
+// SaveQueueAsPlaylist creates new playlist in server from songs in queue, including current song.
+func (p *Player) SaveQueueAsPlaylist(name string) (models.Id, error) {
+	if config.ReadOnly() {
+		return "", models.ErrReadOnly
+	}
+	editor, ok := p.api.(api.PlaylistEditor)
+	if !ok {
+		return "", fmt.Errorf("server does not support creating playlists")
+	}
+	songs := p.Queue.GetQueue()
+	if len(songs) == 0 {
+		return "", fmt.Errorf("queue is empty")
+	}
+	ids := make([]models.Id, len(songs))
+	for i, v := range songs {
+		ids[i] = v.Id
+	}
+	id, err := editor.CreatePlaylist(name, ids)
+	if err != nil {
+		return "", fmt.Errorf("create playlist: %v", err)
+	}
+	logrus.Infof("Saved queue of %d songs as playlist %s", len(ids), name)
+	return id, nil
+}
+
 // report audio status to server
 func (p *Player) audioCallback(status models.AudioStatus) {
 	// Skip reporting if disabled in config
-	if config.AppConfig.Player.DisablePlaybackReporting {
+	if config.PlaybackReportingDisabled() {
 		return
 	}
 
 	p.lock.RLock()
-	lastTime := p.lastApiReport
+	shuttingDown := p.shuttingDown
 	p.lock.RUnlock()
+	if shuttingDown {
+		// final report has been sent
+		return
+	}
 
-	if time.Now().Sub(lastTime) < time.Millisecond*9500 && status.Action == models.AudioActionTimeUpdate {
-		// jellyfin server instructs to update every 10 sec
+	if status.Action == models.AudioActionTimeUpdate && !p.progress.timeUpdateDue() {
 		return
 	}
 
@@ -262,10 +540,6 @@ func (p *Player) audioCallback(status models.AudioStatus) {
 		return
 	}
 
-	p.lock.Lock()
-	p.lastApiReport = time.Now()
-	p.lock.Unlock()
-
 	apiStatus := &interfaces.ApiPlaybackState{
 		Event:          "",
 		ItemId:         "",
@@ -273,17 +547,26 @@ func (p *Player) audioCallback(status models.AudioStatus) {
 		IsMuted:        status.Muted,
 		PlaylistLength: 0,
 		Position:       status.SongPast.Seconds(),
-		Volume:         int(status.Volume),
+		Volume:         int(status.EffectiveVolume),
 		Shuffle:        status.Shuffle,
-		PlayedToCompletion: true, // Default to true
+		Transcoded:     status.Transcoded,
 	}
 
 	switch status.Action {
 	case models.AudioActionStop:
 		apiStatus.Event = interfaces.EventStop // Reverted back to interfaces
-		apiStatus.PlayedToCompletion = false // Explicit stop means not completed naturally
+		if status.Song != nil {
+			apiStatus.PlayedToCompletion = playedEnough(status.SongPast.Seconds(), status.Song.Duration)
+		}
 	case models.AudioActionPlay:
 		apiStatus.Event = interfaces.EventStart
+	case models.AudioActionSeek:
+		// song is restarted from new position, server sees it as stopped and started again
+		if status.State == models.AudioStateStopped {
+			apiStatus.Event = interfaces.EventStop
+		} else {
+			apiStatus.Event = interfaces.EventStart
+		}
 	case models.AudioActionNext:
 		apiStatus.Event = interfaces.EventAudioTrackChange
 	case models.AudioActionPrevious:
@@ -300,43 +583,126 @@ func (p *Player) audioCallback(status models.AudioStatus) {
 		}
 	case models.AudioActionShuffleChanged:
 		apiStatus.Event = interfaces.EventShuffleModeChange
+	case models.AudioActionQueueChanged:
+		// progress report carries the queue
+		apiStatus.Event = interfaces.EventTimeUpdate
 	default:
 		apiStatus.Event = interfaces.EventTimeUpdate
 		logrus.Warningf("cannot map audio state to browser event: %v", status.Action)
 	}
 
-	songs := p.GetQueue()
-	queue := make([]models.Id, len(songs))
-	for i, v := range songs {
-		queue[i] = v.Id
-	}
-	apiStatus.Queue = queue
+	apiStatus.Queue, apiStatus.QueueIndex = p.reportedQueue(status.Song)
 	apiStatus.IsPaused = status.Paused
 
 	if status.Song != nil {
 		apiStatus.ItemId = status.Song.Id.String()
 		apiStatus.PlaylistLength = status.Song.Duration
 	}
-	f := func() {
-		// Type assert p.api to interfaces.Api to call ReportProgress
-		if reporter, ok := p.api.(interfaces.Api); ok {
-			err := reporter.ReportProgress(apiStatus)
-			if err != nil {
-				logrus.Errorf("report audio progress to server: %v", err)
-			}
-		} else {
-			logrus.Warnf("MediaServer does not implement ReportProgress")
+	// stop of previous song must precede start of next one
+	p.progress.send(append(p.trackReported(status, apiStatus), apiStatus)...)
+}
+
+// reportedQueue returns ids of recently played songs and songs in queue in play order, and index of
+// current song in it. If there's no current song, index points to first song in queue.
+func (p *Player) reportedQueue(current *models.Song) ([]models.Id, int) {
+	history := p.GetHistory(config.ReportHistorySize)
+	songs := p.GetQueue()
+	queue := make([]models.Id, 0, len(history)+len(songs))
+	// history is latest first
+	for i := len(history) - 1; i >= 0; i-- {
+		queue = append(queue, history[i].Id)
+	}
+	index := len(queue)
+	found := false
+	for _, v := range songs {
+		if !found && current != nil && v.Id == current.Id {
+			index, found = len(queue), true
 		}
+		queue = append(queue, v.Id)
 	}
-	go f()
+	return queue, index
+}
+
+// progressTarget returns reporter that progress is reported to.
+func (p *Player) progressTarget() interfaces.ProgressReporter {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.reporter != nil {
+		return p.reporter
+	}
+	return p.api
+}
+
+// trackReported keeps track of song reported to server. When a new song starts without previous one
+// being stopped, e.g. previous song played to end, it returns stop report for previous song.
+func (p *Player) trackReported(status models.AudioStatus, state *interfaces.ApiPlaybackState) []*interfaces.ApiPlaybackState {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	previous, position, finished := p.reportedSong, p.reportedPosition, p.songFinished
+	p.songFinished = false
+
+	if state.Event == interfaces.EventStop {
+		p.reportedSong = nil
+		return nil
+	}
+	p.reportedSong = status.Song
+	p.reportedPosition = status.SongPast.Seconds()
+	if state.Event != interfaces.EventStart || previous == nil {
+		return nil
+	}
+
+	stop := *state
+	stop.Event = interfaces.EventStop
+	stop.ItemId = previous.Id.String()
+	stop.PlaylistLength = previous.Duration
+	if finished {
+		position = previous.Duration
+	}
+	stop.Position = position
+	stop.PlayedToCompletion = finished || playedEnough(position, previous.Duration)
+	return []*interfaces.ApiPlaybackState{&stop}
+}
+
+// playedEnough returns true if enough of song was played for it to count as played.
+func playedEnough(position, duration int) bool {
+	return duration > 0 && float64(position) >= float64(duration)*config.PlayedToCompletionRatio
+}
+
+// SetProgressReporter sets reporter that playback progress is reported to instead of server.
+func (p *Player) SetProgressReporter(reporter interfaces.ProgressReporter) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.reporter = reporter
 }
 
 func (p *Player) queueChanged(queue []*models.Song) {
+	p.fillQueue(queue)
+	p.checkPreload(queue)
+	if p.prefetch != nil {
+		p.prefetch.queueChanged(queue)
+	}
 	// if player has nothing to play, start download
 	state := p.Audio.getStatus()
 	if state.State == models.AudioStateStopped && len(queue) > 0 {
 		go p.downloadSong(0)
+	} else if state.State == models.AudioStatePlaying {
+		// keep server queue up to date
+		state.Action = models.AudioActionQueueChanged
+		p.audioCallback(state)
+		go p.preloadNext()
+	}
+}
+
+// JumpTo plays song at index right away. Songs before it are removed from queue.
+func (p *Player) JumpTo(index int) bool {
+	if index < 1 || index >= len(p.Queue.GetQueue()) {
+		return false
+	}
+	if !p.Queue.removeRange(1, index) {
+		return false
 	}
+	p.Next()
+	return true
 }
 
 func (p *Player) Reorder(index int, left bool) bool {
@@ -354,6 +720,7 @@ func (p *Player) Reorder(index int, left bool) bool {
 }
 
 func (p *Player) SetShuffle(enabled bool) {
-	p.Queue.SetShuffle(enabled)
+	// update status first, so that queue report after shuffling has correct shuffle mode
 	p.Audio.SetShuffle(enabled)
+	p.Queue.SetShuffle(enabled)
 }
@@ -0,0 +1,368 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package player
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"tryffel.net/go/jellycli/api"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/models"
+)
+
+// fakeApi records progress reports. Streaming always fails.
+type fakeApi struct {
+	lock    sync.Mutex
+	reports []*interfaces.ApiPlaybackState
+	// received has time of each report
+	received []time.Time
+	// fail is number of reports to fail before succeeding
+	fail int
+}
+
+func (f *fakeApi) ReportProgress(state *interfaces.ApiPlaybackState) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.reports = append(f.reports, state)
+	f.received = append(f.received, time.Now())
+	if f.fail > 0 {
+		f.fail -= 1
+		return errors.New("server unavailable")
+	}
+	return nil
+}
+
+func (f *fakeApi) getReports() []*interfaces.ApiPlaybackState {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]*interfaces.ApiPlaybackState{}, f.reports...)
+}
+
+// waitReports waits until n reports have been received.
+func (f *fakeApi) waitReports(t *testing.T, n int) []*interfaces.ApiPlaybackState {
+	t.Helper()
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) {
+		if reports := f.getReports(); len(reports) >= n {
+			return reports
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	t.Fatalf("expected %d reports, got %d", n, len(f.getReports()))
+	return nil
+}
+
+func (f *fakeApi) GetSongDirect(id string, codec string) (io.ReadCloser, interfaces.AudioFormat, error) {
+	return nil, interfaces.AudioFormatNil, errors.New("not supported")
+}
+
+func (f *fakeApi) Stream(song *models.Song) (io.ReadCloser, interfaces.AudioFormat, error) {
+	return nil, interfaces.AudioFormatNil, errors.New("not supported")
+}
+
+func (f *fakeApi) GetConfig() config.Backend { return nil }
+func (f *fakeApi) ConnectionOk() error       { return nil }
+func (f *fakeApi) Start() error              { return nil }
+func (f *fakeApi) Stop() error               { return nil }
+
+const testSampleRate = 44100
+
+// testHistoryLimit is small enough for tests to fill history
+const testHistoryLimit = 10
+
+// TestMain sets configuration for all tests. It's not changed later, since players of previous tests
+// may still be reading it.
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "jellycli-test")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	config.AppConfig = &config.Config{
+		ClientID: "jellycli-test",
+		Player: config.Player{
+			LocalCacheDir:         dir,
+			Output:                "pipe:" + filepath.Join(dir, "output.pcm"),
+			OutputSampleRate:      testSampleRate,
+			ResampleQuality:       1,
+			AudioBufferingMs:      150,
+			HttpBufferingS:        5,
+			HttpBufferingLimitMem: 20,
+			StreamRetries:         3,
+			HistoryLimit:          testHistoryLimit,
+		},
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newTestPlayer returns player without audio output.
+func newTestPlayer(t *testing.T, browser interfaces.Api) *Player {
+	t.Helper()
+	p := &Player{
+		lock:           &sync.RWMutex{},
+		songComplete:   make(chan bool, 3),
+		audioUpdated:   make(chan models.AudioStatus, 3),
+		songDownloaded: make(chan songMetadata, 3),
+		wake:           make(chan bool, 1),
+		api:            browser,
+	}
+	p.progress = newProgressReporter(p.progressTarget)
+	p.Audio = newAudio()
+	p.Queue = newQueue()
+	p.resume = newResumePoints()
+	p.offline = api.NewOfflineStore("")
+	p.Queue.AddQueueChangedCallback(p.queueChanged)
+	return p
+}
+
+func testSongs(ids ...string) []*models.Song {
+	songs := make([]*models.Song, len(ids))
+	for i, v := range ids {
+		songs[i] = &models.Song{Id: models.Id(v), Name: "song " + v, Duration: 100}
+	}
+	return songs
+}
+
+func TestPlayer_AddSongsWhilePlaying(t *testing.T) {
+	browser := &fakeApi{}
+	p := newTestPlayer(t, browser)
+	songs := testSongs("a", "b", "c")
+	p.Queue.AddSongs(songs[:1])
+	p.Audio.status.State = models.AudioStatePlaying
+	p.Audio.status.Song = songs[0]
+
+	done := make(chan bool)
+	go func() {
+		p.Queue.AddSongs(songs[1:2])
+		p.Queue.PlayNext(songs[2:])
+		p.Queue.ClearQueue(false)
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("queue change while playing did not return")
+	}
+
+	reports := browser.waitReports(t, 3)
+	want := [][]models.Id{{"a", "b"}, {"a", "c", "b"}, {"a"}}
+	for i, v := range want {
+		if len(reports[i].Queue) != len(v) {
+			t.Fatalf("report %d: expected queue %v, got %v", i, v, reports[i].Queue)
+		}
+		for j := range v {
+			if reports[i].Queue[j] != v[j] {
+				t.Errorf("report %d: expected queue %v, got %v", i, v, reports[i].Queue)
+			}
+		}
+	}
+}
+
+func TestPlayer_ReportedQueue(t *testing.T) {
+	p := newTestPlayer(t, &fakeApi{})
+	songs := testSongs("a", "b", "c", "d")
+	p.Queue.AddSongs(songs)
+	// a and b are played
+	p.Queue.songComplete()
+	p.Queue.songComplete()
+
+	tests := []struct {
+		name    string
+		current *models.Song
+		index   int
+	}{
+		{name: "playing", current: songs[2], index: 2},
+		{name: "stopped", current: nil, index: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue, index := p.reportedQueue(tt.current)
+			want := []models.Id{"a", "b", "c", "d"}
+			if len(queue) != len(want) {
+				t.Fatalf("expected queue %v, got %v", want, queue)
+			}
+			for i := range want {
+				if queue[i] != want[i] {
+					t.Fatalf("expected queue %v, got %v", want, queue)
+				}
+			}
+			if index != tt.index {
+				t.Errorf("expected index %d, got %d", tt.index, index)
+			}
+		})
+	}
+}
+
+func TestPlayer_ReportedEvents(t *testing.T) {
+	songs := testSongs("a", "b")
+	type step struct {
+		action models.AudioAction
+		state  models.AudioState
+		song   *models.Song
+		// past is position in song
+		past   models.AudioTick
+		paused bool
+		// finished marks previous song as played to end
+		finished bool
+	}
+	type report struct {
+		event     interfaces.ApiPlaybackEvent
+		item      string
+		position  int
+		paused    bool
+		completed bool
+	}
+	play := func(song *models.Song, past models.AudioTick) step {
+		return step{action: models.AudioActionPlay, state: models.AudioStatePlaying, song: song, past: past}
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+		want  []report
+	}{
+		{
+			name:  "start",
+			steps: []step{play(songs[0], 0)},
+			want:  []report{{event: interfaces.EventStart, item: "a"}},
+		},
+		{
+			name: "pause",
+			steps: []step{
+				play(songs[0], 0),
+				{action: models.AudioActionPlayPause, state: models.AudioStatePlaying, song: songs[0], past: 10000, paused: true},
+				{action: models.AudioActionPlayPause, state: models.AudioStatePlaying, song: songs[0], past: 10000},
+			},
+			want: []report{
+				{event: interfaces.EventStart, item: "a"},
+				{event: interfaces.EventPause, item: "a", position: 10, paused: true},
+				{event: interfaces.EventUnpause, item: "a", position: 10},
+			},
+		},
+		{
+			name: "stop before threshold",
+			steps: []step{
+				play(songs[0], 0),
+				{action: models.AudioActionStop, state: models.AudioStateStopped, song: songs[0], past: 89999},
+			},
+			want: []report{
+				{event: interfaces.EventStart, item: "a"},
+				{event: interfaces.EventStop, item: "a", position: 89},
+			},
+		},
+		{
+			name: "stop at threshold",
+			steps: []step{
+				play(songs[0], 0),
+				{action: models.AudioActionStop, state: models.AudioStateStopped, song: songs[0], past: 90000},
+			},
+			want: []report{
+				{event: interfaces.EventStart, item: "a"},
+				{event: interfaces.EventStop, item: "a", position: 90, completed: true},
+			},
+		},
+		{
+			name:  "next before threshold",
+			steps: []step{play(songs[0], 89999), play(songs[1], 0)},
+			want: []report{
+				{event: interfaces.EventStart, item: "a", position: 89},
+				{event: interfaces.EventStop, item: "a", position: 89},
+				{event: interfaces.EventStart, item: "b"},
+			},
+		},
+		{
+			name:  "next at threshold",
+			steps: []step{play(songs[0], 90000), play(songs[1], 0)},
+			want: []report{
+				{event: interfaces.EventStart, item: "a", position: 90},
+				{event: interfaces.EventStop, item: "a", position: 90, completed: true},
+				{event: interfaces.EventStart, item: "b"},
+			},
+		},
+		{
+			name: "next after song finished",
+			steps: []step{
+				play(songs[0], 0),
+				{action: models.AudioActionPlay, state: models.AudioStatePlaying, song: songs[1], finished: true},
+			},
+			want: []report{
+				{event: interfaces.EventStart, item: "a"},
+				{event: interfaces.EventStop, item: "a", position: 100, completed: true},
+				{event: interfaces.EventStart, item: "b"},
+			},
+		},
+		{
+			name: "start after stop",
+			steps: []step{
+				play(songs[0], 0),
+				{action: models.AudioActionStop, state: models.AudioStateStopped, song: songs[0], past: 50000},
+				play(songs[1], 0),
+			},
+			want: []report{
+				{event: interfaces.EventStart, item: "a"},
+				{event: interfaces.EventStop, item: "a", position: 50},
+				{event: interfaces.EventStart, item: "b"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			browser := &fakeApi{}
+			p := newTestPlayer(t, browser)
+			for _, v := range tt.steps {
+				if v.finished {
+					p.lock.Lock()
+					p.songFinished = true
+					p.lock.Unlock()
+				}
+				p.audioCallback(models.AudioStatus{
+					State:    v.state,
+					Action:   v.action,
+					Song:     v.song,
+					SongPast: v.past,
+					Paused:   v.paused,
+				})
+			}
+
+			reports := browser.waitReports(t, len(tt.want))
+			if len(reports) != len(tt.want) {
+				t.Fatalf("expected %d reports, got %d", len(tt.want), len(reports))
+			}
+			for i, v := range tt.want {
+				got := reports[i]
+				if got.Event != v.event || got.ItemId != v.item || got.Position != v.position ||
+					got.IsPaused != v.paused || got.PlayedToCompletion != v.completed {
+					t.Errorf("report %d: expected %s %s at %d paused %t completed %t, got %s %s at %d paused %t completed %t",
+						i, v.event, v.item, v.position, v.paused, v.completed,
+						got.Event, got.ItemId, got.Position, got.IsPaused, got.PlayedToCompletion)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+)
+
+// itemGetter fetches items by id, e.g. api.Library.
+type itemGetter interface {
+	GetItems(ids []models.Id) ([]models.Item, error)
+}
+
+// prefetcher fetches albums and artists of upcoming songs in background, so that they are available
+// without delay when song starts. Requests are made by a small pool of workers and rate limited.
+type prefetcher struct {
+	lock   sync.Mutex
+	getter itemGetter
+	items  map[models.Id]models.Item
+	// pending are ids being fetched
+	pending map[models.Id]bool
+	jobs    chan []models.Id
+	limiter *time.Ticker
+}
+
+func newPrefetcher(getter itemGetter) *prefetcher {
+	f := &prefetcher{
+		getter:  getter,
+		items:   map[models.Id]models.Item{},
+		pending: map[models.Id]bool{},
+		jobs:    make(chan []models.Id, config.PrefetchQueueSize),
+		limiter: time.NewTicker(config.PrefetchInterval),
+	}
+	for i := 0; i < config.PrefetchWorkers; i++ {
+		go f.work()
+	}
+	return f
+}
+
+// queueChanged schedules fetching metadata of upcoming songs and drops metadata no longer needed.
+func (f *prefetcher) queueChanged(queue []*models.Song) {
+	if len(queue) > config.PrefetchSongs {
+		queue = queue[:config.PrefetchSongs]
+	}
+	wanted := map[models.Id]bool{}
+	ids := []models.Id{}
+	add := func(id models.Id) {
+		if id != "" && !wanted[id] {
+			wanted[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for _, v := range queue {
+		add(v.Album)
+		if len(v.Artists) > 0 {
+			add(v.Artists[0].Id)
+		}
+	}
+
+	f.lock.Lock()
+	for id := range f.items {
+		if !wanted[id] {
+			delete(f.items, id)
+		}
+	}
+	missing := []models.Id{}
+	for _, id := range ids {
+		if _, ok := f.items[id]; !ok && !f.pending[id] {
+			missing = append(missing, id)
+			f.pending[id] = true
+		}
+	}
+	f.lock.Unlock()
+
+	for from := 0; from < len(missing); from += config.PrefetchBatchSize {
+		to := from + config.PrefetchBatchSize
+		if to > len(missing) {
+			to = len(missing)
+		}
+		select {
+		case f.jobs <- missing[from:to]:
+		default:
+			// workers are busy, rest is scheduled on next queue change
+			f.done(missing[from:])
+			return
+		}
+	}
+}
+
+func (f *prefetcher) work() {
+	for ids := range f.jobs {
+		<-f.limiter.C
+		items, err := f.getter.GetItems(ids)
+		if err != nil {
+			logrus.Warningf("prefetch song metadata: %v", err)
+		}
+		f.lock.Lock()
+		for _, v := range items {
+			f.items[v.GetId()] = v
+		}
+		f.lock.Unlock()
+		f.done(ids)
+	}
+}
+
+// done marks ids as no longer pending.
+func (f *prefetcher) done(ids []models.Id) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, id := range ids {
+		delete(f.pending, id)
+	}
+}
+
+// album returns album, if it has been fetched.
+func (f *prefetcher) album(id models.Id) *models.Album {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	album, _ := f.items[id].(*models.Album)
+	return album
+}
+
+// artist returns artist, if it has been fetched.
+func (f *prefetcher) artist(id models.Id) *models.Artist {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	artist, _ := f.items[id].(*models.Artist)
+	return artist
+}
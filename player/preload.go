@@ -0,0 +1,216 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/models"
+)
+
+// newSongMetadata returns metadata for playing song from reader. Album and artist are taken from
+// prefetched metadata, or placeholders are used if they have not been fetched.
+func (p *Player) newSongMetadata(song *models.Song, reader io.ReadCloser, format interfaces.AudioFormat) songMetadata {
+	metadata := songMetadata{
+		song:   song,
+		album:  &models.Album{Name: "unknown album"},
+		artist: &models.Artist{Name: "unknown artist"},
+		reader: reader,
+		format: format,
+	}
+	if p.prefetch != nil {
+		if album := p.prefetch.album(song.Album); album != nil {
+			metadata.album = album
+			metadata.albumImageId = album.ImageId
+		}
+		if len(song.Artists) > 0 {
+			if artist := p.prefetch.artist(song.Artists[0].Id); artist != nil {
+				metadata.artist = artist
+			}
+		}
+	}
+	if p.art != nil {
+		metadata.albumImageUrl = p.art.url(song.Album, metadata.albumImageId)
+	}
+	return metadata
+}
+
+// playSong plays song and starts preloading the next one.
+func (p *Player) playSong(metadata songMetadata) {
+	err := p.Audio.playSongFromReader(metadata)
+	if err != nil {
+		logrus.Errorf("play track: %v", err)
+		return
+	}
+	go p.preloadNext()
+}
+
+// preloadNext opens next song in queue, so that its download runs alongside current song and it starts
+// without delay, also if user skips to it. Song is not preloaded if it would not fit in
+// config.Player.HttpBufferingLimitMem. In low memory mode song is downloaded to disk instead.
+func (p *Player) preloadNext() {
+	queue := p.Queue.GetQueue()
+	if len(queue) < 2 {
+		return
+	}
+	song := queue[1]
+	p.lock.Lock()
+	if p.preloading || (p.preloaded != nil && p.preloaded.song.Id == song.Id) {
+		p.lock.Unlock()
+		return
+	}
+	p.preloading = true
+	p.lock.Unlock()
+	defer func() {
+		p.lock.Lock()
+		p.preloading = false
+		p.lock.Unlock()
+	}()
+
+	lowMemory := config.AppConfig.Player.LowMemory
+	if !lowMemory && !fitsPreload(song) {
+		logrus.Debugf("Song %s is too large to preload", song.Name)
+		return
+	}
+	reader, format, err := p.stream(song)
+	if err == nil && lowMemory {
+		reader, err = preloadToDisk(song, reader, format)
+	}
+	if err != nil {
+		logrus.Warningf("preload song %s: %v", song.Name, err)
+		return
+	}
+	logrus.Debugf("Preload song %s", song.Name)
+	metadata := p.newSongMetadata(song, reader, format)
+	p.setPreload(&metadata)
+	// queue may have changed during request
+	p.checkPreload(p.Queue.GetQueue())
+}
+
+// fitsPreload returns true if estimated size of song stream fits in memory limit. Songs with unknown
+// bitrate are assumed to fit.
+func fitsPreload(song *models.Song) bool {
+	bitrate := song.Bitrate
+	if limit := config.AppConfig.Player.MaxStreamingBitrate; limit > 0 && (bitrate == 0 || bitrate > limit) {
+		bitrate = limit
+	}
+	if bitrate == 0 {
+		return true
+	}
+	size := bitrate * 1000 / 8 * song.Duration
+	return size <= config.HttpBufferingLimitMem()*1024*1024
+}
+
+// setPreload sets preloaded song, closing previous one.
+func (p *Player) setPreload(metadata *songMetadata) {
+	p.lock.Lock()
+	old := p.preloaded
+	p.preloaded = metadata
+	p.lock.Unlock()
+	if old != nil && old != metadata {
+		closePreload(old)
+	}
+}
+
+// checkPreload cancels preloaded song if it is no longer current or next song in queue.
+func (p *Player) checkPreload(queue []*models.Song) {
+	p.lock.Lock()
+	metadata := p.preloaded
+	if metadata == nil {
+		p.lock.Unlock()
+		return
+	}
+	for i := 0; i < len(queue) && i < 2; i++ {
+		if queue[i].Id == metadata.song.Id {
+			p.lock.Unlock()
+			return
+		}
+	}
+	p.preloaded = nil
+	p.lock.Unlock()
+	logrus.Debugf("Cancel preloading song %s", metadata.song.Name)
+	closePreload(metadata)
+}
+
+// takePreload returns preloaded song if it is given song, else nil. Returned song is no longer preloaded.
+func (p *Player) takePreload(song *models.Song) *songMetadata {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	metadata := p.preloaded
+	if metadata == nil || metadata.song.Id != song.Id {
+		return nil
+	}
+	p.preloaded = nil
+	return metadata
+}
+
+// preloadDir returns directory for songs preloaded to disk.
+func preloadDir() string {
+	return filepath.Join(config.AppConfig.Player.LocalCacheDir, "preload")
+}
+
+// preloadToDisk downloads reader to temporary file, which is removed when returned reader is closed.
+// Reader is closed.
+func preloadToDisk(song *models.Song, reader io.ReadCloser, format interfaces.AudioFormat) (io.ReadCloser, error) {
+	defer reader.Close()
+	err := os.MkdirAll(preloadDir(), 0700)
+	if err != nil {
+		return nil, err
+	}
+	file, err := ioutil.TempFile(preloadDir(), song.Id.String()+"-*."+format.String())
+	if err != nil {
+		return nil, err
+	}
+	tmp := &tempFile{File: file}
+	if _, err = io.Copy(file, reader); err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("download to disk: %v", err)
+	}
+	return tmp, nil
+}
+
+// tempFile is a file that is removed when it is closed.
+type tempFile struct {
+	*os.File
+}
+
+func (t *tempFile) Close() error {
+	err := t.File.Close()
+	if removeErr := os.Remove(t.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+func closePreload(metadata *songMetadata) {
+	err := metadata.reader.Close()
+	if err != nil && err != io.EOF {
+		logrus.Errorf("close preloaded song: %v", err)
+	}
+}
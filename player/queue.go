@@ -24,6 +24,7 @@ import (
 	"sort"
 	"sync"
 	"time"
+	"tryffel.net/go/jellycli/config"
 	// "tryffel.net/go/jellycli/interfaces" // Removed unused import
 	"tryffel.net/go/jellycli/models"
 )
@@ -48,6 +49,10 @@ type queueList struct {
 
 	// is shuffling enabled
 	shuffle bool
+
+	// seed of rng. Same seed shuffles same queue always in same order.
+	seed int64
+	rng  *rand.Rand
 }
 
 func (q *queueList) Less(i, j int) bool {
@@ -68,27 +73,39 @@ func newQueueList() *queueList {
 		shuffle:  false,
 		items:    make([]*queueItem, 0),
 	}
+	q.setSeed(time.Now().UnixNano())
 	return q
 }
 
+func (q *queueList) setSeed(seed int64) {
+	q.seed = seed
+	q.rng = rand.New(rand.NewSource(seed))
+}
+
 func (q *queueList) Len() int {
 	return len(q.items)
 }
 
+// SetShuffling enables or disables shuffling. Current song always stays first. Disabling shuffle restores
+// original order of remaining songs, since index is never changed by shuffling.
 func (q *queueList) SetShuffling(enable bool) {
 	if enable == q.shuffle {
 		return
 	}
 	q.shuffle = enable
+	if len(q.items) == 0 {
+		return
+	}
 
-	if enable && len(q.items) > 0 {
-		// make sure 1st stays 1st after shuffling
+	if enable {
 		q.items[0].priority = 0
 		for _, v := range q.items[1:] {
-			v.priority = rand.Int()
+			v.priority = q.rng.Int()
 		}
 	}
-	sort.Sort(q)
+	// sort only upcoming songs, sub-list shares items with q
+	rest := &queueList{items: q.items[1:], shuffle: enable}
+	sort.Sort(rest)
 }
 
 // Clear. First: whether to clear first item too
@@ -106,11 +123,12 @@ func (q *queueList) Clear(first bool) {
 
 func (q *queueList) AddSong(song *models.Song, playNext bool, playFirst bool) {
 	index := q.maxIndex
-	priority := rand.Int()
+	priority := q.rng.Int()
 	needsSort := false
 
 	if len(q.items) == 0 {
 	} else if q.shuffle && playFirst {
+		index = q.items[0].index - 1
 		priority = q.items[0].priority - 1
 		needsSort = true
 	} else if playFirst {
@@ -118,6 +136,9 @@ func (q *queueList) AddSong(song *models.Song, playNext bool, playFirst bool) {
 	} else if playNext {
 		index = q.items[0].index
 		q.items[0].index -= 1
+		// next also when shuffled
+		priority = q.items[0].priority
+		q.items[0].priority -= 1
 	} else {
 		// normal insertion
 	}
@@ -128,7 +149,7 @@ func (q *queueList) AddSong(song *models.Song, playNext bool, playFirst bool) {
 		priority: priority,
 	}
 
-	if len(q.items) == 0 || q.shuffle {
+	if len(q.items) == 0 || (q.shuffle && !playNext) {
 		q.items = append(q.items, item)
 	} else if playNext {
 		temp := append([]*queueItem{q.items[0]}, item)
@@ -175,11 +196,11 @@ func (q *queueList) GetQueue() []*models.Song {
 }
 
 func (q *queueList) GetTotalDuration() models.AudioTick {
-	ms := 0
+	sec := 0
 	for _, v := range q.items {
-		ms += v.song.Duration
+		sec += v.song.Duration
 	}
-	return models.AudioTick(ms)
+	return models.AudioTick(sec * 1000)
 }
 
 func (q *queueList) Reorder(index1 int, down bool) {
@@ -199,20 +220,47 @@ func (q *queueList) Reorder(index1 int, down bool) {
 	}
 }
 
+// Move moves item at index to newIndex. Items keep their sort keys in order, so
+// moved item stays in place when list is sorted again, whether shuffled or not.
+func (q *queueList) Move(index, newIndex int) {
+	keys := make([]int, len(q.items))
+	for i, v := range q.items {
+		if q.shuffle {
+			keys[i] = v.priority
+		} else {
+			keys[i] = v.index
+		}
+	}
+	item := q.items[index]
+	q.items = append(q.items[:index], q.items[index+1:]...)
+	q.items = append(q.items[:newIndex], append([]*queueItem{item}, q.items[newIndex:]...)...)
+	for i, v := range q.items {
+		if q.shuffle {
+			v.priority = keys[i]
+		} else {
+			v.index = keys[i]
+		}
+	}
+}
+
 // Queue implements interfaces.QueueController
 type Queue struct {
 	lock               sync.RWMutex
 	list               *queueList
-	history            []*models.Song
+	history            []*models.HistoryItem
 	queueUpdatedFunc   []func([]*models.Song)
 	historyUpdatedFunc func([]*models.Song)
+
+	// session is current history session
+	session int
 }
 
 func newQueue() *Queue {
 	q := &Queue{
 		list:             newQueueList(),
-		history:          []*models.Song{},
+		history:          []*models.HistoryItem{},
 		queueUpdatedFunc: make([]func([]*models.Song), 0),
+		session:          1,
 	}
 	return q
 }
@@ -224,26 +272,33 @@ func (q *Queue) GetQueue() []*models.Song {
 	return q.list.GetQueue()
 }
 
+// GetTotalDuration returns total duration of all songs in queue, including current song.
+func (q *Queue) GetTotalDuration() models.AudioTick {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	return q.list.GetTotalDuration()
+}
+
 // ClearQueue clears queue. This also calls QueueChangedCallback.
 func (q *Queue) ClearQueue(first bool) {
 	q.lock.Lock()
-	defer q.lock.Unlock()
-	defer q.notifyQueueUpdated()
 	q.list.Clear(first)
+	q.lock.Unlock()
+	q.notifyQueueUpdated()
 }
 
 // AddSongs adds songs to the end of queue.
 // Adding songs calls QueueChangedCallback.
 func (q *Queue) AddSongs(songs []*models.Song) {
 	q.lock.Lock()
-	defer q.lock.Unlock()
-	defer q.notifyQueueUpdated()
-
 	for _, v := range songs {
 		q.list.AddSong(v, false, false)
 	}
 
 	logrus.Debug("Adding songs to queue, current size: ", q.list.Len())
+	// callbacks read queue, so they must run without holding lock
+	q.lock.Unlock()
+	q.notifyQueueUpdated()
 }
 
 func (q *Queue) PlayNext(songs []*models.Song) {
@@ -270,6 +325,38 @@ func (q *Queue) RemoveSong(index int) {
 	}
 }
 
+// MoveSong moves song at index to newIndex. Current song cannot be moved, nor can other song be moved
+// before it. Returns true if song was moved.
+func (q *Queue) MoveSong(index, newIndex int) bool {
+	q.lock.Lock()
+	changed := false
+	n := q.list.Len()
+	if index >= 1 && index < n && newIndex >= 1 && newIndex < n && index != newIndex {
+		q.list.Move(index, newIndex)
+		changed = true
+	}
+	q.lock.Unlock()
+	if changed {
+		q.notifyQueueUpdated()
+	}
+	return changed
+}
+
+// removeRange removes songs in range [from, to). Current song is never removed.
+func (q *Queue) removeRange(from, to int) bool {
+	q.lock.Lock()
+	changed := false
+	if from >= 1 && to <= q.list.Len() && from < to {
+		q.list.items = append(q.list.items[:from], q.list.items[to:]...)
+		changed = true
+	}
+	q.lock.Unlock()
+	if changed {
+		q.notifyQueueUpdated()
+	}
+	return changed
+}
+
 // Reorder sets item in index currentIndex to newIndex.
 // If either currentIndex or NewIndex is not valid, do nothing.
 // On successful order QueueChangedCallback gets called.
@@ -305,9 +392,21 @@ func (q *Queue) GetHistory(n int) []*models.Song {
 	q.lock.RLock()
 	defer q.lock.RUnlock()
 	if n > len(q.history) {
-		return q.history
+		n = len(q.history)
+	}
+	return historySongs(q.history[:n])
+}
+
+// GetHistoryItems gets n past songs with time played and session. If n < 0, return all items.
+func (q *Queue) GetHistoryItems(n int) []*models.HistoryItem {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	if n < 0 || n > len(q.history) {
+		n = len(q.history)
 	}
-	return q.history[:n]
+	items := make([]*models.HistoryItem, n)
+	copy(items, q.history)
+	return items
 }
 
 // AddQueueChangedCallback adds function that is called every time queue changes.
@@ -333,24 +432,58 @@ func (q *Queue) notifyQueueUpdated() {
 
 func (q *Queue) notifyHistoryUpdated() {
 	if q.historyUpdatedFunc != nil {
-		q.historyUpdatedFunc(q.history)
+		q.historyUpdatedFunc(historySongs(q.history))
 	}
 }
 
+func historySongs(items []*models.HistoryItem) []*models.Song {
+	songs := make([]*models.Song, len(items))
+	for i, v := range items {
+		songs[i] = v.Song
+	}
+	return songs
+}
+
 // remove first song from queue and move to history
+// dropFirst removes first song without adding it to history, e.g. song that cannot be played.
+func (q *Queue) dropFirst() {
+	q.lock.Lock()
+	if q.list.Len() == 0 {
+		q.lock.Unlock()
+		return
+	}
+	q.list.RemoveSong(0)
+	q.lock.Unlock()
+	q.notifyQueueUpdated()
+}
+
 func (q *Queue) songComplete() {
 	q.lock.Lock()
 	defer q.notifyQueueUpdated()
 	defer q.notifyHistoryUpdated()
 	if q.list.Len() == 0 {
+		q.lock.Unlock()
 		return
 	}
 
 	song := q.list.RemoveSong(0)
-	if q.history == nil {
-		q.history = []*models.Song{song}
-	} else {
-		q.history = append([]*models.Song{song}, q.history...)
+	now := time.Now()
+	if len(q.history) > 0 {
+		// idle time between previous song and this one
+		started := now.Add(-time.Duration(song.Duration) * time.Second)
+		gap := time.Duration(config.AppConfig.Player.HistorySessionGapMin) * time.Minute
+		if started.Sub(q.history[0].PlayedAt) > gap {
+			q.session += 1
+		}
+	}
+	item := &models.HistoryItem{
+		Song:     song,
+		PlayedAt: now,
+		Session:  q.session,
+	}
+	q.history = append([]*models.HistoryItem{item}, q.history...)
+	if limit := config.AppConfig.Player.HistoryLimit; limit > 0 && len(q.history) > limit {
+		q.history = q.history[:limit]
 	}
 	q.lock.Unlock()
 }
@@ -364,7 +497,7 @@ func (q *Queue) playLastSong() {
 		q.lock.Unlock()
 		return
 	}
-	song := q.history[0]
+	song := q.history[0].Song
 	q.list.AddSong(song, false, true)
 	if q.history == nil {
 		q.history = q.history[1:]
@@ -392,6 +525,97 @@ func (q *Queue) SetShuffle(enabled bool) {
 	q.notifyQueueUpdated()
 }
 
+// GetShuffleSeed returns seed of random source used for shuffling.
+func (q *Queue) GetShuffleSeed() int64 {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	return q.list.seed
+}
+
+// SetShuffleSeed resets random source used for shuffling. Shuffling same queue with same seed always results
+// in same order. If shuffle is enabled, upcoming songs are shuffled again.
+func (q *Queue) SetShuffleSeed(seed int64) {
+	q.lock.Lock()
+	q.list.setSeed(seed)
+	shuffled := q.list.shuffle
+	if shuffled {
+		q.list.SetShuffling(false)
+		q.list.SetShuffling(true)
+	}
+	q.lock.Unlock()
+	if shuffled {
+		q.notifyQueueUpdated()
+	}
+}
+
+// queueState is a snapshot of queue and history that can be saved and restored.
+type queueState struct {
+	Items    []savedQueueItem      `json:"items"`
+	MaxIndex int                   `json:"max_index"`
+	Shuffle  bool                  `json:"shuffle"`
+	Seed     int64                 `json:"shuffle_seed"`
+	History  []*models.HistoryItem `json:"history"`
+	Session  int                   `json:"session"`
+}
+
+type savedQueueItem struct {
+	Song     *models.Song `json:"song"`
+	Index    int          `json:"index"`
+	Priority int          `json:"priority"`
+}
+
+func (q *Queue) getState() *queueState {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	state := &queueState{
+		Items:    make([]savedQueueItem, len(q.list.items)),
+		MaxIndex: q.list.maxIndex,
+		Shuffle:  q.list.shuffle,
+		Seed:     q.list.seed,
+		Session:  q.session,
+	}
+	// persistent history is saved separately
+	if !config.AppConfig.Player.PersistHistory {
+		state.History = q.history
+	}
+	for i, v := range q.list.items {
+		state.Items[i] = savedQueueItem{Song: v.song, Index: v.index, Priority: v.priority}
+	}
+	return state
+}
+
+// setState replaces queue and history with state. Restored state starts a new history session.
+func (q *Queue) setState(state *queueState) {
+	q.lock.Lock()
+	q.list.items = make([]*queueItem, len(state.Items))
+	for i, v := range state.Items {
+		q.list.items[i] = &queueItem{song: v.Song, index: v.Index, priority: v.Priority}
+	}
+	q.list.maxIndex = state.MaxIndex
+	q.list.shuffle = state.Shuffle
+	if state.Seed != 0 {
+		q.list.setSeed(state.Seed)
+	}
+	if state.History != nil {
+		q.history = state.History
+	}
+	q.session = state.Session + 1
+	q.lock.Unlock()
+	q.notifyHistoryUpdated()
+	q.notifyQueueUpdated()
+}
+
+// setHistory replaces history with items, latest first. Next song starts a new session.
+func (q *Queue) setHistory(items []*models.HistoryItem) {
+	q.lock.Lock()
+	q.history = items
+	if len(items) > 0 && items[0].Session >= q.session {
+		q.session = items[0].Session + 1
+	}
+	q.lock.Unlock()
+	q.notifyHistoryUpdated()
+}
+
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
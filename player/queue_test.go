@@ -0,0 +1,126 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package player
+
+import (
+	"fmt"
+	"testing"
+	"testing/quick"
+
+	"tryffel.net/go/jellycli/models"
+)
+
+// newTestQueueList returns list of n songs with ids 0..n-1, shuffled with seed if shuffle is set.
+func newTestQueueList(seed int64, n int, shuffle bool) *queueList {
+	q := newQueueList()
+	q.setSeed(seed)
+	for i := 0; i < n; i++ {
+		q.AddSong(&models.Song{Id: models.Id(fmt.Sprint(i))}, false, false)
+	}
+	q.SetShuffling(shuffle)
+	return q
+}
+
+func queueIds(q *queueList) []models.Id {
+	ids := make([]models.Id, len(q.items))
+	for i, v := range q.items {
+		ids[i] = v.song.Id
+	}
+	return ids
+}
+
+func TestQueueList_ShuffleSameSeed(t *testing.T) {
+	property := func(seed int64, n uint8) bool {
+		a := newTestQueueList(seed, int(n), true)
+		b := newTestQueueList(seed, int(n), true)
+		return sameIds(queueIds(a), queueIds(b))
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQueueList_ShuffleIsPermutation(t *testing.T) {
+	property := func(seed int64, n uint8) bool {
+		ids := queueIds(newTestQueueList(seed, int(n), true))
+		if len(ids) != int(n) {
+			return false
+		}
+		// current song is not shuffled
+		if n > 0 && ids[0] != "0" {
+			return false
+		}
+		seen := map[models.Id]bool{}
+		for _, v := range ids {
+			if seen[v] {
+				return false
+			}
+			seen[v] = true
+		}
+		for i := 0; i < int(n); i++ {
+			if !seen[models.Id(fmt.Sprint(i))] {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQueueList_UnshuffleRestoresOrder(t *testing.T) {
+	property := func(seed int64, n uint8) bool {
+		q := newTestQueueList(seed, int(n), true)
+		q.SetShuffling(false)
+		return sameIds(queueIds(q), queueIds(newTestQueueList(seed, int(n), false)))
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQueue_PlayNextWhileShuffled(t *testing.T) {
+	property := func(seed int64, n uint8) bool {
+		q := newQueue()
+		q.SetShuffleSeed(seed)
+		songs := make([]*models.Song, int(n)+1)
+		for i := range songs {
+			songs[i] = &models.Song{Id: models.Id(fmt.Sprint(i))}
+		}
+		q.AddSongs(songs)
+		q.SetShuffle(true)
+		q.PlayNext([]*models.Song{{Id: "next-1"}, {Id: "next-2"}})
+		q.PlayNext([]*models.Song{{Id: "next-0"}})
+
+		want := []models.Id{"0", "next-0", "next-1", "next-2"}
+		for _, shuffled := range []bool{true, false} {
+			q.SetShuffle(shuffled)
+			queue := q.GetQueue()
+			for i, v := range want {
+				if queue[i].Id != v {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,124 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package player
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/interfaces"
+)
+
+// progressReporter sends playback reports to server from a single worker, so that they arrive in
+// order. Time updates are rate limited and identical consecutive time updates are dropped. Failed
+// reports are retried with backoff before they are dropped.
+type progressReporter struct {
+	lock sync.Mutex
+	// target returns reporter to send reports to
+	target  func() interfaces.ProgressReporter
+	reports chan *interfaces.ApiPlaybackState
+	// lastReport is when last report was queued
+	lastReport time.Time
+	// last is last queued report
+	last *interfaces.ApiPlaybackState
+	// retries and retryDelay are config.ReportRetries and config.ReportRetryDelay
+	retries    int
+	retryDelay time.Duration
+}
+
+func newProgressReporter(target func() interfaces.ProgressReporter) *progressReporter {
+	r := &progressReporter{
+		target:     target,
+		reports:    make(chan *interfaces.ApiPlaybackState, config.ReportQueueSize),
+		retries:    config.ReportRetries,
+		retryDelay: config.ReportRetryDelay,
+	}
+	go r.work()
+	return r
+}
+
+// timeUpdateDue returns true if enough time has passed since last report to send time update.
+func (r *progressReporter) timeUpdateDue() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return time.Since(r.lastReport) >= config.ReportInterval
+}
+
+// send queues reports. Time update identical to previous report is dropped, unless
+// config.ReportRepeatInterval has passed. If queue is full, report is dropped.
+func (r *progressReporter) send(reports ...*interfaces.ApiPlaybackState) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, v := range reports {
+		if v.Event == interfaces.EventTimeUpdate && sameReport(r.last, v) &&
+			time.Since(r.lastReport) < config.ReportRepeatInterval {
+			logrus.Trace("Skip duplicate progress report")
+			continue
+		}
+		select {
+		case r.reports <- v:
+			r.last = v
+			r.lastReport = time.Now()
+		default:
+			logrus.Warningf("progress report queue is full, drop %s report", v.Event)
+		}
+	}
+}
+
+func (r *progressReporter) work() {
+	for report := range r.reports {
+		r.report(report)
+	}
+}
+
+// report sends single report, retrying config.ReportRetries times on failure.
+func (r *progressReporter) report(state *interfaces.ApiPlaybackState) {
+	delay := r.retryDelay
+	for i := 0; ; i++ {
+		err := r.target().ReportProgress(state)
+		if err == nil {
+			return
+		}
+		if i >= r.retries {
+			logrus.Errorf("report audio progress to server: %v, drop %s report", err, state.Event)
+			return
+		}
+		logrus.Warningf("report audio progress to server: %v, retry in %s", err, delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// sameReport returns true if reports describe same playback state.
+func sameReport(a, b *interfaces.ApiPlaybackState) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	if a.Event != b.Event || a.ItemId != b.ItemId || a.Position != b.Position || a.IsPaused != b.IsPaused ||
+		a.IsMuted != b.IsMuted || a.Volume != b.Volume || a.Shuffle != b.Shuffle || a.QueueIndex != b.QueueIndex || len(a.Queue) != len(b.Queue) {
+		return false
+	}
+	for i := range a.Queue {
+		if a.Queue[i] != b.Queue[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package player
+
+import (
+	"testing"
+	"time"
+
+	"tryffel.net/go/jellycli/interfaces"
+	"tryffel.net/go/jellycli/models"
+)
+
+func newTestReporter(browser *fakeApi) *progressReporter {
+	r := newProgressReporter(func() interfaces.ProgressReporter { return browser })
+	r.retryDelay = time.Millisecond * 20
+	return r
+}
+
+func TestProgressReporter_SkipDuplicates(t *testing.T) {
+	browser := &fakeApi{}
+	r := newTestReporter(browser)
+	update := func(position int) *interfaces.ApiPlaybackState {
+		return &interfaces.ApiPlaybackState{
+			Event:    interfaces.EventTimeUpdate,
+			ItemId:   "a",
+			Position: position,
+			Queue:    []models.Id{"a", "b"},
+		}
+	}
+	pause := &interfaces.ApiPlaybackState{Event: interfaces.EventPause, ItemId: "a", Position: 10, IsPaused: true}
+
+	r.send(update(10))
+	// identical time update is dropped, other events are always sent
+	r.send(update(10))
+	r.send(pause, pause)
+	r.send(update(11))
+	queueChanged := update(11)
+	queueChanged.Queue = []models.Id{"a", "c"}
+	r.send(queueChanged)
+
+	reports := browser.waitReports(t, 5)
+	time.Sleep(time.Millisecond * 50)
+	if n := len(browser.getReports()); n != 5 {
+		t.Fatalf("expected 5 reports, got %d", n)
+	}
+	want := []interfaces.ApiPlaybackEvent{interfaces.EventTimeUpdate, interfaces.EventPause, interfaces.EventPause,
+		interfaces.EventTimeUpdate, interfaces.EventTimeUpdate}
+	for i, v := range want {
+		if reports[i].Event != v {
+			t.Errorf("report %d: expected %s, got %s", i, v, reports[i].Event)
+		}
+	}
+	if reports[3].Position != 11 || reports[4].Queue[1] != "c" {
+		t.Errorf("expected changed time updates to be sent, got %v and %v", reports[3], reports[4])
+	}
+}
+
+func TestProgressReporter_Retry(t *testing.T) {
+	browser := &fakeApi{fail: 2}
+	r := newTestReporter(browser)
+	r.send(&interfaces.ApiPlaybackState{Event: interfaces.EventStart, ItemId: "a"})
+
+	browser.waitReports(t, 3)
+	time.Sleep(time.Millisecond * 100)
+	browser.lock.Lock()
+	received := browser.received
+	browser.lock.Unlock()
+	if len(received) != 3 {
+		t.Fatalf("expected report to succeed on 3rd attempt, got %d attempts", len(received))
+	}
+	// delay doubles after each failure
+	first, second := received[1].Sub(received[0]), received[2].Sub(received[1])
+	if first < r.retryDelay || second < 2*r.retryDelay {
+		t.Errorf("expected backoff of %v and %v, got %v and %v", r.retryDelay, 2*r.retryDelay, first, second)
+	}
+}
+
+func TestProgressReporter_DropAfterRetries(t *testing.T) {
+	browser := &fakeApi{fail: 100}
+	r := newTestReporter(browser)
+	r.retries = 2
+	r.send(&interfaces.ApiPlaybackState{Event: interfaces.EventStart, ItemId: "a"})
+	browser.waitReports(t, 3)
+
+	// server recovers, next report is sent after failed one is dropped
+	browser.lock.Lock()
+	browser.fail = 0
+	browser.lock.Unlock()
+	r.send(&interfaces.ApiPlaybackState{Event: interfaces.EventStop, ItemId: "a"})
+	reports := browser.waitReports(t, 4)
+	time.Sleep(time.Millisecond * 100)
+	if n := len(browser.getReports()); n != 4 {
+		t.Fatalf("expected 3 attempts and 1 report, got %d reports", n)
+	}
+	for i := 0; i < 3; i++ {
+		if reports[i].Event != interfaces.EventStart {
+			t.Errorf("attempt %d: expected start, got %s", i, reports[i].Event)
+		}
+	}
+	if reports[3].Event != interfaces.EventStop {
+		t.Errorf("expected stop, got %s", reports[3].Event)
+	}
+}
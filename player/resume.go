@@ -0,0 +1,166 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+	"tryffel.net/go/jellycli/util"
+)
+
+const resumeFile = "resume.json"
+
+// resumeSaveInterval limits how often resume points are written while playing.
+const resumeSaveInterval = time.Second * 30
+
+// resumeMinPosition is minimum position in seconds that is worth resuming from.
+const resumeMinPosition = 10
+
+func resumePath() string {
+	return path.Join(config.AppConfig.Player.LocalCacheDir, resumeFile)
+}
+
+// resumable returns true if song is long enough to resume from where it was left.
+func resumable(song *models.Song) bool {
+	limit := config.AppConfig.Player.ResumeMinDurationMin
+	return song != nil && limit > 0 && song.Duration >= limit*60
+}
+
+type resumePoint struct {
+	Position int       `json:"position"`
+	Updated  time.Time `json:"updated"`
+}
+
+// resumePoints keeps positions of long items, e.g. audiobooks and podcasts, so that playback continues
+// where it was left. Server keeps positions too, and newer of the two is used.
+type resumePoints struct {
+	lock     sync.Mutex
+	points   map[models.Id]resumePoint
+	changed  bool
+	lastSave time.Time
+}
+
+func newResumePoints() *resumePoints {
+	r := &resumePoints{points: map[models.Id]resumePoint{}}
+	data, err := ioutil.ReadFile(resumePath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logrus.Errorf("read resume points: %v", err)
+		}
+		return r
+	}
+	err = json.Unmarshal(data, &r.points)
+	if err != nil {
+		logrus.Errorf("read resume points: decode json: %v", err)
+	}
+	return r
+}
+
+// position returns position in seconds to start song from, or 0 if song is played from beginning.
+func (r *resumePoints) position(song *models.Song) int {
+	if !resumable(song) {
+		return 0
+	}
+	r.lock.Lock()
+	point, ok := r.points[song.Id]
+	r.lock.Unlock()
+
+	position := song.ResumePosition
+	if ok && (position == 0 || point.Updated.After(song.LastPlayed)) {
+		position = point.Position
+	}
+	if position < resumeMinPosition || playedEnough(position, song.Duration) {
+		return 0
+	}
+	return position
+}
+
+// set remembers position of song. Resume point is removed once song has been played through.
+func (r *resumePoints) set(song *models.Song, position int) {
+	if !resumable(song) {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if position < resumeMinPosition || playedEnough(position, song.Duration) {
+		if _, ok := r.points[song.Id]; ok {
+			delete(r.points, song.Id)
+			r.changed = true
+		}
+		return
+	}
+	r.points[song.Id] = resumePoint{Position: position, Updated: time.Now()}
+	r.changed = true
+}
+
+// save writes resume points to disk if they have changed. Unless force, they are written at most once
+// in resumeSaveInterval.
+func (r *resumePoints) save(force bool) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if !r.changed || (!force && time.Since(r.lastSave) < resumeSaveInterval) {
+		return nil
+	}
+	err := writeCacheFile(resumePath(), r.points)
+	if err != nil {
+		return err
+	}
+	r.changed = false
+	r.lastSave = time.Now()
+	return nil
+}
+
+// resumeAt returns position to start song from.
+func (p *Player) resumeAt(song *models.Song) models.AudioTick {
+	position := p.resume.position(song)
+	if position > 0 {
+		logrus.Infof("Resume %s from %s", song.Name, util.SecToString(position))
+	}
+	return models.AudioTick(position * 1000)
+}
+
+// rememberPosition sets resume point of song and writes it to disk. If force is false, it is written
+// only if last write was long enough ago.
+func (p *Player) rememberPosition(song *models.Song, position int, force bool) {
+	if !resumable(song) {
+		return
+	}
+	p.resume.set(song, position)
+	err := p.resume.save(force)
+	if err != nil {
+		logrus.Errorf("save resume points: %v", err)
+	}
+}
+
+// resumeCallback keeps resume point of current song up to date while it plays. Points are written to disk
+// periodically, and when song is skipped or player is stopped.
+func (p *Player) resumeCallback(status models.AudioStatus) {
+	if status.Song == nil || status.State != models.AudioStatePlaying {
+		return
+	}
+	p.rememberPosition(status.Song, status.SongPast.Seconds(), false)
+}
@@ -0,0 +1,110 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package player
+
+import (
+	"math"
+	"time"
+
+	"github.com/faiface/beep"
+	"tryffel.net/go/jellycli/config"
+)
+
+// silenceTrimmer removes silence from the beginning and end of stream. To detect silence at the end,
+// output is delayed by window samples, which are returned without trailing silence once source ends.
+// At most window samples are removed from each end.
+type silenceTrimmer struct {
+	source    beep.Streamer
+	window    int
+	threshold float64
+	// leading is number of samples that can still be skipped at the beginning, -1 once sound is found
+	leading int
+	pending [][2]float64
+	buf     [][2]float64
+	ended   bool
+	err     error
+}
+
+// newSilenceTrimmer returns trimmer with window of config.Player.TrimSilenceMs. If trimLeading is false,
+// e.g. song starts from the middle, only trailing silence is removed.
+func newSilenceTrimmer(source beep.Streamer, sampleRate beep.SampleRate, trimLeading bool) *silenceTrimmer {
+	window := sampleRate.N(time.Duration(config.AppConfig.Player.TrimSilenceMs) * time.Millisecond)
+	t := &silenceTrimmer{
+		source:    source,
+		window:    window,
+		threshold: math.Pow(10, config.AudioSilenceThresholddB/20),
+		leading:   -1,
+		buf:       make([][2]float64, 512),
+	}
+	if trimLeading {
+		t.leading = window
+	}
+	return t
+}
+
+func (t *silenceTrimmer) silent(sample [2]float64) bool {
+	return math.Abs(sample[0]) < t.threshold && math.Abs(sample[1]) < t.threshold
+}
+
+// fill reads source until there is window samples more than needed, or source ends.
+func (t *silenceTrimmer) fill(needed int) {
+	for !t.ended && len(t.pending) < needed+t.window {
+		n, ok := t.source.Stream(t.buf)
+		samples := t.buf[:n]
+		for t.leading > 0 && len(samples) > 0 && t.silent(samples[0]) {
+			samples = samples[1:]
+			t.leading--
+		}
+		if len(samples) > 0 {
+			t.leading = -1
+		}
+		t.pending = append(t.pending, samples...)
+		if !ok {
+			t.ended = true
+			t.err = t.source.Err()
+			t.trimTrailing()
+		}
+	}
+}
+
+// trimTrailing removes silence at the end of pending samples, at most window samples.
+func (t *silenceTrimmer) trimTrailing() {
+	end := len(t.pending)
+	for removed := 0; end > 0 && removed < t.window && t.silent(t.pending[end-1]); removed++ {
+		end--
+	}
+	t.pending = t.pending[:end]
+}
+
+func (t *silenceTrimmer) Stream(samples [][2]float64) (int, bool) {
+	t.fill(len(samples))
+	available := len(t.pending)
+	if !t.ended {
+		available -= t.window
+	}
+	n := copy(samples, t.pending[:available])
+	t.pending = t.pending[n:]
+	if n == 0 && t.ended {
+		return 0, false
+	}
+	return n, true
+}
+
+func (t *silenceTrimmer) Err() error {
+	return t.err
+}
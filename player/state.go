@@ -0,0 +1,110 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+)
+
+const stateFile = "state.json"
+
+// playerState is saved on shutdown and restored on start, if config.Player.RestoreState is enabled.
+type playerState struct {
+	Queue *queueState `json:"queue"`
+	// Position is position of first song in queue
+	Position models.AudioTick   `json:"position"`
+	Volume   models.AudioVolume `json:"volume"`
+}
+
+func statePath() string {
+	return path.Join(config.AppConfig.Player.LocalCacheDir, stateFile)
+}
+
+// saveState writes queue, history and playback position to state file.
+func (p *Player) saveState() error {
+	status := p.Audio.getStatus()
+	state := &playerState{
+		Queue:    p.Queue.getState(),
+		Position: p.Audio.getPastTicks(),
+		Volume:   status.Volume,
+	}
+	if status.State != models.AudioStatePlaying {
+		state.Position = 0
+	}
+
+	return writeCacheFile(statePath(), state)
+}
+
+// writeCacheFile encodes v as json and writes it to file in local cache directory.
+func writeCacheFile(file string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode json: %v", err)
+	}
+	err = os.MkdirAll(config.AppConfig.Player.LocalCacheDir, 0760)
+	if err != nil {
+		return fmt.Errorf("create cache directory: %v", err)
+	}
+	// write to temp file first to not corrupt file if interrupted
+	tmp := file + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0660)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// restoreState reads state file and restores queue, history and position. Missing file is not an error.
+func (p *Player) restoreState() error {
+	data, err := ioutil.ReadFile(statePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	state := &playerState{}
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		return fmt.Errorf("decode json: %v", err)
+	}
+	if state.Queue == nil {
+		return nil
+	}
+
+	logrus.Infof("Restore queue of %d songs", len(state.Queue.Items))
+	p.lock.Lock()
+	p.startPosition = state.Position
+	p.lock.Unlock()
+	if state.Volume > 0 {
+		p.Audio.SetVolume(state.Volume)
+	}
+	p.Audio.SetShuffle(state.Queue.Shuffle)
+	p.Queue.setState(state.Queue)
+	return nil
+}
@@ -0,0 +1,111 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+)
+
+// statsFile contains one json encoded models.PlayRecord per line.
+const statsFile = "stats.jsonl"
+
+func statsPath() string {
+	return path.Join(config.AppConfig.Player.LocalCacheDir, statsFile)
+}
+
+// recordPlay appends play of song to stats file, if config.Player.CollectStats is enabled.
+func (p *Player) recordPlay(song *models.Song, listened int, completed bool) {
+	if !config.AppConfig.Player.CollectStats || song == nil || listened <= 0 {
+		return
+	}
+	record := &models.PlayRecord{
+		SongId:    song.Id,
+		Song:      song.Name,
+		Artist:    song.AlbumArtistName,
+		Album:     song.AlbumName,
+		PlayedAt:  time.Now(),
+		Listened:  listened,
+		Completed: completed,
+	}
+	if record.Artist == "" && len(song.Artists) > 0 {
+		record.Artist = song.Artists[0].Name
+	}
+
+	p.statsLock.Lock()
+	defer p.statsLock.Unlock()
+	err := appendPlayRecord(record)
+	if err != nil {
+		logrus.Errorf("record play statistics: %v", err)
+	}
+}
+
+func appendPlayRecord(record *models.PlayRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encode json: %v", err)
+	}
+	err = os.MkdirAll(config.AppConfig.Player.LocalCacheDir, 0760)
+	if err != nil {
+		return fmt.Errorf("create cache directory: %v", err)
+	}
+	file, err := os.OpenFile(statsPath(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0660)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ReadPlayRecords reads all recorded plays, oldest first. Missing stats file is not an error.
+// Malformed lines, e.g. from interrupted write, are skipped.
+func ReadPlayRecords() ([]*models.PlayRecord, error) {
+	file, err := os.Open(statsPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	records := make([]*models.PlayRecord, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		record := &models.PlayRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			logrus.Debugf("skip invalid stats record: %v", err)
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
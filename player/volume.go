@@ -0,0 +1,102 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package player
+
+import (
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/effects"
+	"github.com/sirupsen/logrus"
+	"tryffel.net/go/jellycli/config"
+	"tryffel.net/go/jellycli/models"
+)
+
+// volumeController applies volume, mute and volume cap to stream. Level is kept when muted or capped, so
+// un-muting or lifting the cap returns to it. Methods must be called with speaker lock held.
+type volumeController struct {
+	effect *effects.Volume
+	level  models.AudioVolume
+	muted  bool
+	// maxLevel caps level, if capped
+	maxLevel models.AudioVolume
+	capped   bool
+}
+
+func newVolumeController(s beep.Streamer) *volumeController {
+	v := &volumeController{
+		effect: &effects.Volume{
+			Streamer: s,
+			Base:     config.AudioVolumeLogBase,
+		},
+		level: models.AudioVolumeMax,
+	}
+	v.apply()
+	return v
+}
+
+func (v *volumeController) Stream(samples [][2]float64) (n int, ok bool) {
+	return v.effect.Stream(samples)
+}
+
+func (v *volumeController) Err() error {
+	return v.effect.Err()
+}
+
+// setLevel sets requested level, which is clamped to valid range.
+func (v *volumeController) setLevel(level models.AudioVolume) {
+	v.level = models.AudioVolume(0).Add(int(level))
+	v.apply()
+}
+
+func (v *volumeController) setMuted(muted bool) {
+	v.muted = muted
+	v.apply()
+}
+
+// setCap limits effective volume to max. If capped is false, the cap is removed.
+func (v *volumeController) setCap(max models.AudioVolume, capped bool) {
+	v.maxLevel = max
+	v.capped = capped
+	v.apply()
+}
+
+// effective returns level that is actually applied when not muted.
+func (v *volumeController) effective() models.AudioVolume {
+	if v.capped && v.level > v.maxLevel {
+		return v.maxLevel
+	}
+	return v.level
+}
+
+func (v *volumeController) apply() {
+	level := v.effective()
+	decibels := float64(volumeTodB(int(level)))
+	logrus.Debugf("Set volume to %d %s -> %.2f Db", level, "%", decibels)
+
+	// settings volume to 0 does not mute audio, set silent to true
+	if decibels <= config.AudioMinVolumedB {
+		v.effect.Silent = true
+		v.effect.Volume = config.AudioMinVolumedB
+	} else if decibels >= config.AudioMaxVolumedB {
+		v.effect.Volume = config.AudioMaxVolumedB
+		v.effect.Silent = v.muted
+	} else {
+		v.effect.Silent = v.muted
+		v.effect.Volume = decibels
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"runtime/debug"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Tasker can be run on background
@@ -44,7 +45,9 @@ type Task struct {
 	initialized bool
 	running     bool
 	chanStop    chan bool
-	loop        func()
+	// stopped is closed once loop returns
+	stopped chan struct{}
+	loop    func()
 }
 
 //IsRunning returns whether task is running or not
@@ -87,7 +90,8 @@ func (t *Task) Start() error {
 	}
 
 	t.running = true
-	go t.run()
+	t.stopped = make(chan struct{})
+	go t.run(t.stopped)
 	return nil
 }
 
@@ -109,12 +113,29 @@ func (t *Task) init() {
 	t.chanStop = make(chan bool, 2)
 }
 
-func (t *Task) run() {
+// Wait waits at most timeout for task loop to return after Stop. It returns false on timeout.
+func (t *Task) Wait(timeout time.Duration) bool {
+	t.lock.RLock()
+	stopped := t.stopped
+	t.lock.RUnlock()
+	if stopped == nil {
+		return true
+	}
+	select {
+	case <-stopped:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (t *Task) run(stopped chan struct{}) {
 	defer t.recoverPanic()
 	t.loop()
 	t.lock.Lock()
 	t.running = false
 	t.lock.Unlock()
+	close(stopped)
 	logrus.Tracef("Task %s stopped", t.Name)
 }
 
@@ -0,0 +1,58 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package util contains small helpers shared by multiple packages.
+package util
+
+import "fmt"
+
+// SecToString formats seconds as mm:ss, or hh:mm:ss if duration is longer than an hour.
+func SecToString(sec int) string {
+	if sec < 0 {
+		sec = 0
+	}
+	hours := sec / 3600
+	minutes := sec / 60 % 60
+	seconds := sec % 60
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds)
+	}
+	return fmt.Sprintf("%d:%02d", minutes, seconds)
+}
+
+// SecToHuman formats duration in short human readable form with locale's units, e.g. "1 h 5 min".
+func SecToHuman(sec int) string {
+	u := getUnits()
+	switch {
+	case sec < 60:
+		return fmt.Sprintf("%s %s", FormatNumber(sec), u.second)
+	case sec < 3600:
+		return fmt.Sprintf("%s %s", FormatNumber(sec/60), u.minute)
+	default:
+		return fmt.Sprintf("%s %s %d %s", FormatNumber(sec/3600), u.hour, sec/60%60, u.minute)
+	}
+}
+
+// SecToStartsIn formats time until something starts, e.g. "in 12 min".
+func SecToStartsIn(sec int) string {
+	u := getUnits()
+	if sec <= 0 {
+		return u.now
+	}
+	return fmt.Sprintf(u.startsIn, SecToHuman(sec))
+}
@@ -0,0 +1,127 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// units are localized duration units and phrases.
+type units struct {
+	hour   string
+	minute string
+	second string
+	now    string
+	// startsIn is format for time until something starts, e.g. 'in %s'.
+	startsIn string
+}
+
+var localeUnits = map[language.Tag]units{
+	language.English: {hour: "h", minute: "min", second: "s", now: "now", startsIn: "in %s"},
+	language.German:  {hour: "Std.", minute: "Min.", second: "Sek.", now: "jetzt", startsIn: "in %s"},
+	language.Finnish: {hour: "t", minute: "min", second: "s", now: "nyt", startsIn: "%s päästä"},
+	language.French:  {hour: "h", minute: "min", second: "s", now: "maintenant", startsIn: "dans %s"},
+	language.Spanish: {hour: "h", minute: "min", second: "s", now: "ahora", startsIn: "en %s"},
+	language.Swedish: {hour: "tim", minute: "min", second: "s", now: "nu", startsIn: "om %s"},
+}
+
+var localeMatcher = language.NewMatcher([]language.Tag{
+	// first one is the default
+	language.English,
+	language.German,
+	language.Finnish,
+	language.French,
+	language.Spanish,
+	language.Swedish,
+})
+
+var locale = struct {
+	lock    sync.RWMutex
+	printer *message.Printer
+	units   units
+}{
+	printer: message.NewPrinter(language.English),
+	units:   localeUnits[language.English],
+}
+
+// SetLocale sets locale for formatting durations and numbers, e.g. 'de' or 'fi-FI'. If name is empty,
+// locale is read from environment (LC_ALL, LC_MESSAGES, LANG). Unsupported languages fall back to english
+// units, but numbers are still formatted according to given locale.
+func SetLocale(name string) error {
+	if name == "" {
+		name = envLocale()
+	}
+	tag := language.English
+	if name != "" {
+		var err error
+		tag, err = language.Parse(name)
+		if err != nil {
+			return fmt.Errorf("invalid locale '%s': %v", name, err)
+		}
+	}
+
+	matched, _, _ := localeMatcher.Match(tag)
+	base, _ := matched.Base()
+	u, ok := localeUnits[language.Make(base.String())]
+	if !ok {
+		u = localeUnits[language.English]
+	}
+
+	locale.lock.Lock()
+	defer locale.lock.Unlock()
+	locale.printer = message.NewPrinter(tag)
+	locale.units = u
+	return nil
+}
+
+func envLocale() string {
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+		// e.g. fi_FI.UTF-8@euro
+		value = strings.SplitN(value, ".", 2)[0]
+		value = strings.SplitN(value, "@", 2)[0]
+		if value == "C" || value == "POSIX" {
+			return ""
+		}
+		return strings.Replace(value, "_", "-", -1)
+	}
+	return ""
+}
+
+func getUnits() units {
+	locale.lock.RLock()
+	defer locale.lock.RUnlock()
+	return locale.units
+}
+
+// FormatNumber formats number with locale's digit grouping, e.g. 1,234 or 1 234.
+func FormatNumber(n int) string {
+	locale.lock.RLock()
+	defer locale.lock.RUnlock()
+	return locale.printer.Sprintf("%d", n)
+}
@@ -0,0 +1,156 @@
+/*
+ * Jellycli is a terminal music player for Jellyfin.
+ * Copyright (C) 2020 Tero Vierimaa
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotatingFile is a log file that is rotated once it grows over MaxSize. Rotated files are named
+// <name>.<timestamp> and at most MaxBackups of them, and none older than MaxAge, are kept.
+type RotatingFile struct {
+	Path string
+	// MaxSize in bytes
+	MaxSize int64
+	// MaxBackups is number of rotated files to keep
+	MaxBackups int
+	// MaxAge of rotated files, 0 keeps files regardless of age
+	MaxAge time.Duration
+
+	lock sync.Mutex
+	file *os.File
+	size int64
+}
+
+// OpenRotatingFile opens or creates log file at path.
+func OpenRotatingFile(path string, maxSize int64, maxBackups int, maxAge time.Duration) (*RotatingFile, error) {
+	r := &RotatingFile{
+		Path:       path,
+		MaxSize:    maxSize,
+		MaxBackups: maxBackups,
+		MaxAge:     maxAge,
+	}
+	err := r.open()
+	if err != nil {
+		return nil, err
+	}
+	r.removeOld()
+	return r, nil
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.MaxSize > 0 && r.size+int64(len(p)) > r.MaxSize && r.size > 0 {
+		err := r.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes log file.
+func (r *RotatingFile) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+func (r *RotatingFile) open() error {
+	err := os.MkdirAll(filepath.Dir(r.Path), 0700)
+	if err != nil {
+		return fmt.Errorf("create log directory: %v", err)
+	}
+	file, err := os.OpenFile(r.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("open log file: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("stat log file: %v", err)
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// rotate renames current file and opens new one. Caller must hold lock.
+func (r *RotatingFile) rotate() error {
+	err := r.file.Close()
+	if err != nil {
+		return fmt.Errorf("close log file: %v", err)
+	}
+	r.file = nil
+	backup := r.Path + "." + time.Now().Format("20060102-150405.000")
+	err = os.Rename(r.Path, backup)
+	if err != nil {
+		return fmt.Errorf("rotate log file: %v", err)
+	}
+	err = r.open()
+	if err != nil {
+		return err
+	}
+	go r.removeOld()
+	return nil
+}
+
+// removeOld removes rotated files exceeding MaxBackups or MaxAge.
+func (r *RotatingFile) removeOld() {
+	files, err := filepath.Glob(r.Path + ".*")
+	if err != nil {
+		return
+	}
+	prefix := r.Path + "."
+	backups := make([]string, 0, len(files))
+	for _, v := range files {
+		if _, err := time.Parse("20060102-150405.000", strings.TrimPrefix(v, prefix)); err == nil {
+			backups = append(backups, v)
+		}
+	}
+	// newest first
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	for i, v := range backups {
+		remove := r.MaxBackups > 0 && i >= r.MaxBackups
+		if !remove && r.MaxAge > 0 {
+			info, err := os.Stat(v)
+			remove = err == nil && time.Since(info.ModTime()) > r.MaxAge
+		}
+		if remove {
+			os.Remove(v)
+		}
+	}
+}